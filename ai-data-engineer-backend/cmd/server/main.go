@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,12 +10,19 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Часовые пояса расписаний пайплайнов не зависят от tzdata в образе
+	_ "time/tzdata"
 
+	"ai-data-engineer-backend/domain/models"
 	repository "ai-data-engineer-backend/domain/repo"
 	"ai-data-engineer-backend/internal/api"
 	"ai-data-engineer-backend/internal/config"
+	"ai-data-engineer-backend/internal/repository/clickhouse"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/repository/postgres"
 	"ai-data-engineer-backend/internal/service"
 	"ai-data-engineer-backend/pkg/logger"
+	"ai-data-engineer-backend/pkg/secrets"
 
 	"ai-data-engineer-backend/pkg/client"
 )
@@ -40,18 +48,48 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Ошибка инициализации репозиториев: %v", err)
 	}
+	defer repositories.Close()
 
 	// Инициализируем сервисы
 	services, err := initializeServices(cfg, logger, repositories)
 	if err != nil {
 		logger.Fatalf("Ошибка инициализации сервисов: %v", err)
 	}
+	defer services.DatabaseService.Close()
+
+	// Фоновые задачи работают до остановки сервера
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if err := services.Instance.Start(backgroundCtx); err != nil {
+		logger.Fatalf("Ошибка регистрации экземпляра сервиса: %v", err)
+	}
+	services.UploadService.StartJanitor(backgroundCtx, cfg.Storage.UploadCleanupInterval)
+	if err := services.AnalysisService.Start(backgroundCtx); err != nil {
+		logger.Fatalf("Ошибка запуска фонового анализа: %v", err)
+	}
+	if err := services.PipelineExecutor.Start(backgroundCtx); err != nil {
+		logger.Fatalf("Ошибка запуска выполнения пайплайнов: %v", err)
+	}
+	if err := services.AirflowService.Start(backgroundCtx); err != nil {
+		logger.Fatalf("Ошибка возобновления отслеживания запусков Airflow: %v", err)
+	}
+	if cfg.Scheduler.Enabled {
+		services.PipelineScheduler.Start(backgroundCtx)
+	}
 
 	// Настраиваем маршруты
 	router := api.SetupRoutes(
 		services.FileService,
-		services.DataAnalyzer,
+		services.UploadService,
+		services.AnalysisService,
+		services.ProgressService,
+		services.DDLService,
+		services.DatabaseService,
+		services.ConnectionService,
+		services.LoadService,
 		services.PipelineService,
+		services.PipelineExecutor,
+		services.AirflowService,
 		services.HealthService,
 		logger,
 	)
@@ -85,6 +123,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopBackground()
 
 	// Graceful shutdown с таймаутом
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -101,31 +140,118 @@ func main() {
 type Repositories struct {
 	Pipeline  repository.PipelineRepository
 	File      repository.FileRepository
+	Upload    repository.UploadSessionRepository
+	Blob      repository.BlobRepository
 	Analysis  repository.AnalysisRepository
 	Execution repository.ExecutionRepository
 	Database  repository.DatabaseRepository
+	// Leases аренды, разделяющие фоновые задачи между экземплярами сервиса
+	Leases repository.LeaseRepository
+	// SavedConnections сохраненные подключения пользователей
+	SavedConnections repository.ConnectionRepository
+	// Connections целевые базы по имени подключения
+	Connections map[string]repository.DatabaseRepository
+
+	db       *sql.DB
+	targetDB *sql.DB
+}
+
+// Close закрывает соединения, открытые репозиториями
+func (r *Repositories) Close() error {
+	if r.targetDB != nil {
+		r.targetDB.Close()
+	}
+	if r.db == nil {
+		return nil
+	}
+	return r.db.Close()
 }
 
 // Services содержит все сервисы
 type Services struct {
+	// Instance экземпляр сервиса, которому принадлежат фоновые задачи
+	Instance        *service.Instance
 	FileService     *service.FileService
-	DataAnalyzer    *service.DataAnalyzer
+	UploadService   *service.UploadService
+	AnalysisService *service.AnalysisService
+	ProgressService *service.ProgressService
+	DDLService      *service.DDLService
+	DatabaseService *service.DatabaseService
+	// ConnectionService сохраненные подключения к целевым базам
+	ConnectionService *service.ConnectionService
+	// LoadService загрузка проанализированных файлов в целевые базы
+	LoadService     *service.LoadService
 	PipelineService *service.PipelineService
-	HealthService   *service.HealthService
+	// PipelineExecutor выполнение пайплайнов в процессе сервера
+	PipelineExecutor *service.PipelineExecutor
+	// PipelineScheduler запуск пайплайнов по расписанию
+	PipelineScheduler *service.PipelineScheduler
+	// AirflowService генерация и публикация DAG Airflow
+	AirflowService *service.AirflowService
+	HealthService  *service.HealthService
 }
 
 // initializeRepositories инициализирует репозитории
 func initializeRepositories(cfg *config.Config, logger logger.Logger) (*Repositories, error) {
-	// Заглушки для репозиториев - будут реализованы в следующих итерациях
-	logger.Info("Initializing repositories (stub implementation)")
-
-	return &Repositories{
-		// Pipeline:  repository.NewPostgreSQLPipelineRepository(cfg, logger),
-		// File:      repository.NewPostgreSQLFileRepository(cfg, logger),
-		// Analysis:  repository.NewPostgreSQLAnalysisRepository(cfg, logger),
-		// Execution: repository.NewPostgreSQLExecutionRepository(cfg, logger),
-		// Database:  repository.NewDatabaseRepository(cfg, logger),
-	}, nil
+	switch cfg.Database.Driver {
+	case "memory":
+		logger.Info("Initializing in-memory repositories")
+		database := memory.NewDatabaseRepository()
+		return &Repositories{
+			Pipeline:         memory.NewPipelineRepository(),
+			File:             memory.NewFileRepository(),
+			Upload:           memory.NewUploadSessionRepository(),
+			Blob:             memory.NewBlobRepository(),
+			Analysis:         memory.NewAnalysisRepository(),
+			Execution:        memory.NewExecutionRepository(),
+			Database:         database,
+			Leases:           memory.NewLeaseRepository(),
+			SavedConnections: memory.NewConnectionRepository(),
+			Connections: map[string]repository.DatabaseRepository{
+				"memory":     database,
+				"clickhouse": newClickHouseRepository(cfg, logger),
+			},
+		}, nil
+	case "postgres", "":
+		logger.Info("Initializing PostgreSQL repositories")
+
+		db, err := postgres.NewDB(context.Background(), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
+
+		connections := map[string]repository.DatabaseRepository{
+			"clickhouse": newClickHouseRepository(cfg, logger),
+		}
+		// База сервиса не может быть целью: загрузка и интроспекция затронули бы ее таблицы
+		var targetDB *sql.DB
+		if cfg.Database.TargetPostgresDSN != "" {
+			targetDB, err = sql.Open("postgres", cfg.Database.TargetPostgresDSN)
+			if err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to open target PostgreSQL: %w", err)
+			}
+			connections["postgres"] = postgres.NewDatabaseRepository(targetDB, logger)
+		}
+
+		database := postgres.NewDatabaseRepository(db, logger)
+		return &Repositories{
+			Pipeline:         postgres.NewPipelineRepository(db, logger),
+			File:             postgres.NewFileRepository(db, logger),
+			Upload:           postgres.NewUploadSessionRepository(db, logger),
+			Blob:             postgres.NewBlobRepository(db, logger),
+			Analysis:         postgres.NewAnalysisRepository(db, logger),
+			Execution:        postgres.NewExecutionRepository(db, logger),
+			Database:         database,
+			Leases:           postgres.NewLeaseRepository(db, logger),
+			SavedConnections: postgres.NewConnectionRepository(db, logger),
+			Connections:      connections,
+			db:               db,
+			targetDB:         targetDB,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Database.Driver)
+	}
 }
 
 // initializeServices инициализирует сервисы
@@ -134,28 +260,188 @@ func initializeServices(cfg *config.Config, logger logger.Logger, repos *Reposit
 
 	// Создаем LLM клиент
 	llmClient := client.NewLLMClient(cfg.LLM.BaseURL, cfg.LLM.APIKey, logger, cfg.LLM.Endpoints)
-	// Создаем MinIO клиент
-	minioClient, err := client.NewMinIOClient(
-		cfg.Storage.Endpoint,
-		cfg.Storage.AccessKey,
-		cfg.Storage.SecretKey,
-		cfg.Storage.UseSSL,
-		logger,
-	)
+	// Создаем клиент хранилища файлов
+	storageClient, err := newStorageClient(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+		return nil, err
 	}
 
-	// Создаем анализатор данных с LLM клиентом
-	dataAnalyzer := service.NewDataAnalyzer(logger, llmClient)
+	// Создаем анализатор данных с LLM клиентом и нативным профилировщиком
+	dataAnalyzer := service.NewDataAnalyzer(logger, llmClient, storageClient, repos.File)
+	// Фоновые задачи сохраняют владельца; задачи остановленного экземпляра восстанавливают другие
+	instance := service.NewInstance(repos.Leases, service.InstanceOptions{
+		ID:       cfg.Instance.ID,
+		LeaseTTL: cfg.Instance.LeaseTTL,
+	}, logger)
+	// Анализы выполняются в фоне пулом воркеров; ход анализа доступен через SSE
+	broker := service.NewEventBroker(0, 0)
+	analysisService := service.NewAnalysisService(dataAnalyzer, repos.Analysis, repos.File, broker, service.AnalysisOptions{
+		Workers:   cfg.Analysis.Workers,
+		QueueSize: cfg.Analysis.QueueSize,
+		Timeout:   cfg.Analysis.Timeout,
+		Instance:  instance,
+	}, logger)
+
+	// Создаем сервисы с зависимостями; одинаковое содержимое файлов хранится один раз
+	blobStore := service.NewBlobStore(storageClient, repos.Blob, logger)
+	fileService := service.NewFileService(storageClient, repos.File, blobStore, service.FileServiceOptions{
+		MaxUploadSize: cfg.Storage.MaxUploadSize,
+		PresignExpiry: cfg.Storage.PresignExpiry,
+	}, logger)
+	uploadService := service.NewUploadService(storageClient, repos.Upload, repos.File, blobStore, service.UploadOptions{
+		SessionTTL:    cfg.Storage.UploadSessionTTL,
+		PartSize:      cfg.Storage.UploadPartSize,
+		MaxUploadSize: cfg.Storage.MaxUploadSize,
+		Instance:      instance,
+	}, logger)
 
-	// Создаем сервисы с зависимостями
-	fileService := service.NewFileService(minioClient, logger)
+	// Пароли сохраненных подключений шифруются ключом из конфигурации
+	cipher, err := newSecretsCipher(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	healthService := service.NewHealthService(logger)
+	connectionService := service.NewConnectionService(repos.SavedConnections, cipher, healthService, logger)
+	databaseService := service.NewDatabaseService(repos.Connections, connectionService, openDatabase(logger), logger)
+	loadService := service.NewLoadService(storageClient, repos.Analysis, repos.File, databaseService, logger)
+
+	// Пайплайны выполняются в процессе сервера; ход выполнения доступен через SSE
+	pipelineExecutor := service.NewPipelineExecutor(repos.Pipeline, repos.Execution, broker, service.ExecutorOptions{
+		Parallelism:        cfg.Pipeline.Parallelism,
+		Instance:           instance,
+		CancelPollInterval: cfg.Pipeline.CancelPollInterval,
+	}, logger)
+	service.RegisterDefaultRunners(pipelineExecutor, repos.Analysis, loadService)
+	// Запуски по расписанию ставит в очередь один экземпляр сервиса, удерживающий аренду
+	pipelineScheduler := service.NewPipelineScheduler(repos.Pipeline, repos.Leases, pipelineExecutor, service.SchedulerOptions{
+		Interval:   cfg.Scheduler.Interval,
+		LeaseTTL:   cfg.Scheduler.LeaseTTL,
+		CatchUp:    models.CatchUpPolicy(cfg.Scheduler.CatchUp),
+		MaxCatchUp: cfg.Scheduler.MaxCatchUp,
+	}, logger)
+	// DAG Airflow публикуются в каталог dags_path или в бакет хранилища dags_bucket
+	// и запускаются через REST API Airflow по адресу base_url
+	var airflowClient client.AirflowClient
+	if cfg.Airflow.BaseURL != "" {
+		airflowClient = client.NewAirflowClient(client.AirflowOptions{
+			BaseURL:  cfg.Airflow.BaseURL,
+			Username: cfg.Airflow.Username,
+			Password: cfg.Airflow.Password,
+		}, logger)
+	}
+	airflowService := service.NewAirflowService(repos.Pipeline, repos.Execution, storageClient, airflowClient, broker, service.AirflowOptions{
+		DAGsPath:     cfg.Airflow.DAGsPath,
+		DAGsBucket:   cfg.Airflow.DAGsBucket,
+		DAGsPrefix:   cfg.Airflow.DAGsPrefix,
+		PollInterval: cfg.Airflow.PollInterval,
+		Instance:     instance,
+	}, logger)
 
 	return &Services{
-		FileService:     fileService,
-		DataAnalyzer:    dataAnalyzer,
-		PipelineService: service.NewPipelineService(logger),
-		HealthService:   service.NewHealthService(logger),
+		Instance:          instance,
+		FileService:       fileService,
+		UploadService:     uploadService,
+		AnalysisService:   analysisService,
+		ProgressService:   service.NewProgressService(broker, repos.Analysis, repos.Execution),
+		DDLService:        service.NewDDLService(repos.Analysis, databaseService, logger),
+		DatabaseService:   databaseService,
+		ConnectionService: connectionService,
+		LoadService:       loadService,
+		PipelineService:   service.NewPipelineService(repos.Pipeline, repos.Analysis, logger),
+		PipelineExecutor:  pipelineExecutor,
+		PipelineScheduler: pipelineScheduler,
+		AirflowService:    airflowService,
+		HealthService:     healthService,
 	}, nil
 }
+
+// newClickHouseRepository создает репозиторий целевого ClickHouse из конфигурации.
+// Соединение устанавливается при первом запросе
+func newClickHouseRepository(cfg *config.Config, logger logger.Logger) repository.DatabaseRepository {
+	clickhouseClient := client.NewClickHouseClient(client.ClickHouseOptions{
+		URL:      cfg.GetClickHouseURL(),
+		User:     cfg.Database.ClickHouse.User,
+		Password: cfg.Database.ClickHouse.Password,
+		Database: cfg.Database.ClickHouse.DBName,
+	}, logger)
+	return clickhouse.NewDatabaseRepository(clickhouseClient, logger)
+}
+
+// newSecretsCipher создает шифр секретов из security.encryption_key или файла ключа.
+// Случайный ключ допустим только для драйвера memory: подключения, сохраненные в PostgreSQL,
+// после перезапуска нельзя было бы расшифровать, поэтому без ключа сервер не запускается
+func newSecretsCipher(cfg *config.Config, logger logger.Logger) (*secrets.Cipher, error) {
+	var key []byte
+	var err error
+	if cfg.Security.EncryptionKey == "" && cfg.Security.EncryptionKeyFile == "" {
+		if cfg.Database.Driver != "memory" {
+			return nil, fmt.Errorf("security.encryption_key or security.encryption_key_file is required for database driver %q", cfg.Database.Driver)
+		}
+		logger.Warn("Encryption key is not configured, saved connection passwords will not survive restart")
+		key, err = secrets.GenerateKey()
+	} else {
+		key, err = secrets.LoadKey(cfg.Security.EncryptionKey, cfg.Security.EncryptionKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	return secrets.NewCipher(key)
+}
+
+// openDatabase открывает целевую базу сохраненного подключения
+func openDatabase(logger logger.Logger) service.DatabaseOpener {
+	return func(params *models.DatabaseTestRequest) (repository.DatabaseRepository, func() error, error) {
+		switch params.Type {
+		case "postgres":
+			db, err := sql.Open("postgres", params.PostgresDSN())
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open postgres connection: %w", err)
+			}
+			db.SetMaxOpenConns(5)
+			db.SetConnMaxIdleTime(5 * time.Minute)
+			return postgres.NewDatabaseRepository(db, logger), db.Close, nil
+		case "clickhouse":
+			clickhouseClient := client.NewClickHouseClient(client.ClickHouseOptions{
+				URL:      params.ClickHouseURL(),
+				User:     params.User,
+				Password: params.Password,
+				Database: params.DBName,
+			}, logger)
+			return clickhouse.NewDatabaseRepository(clickhouseClient, logger), nil, nil
+		default:
+			return nil, nil, fmt.Errorf("unsupported database type %q", params.Type)
+		}
+	}
+}
+
+// newStorageClient создает клиент хранилища по storage.type
+func newStorageClient(cfg *config.Config, logger logger.Logger) (service.MultipartStorage, error) {
+	switch cfg.Storage.Type {
+	case "filesystem":
+		logger.WithField("base_path", cfg.Storage.BasePath).Info("Using filesystem storage")
+		storageClient, err := client.NewFilesystemClient(cfg.Storage.BasePath, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create filesystem storage: %w", err)
+		}
+		return storageClient, nil
+	case "minio", "":
+		minioClient, err := client.NewMinIOClient(
+			cfg.Storage.Endpoint,
+			cfg.Storage.AccessKey,
+			cfg.Storage.SecretKey,
+			cfg.Storage.UseSSL,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+		}
+		if cfg.Storage.PublicEndpoint != "" {
+			if err := minioClient.UsePublicEndpoint(cfg.Storage.PublicEndpoint, cfg.Storage.UseSSL, cfg.Storage.Region); err != nil {
+				return nil, err
+			}
+		}
+		return minioClient, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Storage.Type)
+	}
+}
@@ -1,7 +1,21 @@
 package models
 
+import (
+	"time"
+)
+
+// AnalysisResult результат анализа файла
 type AnalysisResult struct {
-	UserId         string       `json:"user_id"`
-	AnalysisResult *LLMResponse `json:"analysis_result"`
-	Status         string       `json:"status"`
+	ID             string         `json:"id,omitempty"`
+	UserId         string         `json:"user_id"`
+	FileID         string         `json:"file_id,omitempty"`
+	FilePath       string         `json:"file_path,omitempty"`
+	Profile        *DataProfile   `json:"profile,omitempty"`
+	AnalysisResult *LLMResponse   `json:"analysis_result"`
+	Status         AnalysisStatus `json:"status"`
+	Error          string         `json:"error,omitempty"` // причина ошибки для статуса failed
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	// Owner экземпляр сервиса, в очереди которого находится незавершенный анализ
+	Owner string `json:"-"`
 }
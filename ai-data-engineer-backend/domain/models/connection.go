@@ -0,0 +1,69 @@
+package models
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// ConnectionProfile сохраненное подключение к целевой базе данных.
+// Пароль хранится зашифрованным и никогда не возвращается в ответах
+type ConnectionProfile struct {
+	ID      string `json:"id"`
+	UserID  string `json:"user_id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Host    string `json:"host"`
+	Port    string `json:"port"`
+	User    string `json:"user"`
+	DBName  string `json:"dbname"`
+	SSLMode string `json:"sslmode,omitempty"`
+	Secure  bool   `json:"secure,omitempty"`
+	// HasPassword сообщает, что для подключения сохранен пароль
+	HasPassword bool `json:"has_password"`
+	// Secret зашифрованный пароль (AES-GCM, ID профиля — дополнительные данные)
+	Secret    []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Params возвращает параметры подключения профиля с паролем password
+func (p *ConnectionProfile) Params(password string) *DatabaseTestRequest {
+	return &DatabaseTestRequest{
+		Type:     p.Type,
+		Host:     p.Host,
+		Port:     p.Port,
+		User:     p.User,
+		Password: password,
+		DBName:   p.DBName,
+		SSLMode:  p.SSLMode,
+		Secure:   p.Secure,
+	}
+}
+
+// PostgresDSN возвращает строку подключения key=value к PostgreSQL. Значения экранируются,
+// sslmode по умолчанию disable
+func (r *DatabaseTestRequest) PostgresDSN() string {
+	sslMode := r.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	parts := make([]string, 0, 6)
+	for _, param := range [][2]string{
+		{"host", r.Host}, {"port", r.Port}, {"user", r.User}, {"password", r.Password},
+		{"dbname", r.DBName}, {"sslmode", sslMode},
+	} {
+		parts = append(parts, param[0]+"='"+escape.Replace(param[1])+"'")
+	}
+	return strings.Join(parts, " ")
+}
+
+// ClickHouseURL возвращает адрес HTTP интерфейса ClickHouse; Secure включает HTTPS
+func (r *DatabaseTestRequest) ClickHouseURL() string {
+	protocol := "http"
+	if r.Secure {
+		protocol = "https"
+	}
+	return protocol + "://" + net.JoinHostPort(r.Host, r.Port)
+}
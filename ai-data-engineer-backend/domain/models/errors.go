@@ -144,6 +144,84 @@ func NewPipelineNotFoundError(pipelineID string) *AppError {
 	}
 }
 
+// NewAnalysisNotFoundError создает ошибку "анализ не найден"
+func NewAnalysisNotFoundError(analysisID string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Анализ не найден",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"analysis_id": analysisID},
+	}
+}
+
+// NewExecutionNotFoundError создает ошибку "выполнение не найдено"
+func NewExecutionNotFoundError(executionID string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Выполнение пайплайна не найдено",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"execution_id": executionID},
+	}
+}
+
+// NewBlobNotFoundError создает ошибку "объект не найден"
+func NewBlobNotFoundError(checksum string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Объект с таким содержимым не найден",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"checksum": checksum},
+	}
+}
+
+// NewUploadSessionNotFoundError создает ошибку "сессия загрузки не найдена"
+func NewUploadSessionNotFoundError(sessionID string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Сессия загрузки не найдена",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"upload_id": sessionID},
+	}
+}
+
+// NewTableNotFoundError создает ошибку "таблица не найдена"
+func NewTableNotFoundError(tableName string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Таблица не найдена",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"table_name": tableName},
+	}
+}
+
+// NewConnectionNotFoundError создает ошибку "подключение не найдено"
+func NewConnectionNotFoundError(connection string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeNotFound,
+		Message:  "Подключение к базе данных не найдено",
+		HTTPCode: http.StatusNotFound,
+		Details:  map[string]interface{}{"connection": connection},
+	}
+}
+
+// NewServiceUnavailableError создает ошибку временной недоступности сервиса
+func NewServiceUnavailableError(message string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeServiceUnavailable,
+		Message:  message,
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+}
+
+// NewConflictError создает ошибку конфликта
+func NewConflictError(message string) *AppError {
+	return &AppError{
+		Code:     ErrorCodeConflict,
+		Message:  message,
+		HTTPCode: http.StatusConflict,
+	}
+}
+
 // NewInternalError создает внутреннюю ошибку
 func NewInternalError(message string, cause error) *AppError {
 	return &AppError{
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// ProgressEvent событие хода анализа или выполнения пайплайна.
+// ID возрастает в пределах одного потока и передается клиенту как id события SSE
+type ProgressEvent struct {
+	ID        int64       `json:"id"`
+	Type      EventType   `json:"type"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// EventType тип события хода выполнения
+type EventType string
+
+const (
+	EventTypeStatus  EventType = "status"  // смена статуса анализа или выполнения
+	EventTypeStage   EventType = "stage"   // переход к следующему этапу
+	EventTypeProfile EventType = "profile" // промежуточный результат: профиль данных
+	EventTypeLog     EventType = "log"     // строка ExecutionLog
+	EventTypeDone    EventType = "done"    // завершающее событие, после него поток закрывается
+)
+
+// DoneEventID номер события EventTypeDone. Он одинаков в живом потоке и в потоке, восстановленном
+// из репозитория, и больше номера любого промежуточного события, поэтому клиент, получивший done,
+// переподключается с Last-Event-ID не меньше DoneEventID. Значение точно представимо в JavaScript
+const DoneEventID int64 = 1<<53 - 1
+
+// StageEvent данные события EventTypeStage
+type StageEvent struct {
+	Stage   string `json:"stage"`
+	Message string `json:"message,omitempty"`
+	StepID  string `json:"step_id,omitempty"`
+}
+
+// StatusEvent данные событий EventTypeStatus и EventTypeDone
+type StatusEvent struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EventSubscription подписка на поток событий.
+// Replay содержит события после Last-Event-ID, накопленные до подписки;
+// Events закрывается после завершающего события или при отставании подписчика
+type EventSubscription struct {
+	Replay   []ProgressEvent
+	Events   <-chan ProgressEvent
+	Finished bool // поток уже завершен, новых событий не будет
+	Cancel   func()
+}
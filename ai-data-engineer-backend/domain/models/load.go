@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// LoadRequest запрос на загрузку проанализированного файла в таблицу целевой базы.
+// Колонки файла сопоставляются с колонками таблицы по схеме, построенной из профиля анализа,
+// или по Mapping, если схема таблицы задана явно
+type LoadRequest struct {
+	AnalysisID string `json:"analysis_id" binding:"required"`
+	// Connection имя подключения или ID сохраненного подключения
+	Connection string `json:"connection" binding:"required"`
+	// TableName имя существующей таблицы, может содержать схему: staging.orders
+	TableName string `json:"table_name" binding:"required"`
+	// TableSchema схема таблицы; по умолчанию строится из профиля анализа
+	TableSchema *TableSchema `json:"table_schema,omitempty"`
+	// Mapping колонка таблицы → колонка файла (имя из профиля анализа)
+	Mapping map[string]string `json:"mapping,omitempty"`
+	// MaxRejects прерывает загрузку, если отклонено больше строк; 0 — без ограничения
+	MaxRejects int64 `json:"max_rejects,omitempty" binding:"omitempty,min=0"`
+	// BatchSize число строк в одном INSERT для ClickHouse; 0 — по умолчанию
+	BatchSize int `json:"batch_size,omitempty" binding:"omitempty,min=1,max=1000000"`
+	// IdempotencyKey ключ повторной загрузки в ClickHouse. По умолчанию строится из анализа,
+	// таблицы и колонок, поэтому повтор неудавшейся загрузки не дублирует уже вставленные батчи.
+	// Новый ключ нужен, чтобы намеренно загрузить те же данные еще раз
+	IdempotencyKey string `json:"idempotency_key,omitempty" binding:"max=200"`
+}
+
+// BulkInsertOptions параметры массовой загрузки
+type BulkInsertOptions struct {
+	// BatchSize число строк в одном запросе; 0 — по умолчанию репозитория
+	BatchSize int
+	// DedupKey ключ идемпотентности: токен дедупликации батча строится из него и номера батча
+	DedupKey string
+}
+
+// LoadColumn сопоставление колонки таблицы с колонкой файла
+type LoadColumn struct {
+	Column string `json:"column"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	// SourceIndex номер колонки в файле, начиная с 0
+	SourceIndex int `json:"source_index"`
+}
+
+// LoadResult результат загрузки файла в таблицу
+type LoadResult struct {
+	ID         string `json:"id"`
+	AnalysisID string `json:"analysis_id"`
+	Connection string `json:"connection"`
+	Database   string `json:"database"`
+	TableName  string `json:"table_name"`
+	// IdempotencyKey ключ дедупликации батчей ClickHouse
+	IdempotencyKey string       `json:"idempotency_key,omitempty"`
+	Columns        []LoadColumn `json:"columns"`
+	RowsRead       int64        `json:"rows_read"`
+	RowsLoaded     int64        `json:"rows_loaded"`
+	RowsRejected   int64        `json:"rows_rejected"`
+	// RejectsBucket и RejectsPath CSV с отклоненными строками: номер строки, причина и исходные значения
+	RejectsBucket  string    `json:"rejects_bucket,omitempty"`
+	RejectsPath    string    `json:"rejects_path,omitempty"`
+	BytesRead      int64     `json:"bytes_read"`
+	DurationMs     int64     `json:"duration_ms"`
+	RowsPerSecond  float64   `json:"rows_per_second"`
+	BytesPerSecond float64   `json:"bytes_per_second"`
+	StartedAt      time.Time `json:"started_at"`
+	CompletedAt    time.Time `json:"completed_at"`
+}
@@ -15,6 +15,7 @@ type Pipeline struct {
 	Source      DataSource             `json:"source" gorm:"type:jsonb"`
 	Target      DataTarget             `json:"target" gorm:"type:jsonb"`
 	Steps       []PipelineStep         `json:"steps" gorm:"type:jsonb"`
+	Schedule    *PipelineSchedule      `json:"schedule,omitempty" gorm:"type:jsonb"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 	ExecutedAt  *time.Time             `json:"executed_at,omitempty"`
@@ -32,6 +33,39 @@ const (
 	PipelineStatusCancelled PipelineStatus = "cancelled"
 )
 
+// PipelineSchedule расписание запусков пайплайна
+type PipelineSchedule struct {
+	// Cron выражение из пяти полей (минута час день месяц день_недели) или макрос @daily, @hourly и др.
+	Cron string `json:"cron" binding:"required"`
+	// Timezone часовой пояс IANA, в котором вычисляется расписание; по умолчанию UTC
+	Timezone string `json:"timezone,omitempty"`
+	// CatchUp обработка запусков, пропущенных, пока сервер не работал; по умолчанию из конфигурации
+	CatchUp CatchUpPolicy `json:"catch_up,omitempty"`
+	// NextRunAt время следующего запуска, вычисляется сервером
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	// LastRunAt время последнего запуска по расписанию
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+}
+
+// CatchUpPolicy обработка пропущенных запусков по расписанию
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone пропущенные запуски не выполняются
+	CatchUpNone CatchUpPolicy = "none"
+	// CatchUpLatest выполняется один запуск за все пропущенные
+	CatchUpLatest CatchUpPolicy = "latest"
+	// CatchUpAll выполняется каждый пропущенный запуск (с ограничением количества)
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+// Lease аренда, которую в каждый момент удерживает один экземпляр сервиса
+type Lease struct {
+	Name      string    `json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // DataSource источник данных
 type DataSource struct {
 	Type   string                 `json:"type"`
@@ -42,7 +76,9 @@ type DataSource struct {
 
 // DataTarget целевая система
 type DataTarget struct {
-	Type             string                 `json:"type"`
+	Type string `json:"type"`
+	// ConnectionString имя подключения (postgres, clickhouse) или ID сохраненного подключения
+	// из /api/v1/connections; учетные данные в конфигурации пайплайна не хранятся
 	ConnectionString string                 `json:"connection_string,omitempty"`
 	TableName        string                 `json:"table_name,omitempty"`
 	Schema           string                 `json:"schema,omitempty"`
@@ -83,6 +119,35 @@ const (
 	StepStatusSkipped   StepStatus = "skipped"
 )
 
+// StepProblem нарушение графа шагов пайплайна
+type StepProblem struct {
+	Code StepProblemCode `json:"code"`
+	// StepID шаг, к которому относится нарушение
+	StepID string `json:"step_id,omitempty"`
+	// Steps все шаги нарушения, например шаги цикла
+	Steps   []string `json:"steps,omitempty"`
+	Message string   `json:"message"`
+}
+
+// StepProblemCode код нарушения графа шагов
+type StepProblemCode string
+
+const (
+	StepProblemDuplicateID       StepProblemCode = "duplicate_id"
+	StepProblemUnknownType       StepProblemCode = "unknown_type"
+	StepProblemMissingDependency StepProblemCode = "missing_dependency"
+	StepProblemCycle             StepProblemCode = "cycle"
+	StepProblemOrder             StepProblemCode = "invalid_order"
+)
+
+// PipelinePlan порядок выполнения шагов пайплайна. Шаги одного уровня
+// не зависят друг от друга и могут выполняться параллельно
+type PipelinePlan struct {
+	PipelineID string     `json:"pipeline_id"`
+	Order      []string   `json:"order"`
+	Levels     [][]string `json:"levels"`
+}
+
 // DataSchema схема данных
 type DataSchema struct {
 	Fields []DataField              `json:"fields"`
@@ -100,6 +165,10 @@ type PipelineExecution struct {
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	Error       string                 `json:"error,omitempty"`
 	Logs        []ExecutionLog         `json:"logs" gorm:"type:jsonb"`
+	// DAGRunID ID запуска DAG, если выполнение ведется в Airflow
+	DAGRunID string `json:"dag_run_id,omitempty"`
+	// Owner экземпляр сервиса, который ведет выполнение; см. service.Instance
+	Owner string `json:"-"`
 }
 
 // ExecutionStatus статус выполнения
@@ -108,9 +177,11 @@ type ExecutionStatus string
 const (
 	ExecutionStatusScheduled ExecutionStatus = "scheduled"
 	ExecutionStatusRunning   ExecutionStatus = "running"
-	ExecutionStatusCompleted ExecutionStatus = "completed"
-	ExecutionStatusFailed    ExecutionStatus = "failed"
-	ExecutionStatusCancelled ExecutionStatus = "cancelled"
+	// ExecutionStatusCancelling отмена запрошена; выполнение остановит экземпляр сервиса, который его ведет
+	ExecutionStatusCancelling ExecutionStatus = "cancelling"
+	ExecutionStatusCompleted  ExecutionStatus = "completed"
+	ExecutionStatusFailed     ExecutionStatus = "failed"
+	ExecutionStatusCancelled  ExecutionStatus = "cancelled"
 )
 
 // ExecutionLog лог выполнения
@@ -120,3 +191,10 @@ type ExecutionLog struct {
 	Message   string    `json:"message"`
 	StepID    string    `json:"step_id,omitempty"`
 }
+
+// Уровни записей ExecutionLog
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
@@ -9,22 +9,29 @@ type FileUploadRequest struct {
 
 // AnalysisRequest запрос на анализ файла
 type AnalysisRequest struct {
-	FileID   string `json:"file_id" binding:"required"`
-	UserID   string `json:"user_id" binding:"required"`
-	FilePath string `json:"file_path" binding:"required"`
+	FileID   string `json:"file_id"`
+	UserID   string `json:"user_id"`
+	FilePath string `json:"file_path"`
 }
 
-// PipelineRequest запрос на создание пайплайна
+// PipelineRequest запрос на создание или изменение пайплайна. Источник строится
+// из анализа: файл и схема его профиля. Без шагов создается extract → load
 type PipelineRequest struct {
-	AnalysisID string                 `json:"analysis_id" binding:"required"`
-	UserID     string                 `json:"user_id" binding:"required"`
-	Config     map[string]interface{} `json:"config"`
+	AnalysisID  string                 `json:"analysis_id" binding:"required"`
+	UserID      string                 `json:"user_id"`
+	Name        string                 `json:"name" binding:"max=200"`
+	Description string                 `json:"description"`
+	Target      DataTarget             `json:"target" binding:"required"`
+	Steps       []PipelineStep         `json:"steps"`
+	Config      map[string]interface{} `json:"config"`
+	// Schedule расписание запусков; без него пайплайн запускается вручную
+	Schedule *PipelineSchedule `json:"schedule"`
 }
 
-// ExecutePipelineRequest запрос на выполнение пайплайна
+// ExecutePipelineRequest запрос на выполнение пайплайна. Пайплайн задается в пути запроса,
+// тело необязательно
 type ExecutePipelineRequest struct {
-	PipelineID string                 `json:"pipeline_id" binding:"required"`
-	UserID     string                 `json:"user_id" binding:"required"`
+	UserID     string                 `json:"user_id"`
 	Parameters map[string]interface{} `json:"parameters"`
 }
 
@@ -38,4 +45,29 @@ type DatabaseTestRequest struct {
 	DBName   string `json:"dbname" binding:"required"`
 	SSLMode  string `json:"sslmode,omitempty"`
 	Secure   bool   `json:"secure,omitempty"`
+	// TimeoutSeconds время на проверку подключения; по умолчанию 10 секунд
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" binding:"omitempty,min=1,max=60"`
+}
+
+// ConnectionRequest запрос на создание или изменение сохраненного подключения.
+// При изменении пустой пароль сохраняет текущий
+type ConnectionRequest struct {
+	Name   string `json:"name" binding:"required,max=100"`
+	UserID string `json:"user_id,omitempty"`
+	DatabaseTestRequest
+}
+
+// UploadInitRequest запрос на создание сессии загрузки частями
+type UploadInitRequest struct {
+	UserID   string `json:"user_id"`
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"min=0"` // ожидаемый размер файла, 0 — неизвестен
+}
+
+// PresignUploadRequest запрос ссылки на прямую загрузку файла в хранилище
+type PresignUploadRequest struct {
+	UserID   string `json:"user_id"`
+	Filename string `json:"filename" binding:"required"`
+	// Checksum SHA-256 файла в hex: если пользователь уже загружал такой файл, загрузка не нужна
+	Checksum string `json:"checksum,omitempty" binding:"omitempty,len=64,hexadecimal"`
 }
@@ -13,21 +13,60 @@ type FileUploadResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// AnalysisResponse ответ на анализ файла
-type AnalysisResponse struct {
-	Status    string                 `json:"status"`
-	Message   string                 `json:"message"`
-	Result    map[string]interface{} `json:"result"`
-	CreatedAt time.Time              `json:"created_at"`
+// PresignedURL временная ссылка для прямого доступа к объекту в хранилище
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// PipelineResponse ответ на создание пайплайна
-type PipelineResponse struct {
-	PipelineID string                 `json:"pipeline_id"`
+// AnalysisResponse ответ на анализ файла
+type AnalysisResponse struct {
+	AnalysisID string                 `json:"analysis_id,omitempty"`
 	Status     string                 `json:"status"`
 	Message    string                 `json:"message"`
-	Config     map[string]interface{} `json:"config,omitempty"`
+	Result     map[string]interface{} `json:"result"`
+	Error      string                 `json:"error,omitempty"`
 	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at,omitempty"`
+}
+
+// PipelineResponse пайплайн в ответах API
+type PipelineResponse struct {
+	PipelineID  string                 `json:"pipeline_id"`
+	UserID      string                 `json:"user_id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Status      string                 `json:"status"`
+	Message     string                 `json:"message,omitempty"`
+	Source      DataSource             `json:"source"`
+	Target      DataTarget             `json:"target"`
+	Steps       []PipelineStep         `json:"steps"`
+	Config      map[string]interface{} `json:"config,omitempty"`
+	Schedule    *PipelineSchedule      `json:"schedule,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	ExecutedAt  *time.Time             `json:"executed_at,omitempty"`
+}
+
+// NewPipelineResponse формирует ответ по пайплайну
+func NewPipelineResponse(pipeline *Pipeline, message string) *PipelineResponse {
+	return &PipelineResponse{
+		PipelineID:  pipeline.ID,
+		UserID:      pipeline.UserID,
+		Name:        pipeline.Name,
+		Description: pipeline.Description,
+		Status:      string(pipeline.Status),
+		Message:     message,
+		Source:      pipeline.Source,
+		Target:      pipeline.Target,
+		Steps:       pipeline.Steps,
+		Config:      pipeline.Config,
+		Schedule:    pipeline.Schedule,
+		CreatedAt:   pipeline.CreatedAt,
+		UpdatedAt:   pipeline.UpdatedAt,
+		ExecutedAt:  pipeline.ExecutedAt,
+	}
 }
 
 // ExecutePipelineResponse ответ на выполнение пайплайна
@@ -39,6 +78,16 @@ type ExecutePipelineResponse struct {
 	StartedAt   time.Time              `json:"started_at"`
 }
 
+// AirflowDAGResponse DAG Airflow, сформированный по пайплайну
+type AirflowDAGResponse struct {
+	PipelineID string `json:"pipeline_id"`
+	DAGID      string `json:"dag_id"`
+	FileName   string `json:"file_name"`
+	// Location путь к файлу или адрес объекта в хранилище; пусто, если DAG не опубликован
+	Location string `json:"location,omitempty"`
+	Source   string `json:"source"`
+}
+
 // HealthResponse ответ на health check
 type HealthResponse struct {
 	Status    string            `json:"status"`
@@ -68,8 +117,9 @@ type ErrorResponse struct {
 
 // LLMRequest запрос к LLM
 type LLMRequest struct {
-	UserID string `json:"user_id"`
-	// TODO: add file name
+	UserID      string       `json:"user_id"`
+	FileName    string       `json:"file_name,omitempty"`
+	DataProfile *DataProfile `json:"data_profile,omitempty"`
 }
 
 // LLMResponse ответ от LLM
@@ -77,13 +127,16 @@ type LLMResponse struct {
 	Content interface{} `json:"content"`
 }
 
-// GenerateDDLRequest запрос на генерацию DDL
+// GenerateDDLRequest запрос на генерацию DDL. Источник схемы по приоритету:
+// TableSchema, профиль анализа AnalysisID, DataProfile, Schema
 type GenerateDDLRequest struct {
 	Schema      *DataSchema            `json:"schema"`
 	Database    string                 `json:"database"`
 	Options     map[string]interface{} `json:"options,omitempty"`
 	Target      *TargetConfig          `json:"target"`
 	DataProfile *DataProfile           `json:"data_profile"`
+	TableSchema *TableSchema           `json:"table_schema,omitempty"`
+	AnalysisID  string                 `json:"analysis_id,omitempty"`
 }
 
 // TargetConfig конфигурация целевой системы
@@ -99,3 +152,58 @@ type GenerateDDLResponse struct {
 	Tables   []string               `json:"tables,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
+
+// SchemaDiffRequest запрос на сравнение схем таблицы. Исходная схема по приоритету:
+// From, результат анализа FromAnalysisID, живая таблица (LiveTable) в базе подключения
+// Connection; целевая: To или ToAnalysisID
+type SchemaDiffRequest struct {
+	Database       string       `json:"database"`
+	TableName      string       `json:"table_name"`
+	Schema         string       `json:"schema,omitempty"`
+	From           *TableSchema `json:"from,omitempty"`
+	To             *TableSchema `json:"to,omitempty"`
+	FromAnalysisID string       `json:"from_analysis_id,omitempty"`
+	ToAnalysisID   string       `json:"to_analysis_id,omitempty"`
+	LiveTable      bool         `json:"live_table,omitempty"`
+	Connection     string       `json:"connection,omitempty"`
+	// ApproveDestructive включает разрушающие изменения в Statements
+	ApproveDestructive bool `json:"approve_destructive,omitempty"`
+}
+
+// Типы изменений схемы
+const (
+	SchemaChangeAddColumn      = "add_column"
+	SchemaChangeDropColumn     = "drop_column"
+	SchemaChangeAlterType      = "alter_type"
+	SchemaChangeSetNotNull     = "set_not_null"
+	SchemaChangeDropNotNull    = "drop_not_null"
+	SchemaChangePrimaryKey     = "alter_primary_key"
+	SchemaChangeAddIndex       = "add_index"
+	SchemaChangeDropIndex      = "drop_index"
+	SchemaChangeAddConstraint  = "add_constraint"
+	SchemaChangeDropConstraint = "drop_constraint"
+	SchemaChangeComment        = "alter_comment"
+)
+
+// SchemaChange одно изменение схемы и выполняющие его SQL команды
+type SchemaChange struct {
+	Type string `json:"type"`
+	// Object колонка, индекс или ограничение
+	Object      string   `json:"object"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Destructive bool     `json:"destructive"`
+	Reason      string   `json:"reason,omitempty"`
+	Statements  []string `json:"statements"`
+}
+
+// SchemaDiffResponse упорядоченные изменения схемы. Statements содержит команды
+// в порядке выполнения; без подтверждения разрушающие изменения в него не входят
+type SchemaDiffResponse struct {
+	Database         string         `json:"database"`
+	Table            string         `json:"table"`
+	Changes          []SchemaChange `json:"changes"`
+	Statements       []string       `json:"statements"`
+	Destructive      bool           `json:"destructive"`
+	RequiresApproval bool           `json:"requires_approval"`
+}
@@ -6,16 +6,27 @@ import (
 
 // DataField представляет поле данных
 type DataField struct {
-	Name        string  `json:"name"`
-	Type        string  `json:"type"`
-	Nullable    bool    `json:"nullable"`
-	NullCount   int     `json:"null_count"`
-	SampleValue string  `json:"sample_value"`
-	MinValue    float64 `json:"min_value"`
-	MaxValue    float64 `json:"max_value"`
-	Description string  `json:"description"`
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Nullable      bool    `json:"nullable"`
+	NullCount     int     `json:"null_count"`
+	DistinctCount int     `json:"distinct_count"`
+	SampleValue   string  `json:"sample_value"`
+	MinValue      float64 `json:"min_value"`
+	MaxValue      float64 `json:"max_value"`
+	Description   string  `json:"description"`
 }
 
+// Типы полей, которые выводит профилировщик данных
+const (
+	FieldTypeString    = "string"
+	FieldTypeInteger   = "integer"
+	FieldTypeFloat     = "float"
+	FieldTypeBoolean   = "boolean"
+	FieldTypeDate      = "date"
+	FieldTypeTimestamp = "timestamp"
+)
+
 // DataProfile профиль данных
 type DataProfile struct {
 	DataType         string      `json:"data_type"`
@@ -54,6 +65,7 @@ type TableSchema struct {
 	TableName   string            `json:"table_name"`
 	Fields      []TableField      `json:"fields"`
 	PrimaryKey  []string          `json:"primary_key"`
+	SortingKey  []string          `json:"sorting_key,omitempty"` // ORDER BY ClickHouse, если длиннее первичного ключа
 	Indexes     []TableIndex      `json:"indexes"`
 	Constraints []TableConstraint `json:"constraints"`
 }
@@ -74,11 +86,21 @@ type TableIndex struct {
 	Type   string   `json:"type,omitempty"`
 }
 
-// TableConstraint ограничение таблицы
+// TableConstraint ограничение таблицы. unique задается колонками через запятую в Expression,
+// check — колонкой, оператором и значением, foreign_key — колонкой и ссылкой на колонку
+// другой таблицы. Expression у check и foreign_key заполняется только при чтении живой
+// таблицы, если ограничение нельзя представить полями; в DDL такое выражение не попадает
 type TableConstraint struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	Expression string `json:"expression,omitempty"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Expression string      `json:"expression,omitempty"`
+	Column     string      `json:"column,omitempty"`
+	Operator   string      `json:"operator,omitempty"` // =, <>, <, <=, >, >=, in, not_in
+	Value      interface{} `json:"value,omitempty"`    // число, строка, логическое значение или список для in
+	RefSchema  string      `json:"ref_schema,omitempty"`
+	RefTable   string      `json:"ref_table,omitempty"`
+	RefColumn  string      `json:"ref_column,omitempty"`
+	OnDelete   string      `json:"on_delete,omitempty"` // cascade, restrict, set_null, set_default, no_action
 }
 
 // DDLMetadata метаданные DDL
@@ -94,6 +116,7 @@ type FileMetadata struct {
 	Filename    string     `json:"filename"`
 	ContentType string     `json:"content_type"`
 	Size        int64      `json:"size"`
+	Checksum    string     `json:"checksum,omitempty"` // SHA-256 содержимого в hex
 	Path        string     `json:"path"`
 	Bucket      string     `json:"bucket"`
 	Status      FileStatus `json:"status"`
@@ -101,6 +124,18 @@ type FileMetadata struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// Blob объект хранилища с уникальным содержимым. Несколько FileMetadata с одинаковым
+// Checksum ссылаются на один blob; объект удаляется, когда RefCount становится нулевым
+type Blob struct {
+	Checksum  string    `json:"checksum"` // SHA-256 содержимого в hex
+	Bucket    string    `json:"bucket"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	RefCount  int       `json:"ref_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // FileStatus статус файла
 type FileStatus string
 
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// UploadSession сессия возобновляемой загрузки файла частями
+type UploadSession struct {
+	ID           string              `json:"id"`
+	UserID       string              `json:"user_id"`
+	Filename     string              `json:"filename"`
+	ContentType  string              `json:"content_type"`
+	Size         int64               `json:"size,omitempty"` // ожидаемый размер файла, если известен
+	PartSize     int64               `json:"part_size"`      // рекомендуемый размер части
+	Bucket       string              `json:"-"`
+	Path         string              `json:"-"`
+	UploadID     string              `json:"-"` // ID multipart загрузки в хранилище
+	Parts        []UploadPart        `json:"parts"`
+	Status       UploadSessionStatus `json:"status"`
+	FileID       string              `json:"file_id,omitempty"` // назначается при начале сборки
+	Checksum     string              `json:"-"`                 // SHA-256 собранного объекта; пусто, пока объект не собран
+	BlobAcquired bool                `json:"-"`                 // ссылка файла на blob получена; повторно не берется
+	Owner        string              `json:"-"`                 // экземпляр сервиса, который завершает сессию; см. service.Instance
+	ExpiresAt    time.Time           `json:"expires_at"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// UploadedSize возвращает суммарный размер загруженных частей
+func (s *UploadSession) UploadedSize() int64 {
+	var total int64
+	for _, part := range s.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+// UploadPart загруженная часть файла
+type UploadPart struct {
+	Number     int       `json:"number"`
+	Size       int64     `json:"size"`
+	ETag       string    `json:"etag"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// UploadSessionStatus статус сессии загрузки
+type UploadSessionStatus string
+
+const (
+	UploadSessionStatusActive UploadSessionStatus = "active"
+	// UploadSessionStatusCompleting файл собирается; повторное завершение продолжает сборку
+	UploadSessionStatusCompleting UploadSessionStatus = "completing"
+	UploadSessionStatusCompleted  UploadSessionStatus = "completed"
+	UploadSessionStatusAborted    UploadSessionStatus = "aborted"
+	UploadSessionStatusExpired    UploadSessionStatus = "expired"
+)
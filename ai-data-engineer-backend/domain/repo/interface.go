@@ -3,16 +3,43 @@ package repository
 import (
 	"ai-data-engineer-backend/domain/models"
 	"context"
+	"time"
 )
 
 // PipelineRepository интерфейс для работы с пайплайнами
 type PipelineRepository interface {
 	SavePipeline(ctx context.Context, pipeline *models.Pipeline) (string, error)
 	GetPipeline(ctx context.Context, id string) (*models.Pipeline, error)
-	GetPipelinesByUser(ctx context.Context, userID string, limit, offset int) ([]*models.Pipeline, error)
+	// GetPipelinesByUser возвращает пайплайны пользователя, начиная с последних; пустой status — в любом статусе
+	GetPipelinesByUser(ctx context.Context, userID string, status models.PipelineStatus, limit, offset int) ([]*models.Pipeline, error)
+	// UpdatePipeline заменяет пайплайн, если он не выполняется; выполняющийся — ConflictError
 	UpdatePipeline(ctx context.Context, pipeline *models.Pipeline) error
+	// DeletePipeline удаляет пайплайн, если он не выполняется; выполняющийся — ConflictError
 	DeletePipeline(ctx context.Context, id string) error
 	GetPipelinesByStatus(ctx context.Context, status models.PipelineStatus) ([]*models.Pipeline, error)
+	// UpdatePipelineState обновляет статус, шаги и время выполнения, не затрагивая описание и расписание
+	UpdatePipelineState(ctx context.Context, pipeline *models.Pipeline) error
+	// StartPipeline сохраняет состояние пайплайна, переводимого в статус running, только если он
+	// еще не выполняется. Выполняющийся пайплайн — ConflictError: его запустил параллельный запрос,
+	// другой экземпляр сервиса или Airflow
+	StartPipeline(ctx context.Context, pipeline *models.Pipeline) error
+	// GetDuePipelines возвращает пайплайны с расписанием, следующий запуск которых не позже before
+	GetDuePipelines(ctx context.Context, before time.Time) ([]*models.Pipeline, error)
+	// AdvanceSchedule заменяет расписание, только если следующий запуск по-прежнему равен expected.
+	// false — запуск уже обработан другим экземпляром сервиса или расписание изменено
+	AdvanceSchedule(ctx context.Context, id string, expected time.Time, schedule *models.PipelineSchedule) (bool, error)
+}
+
+// LeaseRepository интерфейс аренд для координации нескольких экземпляров сервиса
+type LeaseRepository interface {
+	// AcquireLease захватывает свободную или истекшую аренду name либо продлевает свою на ttl.
+	// false — аренду удерживает другой экземпляр
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease освобождает аренду, если ее удерживает holder
+	ReleaseLease(ctx context.Context, name, holder string) error
+	// LeaseHolder возвращает владельца действующей аренды name; пустая строка — аренда
+	// свободна или истекла
+	LeaseHolder(ctx context.Context, name string) (string, error)
 }
 
 // FileRepository интерфейс для работы с файлами
@@ -23,6 +50,39 @@ type FileRepository interface {
 	UpdateFile(ctx context.Context, file *models.FileMetadata) error
 	DeleteFile(ctx context.Context, id string) error
 	GetFilesByStatus(ctx context.Context, status models.FileStatus) ([]*models.FileMetadata, error)
+	GetFilesByChecksum(ctx context.Context, checksum string) ([]*models.FileMetadata, error)
+}
+
+// BlobRepository интерфейс учета ссылок на объекты с уникальным содержимым
+type BlobRepository interface {
+	// AcquireBlob увеличивает счетчик ссылок существующего blob или создает новый с одной ссылкой.
+	// created сообщает, что blob создан и объект нужно записать в хранилище
+	AcquireBlob(ctx context.Context, blob *models.Blob) (stored *models.Blob, created bool, err error)
+	// ReferenceBlob увеличивает счетчик ссылок blob, только если он существует; иначе BlobNotFoundError
+	ReferenceBlob(ctx context.Context, checksum string) (*models.Blob, error)
+	// ReleaseBlob уменьшает счетчик ссылок. Последняя ссылка освобождается под блокировкой записи:
+	// вызывается remove, и запись удаляется, только если remove выполнен без ошибки. Параллельные
+	// AcquireBlob и ReferenceBlob ждут этого решения, поэтому объект не удаляется из-под новой ссылки
+	ReleaseBlob(ctx context.Context, checksum string, remove func(*models.Blob) error) (remaining int, err error)
+	GetBlob(ctx context.Context, checksum string) (*models.Blob, error)
+}
+
+// UploadSessionRepository интерфейс для работы с сессиями загрузки частями
+type UploadSessionRepository interface {
+	SaveSession(ctx context.Context, session *models.UploadSession) error
+	GetSession(ctx context.Context, id string) (*models.UploadSession, error)
+	// UpdateSession обновляет поля сессии; список частей изменяется только через SavePart
+	UpdateSession(ctx context.Context, session *models.UploadSession) error
+	// TransitionSession обновляет поля сессии, только если ее статус по-прежнему равен from.
+	// false — сессию уже перевел в другой статус параллельный запрос
+	TransitionSession(ctx context.Context, session *models.UploadSession, from models.UploadSessionStatus) (bool, error)
+	// ClaimSession передает сессию в статусе completing экземпляру сервиса owner, только если
+	// владельцем по-прежнему является previousOwner. false — сессия завершена или уже передана
+	ClaimSession(ctx context.Context, id, owner, previousOwner string) (bool, error)
+	// SavePart добавляет или заменяет часть сессии, не затрагивая остальные части
+	SavePart(ctx context.Context, sessionID string, part models.UploadPart) error
+	// GetExpiredSessions возвращает активные сессии, срок действия которых истек до before
+	GetExpiredSessions(ctx context.Context, before time.Time) ([]*models.UploadSession, error)
 }
 
 // AnalysisRepository интерфейс для работы с анализами
@@ -33,6 +93,13 @@ type AnalysisRepository interface {
 	UpdateAnalysis(ctx context.Context, analysis *models.AnalysisResult) error
 	DeleteAnalysis(ctx context.Context, id string) error
 	GetAnalysesByStatus(ctx context.Context, status models.AnalysisStatus) ([]*models.AnalysisResult, error)
+	// ClaimAnalysis передает незавершенный анализ экземпляру сервиса owner и возвращает его в статус
+	// pending, только если владельцем по-прежнему является previousOwner. false — анализ завершен
+	// или уже передан другому экземпляру
+	ClaimAnalysis(ctx context.Context, id, owner, previousOwner string) (bool, error)
+	// GetCompletedAnalysisByFiles возвращает последний завершенный анализ пользователя userID
+	// одного из файлов fileIDs. nil без ошибки, если такого анализа нет
+	GetCompletedAnalysisByFiles(ctx context.Context, userID string, fileIDs []string) (*models.AnalysisResult, error)
 }
 
 // ExecutionRepository интерфейс для работы с выполнениями пайплайнов
@@ -40,10 +107,29 @@ type ExecutionRepository interface {
 	SaveExecution(ctx context.Context, execution *models.PipelineExecution) error
 	GetExecution(ctx context.Context, id string) (*models.PipelineExecution, error)
 	GetExecutionsByPipeline(ctx context.Context, pipelineID string, limit, offset int) ([]*models.PipelineExecution, error)
+	// UpdateExecution обновляет выполнение. Статус cancelling не заменяется на running: запрос отмены,
+	// сохраненный другим экземпляром сервиса, не теряется при сохранении хода выполнения
 	UpdateExecution(ctx context.Context, execution *models.PipelineExecution) error
+	// TransitionExecution обновляет выполнение, только если его статус по-прежнему равен from.
+	// false — выполнение уже перевел в другой статус параллельный запрос или другой экземпляр сервиса
+	TransitionExecution(ctx context.Context, execution *models.PipelineExecution, from models.ExecutionStatus) (bool, error)
+	// ClaimExecution передает выполнение в статусе running или cancelling экземпляру сервиса owner,
+	// только если владельцем по-прежнему является previousOwner. false — выполнение завершено
+	// или уже передано другому экземпляру
+	ClaimExecution(ctx context.Context, id, owner, previousOwner string) (bool, error)
 	GetExecutionsByStatus(ctx context.Context, status models.ExecutionStatus) ([]*models.PipelineExecution, error)
 }
 
+// ConnectionRepository интерфейс для работы с сохраненными подключениями
+type ConnectionRepository interface {
+	// SaveConnection сохраняет новое подключение; имя уникально в пределах пользователя
+	SaveConnection(ctx context.Context, connection *models.ConnectionProfile) error
+	GetConnection(ctx context.Context, id string) (*models.ConnectionProfile, error)
+	GetConnectionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.ConnectionProfile, error)
+	UpdateConnection(ctx context.Context, connection *models.ConnectionProfile) error
+	DeleteConnection(ctx context.Context, id string) error
+}
+
 // DatabaseRepository интерфейс для работы с базами данных
 type DatabaseRepository interface {
 	TestConnection(ctx context.Context, config interface{}) error
@@ -52,3 +138,15 @@ type DatabaseRepository interface {
 	CreateTable(ctx context.Context, schema *models.TableSchema) error
 	InsertData(ctx context.Context, tableName string, data []map[string]interface{}) error
 }
+
+// BulkInserter массовая загрузка строк; реализуется репозиториями целевых баз,
+// которые поддерживают потоковую вставку (COPY в PostgreSQL, батчи INSERT в ClickHouse)
+type BulkInserter interface {
+	// Dialect диалект DDL базы: postgres или clickhouse
+	Dialect() string
+	// BulkInsert загружает строки в колонки columns таблицы и возвращает число загруженных.
+	// next возвращает очередную строку и io.EOF после последней; ошибка next прерывает загрузку.
+	// PostgreSQL загружает все строки в одной транзакции. ClickHouse вставляет батчи по
+	// opts.BatchSize, и при ошибке уже вставленные батчи остаются: повтор с тем же opts.DedupKey их пропускает
+	BulkInsert(ctx context.Context, tableName string, columns []string, next func() ([]interface{}, error), opts models.BulkInsertOptions) (int64, error)
+}
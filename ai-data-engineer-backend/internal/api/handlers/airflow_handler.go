@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AirflowService интерфейс генерации и запуска DAG Airflow по пайплайнам
+type AirflowService interface {
+	RenderDAG(ctx context.Context, userID, pipelineID string) (*models.AirflowDAGResponse, error)
+	PublishDAG(ctx context.Context, userID, pipelineID string) (*models.AirflowDAGResponse, error)
+	RunDAG(ctx context.Context, pipelineID, userID string, parameters map[string]interface{}) (*models.PipelineExecution, error)
+}
+
+// AirflowHandler обработчик DAG Airflow
+type AirflowHandler struct {
+	airflowService AirflowService
+	logger         logger.Logger
+}
+
+// NewAirflowHandler создает новый AirflowHandler
+func NewAirflowHandler(airflowService AirflowService, logger logger.Logger) *AirflowHandler {
+	return &AirflowHandler{
+		airflowService: airflowService,
+		logger:         logger,
+	}
+}
+
+// GetDAG возвращает исходный код DAG пайплайна как файл Python
+func (h *AirflowHandler) GetDAG(c *gin.Context) {
+	dag, err := h.airflowService.RenderDAG(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось сформировать DAG")
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+dag.FileName+`"`)
+	c.Header("X-Airflow-DAG-ID", dag.DAGID)
+	c.Data(http.StatusOK, "text/x-python; charset=utf-8", []byte(dag.Source))
+}
+
+// PublishDAG записывает DAG пайплайна в каталог DAG Airflow или в хранилище
+func (h *AirflowHandler) PublishDAG(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	dag, err := h.airflowService.PublishDAG(c.Request.Context(), resolveUserID(c, ""), id)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("pipeline_id", id).Error("Failed to publish Airflow DAG")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось опубликовать DAG")
+		return
+	}
+	c.JSON(http.StatusOK, dag)
+}
+
+// RunDAG запускает опубликованный DAG пайплайна в Airflow с параметрами запроса.
+// Ход выполнения доступен через /executions/:id/events
+func (h *AirflowHandler) RunDAG(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	var req models.ExecutePipelineRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			requestLogger.WithField("error", err.Error()).Warn("Invalid Airflow run request")
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "invalid_request",
+				Message:   "Некорректный запрос: " + err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	execution, err := h.airflowService.RunDAG(c.Request.Context(), id, resolveUserID(c, req.UserID), req.Parameters)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("pipeline_id", id).Error("Failed to run Airflow DAG")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось запустить DAG")
+		return
+	}
+	c.JSON(http.StatusAccepted, models.ExecutePipelineResponse{
+		ExecutionID: execution.ID,
+		Status:      string(execution.Status),
+		Message:     "Запуск DAG создан в Airflow",
+		Parameters:  execution.Parameters,
+		StartedAt:   execution.StartedAt,
+	})
+}
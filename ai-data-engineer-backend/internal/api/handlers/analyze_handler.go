@@ -11,61 +11,104 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type DataAnalyzerService interface {
-	AnalyzeFile(ctx context.Context, userID string) (models.AnalysisResult, error)
+// AnalysisService интерфейс фонового анализа файлов
+type AnalysisService interface {
+	SubmitAnalysis(ctx context.Context, req *models.AnalysisRequest) (*models.AnalysisResult, error)
+	GetAnalysis(ctx context.Context, userID, id string) (*models.AnalysisResult, error)
 }
 
 type AnalyzeHandler struct {
-	dataAnalyzer DataAnalyzerService
-	logger       logger.Logger
+	analysisService AnalysisService
+	logger          logger.Logger
 }
 
-func NewAnalyzeHandler(dataAnalyzer DataAnalyzerService, logger logger.Logger) *AnalyzeHandler {
+func NewAnalyzeHandler(analysisService AnalysisService, logger logger.Logger) *AnalyzeHandler {
 	return &AnalyzeHandler{
-		dataAnalyzer: dataAnalyzer,
-		logger:       logger,
+		analysisService: analysisService,
+		logger:          logger,
 	}
 }
 
+// AnalyzeFile ставит анализ файла в очередь и сразу возвращает его ID
 func (h *AnalyzeHandler) AnalyzeFile(c *gin.Context) {
 	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
 	requestLogger.Info("Starting: Handler.AnalyzeHandler.AnalyzeFile")
 
-	userID := c.Param("user_id")
-	if userID == "" {
-		userID = "default_user" // По умолчанию
+	// Тело запроса необязательно: без него анализируется последний файл пользователя
+	var req models.AnalysisRequest
+	if c.Request.ContentLength > 0 {
+		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+			requestLogger.WithField("error", err.Error()).Warn("Invalid request body")
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "validation_error",
+				Message:   "Неверный формат запроса",
+				Timestamp: time.Now(),
+			})
+			return
+		}
 	}
+	req.UserID = resolveUserID(c, req.UserID)
 
-	analysisResult, err := h.dataAnalyzer.AnalyzeFile(c.Request.Context(), userID)
+	analysis, err := h.analysisService.SubmitAnalysis(c.Request.Context(), &req)
 	if err != nil {
-		requestLogger.WithField("error", err.Error()).Error("Failed to analyze file")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "analyze_failed",
-			Message:   "Ошибка анализа файла",
-			Timestamp: time.Now(),
-		})
+		requestLogger.WithField("error", err.Error()).Error("Failed to submit analysis")
+		writeError(c, err, http.StatusInternalServerError, "analyze_failed", "Ошибка анализа файла")
 		return
 	}
 
-	// Парсим JSON строку в map
-	var resultMap map[string]interface{}
-	if err := json.Unmarshal([]byte(analysisResult.AnalysisResult.Content.(string)), &resultMap); err != nil {
-		requestLogger.WithField("error", err.Error()).Error("Failed to parse analysis result")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "parsing_error",
-			Message:   "Ошибка при обработке результата анализа",
-			Timestamp: time.Now(),
-		})
+	requestLogger.WithField("analysis_id", analysis.ID).Info("Analysis submitted")
+	c.JSON(http.StatusAccepted, models.AnalysisResponse{
+		AnalysisID: analysis.ID,
+		Status:     string(analysis.Status),
+		Message:    "Анализ поставлен в очередь",
+		CreatedAt:  analysis.CreatedAt,
+		UpdatedAt:  analysis.UpdatedAt,
+	})
+	requestLogger.Info("End: Handler.AnalyzeHandler.AnalyzeFile")
+}
+
+// GetAnalysis возвращает статус анализа и результат после его завершения
+func (h *AnalyzeHandler) GetAnalysis(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	analysis, err := h.analysisService.GetAnalysis(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Failed to get analysis")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось получить анализ")
 		return
 	}
 
 	response := models.AnalysisResponse{
-		Status:    analysisResult.Status,
-		Message:   "Файл успешно проанализирован",
-		Result:    resultMap,
-		CreatedAt: time.Now(),
+		AnalysisID: analysis.ID,
+		Status:     string(analysis.Status),
+		Error:      analysis.Error,
+		CreatedAt:  analysis.CreatedAt,
+		UpdatedAt:  analysis.UpdatedAt,
+	}
+	switch analysis.Status {
+	case models.AnalysisStatusCompleted:
+		response.Message = "Файл успешно проанализирован"
+		response.Result = analysisResultMap(analysis)
+	case models.AnalysisStatusFailed:
+		response.Message = "Ошибка анализа файла"
+	default:
+		response.Message = "Анализ выполняется"
 	}
-	requestLogger.WithField("analysis_id", analysisResult).Info("File analyzed successfully")
 	c.JSON(http.StatusOK, response)
-	requestLogger.Info("End: Handler.AnalyzeHandler.AnalyzeFile")
+}
+
+// analysisResultMap объединяет ответ LLM и профиль данных в один объект
+func analysisResultMap(analysis *models.AnalysisResult) map[string]interface{} {
+	resultMap := map[string]interface{}{}
+	if analysis.AnalysisResult != nil {
+		content, _ := analysis.AnalysisResult.Content.(string)
+		// Ответ LLM, не являющийся JSON объектом, возвращаем как есть
+		if err := json.Unmarshal([]byte(content), &resultMap); err != nil {
+			resultMap = map[string]interface{}{"content": content}
+		}
+	}
+	if analysis.Profile != nil {
+		resultMap["profile"] = analysis.Profile
+	}
+	return resultMap
 }
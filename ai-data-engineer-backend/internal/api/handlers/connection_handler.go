@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectionService интерфейс сохраненных подключений к целевым базам
+type ConnectionService interface {
+	CreateConnection(ctx context.Context, userID string, req *models.ConnectionRequest) (*models.ConnectionProfile, error)
+	GetConnection(ctx context.Context, userID, id string) (*models.ConnectionProfile, error)
+	ListConnections(ctx context.Context, userID string, limit, offset int) ([]*models.ConnectionProfile, error)
+	UpdateConnection(ctx context.Context, userID, id string, req *models.ConnectionRequest) (*models.ConnectionProfile, error)
+	DeleteConnection(ctx context.Context, userID, id string) error
+	TestConnection(ctx context.Context, userID, id string) (*models.DatabaseTestResponse, error)
+}
+
+// ConnectionHandler обработчик сохраненных подключений. Пароль принимается
+// при создании и изменении, но никогда не возвращается
+type ConnectionHandler struct {
+	connectionService ConnectionService
+	logger            logger.Logger
+}
+
+// NewConnectionHandler создает новый ConnectionHandler
+func NewConnectionHandler(connectionService ConnectionService, logger logger.Logger) *ConnectionHandler {
+	return &ConnectionHandler{
+		connectionService: connectionService,
+		logger:            logger,
+	}
+}
+
+// CreateConnection сохраняет подключение
+func (h *ConnectionHandler) CreateConnection(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.ConnectionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	profile, err := h.connectionService.CreateConnection(c.Request.Context(), resolveUserID(c, req.UserID), &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Error("Failed to create connection")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось сохранить подключение")
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+// GetConnection возвращает подключение без пароля
+func (h *ConnectionHandler) GetConnection(c *gin.Context) {
+	profile, err := h.connectionService.GetConnection(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось получить подключение")
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListConnections возвращает подключения пользователя
+func (h *ConnectionHandler) ListConnections(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	userID := resolveUserID(c, "")
+
+	limit := 50
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, maxListLimit)
+	}
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+
+	connections, err := h.connectionService.ListConnections(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("user_id", userID).Error("Failed to list connections")
+		writeError(c, err, http.StatusInternalServerError, "list_failed", "Не удалось получить список подключений")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"connections": connections,
+		"limit":       limit,
+		"offset":      offset,
+		"count":       len(connections),
+	})
+}
+
+// UpdateConnection изменяет подключение; пустой пароль сохраняет текущий
+func (h *ConnectionHandler) UpdateConnection(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	var req models.ConnectionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	profile, err := h.connectionService.UpdateConnection(c.Request.Context(), resolveUserID(c, req.UserID), id, &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("connection_id", id).Error("Failed to update connection")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось изменить подключение")
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteConnection удаляет подключение
+func (h *ConnectionHandler) DeleteConnection(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	if err := h.connectionService.DeleteConnection(c.Request.Context(), resolveUserID(c, ""), id); err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("connection_id", id).Error("Failed to delete connection")
+		writeError(c, err, http.StatusInternalServerError, "delete_failed", "Не удалось удалить подключение")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Подключение удалено",
+		"connection_id": id,
+	})
+}
+
+// TestConnection проверяет сохраненное подключение, см. POST /databases/test
+func (h *ConnectionHandler) TestConnection(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	response, err := h.connectionService.TestConnection(c.Request.Context(), resolveUserID(c, ""), id)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("connection_id", id).Warn("Connection test failed")
+		writeError(c, err, http.StatusBadGateway, string(models.ErrorCodeConnectionFailed), "Не удалось подключиться к базе данных")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// bind разбирает тело запроса и отвечает 400 при ошибке
+func (h *ConnectionHandler) bind(c *gin.Context, req *models.ConnectionRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		logger.GetLoggerFromContext(c.Request.Context()).WithField("error", err.Error()).Warn("Invalid connection request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseService интерфейс работы с таблицами целевых баз
+type DatabaseService interface {
+	GetTableSchema(ctx context.Context, userID, conn, tableName string) (*models.TableSchema, error)
+}
+
+// DatabaseHandler обработчик запросов к целевым базам данных
+type DatabaseHandler struct {
+	databaseService DatabaseService
+	logger          logger.Logger
+}
+
+// NewDatabaseHandler создает новый DatabaseHandler
+func NewDatabaseHandler(databaseService DatabaseService, logger logger.Logger) *DatabaseHandler {
+	return &DatabaseHandler{
+		databaseService: databaseService,
+		logger:          logger,
+	}
+}
+
+// GetTableSchema возвращает схему существующей таблицы: колонки, первичный ключ,
+// индексы и ограничения. Имя таблицы может содержать схему или базу: staging.orders
+func (h *DatabaseHandler) GetTableSchema(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	conn, table := c.Param("conn"), c.Param("name")
+
+	schema, err := h.databaseService.GetTableSchema(c.Request.Context(), resolveUserID(c, ""), conn, table)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("connection", conn).WithField("table", table).
+			Warn("Failed to get table schema")
+		writeError(c, err, http.StatusInternalServerError, "schema_introspection_failed", "Не удалось получить схему таблицы")
+		return
+	}
+	c.JSON(http.StatusOK, schema)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DDLService интерфейс генерации DDL
+type DDLService interface {
+	GenerateDDL(ctx context.Context, req *models.GenerateDDLRequest) (*models.GenerateDDLResponse, error)
+	DiffSchemas(ctx context.Context, userID string, req *models.SchemaDiffRequest) (*models.SchemaDiffResponse, error)
+}
+
+// DDLHandler обработчик генерации DDL
+type DDLHandler struct {
+	ddlService DDLService
+	logger     logger.Logger
+}
+
+// NewDDLHandler создает новый DDLHandler
+func NewDDLHandler(ddlService DDLService, logger logger.Logger) *DDLHandler {
+	return &DDLHandler{
+		ddlService: ddlService,
+		logger:     logger,
+	}
+}
+
+// GenerateDDL генерирует DDL таблицы по схеме, профилю данных или результату анализа
+func (h *DDLHandler) GenerateDDL(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.GenerateDDLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Invalid DDL request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response, err := h.ddlService.GenerateDDL(c.Request.Context(), &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Failed to generate DDL")
+		writeError(c, err, http.StatusInternalServerError, "ddl_generation_failed", "Не удалось сгенерировать DDL")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DiffSchemas сравнивает две схемы таблицы и возвращает упорядоченные ALTER.
+// Разрушающие изменения включаются в statements только при approve_destructive
+func (h *DDLHandler) DiffSchemas(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.SchemaDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Invalid schema diff request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response, err := h.ddlService.DiffSchemas(c.Request.Context(), resolveUserID(c, ""), &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Failed to diff schemas")
+		writeError(c, err, http.StatusInternalServerError, "schema_diff_failed", "Не удалось сравнить схемы")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeError отвечает клиенту ошибкой. AppError передает свои код, сообщение
+// и HTTP статус, остальные ошибки отдаются с переданными значениями по умолчанию
+func writeError(c *gin.Context, err error, httpCode int, code, message string) {
+	response := models.ErrorResponse{
+		Error:     code,
+		Message:   message,
+		RequestID: middleware.GetRequestID(c.Request.Context()),
+		Timestamp: time.Now(),
+	}
+	if appErr, ok := models.IsAppError(err); ok {
+		httpCode = appErr.HTTPCode
+		response.Error = string(appErr.Code)
+		response.Message = appErr.Message
+		response.Details = appErr.Details
+	}
+	c.JSON(httpCode, response)
+}
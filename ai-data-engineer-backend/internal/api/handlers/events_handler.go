@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventsHeartbeat интервал комментариев, поддерживающих соединение через прокси
+	eventsHeartbeat = 15 * time.Second
+	// eventsRetry интервал переподключения EventSource в миллисекундах
+	eventsRetry = 3000
+)
+
+// ProgressService интерфейс потоков событий хода выполнения
+type ProgressService interface {
+	AnalysisEvents(ctx context.Context, userID, analysisID string, lastEventID int64) (*models.EventSubscription, error)
+	ExecutionEvents(ctx context.Context, userID, executionID string, lastEventID int64) (*models.EventSubscription, error)
+}
+
+// EventsHandler отдает ход анализов и выполнений пайплайнов как Server-Sent Events.
+// После обрыва клиент переподключается с заголовком Last-Event-ID и получает пропущенные события
+type EventsHandler struct {
+	progressService ProgressService
+	logger          logger.Logger
+}
+
+// NewEventsHandler создает новый EventsHandler
+func NewEventsHandler(progressService ProgressService, logger logger.Logger) *EventsHandler {
+	return &EventsHandler{
+		progressService: progressService,
+		logger:          logger,
+	}
+}
+
+// AnalysisEvents поток событий анализа
+func (h *EventsHandler) AnalysisEvents(c *gin.Context) {
+	h.stream(c, h.progressService.AnalysisEvents)
+}
+
+// ExecutionEvents поток событий выполнения пайплайна
+func (h *EventsHandler) ExecutionEvents(c *gin.Context) {
+	h.stream(c, h.progressService.ExecutionEvents)
+}
+
+type subscribeFunc func(ctx context.Context, userID, id string, lastEventID int64) (*models.EventSubscription, error)
+
+func (h *EventsHandler) stream(c *gin.Context, subscribe subscribeFunc) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		writeError(c, models.NewValidationError("Некорректный Last-Event-ID", map[string]interface{}{"last_event_id": err.Error()}),
+			http.StatusBadRequest, "validation_error", "Некорректный Last-Event-ID")
+		return
+	}
+
+	subscription, err := subscribe(c.Request.Context(), resolveUserID(c, ""), c.Param("id"), lastEventID)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Failed to subscribe to events")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось подписаться на события")
+		return
+	}
+	defer subscription.Cancel()
+
+	// Клиент уже получил завершающее событие (Last-Event-ID не меньше DoneEventID):
+	// 204 останавливает переподключения EventSource
+	if subscription.Finished && len(subscription.Replay) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// Поток живет дольше server.write_timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.WriteString("retry: " + strconv.Itoa(eventsRetry) + "\n\n")
+
+	for _, event := range subscription.Replay {
+		if err := writeProgressEvent(c, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			if err := writeProgressEvent(c, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeProgressEvent записывает событие в формате SSE: id, тип события и JSON в data
+func writeProgressEvent(c *gin.Context, event models.ProgressEvent) error {
+	return sse.Encode(c.Writer, sse.Event{
+		Id:    strconv.FormatInt(event.ID, 10),
+		Event: string(event.Type),
+		Data:  event,
+	})
+}
+
+// parseLastEventID читает ID последнего полученного события из заголовка Last-Event-ID
+// или параметра last_event_id для первого подключения
+func parseLastEventID(c *gin.Context) (int64, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
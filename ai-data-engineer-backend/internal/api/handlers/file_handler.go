@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,12 +16,21 @@ import (
 
 // ! FileService интерфейс для работы с файлами
 type FileService interface {
-	UploadFile(ctx context.Context, userID, filename string, file io.Reader) (string, error)
-	GetFileInfo(ctx context.Context, fileID string) (interface{}, error)
-	DeleteFile(ctx context.Context, fileID string) error
-	ListFiles(ctx context.Context, userID string, limit, offset int) ([]interface{}, error)
+	UploadFile(ctx context.Context, userID, filename string, file io.Reader) (*models.FileMetadata, error)
+	GetFileInfo(ctx context.Context, fileID, userID string) (*models.FileMetadata, error)
+	DeleteFile(ctx context.Context, fileID, userID string) error
+	ListFiles(ctx context.Context, userID string, limit, offset int) ([]*models.FileMetadata, error)
+	PresignUpload(ctx context.Context, userID, filename, checksum string) (*models.FileMetadata, *models.PresignedURL, error)
+	ConfirmUpload(ctx context.Context, fileID, userID string) (*models.FileMetadata, error)
+	PresignDownload(ctx context.Context, fileID, userID string) (*models.PresignedURL, error)
 }
 
+// maxListLimit максимальный размер страницы при получении списка файлов
+const maxListLimit = 100
+
+// maxFormValueSize максимальный размер текстового поля multipart формы
+const maxFormValueSize = 4 << 10
+
 // ! FileHandler обработчик для работы с файлами
 type FileHandler struct {
 	fileService FileService
@@ -35,15 +45,17 @@ func NewFileHandler(fileService FileService, logger logger.Logger) *FileHandler
 	}
 }
 
-// * UploadFile загружает и анализирует файл
+// * UploadFile потоково загружает файл.
+// Тело читается через MultipartReader без сохранения во временные файлы, поэтому
+// поля формы (user_id, file_type, target_db) учитываются, только если идут до части file.
+// ID пользователя также можно передать заголовком X-User-ID или параметром user_id
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
 	requestLogger.Info("Starting: Handler.FileHandler.UploadFile")
 
-	// Получаем файл из multipart form
-	file, header, err := c.Request.FormFile("file")
+	reader, err := c.Request.MultipartReader()
 	if err != nil {
-		requestLogger.WithField("error", err.Error()).Warn("Failed to get file from form")
+		requestLogger.WithField("error", err.Error()).Warn("Request is not multipart")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:     "file_not_found",
 			Message:   "Файл не найден в запросе",
@@ -51,10 +63,58 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		})
 		return
 	}
-	defer file.Close()
+
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			requestLogger.WithField("error", err.Error()).Warn("Failed to read multipart body")
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "invalid_request",
+				Message:   "Некорректное тело запроса",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+			part.Close()
+			if err != nil {
+				requestLogger.WithField("error", err.Error()).Warn("Failed to read form field")
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:     "invalid_request",
+					Message:   "Некорректное тело запроса",
+					Timestamp: time.Now(),
+				})
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		h.uploadPart(c, part, fields)
+		part.Close()
+		return
+	}
+
+	requestLogger.Warn("File part not found in form")
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "file_not_found",
+		Message:   "Файл не найден в запросе",
+		Timestamp: time.Now(),
+	})
+}
+
+// uploadPart передает часть file в сервис, не читая ее в память
+func (h *FileHandler) uploadPart(c *gin.Context, part *multipart.Part, fields map[string]string) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
 
 	// Получаем тип файла
-	fileType := c.PostForm("file_type")
+	fileType := fields["file_type"]
 	if fileType != "csv" && fileType != "json" && fileType != "xml" && fileType != "" {
 		requestLogger.WithField("error", "invalid_file_type").Warn("Invalid file type")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
@@ -65,42 +125,121 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// Получаем ID пользователя
-	userID := c.PostForm("user_id")
-	if userID == "" {
-		userID = "default_user" // По умолчанию
+	filename := part.FileName()
+	if filename == "" {
+		requestLogger.Warn("File part has no filename")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "file_not_found",
+			Message:   "Файл не найден в запросе",
+			Timestamp: time.Now(),
+		})
+		return
 	}
 
-	// Получаем целевую систему
-	targetDB := c.PostForm("target_db")
-	if targetDB == "" {
-		targetDB = "postgres" // По умолчанию
-	}
+	// Получаем ID пользователя
+	userID := resolveUserID(c, fields["user_id"])
 
 	// Загружаем файл
-	result, err := h.fileService.UploadFile(c.Request.Context(), userID, header.Filename, file)
+	metadata, err := h.fileService.UploadFile(c.Request.Context(), userID, filename, part)
 	if err != nil {
 		requestLogger.WithField("error", err.Error()).Error("Failed to upload file")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "upload_failed",
-			Message:   "Ошибка загрузки файла",
-			Timestamp: time.Now(),
-		})
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Ошибка загрузки файла")
 		return
 	}
 
 	response := models.FileUploadResponse{
-		FileID:    result,
-		Status:    "uploaded",
-		Message:   "Файл успешно загружен и проанализирован",
-		CreatedAt: time.Now(),
+		FileID:    metadata.ID,
+		Status:    string(metadata.Status),
+		Message:   "Файл успешно загружен",
+		CreatedAt: metadata.CreatedAt,
 	}
-	requestLogger.WithField("file_id", result).Info("File uploaded successfully")
+	requestLogger.WithField("file_id", metadata.ID).WithField("size", metadata.Size).Info("File uploaded successfully")
 
 	c.JSON(http.StatusOK, response)
 	requestLogger.Info("End: Handler.FileHandler.UploadFile")
 }
 
+// PresignUpload возвращает временную ссылку для загрузки файла напрямую в хранилище
+func (h *FileHandler) PresignUpload(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Invalid presign request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	metadata, presigned, err := h.fileService.PresignUpload(c.Request.Context(), resolveUserID(c, req.UserID), req.Filename, req.Checksum)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Error("Failed to presign upload")
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Не удалось создать ссылку для загрузки")
+		return
+	}
+
+	// Содержимое уже есть в хранилище: загружать ничего не нужно
+	if presigned == nil {
+		requestLogger.WithField("file_id", metadata.ID).Info("File registered from existing content")
+		c.JSON(http.StatusOK, models.FileUploadResponse{
+			FileID:    metadata.ID,
+			Status:    string(metadata.Status),
+			Message:   "Файл с таким содержимым уже загружен",
+			CreatedAt: metadata.CreatedAt,
+		})
+		return
+	}
+
+	requestLogger.WithField("file_id", metadata.ID).Info("Upload URL issued")
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":    metadata.ID,
+		"status":     metadata.Status,
+		"message":    "Загрузите файл PUT запросом по ссылке и подтвердите загрузку",
+		"upload_url": presigned.URL,
+		"method":     presigned.Method,
+		"expires_at": presigned.ExpiresAt,
+		"created_at": metadata.CreatedAt,
+	})
+}
+
+// ConfirmUpload подтверждает загрузку файла по временной ссылке
+func (h *FileHandler) ConfirmUpload(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	fileID := c.Param("id")
+
+	metadata, err := h.fileService.ConfirmUpload(c.Request.Context(), fileID, resolveUserID(c, ""))
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to confirm upload")
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Не удалось подтвердить загрузку")
+		return
+	}
+
+	requestLogger.WithField("file_id", fileID).WithField("size", metadata.Size).Info("Upload confirmed")
+	c.JSON(http.StatusOK, models.FileUploadResponse{
+		FileID:    metadata.ID,
+		Status:    string(metadata.Status),
+		Message:   "Файл успешно загружен",
+		CreatedAt: metadata.CreatedAt,
+	})
+}
+
+// GetDownloadURL возвращает временную ссылку на скачивание файла
+func (h *FileHandler) GetDownloadURL(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	fileID := c.Param("id")
+
+	presigned, err := h.fileService.PresignDownload(c.Request.Context(), fileID, resolveUserID(c, ""))
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to presign download")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось создать ссылку для скачивания")
+		return
+	}
+	c.JSON(http.StatusOK, presigned)
+}
+
 // GetFileInfo получает информацию о файле
 func (h *FileHandler) GetFileInfo(c *gin.Context) {
 	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
@@ -116,14 +255,10 @@ func (h *FileHandler) GetFileInfo(c *gin.Context) {
 		return
 	}
 
-	fileInfo, err := h.fileService.GetFileInfo(c.Request.Context(), fileID)
+	fileInfo, err := h.fileService.GetFileInfo(c.Request.Context(), fileID, resolveUserID(c, ""))
 	if err != nil {
 		requestLogger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to get file info")
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error:     "file_not_found",
-			Message:   "Файл не найден",
-			Timestamp: time.Now(),
-		})
+		writeError(c, err, http.StatusNotFound, "file_not_found", "Файл не найден")
 		return
 	}
 
@@ -146,14 +281,10 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	err := h.fileService.DeleteFile(c.Request.Context(), fileID)
+	err := h.fileService.DeleteFile(c.Request.Context(), fileID, resolveUserID(c, ""))
 	if err != nil {
 		requestLogger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to delete file")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "delete_failed",
-			Message:   "Ошибка удаления файла",
-			Timestamp: time.Now(),
-		})
+		writeError(c, err, http.StatusInternalServerError, "delete_failed", "Ошибка удаления файла")
 		return
 	}
 
@@ -185,7 +316,7 @@ func (h *FileHandler) ListFiles(c *gin.Context) {
 
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+			limit = min(parsedLimit, maxListLimit)
 		}
 	}
 
@@ -195,22 +326,39 @@ func (h *FileHandler) ListFiles(c *gin.Context) {
 		}
 	}
 
-	files, err := h.fileService.ListFiles(c.Request.Context(), userID, limit, offset)
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	files, err := h.fileService.ListFiles(c.Request.Context(), userID, limit+1, offset)
 	if err != nil {
 		requestLogger.WithField("error", err.Error()).WithField("user_id", userID).Error("Failed to list files")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "list_failed",
-			Message:   "Ошибка получения списка файлов",
-			Timestamp: time.Now(),
-		})
+		writeError(c, err, http.StatusInternalServerError, "list_failed", "Ошибка получения списка файлов")
 		return
 	}
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
 
 	requestLogger.WithField("user_id", userID).WithField("count", len(files)).Info("Files listed")
 	c.JSON(http.StatusOK, gin.H{
-		"files":  files,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(files),
+		"files":    files,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(files),
+		"has_more": hasMore,
 	})
 }
+
+// resolveUserID возвращает ID пользователя из тела запроса, заголовка X-User-ID
+// или параметра user_id, по умолчанию default_user
+func resolveUserID(c *gin.Context, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if userID := c.GetHeader("X-User-ID"); userID != "" {
+		return userID
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		return userID
+	}
+	return "default_user"
+}
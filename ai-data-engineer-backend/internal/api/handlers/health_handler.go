@@ -16,7 +16,7 @@ type HealthService interface {
 	CheckHealth(ctx context.Context) (bool, error)
 	CheckDatabase(ctx context.Context) (bool, error)
 	CheckLLM(ctx context.Context) (bool, error)
-	TestDatabaseConnection(ctx context.Context, req *models.DatabaseTestRequest) (bool, error)
+	TestDatabaseConnection(ctx context.Context, req *models.DatabaseTestRequest) (*models.DatabaseTestResponse, error)
 }
 
 // HealthHandler обработчик для health checks
@@ -88,7 +88,8 @@ func (h *HealthHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// DatabaseTest тестирует подключение к базе данных
+// DatabaseTest подключается к базе данных из запроса и возвращает задержку,
+// версию сервера, статус SSL и результат проверки прав
 func (h *HealthHandler) DatabaseTest(c *gin.Context) {
 	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
 	requestLogger.Info("Database test requested")
@@ -104,11 +105,13 @@ func (h *HealthHandler) DatabaseTest(c *gin.Context) {
 		return
 	}
 
-	requestLogger.WithField("type", req.Type).Info("Database test completed")
-	c.JSON(http.StatusOK, models.DatabaseTestResponse{
-		Status:    "healthy",
-		Message:   "Database test completed",
-		Connected: true,
-		TestedAt:  time.Now(),
-	})
+	response, err := h.healthService.TestDatabaseConnection(c.Request.Context(), &req)
+	if err != nil {
+		requestLogger.WithField("type", req.Type).WithField("error", err.Error()).Warn("Database test failed")
+		writeError(c, err, http.StatusBadGateway, string(models.ErrorCodeConnectionFailed), "Не удалось подключиться к базе данных")
+		return
+	}
+
+	requestLogger.WithField("type", req.Type).WithField("status", response.Status).Info("Database test completed")
+	c.JSON(http.StatusOK, response)
 }
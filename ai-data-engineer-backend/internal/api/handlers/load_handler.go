@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadService интерфейс загрузки файлов в целевые базы
+type LoadService interface {
+	Load(ctx context.Context, userID string, req *models.LoadRequest) (*models.LoadResult, error)
+}
+
+// LoadHandler обработчик загрузки проанализированных файлов в таблицы
+type LoadHandler struct {
+	loadService LoadService
+	logger      logger.Logger
+}
+
+// NewLoadHandler создает новый LoadHandler
+func NewLoadHandler(loadService LoadService, logger logger.Logger) *LoadHandler {
+	return &LoadHandler{
+		loadService: loadService,
+		logger:      logger,
+	}
+}
+
+// LoadFile загружает файл анализа в существующую таблицу и возвращает статистику загрузки
+func (h *LoadHandler) LoadFile(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.LoadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Invalid load request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	result, err := h.loadService.Load(c.Request.Context(), resolveUserID(c, ""), &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("analysis_id", req.AnalysisID).Error("Failed to load file")
+		writeError(c, err, http.StatusInternalServerError, "load_failed", "Не удалось загрузить файл в таблицу")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
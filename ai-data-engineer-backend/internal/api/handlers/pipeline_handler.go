@@ -1,6 +1,12 @@
 package handlers
 
 import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
 	"ai-data-engineer-backend/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -8,49 +14,236 @@ import (
 
 // PipelineService интерфейс для работы с пайплайнами
 type PipelineService interface {
-	CreatePipeline(name string) error
+	CreatePipeline(ctx context.Context, userID string, req *models.PipelineRequest) (*models.PipelineResponse, error)
+	GetPipeline(ctx context.Context, userID, id string) (*models.PipelineResponse, error)
+	ListPipelines(ctx context.Context, userID string, status models.PipelineStatus, limit, offset int) ([]*models.PipelineResponse, error)
+	UpdatePipeline(ctx context.Context, userID, id string, req *models.PipelineRequest) (*models.PipelineResponse, error)
+	DeletePipeline(ctx context.Context, userID, id string) error
+	PlanPipeline(ctx context.Context, userID, id string) (*models.PipelinePlan, error)
+}
+
+// PipelineExecutor интерфейс выполнения пайплайнов
+type PipelineExecutor interface {
+	Execute(ctx context.Context, pipelineID, userID string, parameters map[string]interface{}) (*models.PipelineExecution, error)
+	GetExecution(ctx context.Context, userID, id string) (*models.PipelineExecution, error)
+	ListExecutions(ctx context.Context, userID, pipelineID string, limit, offset int) ([]*models.PipelineExecution, error)
+	Cancel(ctx context.Context, userID, executionID string) error
 }
 
 // PipelineHandler обработчик для работы с пайплайнами
 type PipelineHandler struct {
 	pipelineService PipelineService
+	executor        PipelineExecutor
 	logger          logger.Logger
 }
 
 // NewPipelineHandler создает новый PipelineHandler
-func NewPipelineHandler(pipelineService PipelineService, logger logger.Logger) *PipelineHandler {
+func NewPipelineHandler(pipelineService PipelineService, executor PipelineExecutor, logger logger.Logger) *PipelineHandler {
 	return &PipelineHandler{
 		pipelineService: pipelineService,
+		executor:        executor,
 		logger:          logger,
 	}
 }
 
 // CreatePipeline создает новый пайплайн
 func (h *PipelineHandler) CreatePipeline(c *gin.Context) {
-	// TODO: Implement pipeline creation
-	c.JSON(200, gin.H{"message": "Pipeline created"})
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.PipelineRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	response, err := h.pipelineService.CreatePipeline(c.Request.Context(), resolveUserID(c, req.UserID), &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("analysis_id", req.AnalysisID).Error("Failed to create pipeline")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось создать пайплайн")
+		return
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // GetPipeline получает пайплайн по ID
 func (h *PipelineHandler) GetPipeline(c *gin.Context) {
-	// TODO: Implement pipeline retrieval
-	c.JSON(200, gin.H{"message": "Pipeline retrieved"})
+	response, err := h.pipelineService.GetPipeline(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось получить пайплайн")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPipelinePlan возвращает топологический порядок шагов и уровни выполнения
+func (h *PipelineHandler) GetPipelinePlan(c *gin.Context) {
+	plan, err := h.pipelineService.PlanPipeline(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось построить порядок выполнения")
+		return
+	}
+	c.JSON(http.StatusOK, plan)
 }
 
-// ExecutePipeline выполняет пайплайн
+// ExecutePipeline запускает выполнение пайплайна в фоне. Ход выполнения доступен
+// через /executions/:id/events
 func (h *PipelineHandler) ExecutePipeline(c *gin.Context) {
-	// TODO: Implement pipeline execution
-	c.JSON(200, gin.H{"message": "Pipeline executed"})
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	var req models.ExecutePipelineRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			requestLogger.WithField("error", err.Error()).Warn("Invalid execute request")
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "invalid_request",
+				Message:   "Некорректный запрос: " + err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	execution, err := h.executor.Execute(c.Request.Context(), id, resolveUserID(c, req.UserID), req.Parameters)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("pipeline_id", id).Error("Failed to execute pipeline")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось запустить пайплайн")
+		return
+	}
+	c.JSON(http.StatusAccepted, models.ExecutePipelineResponse{
+		ExecutionID: execution.ID,
+		Status:      string(execution.Status),
+		Message:     "Выполнение пайплайна запущено",
+		Parameters:  execution.Parameters,
+		StartedAt:   execution.StartedAt,
+	})
+}
+
+// CancelExecution запрашивает отмену выполнения. Выполнение получает статус cancelled,
+// когда выполняемые шаги остановятся
+func (h *PipelineHandler) CancelExecution(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	if err := h.executor.Cancel(c.Request.Context(), resolveUserID(c, ""), id); err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("execution_id", id).Error("Failed to cancel execution")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось отменить выполнение")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Отмена выполнения запрошена",
+		"execution_id": id,
+	})
+}
+
+// ListExecutions получает выполнения пайплайна, начиная с последних
+func (h *PipelineHandler) ListExecutions(c *gin.Context) {
+	id := c.Param("id")
+	limit := 50
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, maxListLimit)
+	}
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+
+	executions, err := h.executor.ListExecutions(c.Request.Context(), resolveUserID(c, ""), id, limit, offset)
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "list_failed", "Не удалось получить список выполнений")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+		"limit":      limit,
+		"offset":     offset,
+		"count":      len(executions),
+	})
+}
+
+// GetExecution получает выполнение пайплайна с журналом
+func (h *PipelineHandler) GetExecution(c *gin.Context) {
+	execution, err := h.executor.GetExecution(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось получить выполнение")
+		return
+	}
+	c.JSON(http.StatusOK, execution)
+}
+
+// UpdatePipeline изменяет пайплайн
+func (h *PipelineHandler) UpdatePipeline(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	var req models.PipelineRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	response, err := h.pipelineService.UpdatePipeline(c.Request.Context(), resolveUserID(c, req.UserID), id, &req)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("pipeline_id", id).Error("Failed to update pipeline")
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось изменить пайплайн")
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // DeletePipeline удаляет пайплайн
 func (h *PipelineHandler) DeletePipeline(c *gin.Context) {
-	// TODO: Implement pipeline deletion
-	c.JSON(200, gin.H{"message": "Pipeline deleted"})
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	id := c.Param("id")
+
+	if err := h.pipelineService.DeletePipeline(c.Request.Context(), resolveUserID(c, ""), id); err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("pipeline_id", id).Error("Failed to delete pipeline")
+		writeError(c, err, http.StatusInternalServerError, "delete_failed", "Не удалось удалить пайплайн")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Пайплайн удален",
+		"pipeline_id": id,
+	})
 }
 
-// ListPipelines получает список пайплайнов
+// ListPipelines получает список пайплайнов пользователя с фильтром status
 func (h *PipelineHandler) ListPipelines(c *gin.Context) {
-	// TODO: Implement pipeline listing
-	c.JSON(200, gin.H{"message": "Pipelines listed"})
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	userID := resolveUserID(c, "")
+	status := models.PipelineStatus(c.Query("status"))
+
+	limit := 50
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, maxListLimit)
+	}
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+
+	pipelines, err := h.pipelineService.ListPipelines(c.Request.Context(), userID, status, limit, offset)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("user_id", userID).Error("Failed to list pipelines")
+		writeError(c, err, http.StatusInternalServerError, "list_failed", "Не удалось получить список пайплайнов")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"pipelines": pipelines,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(pipelines),
+	})
+}
+
+// bind разбирает тело запроса и отвечает 400 при ошибке
+func (h *PipelineHandler) bind(c *gin.Context, req *models.PipelineRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		logger.GetLoggerFromContext(c.Request.Context()).WithField("error", err.Error()).Warn("Invalid pipeline request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return false
+	}
+	return true
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadService интерфейс возобновляемой загрузки файлов частями
+type UploadService interface {
+	InitUpload(ctx context.Context, userID, filename string, size int64) (*models.UploadSession, error)
+	GetUpload(ctx context.Context, userID, sessionID string) (*models.UploadSession, error)
+	UploadPart(ctx context.Context, userID, sessionID string, number int, reader io.Reader, size int64) (*models.UploadPart, error)
+	CompleteUpload(ctx context.Context, userID, sessionID string) (*models.FileMetadata, error)
+	AbortUpload(ctx context.Context, userID, sessionID string) error
+}
+
+// UploadHandler обработчик загрузки файлов частями.
+// Клиент создает сессию, загружает части PUT запросами в любом порядке
+// (после обрыва — только недостающие, см. GET сессии) и завершает загрузку.
+// Владелец сессии передается заголовком X-User-ID или параметром user_id
+type UploadHandler struct {
+	uploadService UploadService
+	logger        logger.Logger
+}
+
+// NewUploadHandler создает новый UploadHandler
+func NewUploadHandler(uploadService UploadService, logger logger.Logger) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+		logger:        logger,
+	}
+}
+
+// InitUpload создает сессию загрузки
+func (h *UploadHandler) InitUpload(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+
+	var req models.UploadInitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger.WithField("error", err.Error()).Warn("Invalid upload init request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_request",
+			Message:   "Некорректный запрос: " + err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	session, err := h.uploadService.InitUpload(c.Request.Context(), resolveUserID(c, req.UserID), req.Filename, req.Size)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).Error("Failed to create upload session")
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Не удалось создать сессию загрузки")
+		return
+	}
+
+	requestLogger.WithField("upload_id", session.ID).Info("Upload session created")
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUpload возвращает состояние сессии загрузки
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	session, err := h.uploadService.GetUpload(c.Request.Context(), resolveUserID(c, ""), c.Param("id"))
+	if err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось получить сессию загрузки")
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// UploadPart принимает часть файла в теле запроса. Размер части задается заголовком Content-Length
+func (h *UploadHandler) UploadPart(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	sessionID := c.Param("id")
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_input",
+			Message:   "Номер части должен быть числом",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if c.Request.ContentLength < 0 {
+		c.JSON(http.StatusLengthRequired, models.ErrorResponse{
+			Error:     "missing_field",
+			Message:   "Заголовок Content-Length обязателен",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	part, err := h.uploadService.UploadPart(c.Request.Context(), resolveUserID(c, ""), sessionID, number, c.Request.Body, c.Request.ContentLength)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("upload_id", sessionID).WithField("part", number).Error("Failed to upload part")
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Не удалось загрузить часть файла")
+		return
+	}
+	c.JSON(http.StatusOK, part)
+}
+
+// CompleteUpload собирает файл из загруженных частей
+func (h *UploadHandler) CompleteUpload(c *gin.Context) {
+	requestLogger := logger.GetLoggerFromContext(c.Request.Context())
+	sessionID := c.Param("id")
+
+	metadata, err := h.uploadService.CompleteUpload(c.Request.Context(), resolveUserID(c, ""), sessionID)
+	if err != nil {
+		requestLogger.WithField("error", err.Error()).WithField("upload_id", sessionID).Error("Failed to complete upload")
+		writeError(c, err, http.StatusInternalServerError, "upload_failed", "Не удалось завершить загрузку")
+		return
+	}
+
+	requestLogger.WithField("upload_id", sessionID).WithField("file_id", metadata.ID).Info("Upload completed")
+	c.JSON(http.StatusOK, models.FileUploadResponse{
+		FileID:    metadata.ID,
+		Status:    string(metadata.Status),
+		Message:   "Файл успешно загружен",
+		CreatedAt: metadata.CreatedAt,
+	})
+}
+
+// AbortUpload отменяет загрузку
+func (h *UploadHandler) AbortUpload(c *gin.Context) {
+	sessionID := c.Param("id")
+	if err := h.uploadService.AbortUpload(c.Request.Context(), resolveUserID(c, ""), sessionID); err != nil {
+		writeError(c, err, http.StatusInternalServerError, "internal_error", "Не удалось отменить загрузку")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Загрузка отменена",
+		"upload_id": sessionID,
+	})
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"ai-data-engineer-backend/internal/api/handlers"
 	"ai-data-engineer-backend/internal/api/middleware"
-	"ai-data-engineer-backend/internal/service"
 	"ai-data-engineer-backend/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -12,8 +11,16 @@ import (
 // SetupRoutes настраивает маршруты API
 func SetupRoutes(
 	fileService handlers.FileService,
-	dataAnalyzer *service.DataAnalyzer,
-	pipelineService *service.PipelineService,
+	uploadService handlers.UploadService,
+	analysisService handlers.AnalysisService,
+	progressService handlers.ProgressService,
+	ddlService handlers.DDLService,
+	databaseService handlers.DatabaseService,
+	connectionService handlers.ConnectionService,
+	loadService handlers.LoadService,
+	pipelineService handlers.PipelineService,
+	pipelineExecutor handlers.PipelineExecutor,
+	airflowService handlers.AirflowService,
 	healthService handlers.HealthService,
 	log logger.Logger,
 ) *gin.Engine {
@@ -34,34 +41,83 @@ func SetupRoutes(
 
 	// Создаем handlers
 	fileHandler := handlers.NewFileHandler(fileService, log)
-	pipelineHandler := handlers.NewPipelineHandler(pipelineService, log)
+	uploadHandler := handlers.NewUploadHandler(uploadService, log)
+	pipelineHandler := handlers.NewPipelineHandler(pipelineService, pipelineExecutor, log)
 	healthHandler := handlers.NewHealthHandler(healthService, log)
-	dataAnalyzerHandler := handlers.NewAnalyzeHandler(dataAnalyzer, log)
+	dataAnalyzerHandler := handlers.NewAnalyzeHandler(analysisService, log)
+	eventsHandler := handlers.NewEventsHandler(progressService, log)
+	ddlHandler := handlers.NewDDLHandler(ddlService, log)
+	databaseHandler := handlers.NewDatabaseHandler(databaseService, log)
+	connectionHandler := handlers.NewConnectionHandler(connectionService, log)
+	loadHandler := handlers.NewLoadHandler(loadService, log)
+	airflowHandler := handlers.NewAirflowHandler(airflowService, log)
 	// API v1 группа
 	v1 := r.Group("/api/v1")
 	{
 		// Health check
 		v1.GET("/health", healthHandler.HealthCheck)
 		v1.POST("/databases/test", healthHandler.DatabaseTest)
+		v1.GET("/databases/:conn/tables/:name/schema", databaseHandler.GetTableSchema)
+
+		// Сохраненные подключения к целевым базам
+		connections := v1.Group("/connections")
+		{
+			connections.POST("", connectionHandler.CreateConnection)
+			connections.GET("", connectionHandler.ListConnections)
+			connections.GET("/:id", connectionHandler.GetConnection)
+			connections.PUT("/:id", connectionHandler.UpdateConnection)
+			connections.DELETE("/:id", connectionHandler.DeleteConnection)
+			connections.POST("/:id/test", connectionHandler.TestConnection)
+		}
 
 		// File operations
 		files := v1.Group("/files")
 		{
 			files.POST("/upload", fileHandler.UploadFile)
+			files.POST("/presign", fileHandler.PresignUpload)
 			files.GET("/:id", fileHandler.GetFileInfo)
+			files.GET("/:id/download-url", fileHandler.GetDownloadURL)
+			files.POST("/:id/confirm", fileHandler.ConfirmUpload)
 			files.DELETE("/:id", fileHandler.DeleteFile)
 			files.GET("", fileHandler.ListFiles)
+
+			// Возобновляемая загрузка частями
+			files.POST("/uploads", uploadHandler.InitUpload)
+			files.GET("/uploads/:id", uploadHandler.GetUpload)
+			files.PUT("/uploads/:id/parts/:number", uploadHandler.UploadPart)
+			files.POST("/uploads/:id/complete", uploadHandler.CompleteUpload)
+			files.DELETE("/uploads/:id", uploadHandler.AbortUpload)
 		}
 
 		// Analyze file
 		v1.POST("/analyze-file", dataAnalyzerHandler.AnalyzeFile)
+		v1.GET("/analyses/:id", dataAnalyzerHandler.GetAnalysis)
+		v1.GET("/analyses/:id/events", eventsHandler.AnalysisEvents)
+
+		// Ход выполнения пайплайнов (Server-Sent Events)
+		v1.GET("/executions/:id", pipelineHandler.GetExecution)
+		v1.GET("/executions/:id/events", eventsHandler.ExecutionEvents)
+		v1.POST("/executions/:id/cancel", pipelineHandler.CancelExecution)
+
+		// Генерация DDL без LLM
+		v1.POST("/ddl/generate", ddlHandler.GenerateDDL)
+		v1.POST("/schemas/diff", ddlHandler.DiffSchemas)
+
+		// Загрузка проанализированных файлов в таблицы
+		v1.POST("/loads", loadHandler.LoadFile)
 
 		// Pipeline operations
 		pipelines := v1.Group("/pipelines")
 		{
 			pipelines.POST("", pipelineHandler.CreatePipeline)
 			pipelines.GET("/:id", pipelineHandler.GetPipeline)
+			pipelines.PUT("/:id", pipelineHandler.UpdatePipeline)
+			pipelines.GET("/:id/plan", pipelineHandler.GetPipelinePlan)
 			pipelines.POST("/:id/execute", pipelineHandler.ExecutePipeline)
+			pipelines.GET("/:id/executions", pipelineHandler.ListExecutions)
+			pipelines.GET("/:id/airflow-dag", airflowHandler.GetDAG)
+			pipelines.POST("/:id/airflow-dag", airflowHandler.PublishDAG)
+			pipelines.POST("/:id/airflow-runs", airflowHandler.RunDAG)
 			pipelines.DELETE("/:id", pipelineHandler.DeletePipeline)
 			pipelines.GET("", pipelineHandler.ListPipelines)
 		}
@@ -10,12 +10,17 @@ import (
 
 // Config представляет конфигурацию приложения
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	LLM      LLMConfig      `mapstructure:"llm"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	Airflow  AirflowConfig  `mapstructure:"airflow"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Instance  InstanceConfig  `mapstructure:"instance"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	LLM       LLMConfig       `mapstructure:"llm"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Analysis  AnalysisConfig  `mapstructure:"analysis"`
+	Pipeline  PipelineConfig  `mapstructure:"pipeline"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Airflow   AirflowConfig   `mapstructure:"airflow"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
 }
 
 // ServerConfig конфигурация HTTP сервера
@@ -26,10 +31,22 @@ type ServerConfig struct {
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
 }
 
+// InstanceConfig конфигурация экземпляра сервиса. Фоновые задачи остановленного экземпляра
+// восстанавливают другие экземпляры после истечения его аренды
+type InstanceConfig struct {
+	// ID имя экземпляра; пустое — имя хоста и случайный суффикс
+	ID       string        `mapstructure:"id"`
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+}
+
 // DatabaseConfig конфигурация баз данных
 type DatabaseConfig struct {
+	Driver     string           `mapstructure:"driver"` // postgres | memory
 	PostgreSQL PostgreSQLConfig `mapstructure:"postgresql"`
 	ClickHouse ClickHouseConfig `mapstructure:"clickhouse"`
+	// TargetPostgresDSN DSN целевого PostgreSQL подключения postgres. База сервиса
+	// целью не является: без DSN подключение postgres недоступно
+	TargetPostgresDSN string `mapstructure:"target_postgres_dsn"`
 }
 
 // PostgreSQLConfig конфигурация PostgreSQL
@@ -44,7 +61,7 @@ type PostgreSQLConfig struct {
 	MaxIdle  int    `mapstructure:"max_idle"`
 }
 
-// ClickHouseConfig конфигурация ClickHouse
+// ClickHouseConfig конфигурация ClickHouse. Сервис работает через HTTP интерфейс (порт 8123)
 type ClickHouseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
@@ -66,20 +83,69 @@ type LLMConfig struct {
 
 // StorageConfig конфигурация хранилища
 type StorageConfig struct {
-	Type      string `mapstructure:"type"`
+	Type      string `mapstructure:"type"` // minio | filesystem
+	BasePath  string `mapstructure:"base_path"`
 	Endpoint  string `mapstructure:"endpoint"`
 	AccessKey string `mapstructure:"access_key"`
 	SecretKey string `mapstructure:"secret_key"`
 	Bucket    string `mapstructure:"bucket"`
 	UseSSL    bool   `mapstructure:"use_ssl"`
+	// PublicEndpoint адрес MinIO для временных ссылок, если отличается от Endpoint
+	PublicEndpoint string        `mapstructure:"public_endpoint"`
+	Region         string        `mapstructure:"region"`
+	PresignExpiry  time.Duration `mapstructure:"presign_expiry"`
+	// MaxUploadSize максимальный размер загружаемого файла в байтах
+	MaxUploadSize int64 `mapstructure:"max_upload_size"`
+	// Параметры загрузки частями
+	UploadPartSize        int64         `mapstructure:"upload_part_size"`
+	UploadSessionTTL      time.Duration `mapstructure:"upload_session_ttl"`
+	UploadCleanupInterval time.Duration `mapstructure:"upload_cleanup_interval"`
+}
+
+// AnalysisConfig конфигурация фонового анализа файлов
+type AnalysisConfig struct {
+	Workers   int           `mapstructure:"workers"`
+	QueueSize int           `mapstructure:"queue_size"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// PipelineConfig конфигурация выполнения пайплайнов
+type PipelineConfig struct {
+	// Parallelism максимальное число одновременно выполняемых шагов одного уровня
+	Parallelism int `mapstructure:"parallelism"`
+	// CancelPollInterval период проверки отмены, запрошенной через другой экземпляр сервиса
+	CancelPollInterval time.Duration `mapstructure:"cancel_poll_interval"`
+}
+
+// SchedulerConfig конфигурация запуска пайплайнов по расписанию
+type SchedulerConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// LeaseTTL срок аренды, которую удерживает проверяющий расписания экземпляр сервиса
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+	// CatchUp обработка пропущенных запусков по умолчанию: none, latest или all
+	CatchUp    string `mapstructure:"catch_up"`
+	MaxCatchUp int    `mapstructure:"max_catch_up"`
 }
 
 // AirflowConfig конфигурация Airflow
 type AirflowConfig struct {
 	DAGsPath string `mapstructure:"dags_path"`
-	BaseURL  string `mapstructure:"base_url"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
+	// DAGsBucket бакет хранилища для DAG; если задан, DAG публикуются в хранилище под DAGsPrefix
+	DAGsBucket string `mapstructure:"dags_bucket"`
+	DAGsPrefix string `mapstructure:"dags_prefix"`
+	BaseURL    string `mapstructure:"base_url"`
+	Username   string `mapstructure:"username"`
+	Password   string `mapstructure:"password"`
+	// PollInterval период опроса состояния запусков DAG
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// SecurityConfig конфигурация шифрования секретов сохраненных подключений.
+// Ключ AES-256 задается в base64 или hex значением или файлом; значение имеет приоритет
+type SecurityConfig struct {
+	EncryptionKey     string `mapstructure:"encryption_key"`
+	EncryptionKeyFile string `mapstructure:"encryption_key_file"`
 }
 
 // LoggingConfig конфигурация логирования
@@ -124,6 +190,9 @@ func setDefaults() {
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "120s")
 
+	// Database
+	viper.SetDefault("database.driver", "postgres")
+
 	// PostgreSQL
 	viper.SetDefault("database.postgresql.host", "localhost")
 	viper.SetDefault("database.postgresql.port", "5432")
@@ -133,10 +202,11 @@ func setDefaults() {
 	viper.SetDefault("database.postgresql.sslmode", "disable")
 	viper.SetDefault("database.postgresql.max_open", 25)
 	viper.SetDefault("database.postgresql.max_idle", 5)
+	viper.SetDefault("database.target_postgres_dsn", "")
 
 	// ClickHouse
 	viper.SetDefault("database.clickhouse.host", "localhost")
-	viper.SetDefault("database.clickhouse.port", "9000")
+	viper.SetDefault("database.clickhouse.port", "8123")
 	viper.SetDefault("database.clickhouse.user", "default")
 	viper.SetDefault("database.clickhouse.password", "")
 	viper.SetDefault("database.clickhouse.dbname", "aien_db")
@@ -151,17 +221,51 @@ func setDefaults() {
 
 	// Storage
 	viper.SetDefault("storage.type", "minio")
+	viper.SetDefault("storage.base_path", "./data/storage")
 	viper.SetDefault("storage.endpoint", "localhost:9000")
 	viper.SetDefault("storage.access_key", "minioadmin")
 	viper.SetDefault("storage.secret_key", "minioadmin")
 	viper.SetDefault("storage.bucket", "files")
 	viper.SetDefault("storage.use_ssl", false)
+	viper.SetDefault("storage.max_upload_size", 10<<30)
+	viper.SetDefault("storage.region", "us-east-1")
+	viper.SetDefault("storage.presign_expiry", "15m")
+	viper.SetDefault("storage.upload_part_size", 16<<20)
+	viper.SetDefault("storage.upload_session_ttl", "24h")
+	viper.SetDefault("storage.upload_cleanup_interval", "10m")
+
+	// Analysis
+	viper.SetDefault("analysis.workers", 4)
+	viper.SetDefault("analysis.queue_size", 100)
+	viper.SetDefault("analysis.timeout", "5m")
+
+	// Pipeline
+	viper.SetDefault("pipeline.parallelism", 4)
+	viper.SetDefault("pipeline.cancel_poll_interval", "5s")
+
+	// Instance
+	viper.SetDefault("instance.id", "")
+	viper.SetDefault("instance.lease_ttl", "30s")
+
+	// Scheduler
+	viper.SetDefault("scheduler.enabled", true)
+	viper.SetDefault("scheduler.interval", "30s")
+	viper.SetDefault("scheduler.lease_ttl", "2m")
+	viper.SetDefault("scheduler.catch_up", "latest")
+	viper.SetDefault("scheduler.max_catch_up", 10)
 
 	// Airflow
 	viper.SetDefault("airflow.dags_path", "/opt/airflow/dags")
+	viper.SetDefault("airflow.dags_bucket", "")
+	viper.SetDefault("airflow.dags_prefix", "dags")
 	viper.SetDefault("airflow.base_url", "http://localhost:8081")
 	viper.SetDefault("airflow.username", "admin")
 	viper.SetDefault("airflow.password", "admin")
+	viper.SetDefault("airflow.poll_interval", "10s")
+
+	// Security
+	viper.SetDefault("security.encryption_key", "")
+	viper.SetDefault("security.encryption_key_file", "")
 
 	// Logging
 	viper.SetDefault("logging.level", "info")
@@ -196,3 +300,12 @@ func (c *Config) GetClickHouseDSN() string {
 		c.Database.ClickHouse.DBName,
 	)
 }
+
+// GetClickHouseURL возвращает адрес HTTP интерфейса ClickHouse без учетных данных
+func (c *Config) GetClickHouseURL() string {
+	protocol := "http"
+	if c.Database.ClickHouse.Secure {
+		protocol = "https"
+	}
+	return fmt.Sprintf("%s://%s:%s", protocol, c.Database.ClickHouse.Host, c.Database.ClickHouse.Port)
+}
@@ -0,0 +1,403 @@
+package ddl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+const (
+	// lowCardinalityMax предел уникальных значений, при котором LowCardinality эффективен
+	lowCardinalityMax = 10000
+	// orderByMaxColumns максимальное число колонок ORDER BY, выбираемых автоматически
+	orderByMaxColumns = 3
+	// yearlyPartitionSpan при большем охвате дат партиции по годам вместо месяцев
+	yearlyPartitionSpan = 5 * 365 * 24 * 60 * 60
+	// deduplicationWindow число последних вставок, токены которых помнит нереплицируемая таблица:
+	// без этой настройки insert_deduplication_token массовой загрузки не действует
+	deduplicationWindow = 1000
+)
+
+// clickhouseEnginePattern допустимые движки: семейство MergeTree без параметров
+var clickhouseEnginePattern = regexp.MustCompile(`^[A-Za-z]*MergeTree$`)
+
+// versionColumnNames имена колонок версии строки для ReplacingMergeTree
+var versionColumnNames = []string{"updated_at", "modified_at", "version", "updated"}
+
+// clickhouseGenerator генерирует DDL ClickHouse с движком семейства MergeTree
+type clickhouseGenerator struct{}
+
+func (clickhouseGenerator) Dialect() string {
+	return DialectClickHouse
+}
+
+// clickhouseColumn колонка с выбранным типом и сведениями для выбора ключей
+type clickhouseColumn struct {
+	field    models.TableField
+	stats    *models.DataField
+	logical  string
+	typ      string
+	nullable bool
+}
+
+// Generate формирует CREATE TABLE с ENGINE, ORDER BY и PARTITION BY.
+// Каждый выбор объясняется в Metadata
+func (g clickhouseGenerator) Generate(schema *models.TableSchema, profile *models.DataProfile, opts Options) (*models.GenerateDDLResponse, error) {
+	if err := validateSchema(schema); err != nil {
+		return nil, err
+	}
+	if opts.Engine != "" && !clickhouseEnginePattern.MatchString(opts.Engine) {
+		return nil, models.NewValidationError("Недопустимый движок ClickHouse", map[string]interface{}{"engine": opts.Engine})
+	}
+
+	stats := profileFields(profile)
+	var rows int
+	if profile != nil {
+		rows = profile.SampledRows
+	}
+	primaryKey := make(map[string]bool, len(schema.PrimaryKey))
+	for _, name := range schema.PrimaryKey {
+		primaryKey[name] = true
+	}
+
+	columns := make([]*clickhouseColumn, 0, len(schema.Fields))
+	columnInfo := make([]map[string]interface{}, 0, len(schema.Fields))
+	byName := make(map[string]*clickhouseColumn, len(schema.Fields))
+	for _, field := range schema.Fields {
+		column := &clickhouseColumn{field: field, stats: stats[field.Name], logical: strings.TrimSpace(field.Type)}
+		var reasons []string
+		column.typ, reasons = clickhouseType(column, rows, primaryKey[field.Name])
+		columns = append(columns, column)
+		byName[field.Name] = column
+		columnInfo = append(columnInfo, map[string]interface{}{
+			"column": field.Name, "type": column.typ, "reason": strings.Join(reasons, "; "),
+		})
+	}
+
+	orderBy, orderReason := clickhouseOrderBy(schema, columns)
+	engine, engineReason := clickhouseEngine(schema, byName, opts.Engine)
+	partition, partitionReason := clickhousePartition(columns, orderBy, len(schema.PrimaryKey) > 0)
+
+	definitions := make([]string, 0, len(columns))
+	for _, column := range columns {
+		definition := quoteClickHouse(column.field.Name) + " " + column.typ
+		if column.field.Description != "" {
+			definition += " COMMENT " + quoteClickHouseString(column.field.Description)
+		}
+		definitions = append(definitions, definition)
+	}
+
+	var skipped []string
+	for _, index := range schema.Indexes {
+		definition, err := clickhouseIndex(schema.TableName, index, byName)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	for i, constraint := range schema.Constraints {
+		if strings.ToLower(constraint.Type) != "check" {
+			skipped = append(skipped, fmt.Sprintf("ограничение %q (%s): ClickHouse поддерживает только CHECK", constraint.Name, constraint.Type))
+			continue
+		}
+		definition, err := clickhouseCheck(schema.TableName, i, constraint)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+
+	table := quoteClickHouse(schema.TableName)
+	if opts.Schema != "" {
+		table = quoteClickHouse(opts.Schema) + "." + table
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n    %s\n)\nENGINE = %s", table, strings.Join(definitions, ",\n    "), engine)
+	if partition != "" {
+		fmt.Fprintf(&b, "\nPARTITION BY %s", partition)
+	}
+	if len(orderBy) > 0 {
+		fmt.Fprintf(&b, "\nORDER BY (%s)", quoteClickHouseList(orderBy))
+	} else {
+		b.WriteString("\nORDER BY tuple()")
+	}
+	if len(schema.PrimaryKey) > 0 && !sameColumns(schema.PrimaryKey, orderBy) {
+		fmt.Fprintf(&b, "\nPRIMARY KEY (%s)", quoteClickHouseList(schema.PrimaryKey))
+	}
+	var settings map[string]interface{}
+	if !strings.HasPrefix(engine, "Replicated") {
+		fmt.Fprintf(&b, "\nSETTINGS non_replicated_deduplication_window = %d", deduplicationWindow)
+		settings = map[string]interface{}{
+			"non_replicated_deduplication_window": deduplicationWindow,
+			"reason":                              "повторная загрузка батча с тем же insert_deduplication_token не создает дублей",
+		}
+	}
+	b.WriteString(";\n")
+
+	metadata := map[string]interface{}{
+		"generator":    "native",
+		"dialect":      DialectClickHouse,
+		"columns":      columnInfo,
+		"engine":       map[string]interface{}{"value": engine, "reason": engineReason},
+		"order_by":     map[string]interface{}{"columns": orderBy, "reason": orderReason},
+		"partition_by": map[string]interface{}{"expression": partition, "reason": partitionReason},
+	}
+	if settings != nil {
+		metadata["settings"] = settings
+	}
+	if len(skipped) > 0 {
+		metadata["skipped"] = skipped
+	}
+
+	return &models.GenerateDDLResponse{
+		DDL:      b.String(),
+		Database: DialectClickHouse,
+		Tables:   []string{schema.TableName},
+		Metadata: metadata,
+	}, nil
+}
+
+// clickhouseType выбирает тип колонки и возвращает причины выбора.
+// Nullable добавляется только при наличии пропусков: он замедляет чтение и запрещен в ключах
+func clickhouseType(column *clickhouseColumn, rows int, key bool) (string, []string) {
+	field, stats := column.field, column.stats
+	base, reason := clickhouseBaseType(column.logical, field.Type, stats, key)
+	reasons := []string{reason}
+
+	if base == "String" && stats != nil && lowCardinality(stats, rows) {
+		base = "LowCardinality(String)"
+		reasons = append(reasons, fmt.Sprintf("LowCardinality: %d уникальных значений на %d строк", stats.DistinctCount, rows))
+	}
+
+	switch {
+	case key:
+		reasons = append(reasons, "без Nullable: колонка входит в первичный ключ")
+	case stats != nil && stats.NullCount > 0:
+		column.nullable = true
+		reasons = append(reasons, fmt.Sprintf("Nullable: %d пропусков в данных", stats.NullCount))
+	case stats == nil && field.Nullable:
+		column.nullable = true
+		reasons = append(reasons, "Nullable: колонка допускает NULL по схеме")
+	}
+	if column.nullable && !strings.HasPrefix(base, "Nullable(") {
+		// LowCardinality оборачивает Nullable, а не наоборот
+		if inner, ok := strings.CutPrefix(base, "LowCardinality("); ok {
+			base = "LowCardinality(Nullable(" + inner + ")"
+		} else {
+			base = "Nullable(" + base + ")"
+		}
+	}
+	return base, reasons
+}
+
+// clickhouseBaseType переводит логический тип в тип ClickHouse с учетом диапазона значений.
+// Остальные типы считаются типами ClickHouse и передаются как есть
+func clickhouseBaseType(logical, declared string, stats *models.DataField, key bool) (string, string) {
+	switch logical {
+	case models.FieldTypeInteger, "int", "int64", "long":
+		if stats == nil {
+			return "Int64", "целые числа без статистики диапазона"
+		}
+		if stats.MinValue >= 0 {
+			if !key && stats.MaxValue <= math.MaxUint32 {
+				return "UInt32", fmt.Sprintf("неотрицательные значения до %g помещаются в UInt32", stats.MaxValue)
+			}
+			return "UInt64", fmt.Sprintf("неотрицательные значения до %g", stats.MaxValue)
+		}
+		if !key && stats.MinValue >= math.MinInt32 && stats.MaxValue <= math.MaxInt32 {
+			return "Int32", fmt.Sprintf("значения от %g до %g помещаются в Int32", stats.MinValue, stats.MaxValue)
+		}
+		return "Int64", fmt.Sprintf("значения от %g до %g", stats.MinValue, stats.MaxValue)
+	case models.FieldTypeFloat, "double", "number":
+		return "Float64", "дробные числа"
+	case "decimal":
+		return "Decimal(38, 10)", "точные десятичные числа"
+	case models.FieldTypeBoolean, "bool":
+		return "Bool", "логические значения"
+	case models.FieldTypeDate:
+		if stats != nil && stats.MinValue < 0 {
+			return "Date32", "даты раньше 1970 года не помещаются в Date"
+		}
+		return "Date", "даты без времени"
+	case models.FieldTypeTimestamp, "datetime":
+		if stats != nil && stats.MinValue < 0 {
+			return "DateTime64(3)", "время раньше 1970 года не помещается в DateTime"
+		}
+		return "DateTime", "дата и время с точностью до секунды"
+	case models.FieldTypeString, "", "text", "varchar", "json":
+		return "String", "строки"
+	case "uuid":
+		return "UUID", "идентификаторы UUID"
+	default:
+		return declared, "тип указан в схеме"
+	}
+}
+
+// lowCardinality сообщает, что уникальных значений мало относительно числа строк
+func lowCardinality(stats *models.DataField, rows int) bool {
+	distinct := stats.DistinctCount
+	return distinct > 0 && distinct <= lowCardinalityMax && rows > 0 && distinct*2 <= rows
+}
+
+// clickhouseOrderBy выбирает ключ сортировки: первичный ключ схемы или колонки без пропусков
+// с низкой кардинальностью (LowCardinality, даты, *_id) по возрастанию числа уникальных значений
+func clickhouseOrderBy(schema *models.TableSchema, columns []*clickhouseColumn) ([]string, string) {
+	if len(schema.SortingKey) > 0 {
+		return schema.SortingKey, "ключ сортировки схемы"
+	}
+	if len(schema.PrimaryKey) > 0 {
+		return schema.PrimaryKey, "первичный ключ схемы"
+	}
+
+	var candidates []*clickhouseColumn
+	for _, column := range columns {
+		if column.nullable {
+			continue
+		}
+		name := column.field.Name
+		switch {
+		case strings.HasPrefix(column.typ, "LowCardinality("),
+			column.logical == models.FieldTypeDate, column.logical == models.FieldTypeTimestamp,
+			column.field.Indexed,
+			(column.logical == models.FieldTypeInteger || column.logical == models.FieldTypeString) && (name == "id" || strings.HasSuffix(name, "_id")):
+			candidates = append(candidates, column)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "нет колонок без пропусков, подходящих для ключа сортировки: ORDER BY tuple()"
+	}
+
+	// Колонки с меньшим числом уникальных значений первыми: лучше сжатие и отсечение гранул
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distinctCount(candidates[i]) < distinctCount(candidates[j])
+	})
+	if len(candidates) > orderByMaxColumns {
+		candidates = candidates[:orderByMaxColumns]
+	}
+	orderBy := make([]string, len(candidates))
+	for i, column := range candidates {
+		orderBy[i] = column.field.Name
+	}
+	return orderBy, "колонки без пропусков с низкой кардинальностью, даты и идентификаторы по возрастанию числа уникальных значений"
+}
+
+// distinctCount возвращает число уникальных значений; без статистики колонка считается уникальной
+func distinctCount(column *clickhouseColumn) int {
+	if column.stats == nil || column.stats.DistinctCount == 0 {
+		return math.MaxInt
+	}
+	return column.stats.DistinctCount
+}
+
+// clickhouseEngine выбирает движок: явно заданный, ReplacingMergeTree для таблиц
+// с первичным ключом (повторная загрузка не создает дублей) или MergeTree
+func clickhouseEngine(schema *models.TableSchema, columns map[string]*clickhouseColumn, engine string) (string, string) {
+	if engine != "" {
+		return engine + "()", "движок задан в options.engine"
+	}
+	if len(schema.PrimaryKey) == 0 {
+		return "MergeTree()", "первичный ключ не определен: строки только добавляются"
+	}
+	for _, name := range versionColumnNames {
+		column, ok := columns[name]
+		if !ok || column.nullable {
+			continue
+		}
+		switch column.logical {
+		case models.FieldTypeInteger, models.FieldTypeDate, models.FieldTypeTimestamp:
+			return fmt.Sprintf("ReplacingMergeTree(%s)", quoteClickHouse(name)),
+				fmt.Sprintf("первичный ключ определен: при слиянии остается строка с наибольшим %s", name)
+		}
+	}
+	return "ReplacingMergeTree()", "первичный ключ определен: дубли по ключу удаляются при слиянии"
+}
+
+// clickhousePartition выбирает колонку даты без пропусков для PARTITION BY:
+// сначала из ключа сортировки, затем первую в таблице. ReplacingMergeTree удаляет
+// дубли только внутри партиции, поэтому для таблиц с ключом дата берется только из ключа
+func clickhousePartition(columns []*clickhouseColumn, orderBy []string, keyOnly bool) (string, string) {
+	inOrder := make(map[string]bool, len(orderBy))
+	for _, name := range orderBy {
+		inOrder[name] = true
+	}
+
+	var chosen *clickhouseColumn
+	for _, column := range columns {
+		if column.nullable || (column.logical != models.FieldTypeDate && column.logical != models.FieldTypeTimestamp) {
+			continue
+		}
+		if keyOnly && !inOrder[column.field.Name] {
+			continue
+		}
+		if chosen == nil || (inOrder[column.field.Name] && !inOrder[chosen.field.Name]) {
+			chosen = column
+		}
+	}
+	if chosen == nil && keyOnly {
+		return "", "первичный ключ не содержит дат: таблица без партиций, чтобы дубли по ключу удалялись"
+	}
+	if chosen == nil {
+		return "", "нет колонок даты без пропусков: таблица без партиций"
+	}
+
+	name := quoteClickHouse(chosen.field.Name)
+	if chosen.stats != nil && chosen.stats.MaxValue-chosen.stats.MinValue > yearlyPartitionSpan {
+		return "toYear(" + name + ")", fmt.Sprintf("колонка %s охватывает больше 5 лет: партиции по годам", chosen.field.Name)
+	}
+	return "toYYYYMM(" + name + ")", fmt.Sprintf("колонка %s: партиции по месяцам", chosen.field.Name)
+}
+
+// clickhouseIndex формирует индекс пропуска данных. Без типа для строк используется
+// bloom_filter, для остальных колонок — minmax
+func clickhouseIndex(table string, index models.TableIndex, columns map[string]*clickhouseColumn) (string, error) {
+	kind := strings.ToLower(index.Type)
+	if kind == "" {
+		kind = "minmax"
+		if column := columns[index.Fields[0]]; column != nil && strings.Contains(column.typ, "String") {
+			kind = "bloom_filter"
+		}
+	}
+	if !nativeTypePattern.MatchString(kind) {
+		return "", models.NewValidationError("Недопустимый тип индекса ClickHouse", map[string]interface{}{"index": index.Name, "type": index.Type})
+	}
+
+	expression := quoteClickHouse(index.Fields[0])
+	if len(index.Fields) > 1 {
+		expression = "(" + quoteClickHouseList(index.Fields) + ")"
+	}
+	return fmt.Sprintf("INDEX %s %s TYPE %s GRANULARITY 4", quoteClickHouse(indexName(table, index)), expression, kind), nil
+}
+
+// clickhouseCheck формирует ограничение CHECK из колонки, оператора и значения
+func clickhouseCheck(table string, position int, constraint models.TableConstraint) (string, error) {
+	name := constraintName(table, position, constraint)
+	expression, err := checkExpression(constraint, quoteClickHouse, quoteClickHouseString)
+	if err != nil {
+		return "", models.NewValidationError("Некорректное ограничение таблицы", map[string]interface{}{
+			"constraint": name, "problem": err.Error(),
+		})
+	}
+	return "CONSTRAINT " + quoteClickHouse(name) + " CHECK " + expression, nil
+}
+
+// quoteClickHouse заключает идентификатор в обратные кавычки
+func quoteClickHouse(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+func quoteClickHouseList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteClickHouse(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteClickHouseString возвращает строковый литерал ClickHouse
+func quoteClickHouseString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
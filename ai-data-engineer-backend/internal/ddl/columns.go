@@ -0,0 +1,56 @@
+package ddl
+
+import (
+	"ai-data-engineer-backend/domain/models"
+)
+
+// ColumnType тип колонки целевой таблицы, выбранный так же, как при генерации DDL
+type ColumnType struct {
+	Name string `json:"name"`
+	// Type тип СУБД: BIGINT, VARCHAR(20), Nullable(DateTime64(3))
+	Type string `json:"type"`
+	// Family семейство типа (Family*); пустое для типов без приведения (JSONB, UUID, массивы)
+	Family   string `json:"family,omitempty"`
+	Nullable bool   `json:"nullable"`
+	// Bits разрядность целых чисел или мантиссы дробных
+	Bits     int  `json:"-"`
+	Unsigned bool `json:"-"`
+	// Length максимальная длина строки; 0 — без ограничения
+	Length int `json:"-"`
+	// Precision и Scale точность десятичных чисел; Precision 0 — без ограничения
+	Precision int `json:"-"`
+	Scale     int `json:"-"`
+	// DateOnly дата без времени
+	DateOnly bool `json:"-"`
+}
+
+// ResolveColumns возвращает типы колонок схемы в диалекте dialect в порядке schema.Fields.
+// profile необязателен и уточняет типы так же, как при генерации DDL
+func ResolveColumns(dialect string, schema *models.TableSchema, profile *models.DataProfile) ([]ColumnType, error) {
+	generator, err := NewGenerator(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchema(schema); err != nil {
+		return nil, err
+	}
+
+	resolved := resolveSchema(generator.Dialect(), schema, profile)
+	columns := make([]ColumnType, 0, len(resolved.columns))
+	for _, column := range resolved.columns {
+		info := parseType(generator.Dialect(), column.typ)
+		columns = append(columns, ColumnType{
+			Name:      column.field.Name,
+			Type:      column.typ,
+			Family:    info.family,
+			Nullable:  column.nullable,
+			Bits:      info.bits,
+			Unsigned:  info.unsigned,
+			Length:    info.length,
+			Precision: info.precision,
+			Scale:     info.scale,
+			DateOnly:  info.family == FamilyTime && info.resolution == 0,
+		})
+	}
+	return columns, nil
+}
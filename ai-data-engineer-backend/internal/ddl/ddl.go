@@ -0,0 +1,233 @@
+// Package ddl генерирует DDL целевых СУБД из models.TableSchema без обращения к LLM.
+// Результат детерминирован: одинаковая схема всегда дает одинаковый скрипт
+package ddl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// Поддерживаемые диалекты
+const (
+	DialectPostgres   = "postgres"
+	DialectClickHouse = "clickhouse"
+)
+
+// Options параметры генерации
+type Options struct {
+	// Schema схема (namespace) таблицы, для ClickHouse — база данных; пустая — по умолчанию
+	Schema string
+	// Engine движок ClickHouse семейства MergeTree; пустой — выбирается по схеме
+	Engine string
+	// ApproveDestructive подтверждает разрушающие изменения в Diff
+	ApproveDestructive bool
+}
+
+// Generator генерирует DDL для одной СУБД.
+// profile необязателен: по нему уточняются типы колонок
+type Generator interface {
+	Dialect() string
+	Generate(schema *models.TableSchema, profile *models.DataProfile, opts Options) (*models.GenerateDDLResponse, error)
+}
+
+// NewGenerator возвращает генератор для database. Пустое значение — PostgreSQL
+func NewGenerator(database string) (Generator, error) {
+	switch strings.ToLower(strings.TrimSpace(database)) {
+	case "", "postgres", "postgresql":
+		return postgresGenerator{}, nil
+	case "clickhouse":
+		return clickhouseGenerator{}, nil
+	default:
+		return nil, models.NewValidationError(fmt.Sprintf("Генерация DDL для %q не поддерживается", database),
+			map[string]interface{}{"database": database, "supported": []string{DialectPostgres, DialectClickHouse}})
+	}
+}
+
+// OptionsFromMap читает Options из GenerateDDLRequest.Options
+func OptionsFromMap(values map[string]interface{}) Options {
+	var opts Options
+	if schema, ok := values["schema"].(string); ok {
+		opts.Schema = schema
+	}
+	if engine, ok := values["engine"].(string); ok {
+		opts.Engine = engine
+	}
+	return opts
+}
+
+// nativeTypePattern допустимые типы, передаваемые в DDL как есть: имя типа,
+// необязательные параметры и признак массива. Параметрами могут быть числа,
+// строковые литералы (часовой пояс) и вложенные типы ClickHouse до трех уровней:
+// LowCardinality(Nullable(String)), Nullable(DateTime64(3, 'UTC'))
+var nativeTypePattern = regexp.MustCompile(`^` + typeExpression(3) + `(\[\])?$`)
+
+// typeExpression возвращает регулярное выражение типа с вложенностью до depth уровней
+func typeExpression(depth int) string {
+	const name, literal = `[A-Za-z][A-Za-z0-9_ ]*`, `\d+|'[A-Za-z0-9_/+:-]*'`
+	argument := literal
+	if depth > 1 {
+		argument += "|" + typeExpression(depth-1)
+	}
+	argument = "(" + argument + ")"
+	return name + `(\(\s*` + argument + `(\s*,\s*` + argument + `)*\s*\))?`
+}
+
+// validateSchema проверяет схему перед генерацией и возвращает список проблем
+func validateSchema(schema *models.TableSchema) error {
+	var problems []string
+	if schema == nil {
+		return models.NewValidationError("Схема таблицы обязательна", nil)
+	}
+	if strings.TrimSpace(schema.TableName) == "" {
+		problems = append(problems, "не указано имя таблицы")
+	}
+	if len(schema.Fields) == 0 {
+		problems = append(problems, "таблица не содержит колонок")
+	}
+
+	columns := make(map[string]bool, len(schema.Fields))
+	for i, field := range schema.Fields {
+		switch {
+		case strings.TrimSpace(field.Name) == "":
+			problems = append(problems, fmt.Sprintf("колонка %d: не указано имя", i+1))
+		case columns[field.Name]:
+			problems = append(problems, fmt.Sprintf("колонка %q указана несколько раз", field.Name))
+		}
+		columns[field.Name] = true
+		if field.Type != "" && !nativeTypePattern.MatchString(field.Type) {
+			problems = append(problems, fmt.Sprintf("колонка %q: недопустимый тип %q", field.Name, field.Type))
+		}
+	}
+
+	checkColumns := func(owner string, names []string) {
+		for _, name := range names {
+			if !columns[name] {
+				problems = append(problems, fmt.Sprintf("%s: колонка %q отсутствует в таблице", owner, name))
+			}
+		}
+	}
+	checkColumns("первичный ключ", schema.PrimaryKey)
+	checkColumns("ключ сортировки", schema.SortingKey)
+	if len(schema.SortingKey) > 0 && !hasPrefix(schema.SortingKey, schema.PrimaryKey) {
+		problems = append(problems, "первичный ключ должен быть началом ключа сортировки")
+	}
+	for _, index := range schema.Indexes {
+		if len(index.Fields) == 0 {
+			problems = append(problems, fmt.Sprintf("индекс %q: не указаны колонки", index.Name))
+		}
+		checkColumns(fmt.Sprintf("индекс %q", index.Name), index.Fields)
+	}
+	for _, constraint := range schema.Constraints {
+		if constraint.Column != "" {
+			checkColumns(fmt.Sprintf("ограничение %q", constraint.Name), []string{constraint.Column})
+		}
+	}
+
+	if len(problems) > 0 {
+		return models.NewValidationError("Некорректная схема таблицы", map[string]interface{}{"problems": problems})
+	}
+	return nil
+}
+
+// profileFields индексирует поля профиля по имени колонки
+func profileFields(profile *models.DataProfile) map[string]*models.DataField {
+	fields := make(map[string]*models.DataField)
+	if profile == nil {
+		return fields
+	}
+	for i := range profile.Fields {
+		fields[profile.Fields[i].Name] = &profile.Fields[i]
+		fields[Identifier(profile.Fields[i].Name)] = &profile.Fields[i]
+	}
+	return fields
+}
+
+// indexName возвращает имя индекса: заданное или построенное из таблицы и колонок
+func indexName(table string, index models.TableIndex) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	return truncateIdentifier("idx_" + table + "_" + strings.Join(index.Fields, "_"))
+}
+
+// hasPrefix сообщает, начинается ли список колонок key с prefix
+func hasPrefix(key, prefix []string) bool {
+	return len(prefix) <= len(key) && sameColumns(key[:len(prefix)], prefix)
+}
+
+// checkOperators операторы ограничения CHECK и их запись в SQL
+var checkOperators = map[string]string{
+	"=": "=", "<>": "<>", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">=", "in": "IN", "not_in": "NOT IN",
+}
+
+// checkExpression строит выражение CHECK из колонки, оператора и значения ограничения.
+// Колонка и строки заключаются в кавычки функциями диалекта, поэтому выражение не может
+// выйти за пределы ограничения
+func checkExpression(constraint models.TableConstraint, quote, quoteString func(string) string) (string, error) {
+	if constraint.Column == "" {
+		return "", fmt.Errorf("не указана колонка")
+	}
+	operator, ok := checkOperators[strings.ToLower(constraint.Operator)]
+	if !ok {
+		return "", fmt.Errorf("недопустимый оператор %q", constraint.Operator)
+	}
+	if operator != "IN" && operator != "NOT IN" {
+		literal, err := checkLiteral(constraint.Value, quoteString)
+		if err != nil {
+			return "", err
+		}
+		return quote(constraint.Column) + " " + operator + " " + literal, nil
+	}
+
+	values, ok := constraint.Value.([]interface{})
+	if !ok || len(values) == 0 {
+		return "", fmt.Errorf("для %s значение должно быть непустым списком", constraint.Operator)
+	}
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literal, err := checkLiteral(value, quoteString)
+		if err != nil {
+			return "", err
+		}
+		literals[i] = literal
+	}
+	return quote(constraint.Column) + " " + operator + " (" + strings.Join(literals, ", ") + ")", nil
+}
+
+// checkLiteral возвращает литерал значения: числа и логические значения записываются как есть,
+// строки — через quoteString
+func checkLiteral(value interface{}, quoteString func(string) string) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v), nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("недопустимое число %v", v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case nil:
+		return "", fmt.Errorf("не указано значение")
+	default:
+		return "", fmt.Errorf("значение должно быть числом, строкой или логическим значением")
+	}
+}
+
+// constraintName возвращает имя ограничения: заданное или построенное из таблицы,
+// типа и позиции ограничения в схеме
+func constraintName(table string, position int, constraint models.TableConstraint) string {
+	if constraint.Name != "" {
+		return constraint.Name
+	}
+	return withSuffix(table, fmt.Sprintf("_%s_%d", strings.ToLower(constraint.Type), position+1))
+}
@@ -0,0 +1,688 @@
+package ddl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// resolvedColumn колонка схемы с типом, выбранным генератором диалекта
+type resolvedColumn struct {
+	field    models.TableField
+	typ      string
+	nullable bool
+}
+
+// resolvedSchema схема таблицы с типами колонок диалекта
+type resolvedSchema struct {
+	schema     *models.TableSchema
+	columns    []*resolvedColumn
+	byName     map[string]*resolvedColumn
+	primaryKey map[string]bool
+	// sortingKey колонки ORDER BY ClickHouse, выбранные так же, как при генерации DDL
+	sortingKey map[string]bool
+	// clickhouse колонки для выбора типа индексов пропуска данных
+	clickhouse map[string]*clickhouseColumn
+}
+
+// definition именованный индекс или ограничение с SQL определением
+type definition struct {
+	name string
+	sql  string
+}
+
+// differ строит изменения, переводящие схему from в схему to
+type differ struct {
+	dialect string
+	table   string
+	opts    Options
+	from    *resolvedSchema
+	to      *resolvedSchema
+}
+
+// Diff сравнивает две схемы одной таблицы и возвращает изменения, переводящие from в to,
+// с командами ALTER диалекта в порядке выполнения. Колонки сопоставляются по имени:
+// переименование выглядит как удаление и добавление. Имя таблицы берется из from.
+// Профили необязательны и уточняют типы колонок так же, как при генерации DDL.
+// Разрушающие изменения попадают в Statements только при opts.ApproveDestructive
+func Diff(dialect string, from, to *models.TableSchema, fromProfile, toProfile *models.DataProfile, opts Options) (*models.SchemaDiffResponse, error) {
+	generator, err := NewGenerator(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchema(from); err != nil {
+		return nil, err
+	}
+	if err := validateSchema(to); err != nil {
+		return nil, err
+	}
+	target := *to
+	target.TableName = from.TableName
+
+	d := &differ{dialect: generator.Dialect(), opts: opts}
+	if d.dialect == DialectPostgres {
+		if err := validatePostgres(&target); err != nil {
+			return nil, err
+		}
+		d.table = quotePostgres(from.TableName)
+		if opts.Schema != "" {
+			d.table = quotePostgres(opts.Schema) + "." + d.table
+		}
+	} else {
+		d.table = quoteClickHouse(from.TableName)
+		if opts.Schema != "" {
+			d.table = quoteClickHouse(opts.Schema) + "." + d.table
+		}
+	}
+	d.from = resolveSchema(d.dialect, from, fromProfile)
+	d.to = resolveSchema(d.dialect, &target, toProfile)
+
+	changes, err := d.changes()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SchemaDiffResponse{
+		Database:   d.dialect,
+		Table:      from.TableName,
+		Changes:    changes,
+		Statements: []string{},
+	}
+	for _, change := range changes {
+		if change.Destructive {
+			response.Destructive = true
+			if !opts.ApproveDestructive {
+				continue
+			}
+		}
+		response.Statements = append(response.Statements, change.Statements...)
+	}
+	response.RequiresApproval = response.Destructive && !opts.ApproveDestructive
+	return response, nil
+}
+
+// resolveSchema выбирает типы колонок так же, как генератор диалекта
+func resolveSchema(dialect string, schema *models.TableSchema, profile *models.DataProfile) *resolvedSchema {
+	stats := profileFields(profile)
+	var rows int
+	if profile != nil {
+		rows = profile.SampledRows
+	}
+	resolved := &resolvedSchema{
+		schema:     schema,
+		byName:     make(map[string]*resolvedColumn, len(schema.Fields)),
+		primaryKey: make(map[string]bool, len(schema.PrimaryKey)),
+		sortingKey: make(map[string]bool),
+		clickhouse: make(map[string]*clickhouseColumn),
+	}
+	var clickhouseColumns []*clickhouseColumn
+	for _, name := range schema.PrimaryKey {
+		resolved.primaryKey[name] = true
+	}
+
+	for _, field := range schema.Fields {
+		column := &resolvedColumn{field: field}
+		key := resolved.primaryKey[field.Name]
+		if dialect == DialectClickHouse {
+			ch := &clickhouseColumn{field: field, stats: stats[field.Name], logical: strings.TrimSpace(field.Type)}
+			ch.typ, _ = clickhouseType(ch, rows, key)
+			column.typ = ch.typ
+			column.nullable = strings.Contains(ch.typ, "Nullable(")
+			resolved.clickhouse[field.Name] = ch
+			clickhouseColumns = append(clickhouseColumns, ch)
+		} else {
+			column.typ, _ = postgresType(field, stats[field.Name], key)
+			column.nullable = field.Nullable && !key
+		}
+		resolved.columns = append(resolved.columns, column)
+		resolved.byName[field.Name] = column
+	}
+	if dialect == DialectClickHouse {
+		orderBy, _ := clickhouseOrderBy(schema, clickhouseColumns)
+		for _, name := range append(orderBy, schema.PrimaryKey...) {
+			resolved.sortingKey[name] = true
+		}
+	}
+	return resolved
+}
+
+// changes возвращает изменения в порядке выполнения: удаление индексов и ограничений,
+// добавление колонок, смена типов и NULL, удаление колонок, первичный ключ,
+// новые ограничения и индексы, комментарии
+func (d *differ) changes() ([]models.SchemaChange, error) {
+	fromIndexes, err := d.indexes(d.from)
+	if err != nil {
+		return nil, err
+	}
+	toIndexes, err := d.indexes(d.to)
+	if err != nil {
+		return nil, err
+	}
+	fromConstraints, err := d.constraints(d.from, nil)
+	if err != nil {
+		return nil, err
+	}
+	toConstraints, err := d.constraints(d.to, fromConstraints)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.SchemaChange{}
+	for _, index := range removedDefinitions(fromIndexes, toIndexes) {
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeDropIndex,
+			Object:     index.name,
+			Statements: []string{d.dropIndex(index.name)},
+		})
+	}
+	for _, constraint := range removedDefinitions(fromConstraints, toConstraints) {
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeDropConstraint,
+			Object:     constraint.name,
+			Statements: []string{d.alter("DROP CONSTRAINT IF EXISTS " + d.quote(constraint.name))},
+		})
+	}
+
+	keyChanged := !sameColumns(d.from.schema.PrimaryKey, d.to.schema.PrimaryKey)
+	if keyChanged && d.dialect == DialectPostgres && len(d.from.schema.PrimaryKey) > 0 {
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangePrimaryKey,
+			Object:     withSuffix(d.from.schema.TableName, "_pkey"),
+			From:       strings.Join(d.from.schema.PrimaryKey, ", "),
+			Reason:     "первичный ключ изменился: старый ключ удаляется до изменения колонок",
+			Statements: []string{d.alter("DROP CONSTRAINT IF EXISTS " + d.quote(withSuffix(d.from.schema.TableName, "_pkey")))},
+		})
+	}
+
+	for _, column := range d.to.columns {
+		if _, exists := d.from.byName[column.field.Name]; !exists {
+			changes = append(changes, d.addColumn(column))
+		}
+	}
+	for _, column := range d.to.columns {
+		if old, exists := d.from.byName[column.field.Name]; exists {
+			changes = append(changes, d.alterColumn(old, column)...)
+		}
+	}
+	for _, column := range d.from.columns {
+		if _, exists := d.to.byName[column.field.Name]; !exists {
+			change := models.SchemaChange{
+				Type:        models.SchemaChangeDropColumn,
+				Object:      column.field.Name,
+				From:        column.typ,
+				Destructive: true,
+				Reason:      "данные колонки будут удалены",
+				Statements:  []string{d.alter("DROP COLUMN IF EXISTS " + d.quote(column.field.Name))},
+			}
+			if d.from.sortingKey[column.field.Name] {
+				change.Reason = "колонка входит в ключ сортировки: удаляется только пересозданием таблицы"
+				change.Statements = nil
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	if keyChanged && (d.dialect == DialectClickHouse || len(d.to.schema.PrimaryKey) > 0) {
+		changes = append(changes, d.primaryKey())
+	}
+
+	for _, constraint := range removedDefinitions(toConstraints, fromConstraints) {
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeAddConstraint,
+			Object:     constraint.name,
+			Statements: []string{d.alter("ADD " + constraint.sql)},
+		})
+	}
+	for _, index := range removedDefinitions(toIndexes, fromIndexes) {
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeAddIndex,
+			Object:     index.name,
+			Statements: d.addIndex(index),
+		})
+	}
+
+	for _, column := range d.to.columns {
+		var previous string
+		if old, exists := d.from.byName[column.field.Name]; exists {
+			previous = old.field.Description
+		}
+		if column.field.Description != previous {
+			changes = append(changes, models.SchemaChange{
+				Type:       models.SchemaChangeComment,
+				Object:     column.field.Name,
+				From:       previous,
+				To:         column.field.Description,
+				Statements: []string{d.comment(column.field.Name, column.field.Description)},
+			})
+		}
+	}
+	return changes, nil
+}
+
+// addColumn добавляет колонку. В PostgreSQL NOT NULL устанавливается отдельной командой,
+// чтобы между ними можно было заполнить колонку в непустой таблице
+func (d *differ) addColumn(column *resolvedColumn) models.SchemaChange {
+	change := models.SchemaChange{Type: models.SchemaChangeAddColumn, Object: column.field.Name, To: column.typ}
+	name := d.quote(column.field.Name)
+	change.Statements = []string{d.alter("ADD COLUMN IF NOT EXISTS " + name + " " + column.typ)}
+	if d.dialect == DialectPostgres && !column.nullable {
+		change.Statements = append(change.Statements, d.alter("ALTER COLUMN "+name+" SET NOT NULL"))
+		change.Reason = "SET NOT NULL завершится ошибкой, если в таблице есть строки: заполните колонку перед ним"
+	}
+	return change
+}
+
+// alterColumn сравнивает тип и допустимость NULL колонки
+func (d *differ) alterColumn(old, column *resolvedColumn) []models.SchemaChange {
+	name := column.field.Name
+	if d.dialect == DialectClickHouse {
+		if old.typ == column.typ {
+			return nil
+		}
+		// В ClickHouse допустимость NULL — часть типа: меняется одной командой MODIFY COLUMN
+		change := d.typeChange(old, column)
+		sameBase := parseType(d.dialect, old.typ) == parseType(d.dialect, column.typ)
+		switch {
+		case old.nullable && !column.nullable && sameBase:
+			change.Type, change.Destructive = models.SchemaChangeSetNotNull, true
+			change.Reason = "NULL будут заменены значениями по умолчанию"
+		case old.nullable && !column.nullable:
+			change.Destructive = true
+			change.Reason += "; NULL будут заменены значениями по умолчанию"
+		case !old.nullable && column.nullable && sameBase:
+			change.Type, change.Reason = models.SchemaChangeDropNotNull, ""
+		}
+		change.Statements = []string{d.alter("MODIFY COLUMN " + d.quote(name) + " " + column.typ)}
+		if d.from.sortingKey[name] {
+			change.Destructive = true
+			change.Reason = "колонка входит в ключ сортировки: тип меняется только пересозданием таблицы"
+			change.Statements = nil
+		}
+		return []models.SchemaChange{change}
+	}
+
+	var changes []models.SchemaChange
+	if old.typ != column.typ {
+		change := d.typeChange(old, column)
+		change.Statements = []string{d.alter(fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s",
+			d.quote(name), column.typ, d.quote(name), column.typ))}
+		changes = append(changes, change)
+	}
+	switch {
+	case old.nullable && !column.nullable:
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeSetNotNull,
+			Object:     name,
+			Reason:     "завершится ошибкой, если колонка содержит NULL",
+			Statements: []string{d.alter("ALTER COLUMN " + d.quote(name) + " SET NOT NULL")},
+		})
+	case !old.nullable && column.nullable:
+		changes = append(changes, models.SchemaChange{
+			Type:       models.SchemaChangeDropNotNull,
+			Object:     name,
+			Statements: []string{d.alter("ALTER COLUMN " + d.quote(name) + " DROP NOT NULL")},
+		})
+	}
+	return changes
+}
+
+// typeChange описывает смену типа: расширение сохраняет значения, сужение
+// и несовместимые преобразования отмечаются как разрушающие
+func (d *differ) typeChange(old, column *resolvedColumn) models.SchemaChange {
+	change := models.SchemaChange{
+		Type:   models.SchemaChangeAlterType,
+		Object: column.field.Name,
+		From:   old.typ,
+		To:     column.typ,
+		Reason: "расширение типа: значения сохраняются",
+	}
+	if !widens(parseType(d.dialect, old.typ), parseType(d.dialect, column.typ)) {
+		change.Destructive = true
+		change.Reason = "сужение или несовместимая смена типа: значения могут быть потеряны, преобразование может завершиться ошибкой"
+	}
+	return change
+}
+
+// primaryKey задает новый первичный ключ. Ключ сортировки ClickHouse задается при создании
+// таблицы, поэтому для ClickHouse изменение требует пересоздания и команд не содержит
+func (d *differ) primaryKey() models.SchemaChange {
+	change := models.SchemaChange{
+		Type:   models.SchemaChangePrimaryKey,
+		Object: withSuffix(d.to.schema.TableName, "_pkey"),
+		From:   strings.Join(d.from.schema.PrimaryKey, ", "),
+		To:     strings.Join(d.to.schema.PrimaryKey, ", "),
+	}
+	if d.dialect == DialectClickHouse {
+		change.Destructive = true
+		change.Reason = "ключ сортировки ClickHouse меняется только пересозданием таблицы"
+		return change
+	}
+	if len(d.to.schema.PrimaryKey) > 0 {
+		change.Statements = []string{d.alter(fmt.Sprintf("ADD CONSTRAINT %s PRIMARY KEY (%s)",
+			d.quote(change.Object), quotePostgresList(d.to.schema.PrimaryKey)))}
+	}
+	return change
+}
+
+// indexes возвращает определения индексов схемы
+func (d *differ) indexes(schema *resolvedSchema) ([]definition, error) {
+	var indexes []definition
+	if d.dialect == DialectClickHouse {
+		for _, index := range schema.schema.Indexes {
+			sql, err := clickhouseIndex(schema.schema.TableName, index, schema.clickhouse)
+			if err != nil {
+				return nil, err
+			}
+			indexes = append(indexes, definition{name: indexName(schema.schema.TableName, index), sql: sql})
+		}
+		return indexes, nil
+	}
+	for _, index := range postgresIndexes(schema.schema, schema.primaryKey) {
+		indexes = append(indexes, definition{
+			name: indexName(schema.schema.TableName, index),
+			sql:  postgresCreateIndex(schema.schema.TableName, d.table, index),
+		})
+	}
+	return indexes, nil
+}
+
+// constraints возвращает определения ограничений схемы; ClickHouse поддерживает только CHECK.
+// Ограничение живой таблицы, которое нельзя построить из полей (сложный CHECK, составной
+// внешний ключ), сравнивается по тексту Expression и удаляется по имени, но в DDL не попадает:
+// в целевой схеме оно допускается, только если совпадает с ограничением existing исходной
+func (d *differ) constraints(schema *resolvedSchema, existing []definition) ([]definition, error) {
+	var constraints []definition
+	for i, constraint := range schema.schema.Constraints {
+		var sql string
+		var err error
+		if d.dialect == DialectClickHouse {
+			if strings.ToLower(constraint.Type) != "check" {
+				continue
+			}
+			sql, err = clickhouseCheck(schema.schema.TableName, i, constraint)
+		} else {
+			sql, err = postgresConstraint(schema.schema.TableName, i, constraint)
+		}
+		name := constraintName(schema.schema.TableName, i, constraint)
+		if err != nil {
+			if constraint.Expression == "" {
+				return nil, err
+			}
+			raw := definition{name: name, sql: "-- " + strings.ToLower(constraint.Type) + " " + strings.TrimSpace(constraint.Expression)}
+			if schema != d.from && !slices.Contains(existing, raw) {
+				return nil, err
+			}
+			sql = raw.sql
+		}
+		constraints = append(constraints, definition{name: name, sql: sql})
+	}
+	return constraints, nil
+}
+
+// dropIndex удаляет индекс; индекс PostgreSQL находится в схеме таблицы
+func (d *differ) dropIndex(name string) string {
+	if d.dialect == DialectClickHouse {
+		return d.alter("DROP INDEX IF EXISTS " + quoteClickHouse(name))
+	}
+	index := quotePostgres(name)
+	if d.opts.Schema != "" {
+		index = quotePostgres(d.opts.Schema) + "." + index
+	}
+	return "DROP INDEX IF EXISTS " + index + ";"
+}
+
+// addIndex создает индекс. Индекс пропуска данных ClickHouse строится только для новых
+// данных, поэтому для существующих частей он материализуется отдельной командой
+func (d *differ) addIndex(index definition) []string {
+	if d.dialect == DialectClickHouse {
+		return []string{d.alter("ADD " + index.sql), d.alter("MATERIALIZE INDEX " + quoteClickHouse(index.name))}
+	}
+	return []string{index.sql}
+}
+
+// comment задает комментарий колонки; пустое описание удаляет комментарий
+func (d *differ) comment(column, description string) string {
+	if d.dialect == DialectClickHouse {
+		return d.alter("COMMENT COLUMN " + quoteClickHouse(column) + " " + quoteClickHouseString(description))
+	}
+	value := "NULL"
+	if description != "" {
+		value = quotePostgresString(description)
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", d.table, quotePostgres(column), value)
+}
+
+// alter формирует команду ALTER TABLE
+func (d *differ) alter(action string) string {
+	return "ALTER TABLE " + d.table + " " + action + ";"
+}
+
+// quote заключает идентификатор в кавычки диалекта
+func (d *differ) quote(name string) string {
+	if d.dialect == DialectClickHouse {
+		return quoteClickHouse(name)
+	}
+	return quotePostgres(name)
+}
+
+// removedDefinitions возвращает определения из a, отсутствующие в b или отличающиеся от них
+func removedDefinitions(a, b []definition) []definition {
+	existing := make(map[string]string, len(b))
+	for _, item := range b {
+		existing[item.name] = item.sql
+	}
+	var removed []definition
+	for _, item := range a {
+		if sql, ok := existing[item.name]; !ok || sql != item.sql {
+			removed = append(removed, item)
+		}
+	}
+	return removed
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Семейства типов колонок: по ним сравниваются типы в Diff и приводятся значения при загрузке
+const (
+	FamilyInteger = "integer"
+	FamilyFloat   = "float"
+	FamilyDecimal = "decimal"
+	FamilyString  = "string"
+	FamilyTime    = "time"
+	FamilyBoolean = "boolean"
+)
+
+// typeInfo разобранный тип колонки
+type typeInfo struct {
+	// family семейство типа; пустое для типов, которые сравниваются только по имени
+	family string
+	name   string
+	// bits разрядность целых чисел или мантиссы дробных
+	bits     int
+	unsigned bool
+	// length длина строки; 0 — без ограничения
+	length int
+	// precision и scale точность decimal; precision 0 — без ограничения
+	precision int
+	scale     int
+	// resolution точность времени: 0 — дни, 1 — секунды, 2 — доли секунды
+	resolution int
+	// wide расширенный диапазон дат (до 1970 года и после 2106 года)
+	wide bool
+	zone bool
+}
+
+var clickhouseIntPattern = regexp.MustCompile(`^(U?)Int(\d+)$`)
+
+// clickhouseDecimalPrecision точность DecimalN(S)
+var clickhouseDecimalPrecision = map[string]int{"Decimal32": 9, "Decimal64": 18, "Decimal128": 38, "Decimal256": 76}
+
+// parseType разбирает тип диалекта. Типы ClickHouse чувствительны к регистру,
+// типы PostgreSQL приходят в каноническом написании (CanonicalPostgresType)
+func parseType(dialect, typ string) typeInfo {
+	typ = strings.TrimSpace(typ)
+	if dialect == DialectClickHouse {
+		for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+			if inner, ok := strings.CutPrefix(typ, wrapper); ok {
+				typ = strings.TrimSuffix(inner, ")")
+			}
+		}
+	}
+	base, params, zone := splitType(typ)
+	info := typeInfo{name: typ}
+	param := func(i int) int {
+		if i < len(params) {
+			return params[i]
+		}
+		return 0
+	}
+
+	if dialect == DialectClickHouse {
+		if match := clickhouseIntPattern.FindStringSubmatch(base); match != nil {
+			info.family, info.unsigned = FamilyInteger, match[1] == "U"
+			info.bits, _ = strconv.Atoi(match[2])
+			return info
+		}
+		switch base {
+		case "Float32":
+			info.family, info.bits = FamilyFloat, 24
+		case "Float64":
+			info.family, info.bits = FamilyFloat, 53
+		case "Decimal":
+			info.family, info.precision, info.scale = FamilyDecimal, param(0), param(1)
+		case "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+			info.family, info.precision, info.scale = FamilyDecimal, clickhouseDecimalPrecision[base], param(0)
+		case "String":
+			info.family = FamilyString
+		case "FixedString":
+			info.family, info.length = FamilyString, param(0)
+		case "Date":
+			info.family = FamilyTime
+		case "Date32":
+			info.family, info.wide = FamilyTime, true
+		case "DateTime":
+			info.family, info.resolution, info.zone = FamilyTime, 1, zone
+		case "DateTime64":
+			info.family, info.resolution, info.wide, info.zone = FamilyTime, 2, true, zone
+		case "Bool":
+			info.family = FamilyBoolean
+		}
+		return info
+	}
+
+	switch base {
+	case "SMALLINT":
+		info.family, info.bits = FamilyInteger, 16
+	case "INTEGER":
+		info.family, info.bits = FamilyInteger, 32
+	case "BIGINT":
+		info.family, info.bits = FamilyInteger, 64
+	case "REAL":
+		info.family, info.bits = FamilyFloat, 24
+	case "DOUBLE PRECISION":
+		info.family, info.bits = FamilyFloat, 53
+	case "NUMERIC":
+		info.family, info.precision, info.scale = FamilyDecimal, param(0), param(1)
+	case "TEXT":
+		info.family = FamilyString
+	case "VARCHAR":
+		info.family, info.length = FamilyString, param(0)
+	case "CHAR":
+		info.family, info.length = FamilyString, max(param(0), 1)
+	case "DATE":
+		info.family, info.wide = FamilyTime, true
+	case "TIMESTAMP":
+		info.family, info.resolution, info.wide = FamilyTime, 2, true
+	case "TIMESTAMPTZ":
+		info.family, info.resolution, info.wide, info.zone = FamilyTime, 2, true, true
+	case "BOOLEAN":
+		info.family = FamilyBoolean
+	}
+	return info
+}
+
+// splitType отделяет имя типа от числовых параметров; строковый параметр
+// (часовой пояс DateTime('UTC')) отмечается флагом zone
+func splitType(typ string) (string, []int, bool) {
+	open := strings.IndexByte(typ, '(')
+	if open < 0 || !strings.HasSuffix(typ, ")") {
+		return typ, nil, false
+	}
+	var params []int
+	zone := false
+	for _, raw := range strings.Split(typ[open+1:len(typ)-1], ",") {
+		raw = strings.TrimSpace(raw)
+		if n, err := strconv.Atoi(raw); err == nil {
+			params = append(params, n)
+		} else if strings.HasPrefix(raw, "'") {
+			zone = true
+		}
+	}
+	return strings.TrimSpace(typ[:open]), params, zone
+}
+
+// widens сообщает, что любое значение типа from представимо типом to без потерь
+func widens(from, to typeInfo) bool {
+	if from == to {
+		return true
+	}
+	if from.family == "" || to.family == "" {
+		return false
+	}
+	if to.family == FamilyString && to.length == 0 {
+		// Любое значение имеет строковое представление
+		return true
+	}
+
+	if from.family != to.family {
+		switch {
+		case from.family == FamilyInteger && to.family == FamilyFloat:
+			return from.bits < to.bits
+		case from.family == FamilyInteger && to.family == FamilyDecimal:
+			return to.precision == 0 || to.precision-to.scale >= integerDigits(from.bits)
+		}
+		return false
+	}
+
+	switch from.family {
+	case FamilyInteger:
+		if from.unsigned == to.unsigned {
+			return to.bits >= from.bits
+		}
+		return from.unsigned && to.bits > from.bits
+	case FamilyFloat:
+		return to.bits >= from.bits
+	case FamilyDecimal:
+		if to.precision == 0 {
+			return true
+		}
+		return from.precision != 0 && to.scale >= from.scale && to.precision-to.scale >= from.precision-from.scale
+	case FamilyString:
+		return from.length != 0 && to.length >= from.length
+	case FamilyTime:
+		return to.resolution >= from.resolution && (to.wide || !from.wide) && (to.zone || !from.zone)
+	case FamilyBoolean:
+		return true
+	}
+	return false
+}
+
+// integerDigits число десятичных цифр, достаточное для целого заданной разрядности
+func integerDigits(bits int) int {
+	return int(math.Ceil(float64(bits) * math.Log10(2)))
+}
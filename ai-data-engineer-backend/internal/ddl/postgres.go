@@ -0,0 +1,304 @@
+package ddl
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// postgresIndexTypes методы доступа индексов PostgreSQL; unique — уникальный btree
+var postgresIndexTypes = map[string]bool{
+	"": true, "btree": true, "hash": true, "gin": true, "gist": true, "brin": true, "unique": true,
+}
+
+// postgresGenerator генерирует DDL PostgreSQL
+type postgresGenerator struct{}
+
+func (postgresGenerator) Dialect() string {
+	return DialectPostgres
+}
+
+// Generate формирует CREATE TABLE с первичным ключом и ограничениями,
+// CREATE INDEX для индексов и COMMENT ON COLUMN для описаний колонок
+func (g postgresGenerator) Generate(schema *models.TableSchema, profile *models.DataProfile, opts Options) (*models.GenerateDDLResponse, error) {
+	if err := validateSchema(schema); err != nil {
+		return nil, err
+	}
+	if err := validatePostgres(schema); err != nil {
+		return nil, err
+	}
+
+	stats := profileFields(profile)
+	table := quotePostgres(schema.TableName)
+	if opts.Schema != "" {
+		table = quotePostgres(opts.Schema) + "." + table
+	}
+	primaryKey := make(map[string]bool, len(schema.PrimaryKey))
+	for _, name := range schema.PrimaryKey {
+		primaryKey[name] = true
+	}
+
+	var definitions []string
+	columns := make([]map[string]interface{}, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		typ, reason := postgresType(field, stats[field.Name], primaryKey[field.Name])
+		definition := quotePostgres(field.Name) + " " + typ
+		if !field.Nullable || primaryKey[field.Name] {
+			definition += " NOT NULL"
+		}
+		definitions = append(definitions, definition)
+		columns = append(columns, map[string]interface{}{"column": field.Name, "type": typ, "reason": reason})
+	}
+	if len(schema.PrimaryKey) > 0 {
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)",
+			quotePostgres(withSuffix(schema.TableName, "_pkey")), quotePostgresList(schema.PrimaryKey)))
+	}
+	for i, constraint := range schema.Constraints {
+		definition, err := postgresConstraint(schema.TableName, i, constraint)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+
+	sections := []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n);", table, strings.Join(definitions, ",\n    "))}
+
+	var indexes []string
+	for _, index := range postgresIndexes(schema, primaryKey) {
+		indexes = append(indexes, postgresCreateIndex(schema.TableName, table, index))
+	}
+	if len(indexes) > 0 {
+		sections = append(sections, strings.Join(indexes, "\n"))
+	}
+
+	var comments []string
+	for _, field := range schema.Fields {
+		if field.Description != "" {
+			comments = append(comments, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;",
+				table, quotePostgres(field.Name), quotePostgresString(field.Description)))
+		}
+	}
+	if len(comments) > 0 {
+		sections = append(sections, strings.Join(comments, "\n"))
+	}
+
+	metadata := map[string]interface{}{
+		"generator":   "native",
+		"dialect":     DialectPostgres,
+		"columns":     columns,
+		"primary_key": schema.PrimaryKey,
+	}
+	return &models.GenerateDDLResponse{
+		DDL:      strings.Join(sections, "\n\n") + "\n",
+		Database: DialectPostgres,
+		Tables:   []string{schema.TableName},
+		Metadata: metadata,
+	}, nil
+}
+
+// validatePostgres проверяет ограничения PostgreSQL: длину идентификаторов и методы индексов
+func validatePostgres(schema *models.TableSchema) error {
+	var problems []string
+	names := []string{schema.TableName}
+	for _, field := range schema.Fields {
+		names = append(names, field.Name)
+	}
+	for _, index := range schema.Indexes {
+		names = append(names, index.Name)
+		if !postgresIndexTypes[strings.ToLower(index.Type)] {
+			problems = append(problems, fmt.Sprintf("индекс %q: неизвестный тип %q", index.Name, index.Type))
+		}
+	}
+	for _, name := range names {
+		if len(name) > maxIdentifierLength {
+			problems = append(problems, fmt.Sprintf("имя %q длиннее %d байт", name, maxIdentifierLength))
+		}
+	}
+	if len(problems) > 0 {
+		return models.NewValidationError("Схема несовместима с PostgreSQL", map[string]interface{}{"problems": problems})
+	}
+	return nil
+}
+
+// postgresType возвращает тип PostgreSQL для колонки и объяснение выбора.
+// Логические типы профилировщика (в нижнем регистре) уточняются по статистике,
+// остальные считаются типами PostgreSQL и приводятся к каноническому написанию
+func postgresType(field models.TableField, stats *models.DataField, key bool) (string, string) {
+	switch strings.TrimSpace(field.Type) {
+	case models.FieldTypeInteger, "int", "int64", "long":
+		if key {
+			return "BIGINT", "ключевая колонка: запас диапазона на рост таблицы"
+		}
+		if stats != nil && stats.MinValue >= math.MinInt32 && stats.MaxValue <= math.MaxInt32 {
+			return "INTEGER", fmt.Sprintf("значения от %g до %g помещаются в INTEGER", stats.MinValue, stats.MaxValue)
+		}
+		return "BIGINT", "целые числа без статистики диапазона или вне диапазона INTEGER"
+	case models.FieldTypeFloat, "double", "number":
+		return "DOUBLE PRECISION", "дробные числа"
+	case "decimal":
+		return "NUMERIC", "точные десятичные числа"
+	case models.FieldTypeBoolean, "bool":
+		return "BOOLEAN", "логические значения"
+	case models.FieldTypeDate:
+		return "DATE", "даты без времени"
+	case models.FieldTypeTimestamp, "datetime":
+		return "TIMESTAMP", "дата и время"
+	case models.FieldTypeString, "", "varchar":
+		return "TEXT", "строки: TEXT в PostgreSQL не уступает VARCHAR(n)"
+	case "json":
+		return "JSONB", "JSON документы"
+	default:
+		return CanonicalPostgresType(field.Type), "тип указан в схеме"
+	}
+}
+
+// postgresTypeAliases синонимы типов PostgreSQL и их каноническое написание
+var postgresTypeAliases = map[string]string{
+	"INT":               "INTEGER",
+	"INT4":              "INTEGER",
+	"INT2":              "SMALLINT",
+	"INT8":              "BIGINT",
+	"FLOAT4":            "REAL",
+	"FLOAT8":            "DOUBLE PRECISION",
+	"BOOL":              "BOOLEAN",
+	"DECIMAL":           "NUMERIC",
+	"CHARACTER VARYING": "VARCHAR",
+	"CHARACTER":         "CHAR",
+}
+
+// CanonicalPostgresType приводит тип к верхнему регистру и заменяет синонимы,
+// сохраняя параметры и признак массива: int4[] → INTEGER[], character varying(20) → VARCHAR(20),
+// timestamp(3) with time zone → TIMESTAMPTZ(3)
+func CanonicalPostgresType(declared string) string {
+	typ := strings.ToUpper(strings.Join(strings.Fields(declared), " "))
+	zone := ""
+	if withoutZone, ok := strings.CutSuffix(typ, " WITH TIME ZONE"); ok {
+		typ, zone = withoutZone, "TZ"
+	} else {
+		typ = strings.TrimSuffix(typ, " WITHOUT TIME ZONE")
+	}
+	base, suffix := typ, ""
+	if i := strings.IndexAny(typ, "(["); i >= 0 {
+		base, suffix = strings.TrimSpace(typ[:i]), typ[i:]
+	}
+	if alias, ok := postgresTypeAliases[base]; ok {
+		base = alias
+	}
+	return base + zone + strings.ReplaceAll(suffix, " ", "")
+}
+
+// postgresCreateIndex формирует CREATE INDEX; table — имя таблицы в кавычках со схемой
+func postgresCreateIndex(tableName, table string, index models.TableIndex) string {
+	unique := ""
+	method := strings.ToLower(index.Type)
+	if method == "unique" {
+		unique, method = "UNIQUE ", ""
+	}
+	using := ""
+	if method != "" {
+		using = " USING " + method
+	}
+	return fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s%s (%s);",
+		unique, quotePostgres(indexName(tableName, index)), table, using, quotePostgresList(index.Fields))
+}
+
+// postgresConstraint формирует ограничение таблицы: check из колонки, оператора и значения,
+// unique из колонок через запятую и foreign_key из колонки и ссылки RefTable.RefColumn.
+// Идентификаторы и литералы заключаются в кавычки, выражения как есть не принимаются
+func postgresConstraint(table string, position int, constraint models.TableConstraint) (string, error) {
+	kind := strings.ToLower(constraint.Type)
+	name := constraintName(table, position, constraint)
+	invalid := func(reason string) error {
+		return models.NewValidationError("Некорректное ограничение таблицы", map[string]interface{}{
+			"constraint": name, "problem": reason,
+		})
+	}
+
+	prefix := "CONSTRAINT " + quotePostgres(name) + " "
+	switch kind {
+	case "check":
+		expression, err := checkExpression(constraint, quotePostgres, quotePostgresString)
+		if err != nil {
+			return "", invalid(err.Error())
+		}
+		return prefix + "CHECK (" + expression + ")", nil
+	case "unique":
+		if strings.TrimSpace(constraint.Expression) == "" {
+			return "", invalid("не указаны колонки")
+		}
+		columns := strings.Split(constraint.Expression, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		return prefix + "UNIQUE (" + quotePostgresList(columns) + ")", nil
+	case "foreign_key":
+		key, err := postgresForeignKey(constraint)
+		if err != nil {
+			return "", invalid(err.Error())
+		}
+		return prefix + key, nil
+	default:
+		return "", invalid(fmt.Sprintf("неизвестный тип ограничения %q", constraint.Type))
+	}
+}
+
+// foreignKeyActions действия ON DELETE внешнего ключа
+var foreignKeyActions = map[string]string{
+	"": "", "no_action": "", "cascade": "CASCADE", "restrict": "RESTRICT", "set_null": "SET NULL", "set_default": "SET DEFAULT",
+}
+
+// postgresForeignKey формирует FOREIGN KEY (Column) REFERENCES [RefSchema.]RefTable (RefColumn)
+func postgresForeignKey(constraint models.TableConstraint) (string, error) {
+	if constraint.Column == "" || constraint.RefTable == "" || constraint.RefColumn == "" {
+		return "", fmt.Errorf("нужны column, ref_table и ref_column")
+	}
+	action, ok := foreignKeyActions[strings.ToLower(constraint.OnDelete)]
+	if !ok {
+		return "", fmt.Errorf("неизвестное действие on_delete %q", constraint.OnDelete)
+	}
+	table := quotePostgres(constraint.RefTable)
+	if constraint.RefSchema != "" {
+		table = quotePostgres(constraint.RefSchema) + "." + table
+	}
+	sql := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", quotePostgres(constraint.Column), table, quotePostgres(constraint.RefColumn))
+	if action != "" {
+		sql += " ON DELETE " + action
+	}
+	return sql, nil
+}
+
+// postgresIndexes возвращает явные индексы и индексы для колонок с Indexed,
+// кроме колонок, уже покрытых первичным ключом или явным индексом
+func postgresIndexes(schema *models.TableSchema, primaryKey map[string]bool) []models.TableIndex {
+	indexes := append([]models.TableIndex(nil), schema.Indexes...)
+	covered := make(map[string]bool)
+	for _, index := range schema.Indexes {
+		covered[index.Fields[0]] = true
+	}
+	for _, field := range schema.Fields {
+		if field.Indexed && !primaryKey[field.Name] && !covered[field.Name] {
+			indexes = append(indexes, models.TableIndex{Fields: []string{field.Name}})
+		}
+	}
+	return indexes
+}
+
+// quotePostgres заключает идентификатор в двойные кавычки
+func quotePostgres(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quotePostgresList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quotePostgres(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quotePostgresString возвращает строковый литерал SQL
+func quotePostgresString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
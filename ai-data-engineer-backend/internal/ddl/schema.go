@@ -0,0 +1,121 @@
+package ddl
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// maxIdentifierLength максимальная длина идентификатора PostgreSQL в байтах;
+// более длинные имена СУБД молча обрезает
+const maxIdentifierLength = 63
+
+// SchemaFromProfile строит TableSchema по профилю данных. Имена колонок приводятся
+// к snake_case, типы колонок остаются логическими (models.FieldType*) и
+// переводятся в типы СУБД генератором. Первичным ключом становится колонка id
+// или *_id, если ее значения уникальны и не содержат пропусков
+func SchemaFromProfile(tableName string, profile *models.DataProfile) *models.TableSchema {
+	schema := &models.TableSchema{
+		TableName:   Identifier(tableName),
+		Fields:      make([]models.TableField, 0, len(profile.Fields)),
+		Indexes:     []models.TableIndex{},
+		Constraints: []models.TableConstraint{},
+	}
+
+	used := make(map[string]bool, len(profile.Fields))
+	var keyCandidate string
+	for _, field := range profile.Fields {
+		// Разные заголовки могут дать одно имя: добавляем номер
+		base := Identifier(field.Name)
+		name := base
+		for n := 2; used[name]; n++ {
+			name = withSuffix(base, "_"+strconv.Itoa(n))
+		}
+		used[name] = true
+
+		schema.Fields = append(schema.Fields, models.TableField{
+			Name:        name,
+			Type:        field.Type,
+			Nullable:    field.Nullable || field.NullCount > 0,
+			Description: field.Description,
+		})
+
+		if isKeyCandidate(name, &field, profile) && (keyCandidate == "" || name == "id") {
+			keyCandidate = name
+		}
+	}
+	if keyCandidate != "" {
+		schema.PrimaryKey = []string{keyCandidate}
+	}
+	return schema
+}
+
+// isKeyCandidate сообщает, подходит ли колонка для первичного ключа
+func isKeyCandidate(name string, field *models.DataField, profile *models.DataProfile) bool {
+	if name != "id" && !strings.HasSuffix(name, "_id") {
+		return false
+	}
+	if field.Type != models.FieldTypeInteger && field.Type != models.FieldTypeString {
+		return false
+	}
+	return unique(field, profile)
+}
+
+// unique сообщает, что все значения колонки различны и заполнены
+func unique(field *models.DataField, profile *models.DataProfile) bool {
+	return profile.SampledRows > 0 && field.NullCount == 0 && field.DistinctCount == profile.SampledRows
+}
+
+// Identifier приводит произвольное имя (например, заголовок CSV) к идентификатору
+// в snake_case: строчные буквы, цифры и подчеркивания, не длиннее 63 байт
+func Identifier(raw string) string {
+	var b strings.Builder
+	underscore, prevLower := false, false
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// camelCase → camel_case
+			if unicode.IsUpper(r) && prevLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			underscore, prevLower = false, unicode.IsLower(r)
+		case b.Len() > 0 && !underscore:
+			b.WriteByte('_')
+			underscore, prevLower = true, false
+		}
+	}
+
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" {
+		name = "column"
+	}
+	if first, _ := utf8.DecodeRuneInString(name); unicode.IsDigit(first) {
+		name = "_" + name
+	}
+	return truncateIdentifier(name)
+}
+
+// truncateIdentifier обрезает имя до maxIdentifierLength байт, не разрывая символы UTF-8
+func truncateIdentifier(name string) string {
+	return truncate(name, maxIdentifierLength)
+}
+
+// withSuffix добавляет суффикс к имени, сокращая имя так, чтобы суффикс сохранился
+func withSuffix(name, suffix string) string {
+	return truncate(name, maxIdentifierLength-len(suffix)) + suffix
+}
+
+func truncate(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut]
+}
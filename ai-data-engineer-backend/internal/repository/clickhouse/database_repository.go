@@ -0,0 +1,404 @@
+// Package clickhouse реализует DatabaseRepository для целевой базы ClickHouse
+// через HTTP интерфейс
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/ddl"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const (
+	// defaultInsertBatchSize число строк в одном INSERT при массовой загрузке
+	defaultInsertBatchSize = 100000
+	// insertAttempts число попыток вставки одного батча
+	insertAttempts = 3
+	// insertRetryDelay пауза перед повтором, растет с номером попытки
+	insertRetryDelay = 500 * time.Millisecond
+)
+
+// retryableCodes коды исключений ClickHouse, после которых вставку можно повторить
+var retryableCodes = map[string]bool{
+	"159": true, // TIMEOUT_EXCEEDED
+	"202": true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	"209": true, // SOCKET_TIMEOUT
+	"210": true, // NETWORK_ERROR
+	"242": true, // TABLE_IS_READ_ONLY
+	"252": true, // TOO_MANY_PARTS
+	"319": true, // UNKNOWN_STATUS_OF_INSERT
+	"999": true, // KEEPER_EXCEPTION
+}
+
+// databaseRepository реализация DatabaseRepository на ClickHouse. Имя таблицы
+// может содержать базу данных: "analytics.events"; без нее используется база подключения
+type databaseRepository struct {
+	client client.ClickHouseClient
+	logger logger.Logger
+}
+
+// NewDatabaseRepository создает DatabaseRepository для ClickHouse
+func NewDatabaseRepository(client client.ClickHouseClient, logger logger.Logger) repository.DatabaseRepository {
+	return &databaseRepository{
+		client: client,
+		logger: logger,
+	}
+}
+
+// TestConnection проверяет доступность сервера; config не используется
+func (r *databaseRepository) TestConnection(ctx context.Context, config interface{}) error {
+	if err := r.client.Ping(ctx); err != nil {
+		return models.NewAppErrorWithCause(models.ErrorCodeConnectionFailed, "Не удалось подключиться к ClickHouse", http.StatusBadGateway, err)
+	}
+	return nil
+}
+
+// ExecuteQuery выполняет запрос и возвращает строки как []map[string]interface{}.
+// Аргументы не поддерживаются: значения передаются параметрами запроса ClickHouse
+func (r *databaseRepository) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
+	if len(args) > 0 {
+		return nil, models.NewValidationError("ClickHouse не поддерживает позиционные аргументы запроса", nil)
+	}
+	rows, err := r.client.Query(ctx, query, nil)
+	if err != nil {
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeQueryFailed, "Не удалось выполнить запрос", http.StatusBadRequest, err)
+	}
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	return rows, nil
+}
+
+// GetTableSchema читает схему таблицы из system.tables, system.columns и
+// system.data_skipping_indices. Первичным ключом считается PRIMARY KEY таблицы
+// (по умолчанию совпадает с ORDER BY), ключом сортировки — ORDER BY, если он длиннее;
+// ограничения CHECK разбираются из create_table_query
+func (r *databaseRepository) GetTableSchema(ctx context.Context, tableName string) (*models.TableSchema, error) {
+	database, name := r.splitTableName(tableName)
+	params := map[string]string{"database": database, "table": name}
+
+	tables, err := r.client.Query(ctx, `
+		SELECT primary_key, sorting_key, create_table_query FROM system.tables
+		WHERE database = {database:String} AND name = {table:String}`, params)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить схему таблицы", err)
+	}
+	if len(tables) == 0 {
+		return nil, models.NewTableNotFoundError(tableName)
+	}
+
+	columns, err := r.client.Query(ctx, `
+		SELECT name, type, comment FROM system.columns
+		WHERE database = {database:String} AND table = {table:String}
+		ORDER BY position`, params)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить колонки таблицы", err)
+	}
+	schema := &models.TableSchema{
+		TableName:   name,
+		Fields:      make([]models.TableField, 0, len(columns)),
+		PrimaryKey:  []string{},
+		Indexes:     []models.TableIndex{},
+		Constraints: []models.TableConstraint{},
+	}
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		typ := stringValue(column["type"])
+		schema.Fields = append(schema.Fields, models.TableField{
+			Name:        stringValue(column["name"]),
+			Type:        typ,
+			Nullable:    strings.Contains(typ, "Nullable("),
+			Description: stringValue(column["comment"]),
+		})
+		known[stringValue(column["name"])] = true
+	}
+
+	if key, ok := columnList(stringValue(tables[0]["primary_key"]), known); ok {
+		schema.PrimaryKey = key
+	}
+	// ORDER BY длиннее PRIMARY KEY сохраняется отдельно: его колонки тоже нельзя изменять
+	if key, ok := columnList(stringValue(tables[0]["sorting_key"]), known); ok && len(key) > len(schema.PrimaryKey) {
+		schema.SortingKey = key
+	}
+
+	indexes, err := r.client.Query(ctx, `
+		SELECT name, type, expr FROM system.data_skipping_indices
+		WHERE database = {database:String} AND table = {table:String}
+		ORDER BY name`, params)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить индексы таблицы", err)
+	}
+	for _, index := range indexes {
+		// Индексы по выражениям не представимы в TableIndex и пропускаются
+		fields, ok := columnList(stringValue(index["expr"]), known)
+		if !ok || len(fields) == 0 {
+			continue
+		}
+		schema.Indexes = append(schema.Indexes, models.TableIndex{
+			Name:   stringValue(index["name"]),
+			Fields: fields,
+			Type:   stringValue(index["type"]),
+		})
+	}
+
+	schema.Constraints = append(schema.Constraints, checkConstraints(stringValue(tables[0]["create_table_query"]))...)
+	return schema, nil
+}
+
+// CreateTable создает таблицу по схеме с помощью генератора DDL ClickHouse
+func (r *databaseRepository) CreateTable(ctx context.Context, schema *models.TableSchema) error {
+	generator, err := ddl.NewGenerator(ddl.DialectClickHouse)
+	if err != nil {
+		return err
+	}
+	database, name := r.splitTableName(schema.TableName)
+	table := *schema
+	table.TableName = name
+	response, err := generator.Generate(&table, nil, ddl.Options{Schema: database})
+	if err != nil {
+		return err
+	}
+	if err := r.client.Exec(ctx, response.DDL, nil, nil); err != nil {
+		return models.NewDatabaseError(fmt.Sprintf("Не удалось создать таблицу %s", schema.TableName), err)
+	}
+	return nil
+}
+
+// InsertData добавляет строки одним запросом INSERT в формате JSONEachRow
+func (r *databaseRepository) InsertData(ctx context.Context, tableName string, data []map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, row := range data {
+		if err := encoder.Encode(row); err != nil {
+			return models.NewValidationError("Строка не сериализуется в JSON", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	database, name := r.splitTableName(tableName)
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", quoteIdentifier(database), quoteIdentifier(name))
+	if err := r.client.Exec(ctx, query, &body, nil); err != nil {
+		return models.NewDatabaseError(fmt.Sprintf("Не удалось вставить строки в %s", tableName), err)
+	}
+	return nil
+}
+
+// Dialect возвращает диалект DDL ClickHouse
+func (r *databaseRepository) Dialect() string {
+	return ddl.DialectClickHouse
+}
+
+// BulkInsert вставляет строки батчами по opts.BatchSize в формате JSONCompactEachRow.
+// Каждый батч получает insert_deduplication_token из opts.DedupKey и номера батча, поэтому
+// повтор запроса после сбоя и повтор всей загрузки с тем же ключом не создают дублей
+func (r *databaseRepository) BulkInsert(ctx context.Context, tableName string, columns []string, next func() ([]interface{}, error), opts models.BulkInsertOptions) (int64, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultInsertBatchSize
+	}
+	database, name := r.splitTableName(tableName)
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT JSONCompactEachRow",
+		quoteIdentifier(database), quoteIdentifier(name), strings.Join(quoted, ", "))
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	var loaded int64
+	rows, batch := 0, 0
+	flush := func() error {
+		if rows == 0 {
+			return nil
+		}
+		settings := map[string]string{"date_time_input_format": "best_effort"}
+		if opts.DedupKey != "" {
+			settings["insert_deduplicate"] = "1"
+			settings["insert_deduplication_token"] = fmt.Sprintf("%s-%d", opts.DedupKey, batch)
+		}
+		if err := r.insertBatch(ctx, query, body.Bytes(), settings); err != nil {
+			appErr := models.NewDatabaseError(fmt.Sprintf("Не удалось вставить строки в %s", tableName), err)
+			appErr.Details = map[string]interface{}{"batch": batch, "error": err.Error()}
+			return appErr
+		}
+		loaded += int64(rows)
+		rows = 0
+		batch++
+		body.Reset()
+		return nil
+	}
+
+	for {
+		values, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return loaded, err
+		}
+		for i, value := range values {
+			if t, ok := value.(time.Time); ok {
+				values[i] = formatTime(t)
+			}
+		}
+		if err := encoder.Encode(values); err != nil {
+			return loaded, models.NewValidationError("Строка не сериализуется в JSON", map[string]interface{}{"error": err.Error()})
+		}
+		rows++
+		if rows >= batchSize {
+			if err := flush(); err != nil {
+				return loaded, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return loaded, err
+	}
+	return loaded, nil
+}
+
+// insertBatch отправляет батч, повторяя запрос при временных ошибках сервера и сети
+func (r *databaseRepository) insertBatch(ctx context.Context, query string, body []byte, settings map[string]string) error {
+	for attempt := 1; ; attempt++ {
+		err := r.client.Exec(ctx, query, bytes.NewReader(body), settings)
+		if err == nil || attempt == insertAttempts || !retryable(ctx, err) {
+			return err
+		}
+		r.logger.WithField("error", err.Error()).WithField("attempt", attempt).
+			WithField("token", settings["insert_deduplication_token"]).Warn("ClickHouse insert failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * insertRetryDelay):
+		}
+	}
+}
+
+// retryable сообщает, можно ли повторить вставку: сетевые ошибки, ответы прокси
+// и исключения ClickHouse, не связанные с содержимым батча
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var chErr *client.ClickHouseError
+	if !errors.As(err, &chErr) {
+		return true
+	}
+	switch chErr.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return retryableCodes[chErr.Code]
+}
+
+// formatTime форматирует время для best_effort разбора: полночь UTC — как дату,
+// чтобы значение подходило и для Date, и для DateTime
+func formatTime(t time.Time) string {
+	if _, offset := t.Zone(); offset == 0 && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// splitTableName отделяет базу данных от имени таблицы; по умолчанию — база подключения
+func (r *databaseRepository) splitTableName(tableName string) (string, string) {
+	if database, name, ok := strings.Cut(tableName, "."); ok {
+		return database, name
+	}
+	database := r.client.Database()
+	if database == "" {
+		database = "default"
+	}
+	return database, tableName
+}
+
+// columnList разбирает список колонок выражения ключа или индекса: "a, b", "(a, b)", "`a`".
+// ok ложно, если элемент списка не является колонкой таблицы
+func columnList(expression string, known map[string]bool) ([]string, bool) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") {
+		expression = expression[1 : len(expression)-1]
+	}
+	if expression == "" || expression == "tuple()" {
+		return nil, true
+	}
+	var columns []string
+	for _, part := range strings.Split(expression, ",") {
+		name := strings.Trim(strings.TrimSpace(part), "`")
+		if !known[name] {
+			return nil, false
+		}
+		columns = append(columns, name)
+	}
+	return columns, true
+}
+
+// checkConstraints находит ограничения CONSTRAINT name CHECK expr в тексте CREATE TABLE.
+// Выражение продолжается до запятой или скобки верхнего уровня вне строковых литералов
+func checkConstraints(createQuery string) []models.TableConstraint {
+	var constraints []models.TableConstraint
+	rest := createQuery
+	for {
+		start := strings.Index(rest, "CONSTRAINT ")
+		if start < 0 {
+			return constraints
+		}
+		rest = rest[start+len("CONSTRAINT "):]
+		name, after, ok := strings.Cut(rest, " CHECK ")
+		if !ok || strings.ContainsAny(strings.Trim(name, "`"), " ,()") {
+			continue
+		}
+
+		depth, quoted, end := 0, false, len(after)
+	scan:
+		for i := 0; i < len(after); i++ {
+			switch c := after[i]; {
+			case c == '\\' && quoted:
+				i++
+			case c == '\'':
+				quoted = !quoted
+			case quoted:
+			case c == '(':
+				depth++
+			case c == ')' && depth == 0, c == ',' && depth == 0:
+				end = i
+				break scan
+			case c == ')':
+				depth--
+			}
+		}
+		constraints = append(constraints, models.TableConstraint{
+			Name:       strings.Trim(name, "`"),
+			Type:       "check",
+			Expression: strings.TrimSpace(after[:end]),
+		})
+		rest = after[end:]
+	}
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// analysisRepository реализация AnalysisRepository в памяти
+type analysisRepository struct {
+	mu       sync.RWMutex
+	analyses map[string]models.AnalysisResult
+}
+
+// NewAnalysisRepository создает AnalysisRepository в памяти
+func NewAnalysisRepository() repository.AnalysisRepository {
+	return &analysisRepository{analyses: make(map[string]models.AnalysisResult)}
+}
+
+// SaveAnalysis сохраняет новый анализ
+func (r *analysisRepository) SaveAnalysis(ctx context.Context, analysis *models.AnalysisResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.analyses[analysis.ID]; exists {
+		return models.NewConflictError("Анализ с таким ID уже существует")
+	}
+	r.analyses[analysis.ID] = *analysis
+	return nil
+}
+
+// GetAnalysis возвращает анализ по ID
+func (r *analysisRepository) GetAnalysis(ctx context.Context, id string) (*models.AnalysisResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	analysis, ok := r.analyses[id]
+	if !ok {
+		return nil, models.NewAnalysisNotFoundError(id)
+	}
+	return &analysis, nil
+}
+
+// GetAnalysesByUser возвращает анализы пользователя, начиная с последних
+func (r *analysisRepository) GetAnalysesByUser(ctx context.Context, userID string, limit, offset int) ([]*models.AnalysisResult, error) {
+	return r.filter(func(a *models.AnalysisResult) bool { return a.UserId == userID }, limit, offset), nil
+}
+
+// UpdateAnalysis обновляет анализ
+func (r *analysisRepository) UpdateAnalysis(ctx context.Context, analysis *models.AnalysisResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.analyses[analysis.ID]; !ok {
+		return models.NewAnalysisNotFoundError(analysis.ID)
+	}
+	r.analyses[analysis.ID] = *analysis
+	return nil
+}
+
+// DeleteAnalysis удаляет анализ
+func (r *analysisRepository) DeleteAnalysis(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.analyses[id]; !ok {
+		return models.NewAnalysisNotFoundError(id)
+	}
+	delete(r.analyses, id)
+	return nil
+}
+
+// ClaimAnalysis передает незавершенный анализ экземпляру owner, если его владелец — previousOwner
+func (r *analysisRepository) ClaimAnalysis(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	analysis, ok := r.analyses[id]
+	if !ok {
+		return false, models.NewAnalysisNotFoundError(id)
+	}
+	if analysis.Owner != previousOwner ||
+		(analysis.Status != models.AnalysisStatusPending && analysis.Status != models.AnalysisStatusRunning) {
+		return false, nil
+	}
+	analysis.Owner = owner
+	analysis.Status = models.AnalysisStatusPending
+	analysis.UpdatedAt = time.Now()
+	r.analyses[id] = analysis
+	return true, nil
+}
+
+// GetAnalysesByStatus возвращает анализы в указанном статусе
+func (r *analysisRepository) GetAnalysesByStatus(ctx context.Context, status models.AnalysisStatus) ([]*models.AnalysisResult, error) {
+	return r.filter(func(a *models.AnalysisResult) bool { return a.Status == status }, 0, 0), nil
+}
+
+// GetCompletedAnalysisByFiles возвращает последний завершенный анализ пользователя одного из файлов
+func (r *analysisRepository) GetCompletedAnalysisByFiles(ctx context.Context, userID string, fileIDs []string) (*models.AnalysisResult, error) {
+	ids := make(map[string]bool, len(fileIDs))
+	for _, id := range fileIDs {
+		ids[id] = true
+	}
+	var latest *models.AnalysisResult
+	for _, a := range r.filter(func(a *models.AnalysisResult) bool {
+		return a.Status == models.AnalysisStatusCompleted && a.UserId == userID && a.FileID != "" && ids[a.FileID]
+	}, 0, 0) {
+		if latest == nil || a.UpdatedAt.After(latest.UpdatedAt) {
+			latest = a
+		}
+	}
+	return latest, nil
+}
+
+func (r *analysisRepository) filter(match func(*models.AnalysisResult) bool, limit, offset int) []*models.AnalysisResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.AnalysisResult, 0)
+	for _, a := range r.analyses {
+		a := a
+		if match(&a) {
+			result = append(result, &a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return paginate(result, limit, offset)
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// blobRepository реализация BlobRepository в памяти
+type blobRepository struct {
+	mu    sync.Mutex
+	blobs map[string]models.Blob
+}
+
+// NewBlobRepository создает BlobRepository в памяти
+func NewBlobRepository() repository.BlobRepository {
+	return &blobRepository{blobs: make(map[string]models.Blob)}
+}
+
+// AcquireBlob увеличивает счетчик ссылок или создает blob
+func (r *blobRepository) AcquireBlob(ctx context.Context, blob *models.Blob) (*models.Blob, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	stored, exists := r.blobs[blob.Checksum]
+	if exists {
+		stored.RefCount++
+		stored.UpdatedAt = now
+	} else {
+		stored = *blob
+		stored.RefCount = 1
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+	}
+	r.blobs[blob.Checksum] = stored
+	return &stored, !exists, nil
+}
+
+// ReferenceBlob увеличивает счетчик ссылок существующего blob
+func (r *blobRepository) ReferenceBlob(ctx context.Context, checksum string) (*models.Blob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.blobs[checksum]
+	if !ok {
+		return nil, models.NewBlobNotFoundError(checksum)
+	}
+	stored.RefCount++
+	stored.UpdatedAt = time.Now()
+	r.blobs[checksum] = stored
+	return &stored, nil
+}
+
+// ReleaseBlob уменьшает счетчик ссылок; remove последней ссылки вызывается под блокировкой репозитория
+func (r *blobRepository) ReleaseBlob(ctx context.Context, checksum string, remove func(*models.Blob) error) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.blobs[checksum]
+	if !ok {
+		return 0, models.NewBlobNotFoundError(checksum)
+	}
+	if stored.RefCount <= 1 {
+		if remove != nil {
+			if err := remove(&stored); err != nil {
+				return 0, err
+			}
+		}
+		delete(r.blobs, checksum)
+		return 0, nil
+	}
+	stored.RefCount--
+	stored.UpdatedAt = time.Now()
+	r.blobs[checksum] = stored
+	return stored.RefCount, nil
+}
+
+// GetBlob возвращает blob по контрольной сумме
+func (r *blobRepository) GetBlob(ctx context.Context, checksum string) (*models.Blob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.blobs[checksum]
+	if !ok {
+		return nil, models.NewBlobNotFoundError(checksum)
+	}
+	return &stored, nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// connectionRepository реализация ConnectionRepository в памяти
+type connectionRepository struct {
+	mu          sync.RWMutex
+	connections map[string]models.ConnectionProfile
+}
+
+// NewConnectionRepository создает ConnectionRepository в памяти
+func NewConnectionRepository() repository.ConnectionRepository {
+	return &connectionRepository{connections: make(map[string]models.ConnectionProfile)}
+}
+
+// SaveConnection сохраняет новое подключение
+func (r *connectionRepository) SaveConnection(ctx context.Context, connection *models.ConnectionProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.connections[connection.ID]; exists {
+		return models.NewConflictError("Подключение с таким ID уже существует")
+	}
+	if err := r.checkName(connection); err != nil {
+		return err
+	}
+	r.connections[connection.ID] = cloneConnection(connection)
+	return nil
+}
+
+// GetConnection возвращает подключение по ID
+func (r *connectionRepository) GetConnection(ctx context.Context, id string) (*models.ConnectionProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	connection, ok := r.connections[id]
+	if !ok {
+		return nil, models.NewConnectionNotFoundError(id)
+	}
+	clone := cloneConnection(&connection)
+	return &clone, nil
+}
+
+// GetConnectionsByUser возвращает подключения пользователя, упорядоченные по имени
+func (r *connectionRepository) GetConnectionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.ConnectionProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ConnectionProfile, 0)
+	for _, c := range r.connections {
+		if c.UserID == userID {
+			clone := cloneConnection(&c)
+			result = append(result, &clone)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return paginate(result, limit, offset), nil
+}
+
+// UpdateConnection обновляет подключение
+func (r *connectionRepository) UpdateConnection(ctx context.Context, connection *models.ConnectionProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.connections[connection.ID]
+	if !ok {
+		return models.NewConnectionNotFoundError(connection.ID)
+	}
+	if err := r.checkName(connection); err != nil {
+		return err
+	}
+	updated := cloneConnection(connection)
+	updated.UserID, updated.CreatedAt = stored.UserID, stored.CreatedAt
+	r.connections[connection.ID] = updated
+	return nil
+}
+
+// DeleteConnection удаляет подключение
+func (r *connectionRepository) DeleteConnection(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.connections[id]; !ok {
+		return models.NewConnectionNotFoundError(id)
+	}
+	delete(r.connections, id)
+	return nil
+}
+
+// checkName проверяет уникальность имени подключения в пределах пользователя
+func (r *connectionRepository) checkName(connection *models.ConnectionProfile) error {
+	for id, c := range r.connections {
+		if id != connection.ID && c.UserID == connection.UserID && c.Name == connection.Name {
+			return models.NewConflictError("Подключение с таким именем уже существует")
+		}
+	}
+	return nil
+}
+
+// cloneConnection копирует подключение вместе с зашифрованным паролем
+func cloneConnection(c *models.ConnectionProfile) models.ConnectionProfile {
+	clone := *c
+	clone.Secret = append([]byte(nil), c.Secret...)
+	clone.HasPassword = len(c.Secret) > 0
+	return clone
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/ddl"
+)
+
+// table таблица целевой базы в памяти
+type table struct {
+	schema models.TableSchema
+	rows   []map[string]interface{}
+}
+
+// databaseRepository реализация DatabaseRepository в памяти
+type databaseRepository struct {
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+// NewDatabaseRepository создает DatabaseRepository в памяти
+func NewDatabaseRepository() repository.DatabaseRepository {
+	return &databaseRepository{tables: make(map[string]*table)}
+}
+
+// TestConnection всегда успешен для базы в памяти
+func (r *databaseRepository) TestConnection(ctx context.Context, config interface{}) error {
+	return nil
+}
+
+// ExecuteQuery не поддерживается: база в памяти не разбирает SQL
+func (r *databaseRepository) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
+	return nil, models.NewAppError(models.ErrorCodeQueryFailed, "Выполнение SQL не поддерживается базой в памяти", http.StatusNotImplemented)
+}
+
+// GetTableSchema возвращает схему созданной таблицы
+func (r *databaseRepository) GetTableSchema(ctx context.Context, tableName string) (*models.TableSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tables[tableName]
+	if !ok {
+		return nil, models.NewTableNotFoundError(tableName)
+	}
+	schema := t.schema
+	return &schema, nil
+}
+
+// CreateTable создает таблицу по схеме
+func (r *databaseRepository) CreateTable(ctx context.Context, schema *models.TableSchema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tables[schema.TableName]; exists {
+		return models.NewConflictError(fmt.Sprintf("Таблица %s уже существует", schema.TableName))
+	}
+	r.tables[schema.TableName] = &table{schema: *schema}
+	return nil
+}
+
+// InsertData добавляет строки в таблицу
+func (r *databaseRepository) InsertData(ctx context.Context, tableName string, data []map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tables[tableName]
+	if !ok {
+		return models.NewTableNotFoundError(tableName)
+	}
+	for _, row := range data {
+		copied := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			copied[k] = v
+		}
+		t.rows = append(t.rows, copied)
+	}
+	return nil
+}
+
+// Dialect возвращает диалект DDL: база в памяти заменяет PostgreSQL
+func (r *databaseRepository) Dialect() string {
+	return ddl.DialectPostgres
+}
+
+// BulkInsert добавляет строки в таблицу; при ошибке next таблица не изменяется, opts не используются
+func (r *databaseRepository) BulkInsert(ctx context.Context, tableName string, columns []string, next func() ([]interface{}, error), opts models.BulkInsertOptions) (int64, error) {
+	var rows []map[string]interface{}
+	for {
+		values, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		rows = append(rows, row)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tables[tableName]
+	if !ok {
+		return 0, models.NewTableNotFoundError(tableName)
+	}
+	t.rows = append(t.rows, rows...)
+	return int64(len(rows)), nil
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// executionRepository реализация ExecutionRepository в памяти
+type executionRepository struct {
+	mu         sync.RWMutex
+	executions map[string]models.PipelineExecution
+}
+
+// NewExecutionRepository создает ExecutionRepository в памяти
+func NewExecutionRepository() repository.ExecutionRepository {
+	return &executionRepository{executions: make(map[string]models.PipelineExecution)}
+}
+
+// SaveExecution сохраняет новое выполнение пайплайна
+func (r *executionRepository) SaveExecution(ctx context.Context, execution *models.PipelineExecution) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.executions[execution.ID]; exists {
+		return models.NewConflictError("Выполнение с таким ID уже существует")
+	}
+	r.executions[execution.ID] = cloneExecution(execution)
+	return nil
+}
+
+// GetExecution возвращает выполнение по ID
+func (r *executionRepository) GetExecution(ctx context.Context, id string) (*models.PipelineExecution, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	execution, ok := r.executions[id]
+	if !ok {
+		return nil, models.NewExecutionNotFoundError(id)
+	}
+	clone := cloneExecution(&execution)
+	return &clone, nil
+}
+
+// GetExecutionsByPipeline возвращает выполнения пайплайна, начиная с последних
+func (r *executionRepository) GetExecutionsByPipeline(ctx context.Context, pipelineID string, limit, offset int) ([]*models.PipelineExecution, error) {
+	return r.filter(func(e *models.PipelineExecution) bool { return e.PipelineID == pipelineID }, limit, offset), nil
+}
+
+// UpdateExecution обновляет выполнение, сохраняя запрошенную отмену
+func (r *executionRepository) UpdateExecution(ctx context.Context, execution *models.PipelineExecution) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.executions[execution.ID]
+	if !ok {
+		return models.NewExecutionNotFoundError(execution.ID)
+	}
+	updated := cloneExecution(execution)
+	if stored.Status == models.ExecutionStatusCancelling && updated.Status == models.ExecutionStatusRunning {
+		updated.Status = stored.Status
+	}
+	r.executions[execution.ID] = updated
+	return nil
+}
+
+// TransitionExecution обновляет выполнение, если его статус равен from
+func (r *executionRepository) TransitionExecution(ctx context.Context, execution *models.PipelineExecution, from models.ExecutionStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.executions[execution.ID]
+	if !ok {
+		return false, models.NewExecutionNotFoundError(execution.ID)
+	}
+	if stored.Status != from {
+		return false, nil
+	}
+	r.executions[execution.ID] = cloneExecution(execution)
+	return true, nil
+}
+
+// ClaimExecution передает незавершенное выполнение экземпляру owner, если владелец не изменился
+func (r *executionRepository) ClaimExecution(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.executions[id]
+	if !ok {
+		return false, models.NewExecutionNotFoundError(id)
+	}
+	if stored.Owner != previousOwner ||
+		(stored.Status != models.ExecutionStatusRunning && stored.Status != models.ExecutionStatusCancelling) {
+		return false, nil
+	}
+	stored.Owner = owner
+	r.executions[id] = stored
+	return true, nil
+}
+
+// GetExecutionsByStatus возвращает выполнения в указанном статусе
+func (r *executionRepository) GetExecutionsByStatus(ctx context.Context, status models.ExecutionStatus) ([]*models.PipelineExecution, error) {
+	return r.filter(func(e *models.PipelineExecution) bool { return e.Status == status }, 0, 0), nil
+}
+
+func (r *executionRepository) filter(match func(*models.PipelineExecution) bool, limit, offset int) []*models.PipelineExecution {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.PipelineExecution, 0)
+	for _, e := range r.executions {
+		if match(&e) {
+			clone := cloneExecution(&e)
+			result = append(result, &clone)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+	return paginate(result, limit, offset)
+}
+
+// cloneExecution копирует выполнение вместе с логами
+func cloneExecution(e *models.PipelineExecution) models.PipelineExecution {
+	clone := *e
+	clone.Logs = append([]models.ExecutionLog(nil), e.Logs...)
+	return clone
+}
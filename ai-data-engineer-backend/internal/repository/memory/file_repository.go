@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// fileRepository реализация FileRepository в памяти
+type fileRepository struct {
+	mu    sync.RWMutex
+	files map[string]models.FileMetadata
+}
+
+// NewFileRepository создает FileRepository в памяти
+func NewFileRepository() repository.FileRepository {
+	return &fileRepository{files: make(map[string]models.FileMetadata)}
+}
+
+// SaveFile сохраняет метаданные нового файла
+func (r *fileRepository) SaveFile(ctx context.Context, file *models.FileMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.files[file.ID]; exists {
+		return models.NewConflictError("Файл с таким ID уже существует")
+	}
+	r.files[file.ID] = *file
+	return nil
+}
+
+// GetFile возвращает метаданные файла по ID
+func (r *fileRepository) GetFile(ctx context.Context, id string) (*models.FileMetadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	file, ok := r.files[id]
+	if !ok {
+		return nil, models.NewFileNotFoundError(id)
+	}
+	return &file, nil
+}
+
+// GetFilesByUser возвращает файлы пользователя, начиная с последних
+func (r *fileRepository) GetFilesByUser(ctx context.Context, userID string, limit, offset int) ([]*models.FileMetadata, error) {
+	return r.filter(func(f *models.FileMetadata) bool { return f.UserID == userID }, limit, offset), nil
+}
+
+// UpdateFile обновляет метаданные файла
+func (r *fileRepository) UpdateFile(ctx context.Context, file *models.FileMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[file.ID]; !ok {
+		return models.NewFileNotFoundError(file.ID)
+	}
+	r.files[file.ID] = *file
+	return nil
+}
+
+// DeleteFile удаляет метаданные файла
+func (r *fileRepository) DeleteFile(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[id]; !ok {
+		return models.NewFileNotFoundError(id)
+	}
+	delete(r.files, id)
+	return nil
+}
+
+// GetFilesByStatus возвращает файлы в указанном статусе
+func (r *fileRepository) GetFilesByStatus(ctx context.Context, status models.FileStatus) ([]*models.FileMetadata, error) {
+	return r.filter(func(f *models.FileMetadata) bool { return f.Status == status }, 0, 0), nil
+}
+
+// GetFilesByChecksum возвращает файлы с указанным содержимым
+func (r *fileRepository) GetFilesByChecksum(ctx context.Context, checksum string) ([]*models.FileMetadata, error) {
+	return r.filter(func(f *models.FileMetadata) bool { return f.Checksum == checksum }, 0, 0), nil
+}
+
+func (r *fileRepository) filter(match func(*models.FileMetadata) bool, limit, offset int) []*models.FileMetadata {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.FileMetadata, 0)
+	for _, f := range r.files {
+		f := f
+		if match(&f) {
+			result = append(result, &f)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return paginate(result, limit, offset)
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// leaseRepository реализация LeaseRepository в памяти; координирует только один процесс
+type leaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]models.Lease
+}
+
+// NewLeaseRepository создает LeaseRepository в памяти
+func NewLeaseRepository() repository.LeaseRepository {
+	return &leaseRepository{leases: make(map[string]models.Lease)}
+}
+
+// AcquireLease захватывает свободную или истекшую аренду либо продлевает свою
+func (r *leaseRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	lease, ok := r.leases[name]
+	if ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	r.leases[name] = models.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLease освобождает аренду, если ее удерживает holder
+func (r *leaseRepository) ReleaseLease(ctx context.Context, name, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.Holder == holder {
+		delete(r.leases, name)
+	}
+	return nil
+}
+
+// LeaseHolder возвращает владельца действующей аренды
+func (r *leaseRepository) LeaseHolder(ctx context.Context, name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.ExpiresAt.After(time.Now()) {
+		return lease.Holder, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,15 @@
+// Package memory содержит потокобезопасные реализации репозиториев в памяти
+// для тестов и локальной разработки без PostgreSQL
+package memory
+
+// paginate возвращает срез items с учетом limit и offset
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return make([]T, 0)
+	}
+	end := len(items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return items[offset:end]
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+
+	"github.com/google/uuid"
+)
+
+// pipelineRepository реализация PipelineRepository в памяти
+type pipelineRepository struct {
+	mu        sync.RWMutex
+	pipelines map[string]models.Pipeline
+}
+
+// NewPipelineRepository создает PipelineRepository в памяти
+func NewPipelineRepository() repository.PipelineRepository {
+	return &pipelineRepository{pipelines: make(map[string]models.Pipeline)}
+}
+
+// SavePipeline сохраняет новый пайплайн и возвращает его ID
+func (r *pipelineRepository) SavePipeline(ctx context.Context, pipeline *models.Pipeline) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pipeline.ID == "" {
+		pipeline.ID = uuid.New().String()
+	}
+	if _, exists := r.pipelines[pipeline.ID]; exists {
+		return "", models.NewConflictError("Пайплайн с таким ID уже существует")
+	}
+	r.pipelines[pipeline.ID] = clonePipeline(pipeline)
+	return pipeline.ID, nil
+}
+
+// GetPipeline возвращает пайплайн по ID
+func (r *pipelineRepository) GetPipeline(ctx context.Context, id string) (*models.Pipeline, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pipeline, ok := r.pipelines[id]
+	if !ok {
+		return nil, models.NewPipelineNotFoundError(id)
+	}
+	clone := clonePipeline(&pipeline)
+	return &clone, nil
+}
+
+// GetPipelinesByUser возвращает пайплайны пользователя, начиная с последних
+func (r *pipelineRepository) GetPipelinesByUser(ctx context.Context, userID string, status models.PipelineStatus, limit, offset int) ([]*models.Pipeline, error) {
+	return r.filter(func(p *models.Pipeline) bool {
+		return p.UserID == userID && (status == "" || p.Status == status)
+	}, limit, offset), nil
+}
+
+// UpdatePipeline обновляет пайплайн, если он не выполняется
+func (r *pipelineRepository) UpdatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.pipelines[pipeline.ID]
+	if !ok {
+		return models.NewPipelineNotFoundError(pipeline.ID)
+	}
+	if stored.Status == models.PipelineStatusRunning {
+		return models.NewConflictError("Пайплайн выполняется и не может быть изменен")
+	}
+	r.pipelines[pipeline.ID] = clonePipeline(pipeline)
+	return nil
+}
+
+// DeletePipeline удаляет пайплайн, если он не выполняется
+func (r *pipelineRepository) DeletePipeline(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.pipelines[id]
+	if !ok {
+		return models.NewPipelineNotFoundError(id)
+	}
+	if stored.Status == models.PipelineStatusRunning {
+		return models.NewConflictError("Пайплайн выполняется и не может быть удален")
+	}
+	delete(r.pipelines, id)
+	return nil
+}
+
+// GetPipelinesByStatus возвращает пайплайны в указанном статусе
+func (r *pipelineRepository) GetPipelinesByStatus(ctx context.Context, status models.PipelineStatus) ([]*models.Pipeline, error) {
+	return r.filter(func(p *models.Pipeline) bool { return p.Status == status }, 0, 0), nil
+}
+
+// UpdatePipelineState обновляет статус, шаги и время выполнения пайплайна
+func (r *pipelineRepository) UpdatePipelineState(ctx context.Context, pipeline *models.Pipeline) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.pipelines[pipeline.ID]
+	if !ok {
+		return models.NewPipelineNotFoundError(pipeline.ID)
+	}
+	state := clonePipeline(pipeline)
+	stored.Status = state.Status
+	stored.Steps = state.Steps
+	stored.UpdatedAt = state.UpdatedAt
+	stored.ExecutedAt = state.ExecutedAt
+	r.pipelines[pipeline.ID] = stored
+	return nil
+}
+
+// StartPipeline сохраняет состояние пайплайна, если он не выполняется
+func (r *pipelineRepository) StartPipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.pipelines[pipeline.ID]
+	if !ok {
+		return models.NewPipelineNotFoundError(pipeline.ID)
+	}
+	if stored.Status == models.PipelineStatusRunning {
+		return models.NewConflictError("Пайплайн уже выполняется")
+	}
+	state := clonePipeline(pipeline)
+	stored.Status = state.Status
+	stored.Steps = state.Steps
+	stored.UpdatedAt = state.UpdatedAt
+	stored.ExecutedAt = state.ExecutedAt
+	r.pipelines[pipeline.ID] = stored
+	return nil
+}
+
+// GetDuePipelines возвращает пайплайны, следующий запуск которых не позже before
+func (r *pipelineRepository) GetDuePipelines(ctx context.Context, before time.Time) ([]*models.Pipeline, error) {
+	return r.filter(func(p *models.Pipeline) bool {
+		return p.Schedule != nil && p.Schedule.NextRunAt != nil && !p.Schedule.NextRunAt.After(before)
+	}, 0, 0), nil
+}
+
+// AdvanceSchedule заменяет расписание, если следующий запуск равен expected
+func (r *pipelineRepository) AdvanceSchedule(ctx context.Context, id string, expected time.Time, schedule *models.PipelineSchedule) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.pipelines[id]
+	if !ok {
+		return false, models.NewPipelineNotFoundError(id)
+	}
+	if stored.Schedule == nil || stored.Schedule.NextRunAt == nil || !stored.Schedule.NextRunAt.Equal(expected) {
+		return false, nil
+	}
+	stored.Schedule = cloneSchedule(schedule)
+	r.pipelines[id] = stored
+	return true, nil
+}
+
+func (r *pipelineRepository) filter(match func(*models.Pipeline) bool, limit, offset int) []*models.Pipeline {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.Pipeline, 0)
+	for _, p := range r.pipelines {
+		if match(&p) {
+			clone := clonePipeline(&p)
+			result = append(result, &clone)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return paginate(result, limit, offset)
+}
+
+// clonePipeline копирует пайплайн вместе со шагами, чтобы вызывающий код
+// не мог изменить сохраненное состояние в обход UpdatePipeline
+func clonePipeline(p *models.Pipeline) models.Pipeline {
+	clone := *p
+	if p.Steps != nil {
+		clone.Steps = make([]models.PipelineStep, len(p.Steps))
+		for i, step := range p.Steps {
+			step.DependsOn = append([]string(nil), step.DependsOn...)
+			clone.Steps[i] = step
+		}
+	}
+	clone.Schedule = cloneSchedule(p.Schedule)
+	return clone
+}
+
+func cloneSchedule(schedule *models.PipelineSchedule) *models.PipelineSchedule {
+	if schedule == nil {
+		return nil
+	}
+	clone := *schedule
+	return &clone
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// uploadSessionRepository реализация UploadSessionRepository в памяти
+type uploadSessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]models.UploadSession
+}
+
+// NewUploadSessionRepository создает UploadSessionRepository в памяти
+func NewUploadSessionRepository() repository.UploadSessionRepository {
+	return &uploadSessionRepository{sessions: make(map[string]models.UploadSession)}
+}
+
+// SaveSession сохраняет новую сессию загрузки
+func (r *uploadSessionRepository) SaveSession(ctx context.Context, session *models.UploadSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sessions[session.ID]; exists {
+		return models.NewConflictError("Сессия загрузки с таким ID уже существует")
+	}
+	r.sessions[session.ID] = cloneUploadSession(session)
+	return nil
+}
+
+// GetSession возвращает сессию загрузки по ID
+func (r *uploadSessionRepository) GetSession(ctx context.Context, id string) (*models.UploadSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, models.NewUploadSessionNotFoundError(id)
+	}
+	clone := cloneUploadSession(&session)
+	return &clone, nil
+}
+
+// UpdateSession обновляет поля сессии, сохраняя загруженные части
+func (r *uploadSessionRepository) UpdateSession(ctx context.Context, session *models.UploadSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sessions[session.ID]
+	if !ok {
+		return models.NewUploadSessionNotFoundError(session.ID)
+	}
+	updated := cloneUploadSession(session)
+	updated.Parts = stored.Parts
+	r.sessions[session.ID] = updated
+	return nil
+}
+
+// TransitionSession обновляет поля сессии, если ее статус равен from
+func (r *uploadSessionRepository) TransitionSession(ctx context.Context, session *models.UploadSession, from models.UploadSessionStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sessions[session.ID]
+	if !ok {
+		return false, models.NewUploadSessionNotFoundError(session.ID)
+	}
+	if stored.Status != from {
+		return false, nil
+	}
+	updated := cloneUploadSession(session)
+	updated.Parts = stored.Parts
+	r.sessions[session.ID] = updated
+	return true, nil
+}
+
+// ClaimSession передает сессию в статусе completing экземпляру owner, если владелец не изменился
+func (r *uploadSessionRepository) ClaimSession(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sessions[id]
+	if !ok {
+		return false, models.NewUploadSessionNotFoundError(id)
+	}
+	if stored.Owner != previousOwner || stored.Status != models.UploadSessionStatusCompleting {
+		return false, nil
+	}
+	stored.Owner = owner
+	stored.UpdatedAt = time.Now()
+	r.sessions[id] = stored
+	return true, nil
+}
+
+// SavePart добавляет или заменяет часть сессии
+func (r *uploadSessionRepository) SavePart(ctx context.Context, sessionID string, part models.UploadPart) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return models.NewUploadSessionNotFoundError(sessionID)
+	}
+	parts := make([]models.UploadPart, 0, len(session.Parts)+1)
+	for _, p := range session.Parts {
+		if p.Number != part.Number {
+			parts = append(parts, p)
+		}
+	}
+	parts = append(parts, part)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	session.Parts = parts
+	r.sessions[sessionID] = session
+	return nil
+}
+
+// GetExpiredSessions возвращает активные сессии, истекшие до before
+func (r *uploadSessionRepository) GetExpiredSessions(ctx context.Context, before time.Time) ([]*models.UploadSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.UploadSession, 0)
+	for _, s := range r.sessions {
+		if s.Status == models.UploadSessionStatusActive && s.ExpiresAt.Before(before) {
+			clone := cloneUploadSession(&s)
+			result = append(result, &clone)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ExpiresAt.Before(result[j].ExpiresAt) })
+	return result, nil
+}
+
+// cloneUploadSession копирует сессию вместе со списком частей
+func cloneUploadSession(s *models.UploadSession) models.UploadSession {
+	clone := *s
+	clone.Parts = append(make([]models.UploadPart, 0, len(s.Parts)), s.Parts...)
+	return clone
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+const analysisColumns = `id, user_id, file_id, file_path, status, profile, analysis_result, error, created_at, updated_at, owner`
+
+// analysisRepository реализация AnalysisRepository на PostgreSQL.
+// Профиль и ответ LLM хранятся в JSONB
+type analysisRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewAnalysisRepository создает AnalysisRepository на PostgreSQL
+func NewAnalysisRepository(db *sql.DB, logger logger.Logger) repository.AnalysisRepository {
+	return &analysisRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveAnalysis сохраняет новый анализ
+func (r *analysisRepository) SaveAnalysis(ctx context.Context, analysis *models.AnalysisResult) error {
+	profile, result, err := marshalAnalysis(analysis)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO analyses (`+analysisColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		analysis.ID, analysis.UserId, analysis.FileID, analysis.FilePath, analysis.Status,
+		profile, result, analysis.Error, analysis.CreatedAt, analysis.UpdatedAt, analysis.Owner,
+	)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("analysis_id", analysis.ID).Error("Failed to save analysis")
+		return models.NewDatabaseError("Не удалось сохранить анализ", err)
+	}
+	return nil
+}
+
+// GetAnalysis возвращает анализ по ID
+func (r *analysisRepository) GetAnalysis(ctx context.Context, id string) (*models.AnalysisResult, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+analysisColumns+` FROM analyses WHERE id = $1`, id)
+	analysis, err := scanAnalysis(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewAnalysisNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить анализ", err)
+	}
+	return analysis, nil
+}
+
+// GetAnalysesByUser возвращает анализы пользователя, начиная с последних
+func (r *analysisRepository) GetAnalysesByUser(ctx context.Context, userID string, limit, offset int) ([]*models.AnalysisResult, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+analysisColumns+` FROM analyses WHERE user_id = $1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список анализов", err)
+	}
+	return scanAnalyses(rows)
+}
+
+// UpdateAnalysis обновляет анализ
+func (r *analysisRepository) UpdateAnalysis(ctx context.Context, analysis *models.AnalysisResult) error {
+	profile, result, err := marshalAnalysis(analysis)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE analyses SET user_id = $2, file_id = $3, file_path = $4, status = $5, profile = $6,
+			analysis_result = $7, error = $8, updated_at = $9, owner = $10
+		WHERE id = $1`,
+		analysis.ID, analysis.UserId, analysis.FileID, analysis.FilePath, analysis.Status,
+		profile, result, analysis.Error, analysis.UpdatedAt, analysis.Owner,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить анализ", err)
+	}
+	return expectAffected(res, models.NewAnalysisNotFoundError(analysis.ID))
+}
+
+// DeleteAnalysis удаляет анализ
+func (r *analysisRepository) DeleteAnalysis(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM analyses WHERE id = $1`, id)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось удалить анализ", err)
+	}
+	return expectAffected(res, models.NewAnalysisNotFoundError(id))
+}
+
+// GetAnalysesByStatus возвращает анализы в указанном статусе, начиная с самых старых
+func (r *analysisRepository) GetAnalysesByStatus(ctx context.Context, status models.AnalysisStatus) ([]*models.AnalysisResult, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+analysisColumns+` FROM analyses WHERE status = $1 ORDER BY created_at`, status)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список анализов", err)
+	}
+	return scanAnalyses(rows)
+}
+
+// ClaimAnalysis передает незавершенный анализ экземпляру owner, если его владелец — previousOwner
+func (r *analysisRepository) ClaimAnalysis(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE analyses SET owner = $2, status = $3, updated_at = now()
+		WHERE id = $1 AND owner = $4 AND status IN ($3, $5)`,
+		id, owner, models.AnalysisStatusPending, previousOwner, models.AnalysisStatusRunning,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать анализ", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать анализ", err)
+	}
+	return affected > 0, nil
+}
+
+// GetCompletedAnalysisByFiles возвращает последний завершенный анализ пользователя одного из файлов
+func (r *analysisRepository) GetCompletedAnalysisByFiles(ctx context.Context, userID string, fileIDs []string) (*models.AnalysisResult, error) {
+	if len(fileIDs) == 0 {
+		return nil, nil
+	}
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+analysisColumns+` FROM analyses WHERE file_id = ANY($1) AND status = $2 AND user_id = $3
+		ORDER BY updated_at DESC LIMIT 1`,
+		pq.Array(fileIDs), models.AnalysisStatusCompleted, userID,
+	)
+	analysis, err := scanAnalysis(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить анализ файла", err)
+	}
+	return analysis, nil
+}
+
+// marshalAnalysis сериализует JSONB поля; отсутствующие значения сохраняются как NULL.
+// Значения передаются строками: lib/pq отправляет []byte как bytea
+func marshalAnalysis(analysis *models.AnalysisResult) (profile, result sql.NullString, err error) {
+	if analysis.Profile != nil {
+		data, err := json.Marshal(analysis.Profile)
+		if err != nil {
+			return profile, result, models.NewInternalError("Не удалось сериализовать профиль данных", err)
+		}
+		profile = sql.NullString{String: string(data), Valid: true}
+	}
+	if analysis.AnalysisResult != nil {
+		data, err := json.Marshal(analysis.AnalysisResult)
+		if err != nil {
+			return profile, result, models.NewInternalError("Не удалось сериализовать результат анализа", err)
+		}
+		result = sql.NullString{String: string(data), Valid: true}
+	}
+	return profile, result, nil
+}
+
+func scanAnalysis(row rowScanner) (*models.AnalysisResult, error) {
+	var a models.AnalysisResult
+	var profile, result []byte
+	err := row.Scan(&a.ID, &a.UserId, &a.FileID, &a.FilePath, &a.Status, &profile, &result,
+		&a.Error, &a.CreatedAt, &a.UpdatedAt, &a.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil {
+		a.Profile = &models.DataProfile{}
+		if err := json.Unmarshal(profile, a.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if result != nil {
+		a.AnalysisResult = &models.LLMResponse{}
+		if err := json.Unmarshal(result, a.AnalysisResult); err != nil {
+			return nil, err
+		}
+	}
+	return &a, nil
+}
+
+func scanAnalyses(rows *sql.Rows) ([]*models.AnalysisResult, error) {
+	defer rows.Close()
+	analyses := make([]*models.AnalysisResult, 0)
+	for rows.Next() {
+		a, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать анализ", err)
+		}
+		analyses = append(analyses, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать анализ", err)
+	}
+	return analyses, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const blobColumns = `checksum, bucket, path, size, ref_count, created_at, updated_at`
+
+// blobRepository реализация BlobRepository на PostgreSQL
+type blobRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewBlobRepository создает BlobRepository на PostgreSQL
+func NewBlobRepository(db *sql.DB, logger logger.Logger) repository.BlobRepository {
+	return &blobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AcquireBlob атомарно увеличивает счетчик ссылок или создает blob.
+// xmax = 0 только у строки, вставленной этим запросом
+func (r *blobRepository) AcquireBlob(ctx context.Context, blob *models.Blob) (*models.Blob, bool, error) {
+	now := time.Now()
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO blobs (`+blobColumns+`) VALUES ($1, $2, $3, $4, 1, $5, $5)
+		ON CONFLICT (checksum) DO UPDATE SET ref_count = blobs.ref_count + 1, updated_at = EXCLUDED.updated_at
+		RETURNING `+blobColumns+`, (xmax = 0)`,
+		blob.Checksum, blob.Bucket, blob.Path, blob.Size, now,
+	)
+	var stored models.Blob
+	var created bool
+	err := row.Scan(&stored.Checksum, &stored.Bucket, &stored.Path, &stored.Size, &stored.RefCount,
+		&stored.CreatedAt, &stored.UpdatedAt, &created)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("checksum", blob.Checksum).Error("Failed to acquire blob")
+		return nil, false, models.NewDatabaseError("Не удалось сохранить объект", err)
+	}
+	return &stored, created, nil
+}
+
+// ReferenceBlob увеличивает счетчик ссылок существующего blob. UPDATE ждет блокировки
+// параллельного ReleaseBlob и не находит строку, если тот удалил blob
+func (r *blobRepository) ReferenceBlob(ctx context.Context, checksum string) (*models.Blob, error) {
+	blob, err := scanBlob(r.db.QueryRowContext(ctx,
+		`UPDATE blobs SET ref_count = ref_count + 1, updated_at = $2 WHERE checksum = $1 RETURNING `+blobColumns,
+		checksum, time.Now(),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewBlobNotFoundError(checksum)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось сохранить объект", err)
+	}
+	return blob, nil
+}
+
+// ReleaseBlob уменьшает счетчик ссылок в транзакции с блокировкой строки blob (SELECT … FOR UPDATE).
+// Последняя ссылка удаляет объект через remove и строку blob до фиксации транзакции:
+// AcquireBlob другого экземпляра сервиса дождется ее и создаст blob заново
+func (r *blobRepository) ReleaseBlob(ctx context.Context, checksum string, remove func(*models.Blob) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, models.NewDatabaseError("Не удалось начать транзакцию", err)
+	}
+	defer tx.Rollback()
+
+	blob, err := scanBlob(tx.QueryRowContext(ctx, `SELECT `+blobColumns+` FROM blobs WHERE checksum = $1 FOR UPDATE`, checksum))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.NewBlobNotFoundError(checksum)
+	}
+	if err != nil {
+		return 0, models.NewDatabaseError("Не удалось освободить объект", err)
+	}
+
+	remaining := blob.RefCount - 1
+	if remaining > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE blobs SET ref_count = $2, updated_at = $3 WHERE checksum = $1`, checksum, remaining, time.Now())
+	} else {
+		remaining = 0
+		if remove != nil {
+			if err := remove(blob); err != nil {
+				return 0, err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM blobs WHERE checksum = $1`, checksum)
+	}
+	if err != nil {
+		return 0, models.NewDatabaseError("Не удалось освободить объект", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, models.NewDatabaseError("Не удалось зафиксировать транзакцию", err)
+	}
+	return remaining, nil
+}
+
+// GetBlob возвращает blob по контрольной сумме
+func (r *blobRepository) GetBlob(ctx context.Context, checksum string) (*models.Blob, error) {
+	blob, err := scanBlob(r.db.QueryRowContext(ctx, `SELECT `+blobColumns+` FROM blobs WHERE checksum = $1`, checksum))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewBlobNotFoundError(checksum)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить объект", err)
+	}
+	return blob, nil
+}
+
+func scanBlob(row rowScanner) (*models.Blob, error) {
+	var b models.Blob
+	if err := row.Scan(&b.Checksum, &b.Bucket, &b.Path, &b.Size, &b.RefCount, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+const connectionColumns = `id, user_id, name, type, host, port, db_user, dbname, sslmode, secure, secret, created_at, updated_at`
+
+// connectionRepository реализация ConnectionRepository на PostgreSQL
+type connectionRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewConnectionRepository создает ConnectionRepository на PostgreSQL
+func NewConnectionRepository(db *sql.DB, logger logger.Logger) repository.ConnectionRepository {
+	return &connectionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveConnection сохраняет новое подключение
+func (r *connectionRepository) SaveConnection(ctx context.Context, connection *models.ConnectionProfile) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO connections (`+connectionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		connection.ID, connection.UserID, connection.Name, connection.Type, connection.Host, connection.Port,
+		connection.User, connection.DBName, connection.SSLMode, connection.Secure, connection.Secret,
+		connection.CreatedAt, connection.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return models.NewConflictError("Подключение с таким именем уже существует")
+	}
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("connection_id", connection.ID).Error("Failed to save connection")
+		return models.NewDatabaseError("Не удалось сохранить подключение", err)
+	}
+	return nil
+}
+
+// GetConnection возвращает подключение по ID
+func (r *connectionRepository) GetConnection(ctx context.Context, id string) (*models.ConnectionProfile, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+connectionColumns+` FROM connections WHERE id = $1`, id)
+	connection, err := scanConnection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewConnectionNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить подключение", err)
+	}
+	return connection, nil
+}
+
+// GetConnectionsByUser возвращает подключения пользователя, упорядоченные по имени
+func (r *connectionRepository) GetConnectionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.ConnectionProfile, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+connectionColumns+` FROM connections WHERE user_id = $1 ORDER BY name, id LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список подключений", err)
+	}
+	defer rows.Close()
+
+	connections := make([]*models.ConnectionProfile, 0)
+	for rows.Next() {
+		connection, err := scanConnection(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать подключение", err)
+		}
+		connections = append(connections, connection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать подключения", err)
+	}
+	return connections, nil
+}
+
+// UpdateConnection обновляет подключение
+func (r *connectionRepository) UpdateConnection(ctx context.Context, connection *models.ConnectionProfile) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE connections SET name = $2, type = $3, host = $4, port = $5, db_user = $6, dbname = $7,
+			sslmode = $8, secure = $9, secret = $10, updated_at = $11
+		WHERE id = $1`,
+		connection.ID, connection.Name, connection.Type, connection.Host, connection.Port, connection.User,
+		connection.DBName, connection.SSLMode, connection.Secure, connection.Secret, connection.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return models.NewConflictError("Подключение с таким именем уже существует")
+	}
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить подключение", err)
+	}
+	return expectAffected(res, models.NewConnectionNotFoundError(connection.ID))
+}
+
+// DeleteConnection удаляет подключение
+func (r *connectionRepository) DeleteConnection(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM connections WHERE id = $1`, id)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось удалить подключение", err)
+	}
+	return expectAffected(res, models.NewConnectionNotFoundError(id))
+}
+
+func scanConnection(row rowScanner) (*models.ConnectionProfile, error) {
+	var c models.ConnectionProfile
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.Host, &c.Port, &c.User, &c.DBName,
+		&c.SSLMode, &c.Secure, &c.Secret, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.HasPassword = len(c.Secret) > 0
+	return &c, nil
+}
+
+// isUniqueViolation сообщает, что запрос нарушил уникальный индекс
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,406 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/ddl"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// databaseRepository реализация DatabaseRepository для целевой базы PostgreSQL.
+// Имя таблицы может содержать схему: "staging.orders"; без схемы используется current_schema()
+type databaseRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewDatabaseRepository создает DatabaseRepository для целевой базы PostgreSQL
+func NewDatabaseRepository(db *sql.DB, logger logger.Logger) repository.DatabaseRepository {
+	return &databaseRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// TestConnection проверяет соединение с базой; config не используется
+func (r *databaseRepository) TestConnection(ctx context.Context, config interface{}) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return models.NewAppErrorWithCause(models.ErrorCodeConnectionFailed, "Не удалось подключиться к PostgreSQL", http.StatusBadGateway, err)
+	}
+	return nil
+}
+
+// ExecuteQuery выполняет запрос и возвращает строки как []map[string]interface{}
+func (r *databaseRepository) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeQueryFailed, "Не удалось выполнить запрос", http.StatusBadRequest, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать результат запроса", err)
+	}
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать результат запроса", err)
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if raw, ok := values[i].([]byte); ok {
+				values[i] = string(raw)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать результат запроса", err)
+	}
+	return result, nil
+}
+
+// GetTableSchema читает схему таблицы из pg_catalog: колонки с типами в каноническом
+// написании (ddl.CanonicalPostgresType), первичный ключ, ограничения unique, check,
+// foreign key и индексы по колонкам. Индексы по выражениям и частичные индексы пропускаются
+func (r *databaseRepository) GetTableSchema(ctx context.Context, tableName string) (*models.TableSchema, error) {
+	namespace, name := splitTableName(tableName)
+	var oid int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT c.oid FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1 AND n.nspname = COALESCE(NULLIF($2, ''), current_schema())
+		  AND c.relkind IN ('r', 'p')`, name, namespace).Scan(&oid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewTableNotFoundError(tableName)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить схему таблицы", err)
+	}
+
+	schema := &models.TableSchema{
+		TableName:   name,
+		Fields:      []models.TableField{},
+		PrimaryKey:  []string{},
+		Indexes:     []models.TableIndex{},
+		Constraints: []models.TableConstraint{},
+	}
+	if err := r.readColumns(ctx, oid, schema); err != nil {
+		return nil, err
+	}
+	if err := r.readConstraints(ctx, oid, schema); err != nil {
+		return nil, err
+	}
+	if err := r.readIndexes(ctx, oid, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (r *databaseRepository) readColumns(ctx context.Context, oid int64, schema *models.TableSchema) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+		       COALESCE(col_description(a.attrelid, a.attnum), '')
+		FROM pg_catalog.pg_attribute a
+		WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, oid)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось получить колонки таблицы", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field models.TableField
+		if err := rows.Scan(&field.Name, &field.Type, &field.Nullable, &field.Description); err != nil {
+			return models.NewDatabaseError("Не удалось получить колонки таблицы", err)
+		}
+		field.Type = ddl.CanonicalPostgresType(field.Type)
+		schema.Fields = append(schema.Fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return models.NewDatabaseError("Не удалось получить колонки таблицы", err)
+	}
+	return nil
+}
+
+// readConstraints читает первичный ключ и ограничения в формате TableConstraint: для unique —
+// список колонок, для внешнего ключа по одной колонке без ON UPDATE и DEFERRABLE — колонку
+// и ссылку. CHECK и остальные внешние ключи сохраняются текстом определения в Expression:
+// их можно сравнить и удалить, но DDL из них не строится
+func (r *databaseRepository) readConstraints(ctx context.Context, oid int64, schema *models.TableSchema) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT con.conname, con.contype, pg_get_constraintdef(con.oid),
+		       ARRAY(SELECT a.attname
+		             FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+		             JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		             ORDER BY k.ord),
+		       COALESCE(rc.relname, ''),
+		       CASE WHEN rn.nspname <> n.nspname THEN rn.nspname ELSE '' END,
+		       ARRAY(SELECT a.attname
+		             FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+		             JOIN pg_catalog.pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+		             ORDER BY k.ord),
+		       con.confdeltype, con.confupdtype, con.condeferrable
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_catalog.pg_class rc ON rc.oid = con.confrelid
+		LEFT JOIN pg_catalog.pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE con.conrelid = $1 AND con.contype IN ('p', 'u', 'c', 'f')
+		ORDER BY con.conname`, oid)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось получить ограничения таблицы", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, kind, definition, refTable, refSchema, onDelete, onUpdate string
+		var columns, refColumns []string
+		var deferrable bool
+		if err := rows.Scan(&name, &kind, &definition, pq.Array(&columns), &refTable, &refSchema,
+			pq.Array(&refColumns), &onDelete, &onUpdate, &deferrable); err != nil {
+			return models.NewDatabaseError("Не удалось получить ограничения таблицы", err)
+		}
+		switch kind {
+		case "p":
+			schema.PrimaryKey = columns
+		case "u":
+			schema.Constraints = append(schema.Constraints, models.TableConstraint{
+				Name: name, Type: "unique", Expression: strings.Join(columns, ", "),
+			})
+		case "c":
+			expression := strings.TrimSuffix(strings.TrimPrefix(definition, "CHECK "), " NOT VALID")
+			if strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") {
+				expression = expression[1 : len(expression)-1]
+			}
+			schema.Constraints = append(schema.Constraints, models.TableConstraint{Name: name, Type: "check", Expression: expression})
+		case "f":
+			constraint := models.TableConstraint{Name: name, Type: "foreign_key"}
+			action, known := foreignKeyActions[onDelete]
+			if len(columns) == 1 && len(refColumns) == 1 && known && onUpdate == "a" && !deferrable {
+				constraint.Column, constraint.RefSchema, constraint.RefTable, constraint.RefColumn = columns[0], refSchema, refTable, refColumns[0]
+				constraint.OnDelete = action
+			} else {
+				constraint.Expression = strings.TrimPrefix(definition, "FOREIGN KEY ")
+			}
+			schema.Constraints = append(schema.Constraints, constraint)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.NewDatabaseError("Не удалось получить ограничения таблицы", err)
+	}
+	return nil
+}
+
+// foreignKeyActions действия ON DELETE из pg_constraint.confdeltype в формате TableConstraint.OnDelete
+var foreignKeyActions = map[string]string{
+	"a": "", "r": "restrict", "c": "cascade", "n": "set_null", "d": "set_default",
+}
+
+// readIndexes читает индексы, не созданные ограничениями. Первая колонка индекса
+// отмечается Indexed, как в схеме, по которой генерируется DDL
+func (r *databaseRepository) readIndexes(ctx context.Context, oid int64, schema *models.TableSchema) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.relname, am.amname, ix.indisunique,
+		       ARRAY(SELECT a.attname
+		             FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		             JOIN pg_catalog.pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+		             ORDER BY k.ord)
+		FROM pg_catalog.pg_index ix
+		JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_catalog.pg_am am ON am.oid = i.relam
+		WHERE ix.indrelid = $1 AND ix.indexprs IS NULL AND ix.indpred IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = ix.indexrelid)
+		ORDER BY i.relname`, oid)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось получить индексы таблицы", err)
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]bool)
+	for rows.Next() {
+		var index models.TableIndex
+		var unique bool
+		if err := rows.Scan(&index.Name, &index.Type, &unique, pq.Array(&index.Fields)); err != nil {
+			return models.NewDatabaseError("Не удалось получить индексы таблицы", err)
+		}
+		switch {
+		case unique && index.Type == "btree":
+			index.Type = "unique"
+		case index.Type == "btree":
+			index.Type = ""
+		}
+		schema.Indexes = append(schema.Indexes, index)
+		if len(index.Fields) > 0 {
+			indexed[index.Fields[0]] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.NewDatabaseError("Не удалось получить индексы таблицы", err)
+	}
+	for i := range schema.Fields {
+		schema.Fields[i].Indexed = indexed[schema.Fields[i].Name]
+	}
+	return nil
+}
+
+// CreateTable создает таблицу по схеме с помощью генератора DDL PostgreSQL
+func (r *databaseRepository) CreateTable(ctx context.Context, schema *models.TableSchema) error {
+	generator, err := ddl.NewGenerator(ddl.DialectPostgres)
+	if err != nil {
+		return err
+	}
+	response, err := generator.Generate(schema, nil, ddl.Options{})
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, response.DDL); err != nil {
+		return models.NewDatabaseError(fmt.Sprintf("Не удалось создать таблицу %s", schema.TableName), err)
+	}
+	return nil
+}
+
+// InsertData добавляет строки в таблицу в одной транзакции
+func (r *databaseRepository) InsertData(ctx context.Context, tableName string, data []map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	columns := make([]string, 0, len(data[0]))
+	for column := range data[0] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pq.QuoteIdentifier(column)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	namespace, name := splitTableName(tableName)
+	table := pq.QuoteIdentifier(name)
+	if namespace != "" {
+		table = pq.QuoteIdentifier(namespace) + "." + table
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось начать транзакцию", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(quoted, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return models.NewDatabaseError(fmt.Sprintf("Не удалось подготовить вставку в %s", tableName), err)
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(columns))
+	for _, row := range data {
+		for i, column := range columns {
+			args[i] = row[column]
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return models.NewDatabaseError(fmt.Sprintf("Не удалось вставить строку в %s", tableName), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.NewDatabaseError("Не удалось зафиксировать транзакцию", err)
+	}
+	return nil
+}
+
+// Dialect возвращает диалект DDL целевой базы
+func (r *databaseRepository) Dialect() string {
+	return ddl.DialectPostgres
+}
+
+// BulkInsert загружает строки протоколом COPY в одной транзакции: ошибка любой
+// строки на стороне сервера или ошибка next отменяет всю загрузку, поэтому opts не используются
+func (r *databaseRepository) BulkInsert(ctx context.Context, tableName string, columns []string, next func() ([]interface{}, error), opts models.BulkInsertOptions) (int64, error) {
+	namespace, name := splitTableName(tableName)
+	copyStatement := pq.CopyIn(name, columns...)
+	if namespace != "" {
+		copyStatement = pq.CopyInSchema(namespace, name, columns...)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, models.NewDatabaseError("Не удалось начать транзакцию", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, copyStatement)
+	if err != nil {
+		return 0, copyError(tableName, err)
+	}
+	defer stmt.Close()
+
+	var rows int64
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		// Строки буферизуются драйвером; ошибки сервера приходят при отправке буфера или в конце
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, copyError(tableName, err)
+		}
+		rows++
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, copyError(tableName, err)
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, copyError(tableName, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, models.NewDatabaseError("Не удалось зафиксировать транзакцию", err)
+	}
+	return rows, nil
+}
+
+// copyError оборачивает ошибку COPY; для ошибок сервера добавляет место ошибки (строку COPY)
+func copyError(tableName string, err error) error {
+	appErr := models.NewDatabaseError(fmt.Sprintf("Не удалось загрузить строки в %s", tableName), err)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		appErr.Details = map[string]interface{}{
+			"code":    string(pqErr.Code),
+			"message": pqErr.Message,
+			"detail":  pqErr.Detail,
+			"where":   pqErr.Where,
+		}
+	}
+	return appErr
+}
+
+// splitTableName отделяет схему от имени таблицы: "staging.orders" → "staging", "orders"
+func splitTableName(tableName string) (string, string) {
+	if namespace, name, ok := strings.Cut(tableName, "."); ok {
+		return namespace, name
+	}
+	return "", tableName
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ai-data-engineer-backend/internal/config"
+
+	_ "github.com/lib/pq"
+)
+
+// NewDB открывает пул соединений с PostgreSQL и применяет миграции
+func NewDB(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.GetPostgreSQLDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres connection: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.Database.PostgreSQL.MaxOpen)
+	db.SetMaxIdleConns(cfg.Database.PostgreSQL.MaxIdle)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate создает недостающие таблицы служебной базы
+func Migrate(ctx context.Context, db *sql.DB) error {
+	for i, stmt := range migrations {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// migrations идемпотентные DDL выражения, применяются по порядку при старте
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS files (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT        NOT NULL,
+		filename     TEXT        NOT NULL,
+		content_type TEXT        NOT NULL DEFAULT '',
+		size         BIGINT      NOT NULL DEFAULT 0,
+		path         TEXT        NOT NULL,
+		bucket       TEXT        NOT NULL,
+		status       TEXT        NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL,
+		updated_at   TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_files_user_created ON files (user_id, created_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_files_status ON files (status)`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE IF NOT EXISTS upload_sessions (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT        NOT NULL,
+		filename     TEXT        NOT NULL,
+		content_type TEXT        NOT NULL DEFAULT '',
+		size         BIGINT      NOT NULL DEFAULT 0,
+		part_size    BIGINT      NOT NULL,
+		bucket       TEXT        NOT NULL,
+		path         TEXT        NOT NULL,
+		upload_id    TEXT        NOT NULL,
+		status       TEXT        NOT NULL,
+		file_id      TEXT        NOT NULL DEFAULT '',
+		expires_at   TIMESTAMPTZ NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL,
+		updated_at   TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires ON upload_sessions (status, expires_at)`,
+	`CREATE TABLE IF NOT EXISTS upload_parts (
+		session_id  TEXT        NOT NULL REFERENCES upload_sessions (id) ON DELETE CASCADE,
+		number      INTEGER     NOT NULL,
+		size        BIGINT      NOT NULL,
+		etag        TEXT        NOT NULL,
+		uploaded_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (session_id, number)
+	)`,
+	`CREATE TABLE IF NOT EXISTS blobs (
+		checksum   TEXT PRIMARY KEY,
+		bucket     TEXT        NOT NULL,
+		path       TEXT        NOT NULL,
+		size       BIGINT      NOT NULL,
+		ref_count  INTEGER     NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_files_checksum ON files (checksum)`,
+	`CREATE TABLE IF NOT EXISTS analyses (
+		id              TEXT PRIMARY KEY,
+		user_id         TEXT        NOT NULL,
+		file_id         TEXT        NOT NULL DEFAULT '',
+		file_path       TEXT        NOT NULL DEFAULT '',
+		status          TEXT        NOT NULL,
+		profile         JSONB,
+		analysis_result JSONB,
+		error           TEXT        NOT NULL DEFAULT '',
+		created_at      TIMESTAMPTZ NOT NULL,
+		updated_at      TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_analyses_user_created ON analyses (user_id, created_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_analyses_status ON analyses (status)`,
+	`CREATE INDEX IF NOT EXISTS idx_analyses_file ON analyses (file_id) WHERE file_id <> ''`,
+	`CREATE TABLE IF NOT EXISTS executions (
+		id           TEXT PRIMARY KEY,
+		pipeline_id  TEXT        NOT NULL,
+		user_id      TEXT        NOT NULL DEFAULT '',
+		status       TEXT        NOT NULL,
+		parameters   JSONB,
+		started_at   TIMESTAMPTZ NOT NULL,
+		completed_at TIMESTAMPTZ,
+		error        TEXT        NOT NULL DEFAULT '',
+		logs         JSONB       NOT NULL DEFAULT '[]'
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_executions_pipeline_started ON executions (pipeline_id, started_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_executions_status ON executions (status)`,
+	`CREATE TABLE IF NOT EXISTS connections (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT        NOT NULL,
+		name       TEXT        NOT NULL,
+		type       TEXT        NOT NULL,
+		host       TEXT        NOT NULL,
+		port       TEXT        NOT NULL,
+		db_user    TEXT        NOT NULL,
+		dbname     TEXT        NOT NULL,
+		sslmode    TEXT        NOT NULL DEFAULT '',
+		secure     BOOLEAN     NOT NULL DEFAULT false,
+		secret     BYTEA,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_connections_user_name ON connections (user_id, name)`,
+	`CREATE TABLE IF NOT EXISTS pipelines (
+		id          TEXT PRIMARY KEY,
+		user_id     TEXT        NOT NULL,
+		name        TEXT        NOT NULL,
+		description TEXT        NOT NULL DEFAULT '',
+		status      TEXT        NOT NULL,
+		config      JSONB,
+		source      JSONB       NOT NULL,
+		target      JSONB       NOT NULL,
+		steps       JSONB       NOT NULL DEFAULT '[]',
+		created_at  TIMESTAMPTZ NOT NULL,
+		updated_at  TIMESTAMPTZ NOT NULL,
+		executed_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_pipelines_user_created ON pipelines (user_id, created_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_pipelines_status ON pipelines (status)`,
+	`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS schedule JSONB`,
+	`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS next_run_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS idx_pipelines_next_run ON pipelines (next_run_at) WHERE next_run_at IS NOT NULL`,
+	`CREATE TABLE IF NOT EXISTS leases (
+		name       TEXT PRIMARY KEY,
+		holder     TEXT        NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`,
+	`ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE executions ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE analyses ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE executions ADD COLUMN IF NOT EXISTS dag_run_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS blob_acquired BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT ''`,
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const executionColumns = `id, pipeline_id, user_id, status, parameters, started_at, completed_at, error, logs, owner, dag_run_id`
+
+// executionRepository реализация ExecutionRepository на PostgreSQL.
+// Параметры и логи выполнения хранятся в JSONB
+type executionRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewExecutionRepository создает ExecutionRepository на PostgreSQL
+func NewExecutionRepository(db *sql.DB, logger logger.Logger) repository.ExecutionRepository {
+	return &executionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveExecution сохраняет новое выполнение пайплайна
+func (r *executionRepository) SaveExecution(ctx context.Context, execution *models.PipelineExecution) error {
+	parameters, logs, err := marshalExecution(execution)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO executions (`+executionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		execution.ID, execution.PipelineID, execution.UserID, execution.Status, parameters,
+		execution.StartedAt, execution.CompletedAt, execution.Error, logs, execution.Owner, execution.DAGRunID,
+	)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("execution_id", execution.ID).Error("Failed to save execution")
+		return models.NewDatabaseError("Не удалось сохранить выполнение пайплайна", err)
+	}
+	return nil
+}
+
+// GetExecution возвращает выполнение по ID
+func (r *executionRepository) GetExecution(ctx context.Context, id string) (*models.PipelineExecution, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+executionColumns+` FROM executions WHERE id = $1`, id)
+	execution, err := scanExecution(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewExecutionNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить выполнение пайплайна", err)
+	}
+	return execution, nil
+}
+
+// GetExecutionsByPipeline возвращает выполнения пайплайна, начиная с последних
+func (r *executionRepository) GetExecutionsByPipeline(ctx context.Context, pipelineID string, limit, offset int) ([]*models.PipelineExecution, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+executionColumns+` FROM executions WHERE pipeline_id = $1 ORDER BY started_at DESC, id LIMIT $2 OFFSET $3`,
+		pipelineID, limit, offset,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список выполнений", err)
+	}
+	return scanExecutions(rows)
+}
+
+// UpdateExecution обновляет выполнение, сохраняя запрошенную отмену
+func (r *executionRepository) UpdateExecution(ctx context.Context, execution *models.PipelineExecution) error {
+	parameters, logs, err := marshalExecution(execution)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE executions SET pipeline_id = $2, user_id = $3, parameters = $5,
+			status = CASE WHEN status = 'cancelling' AND $4::text = 'running' THEN status ELSE $4::text END,
+			started_at = $6, completed_at = $7, error = $8, logs = $9, owner = $10, dag_run_id = $11
+		WHERE id = $1`,
+		execution.ID, execution.PipelineID, execution.UserID, execution.Status, parameters,
+		execution.StartedAt, execution.CompletedAt, execution.Error, logs, execution.Owner, execution.DAGRunID,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить выполнение пайплайна", err)
+	}
+	return expectAffected(res, models.NewExecutionNotFoundError(execution.ID))
+}
+
+// TransitionExecution обновляет выполнение, если его статус равен from
+func (r *executionRepository) TransitionExecution(ctx context.Context, execution *models.PipelineExecution, from models.ExecutionStatus) (bool, error) {
+	parameters, logs, err := marshalExecution(execution)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE executions SET pipeline_id = $2, user_id = $3, status = $4, parameters = $5,
+			started_at = $6, completed_at = $7, error = $8, logs = $9, owner = $10, dag_run_id = $11
+		WHERE id = $1 AND status = $12`,
+		execution.ID, execution.PipelineID, execution.UserID, execution.Status, parameters,
+		execution.StartedAt, execution.CompletedAt, execution.Error, logs, execution.Owner, execution.DAGRunID, from,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить выполнение пайплайна", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить выполнение пайплайна", err)
+	}
+	return affected > 0, nil
+}
+
+// ClaimExecution передает незавершенное выполнение экземпляру owner, если владелец не изменился
+func (r *executionRepository) ClaimExecution(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE executions SET owner = $2 WHERE id = $1 AND owner = $3 AND status IN ($4, $5)`,
+		id, owner, previousOwner, models.ExecutionStatusRunning, models.ExecutionStatusCancelling,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать выполнение пайплайна", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать выполнение пайплайна", err)
+	}
+	return affected > 0, nil
+}
+
+// GetExecutionsByStatus возвращает выполнения в указанном статусе, начиная с самых старых
+func (r *executionRepository) GetExecutionsByStatus(ctx context.Context, status models.ExecutionStatus) ([]*models.PipelineExecution, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+executionColumns+` FROM executions WHERE status = $1 ORDER BY started_at`, status)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список выполнений", err)
+	}
+	return scanExecutions(rows)
+}
+
+// marshalExecution сериализует JSONB поля выполнения
+func marshalExecution(execution *models.PipelineExecution) (parameters sql.NullString, logs string, err error) {
+	if execution.Parameters != nil {
+		data, err := json.Marshal(execution.Parameters)
+		if err != nil {
+			return parameters, "", models.NewInternalError("Не удалось сериализовать параметры выполнения", err)
+		}
+		parameters = sql.NullString{String: string(data), Valid: true}
+	}
+	entries := execution.Logs
+	if entries == nil {
+		entries = []models.ExecutionLog{}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return parameters, "", models.NewInternalError("Не удалось сериализовать логи выполнения", err)
+	}
+	return parameters, string(data), nil
+}
+
+func scanExecution(row rowScanner) (*models.PipelineExecution, error) {
+	var e models.PipelineExecution
+	var parameters, logs []byte
+	var completedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.PipelineID, &e.UserID, &e.Status, &parameters, &e.StartedAt,
+		&completedAt, &e.Error, &logs, &e.Owner, &e.DAGRunID)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		e.CompletedAt = &completedAt.Time
+	}
+	if parameters != nil {
+		if err := json.Unmarshal(parameters, &e.Parameters); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(logs, &e.Logs); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func scanExecutions(rows *sql.Rows) ([]*models.PipelineExecution, error) {
+	defer rows.Close()
+	executions := make([]*models.PipelineExecution, 0)
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать выполнение пайплайна", err)
+		}
+		executions = append(executions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать выполнение пайплайна", err)
+	}
+	return executions, nil
+}
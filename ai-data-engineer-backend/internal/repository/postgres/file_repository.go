@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const fileColumns = `id, user_id, filename, content_type, size, checksum, path, bucket, status, created_at, updated_at`
+
+// fileRepository реализация FileRepository на PostgreSQL
+type fileRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewFileRepository создает FileRepository на PostgreSQL
+func NewFileRepository(db *sql.DB, logger logger.Logger) repository.FileRepository {
+	return &fileRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveFile сохраняет метаданные нового файла
+func (r *fileRepository) SaveFile(ctx context.Context, file *models.FileMetadata) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO files (`+fileColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		file.ID, file.UserID, file.Filename, file.ContentType, file.Size, file.Checksum,
+		file.Path, file.Bucket, file.Status, file.CreatedAt, file.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("file_id", file.ID).Error("Failed to save file metadata")
+		return models.NewDatabaseError("Не удалось сохранить метаданные файла", err)
+	}
+	return nil
+}
+
+// GetFile возвращает метаданные файла по ID
+func (r *fileRepository) GetFile(ctx context.Context, id string) (*models.FileMetadata, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+fileColumns+` FROM files WHERE id = $1`, id)
+	file, err := scanFile(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewFileNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить метаданные файла", err)
+	}
+	return file, nil
+}
+
+// GetFilesByUser возвращает файлы пользователя, начиная с последних
+func (r *fileRepository) GetFilesByUser(ctx context.Context, userID string, limit, offset int) ([]*models.FileMetadata, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+fileColumns+` FROM files WHERE user_id = $1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список файлов", err)
+	}
+	return scanFiles(rows)
+}
+
+// UpdateFile обновляет метаданные файла
+func (r *fileRepository) UpdateFile(ctx context.Context, file *models.FileMetadata) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE files SET user_id = $2, filename = $3, content_type = $4, size = $5, checksum = $6,
+			path = $7, bucket = $8, status = $9, updated_at = $10
+		WHERE id = $1`,
+		file.ID, file.UserID, file.Filename, file.ContentType, file.Size, file.Checksum,
+		file.Path, file.Bucket, file.Status, file.UpdatedAt,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить метаданные файла", err)
+	}
+	return expectAffected(res, models.NewFileNotFoundError(file.ID))
+}
+
+// DeleteFile удаляет метаданные файла
+func (r *fileRepository) DeleteFile(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM files WHERE id = $1`, id)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось удалить метаданные файла", err)
+	}
+	return expectAffected(res, models.NewFileNotFoundError(id))
+}
+
+// GetFilesByStatus возвращает файлы в указанном статусе
+func (r *fileRepository) GetFilesByStatus(ctx context.Context, status models.FileStatus) ([]*models.FileMetadata, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+fileColumns+` FROM files WHERE status = $1 ORDER BY created_at`, status)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список файлов", err)
+	}
+	return scanFiles(rows)
+}
+
+// GetFilesByChecksum возвращает файлы с указанным содержимым
+func (r *fileRepository) GetFilesByChecksum(ctx context.Context, checksum string) ([]*models.FileMetadata, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+fileColumns+` FROM files WHERE checksum = $1 ORDER BY created_at DESC, id`, checksum)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список файлов", err)
+	}
+	return scanFiles(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFile(row rowScanner) (*models.FileMetadata, error) {
+	var f models.FileMetadata
+	err := row.Scan(&f.ID, &f.UserID, &f.Filename, &f.ContentType, &f.Size, &f.Checksum,
+		&f.Path, &f.Bucket, &f.Status, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func scanFiles(rows *sql.Rows) ([]*models.FileMetadata, error) {
+	defer rows.Close()
+	files := make([]*models.FileMetadata, 0)
+	for rows.Next() {
+		f, err := scanFile(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать метаданные файла", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать метаданные файла", err)
+	}
+	return files, nil
+}
+
+// expectAffected возвращает notFound, если запрос не затронул ни одной строки
+func expectAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.NewDatabaseError("Не удалось проверить результат запроса", err)
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
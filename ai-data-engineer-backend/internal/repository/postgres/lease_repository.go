@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// leaseRepository реализация LeaseRepository на PostgreSQL. Срок аренды отсчитывается
+// по часам базы, поэтому расхождение часов экземпляров сервиса не влияет на захват
+type leaseRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewLeaseRepository создает LeaseRepository на PostgreSQL
+func NewLeaseRepository(db *sql.DB, logger logger.Logger) repository.LeaseRepository {
+	return &leaseRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AcquireLease захватывает свободную или истекшую аренду либо продлевает свою
+func (r *leaseRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	var current string
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO leases (name, holder, expires_at) VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()
+		RETURNING holder`,
+		name, holder, ttl.Milliseconds(),
+	).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось захватить аренду", err)
+	}
+	return true, nil
+}
+
+// ReleaseLease освобождает аренду, если ее удерживает holder
+func (r *leaseRepository) ReleaseLease(ctx context.Context, name, holder string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM leases WHERE name = $1 AND holder = $2`, name, holder); err != nil {
+		return models.NewDatabaseError("Не удалось освободить аренду", err)
+	}
+	return nil
+}
+
+// LeaseHolder возвращает владельца действующей аренды
+func (r *leaseRepository) LeaseHolder(ctx context.Context, name string) (string, error) {
+	var holder string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT holder FROM leases WHERE name = $1 AND expires_at >= now()`, name,
+	).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", models.NewDatabaseError("Не удалось прочитать аренду", err)
+	}
+	return holder, nil
+}
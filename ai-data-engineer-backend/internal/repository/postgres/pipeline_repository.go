@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const pipelineColumns = `id, user_id, name, description, status, config, source, target, steps, created_at, updated_at, executed_at, schedule`
+
+// pipelineRepository реализация PipelineRepository на PostgreSQL.
+// Конфигурация, источник, цель, шаги и расписание хранятся в JSONB; время следующего
+// запуска дублируется в колонке next_run_at для поиска и атомарного сдвига расписания
+type pipelineRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewPipelineRepository создает PipelineRepository на PostgreSQL
+func NewPipelineRepository(db *sql.DB, logger logger.Logger) repository.PipelineRepository {
+	return &pipelineRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SavePipeline сохраняет новый пайплайн и возвращает его ID
+func (r *pipelineRepository) SavePipeline(ctx context.Context, pipeline *models.Pipeline) (string, error) {
+	if pipeline.ID == "" {
+		pipeline.ID = uuid.New().String()
+	}
+	config, source, target, steps, err := marshalPipeline(pipeline)
+	if err != nil {
+		return "", err
+	}
+	schedule, nextRunAt, err := marshalSchedule(pipeline.Schedule)
+	if err != nil {
+		return "", err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO pipelines (`+pipelineColumns+`, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		pipeline.ID, pipeline.UserID, pipeline.Name, pipeline.Description, pipeline.Status,
+		config, source, target, steps, pipeline.CreatedAt, pipeline.UpdatedAt, pipeline.ExecutedAt,
+		schedule, nextRunAt,
+	)
+	if isUniqueViolation(err) {
+		return "", models.NewConflictError("Пайплайн с таким ID уже существует")
+	}
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("pipeline_id", pipeline.ID).Error("Failed to save pipeline")
+		return "", models.NewDatabaseError("Не удалось сохранить пайплайн", err)
+	}
+	return pipeline.ID, nil
+}
+
+// GetPipeline возвращает пайплайн по ID
+func (r *pipelineRepository) GetPipeline(ctx context.Context, id string) (*models.Pipeline, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+pipelineColumns+` FROM pipelines WHERE id = $1`, id)
+	pipeline, err := scanPipeline(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewPipelineNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить пайплайн", err)
+	}
+	return pipeline, nil
+}
+
+// GetPipelinesByUser возвращает пайплайны пользователя, начиная с последних
+func (r *pipelineRepository) GetPipelinesByUser(ctx context.Context, userID string, status models.PipelineStatus, limit, offset int) ([]*models.Pipeline, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+pipelineColumns+` FROM pipelines
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id LIMIT $3 OFFSET $4`,
+		userID, status, limit, offset,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список пайплайнов", err)
+	}
+	return scanPipelines(rows)
+}
+
+// UpdatePipeline обновляет пайплайн, если он не выполняется
+func (r *pipelineRepository) UpdatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	config, source, target, steps, err := marshalPipeline(pipeline)
+	if err != nil {
+		return err
+	}
+	schedule, nextRunAt, err := marshalSchedule(pipeline.Schedule)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE pipelines SET user_id = $2, name = $3, description = $4, status = $5, config = $6,
+			source = $7, target = $8, steps = $9, created_at = $10, updated_at = $11, executed_at = $12,
+			schedule = $13, next_run_at = $14
+		WHERE id = $1 AND status <> 'running'`,
+		pipeline.ID, pipeline.UserID, pipeline.Name, pipeline.Description, pipeline.Status,
+		config, source, target, steps, pipeline.CreatedAt, pipeline.UpdatedAt, pipeline.ExecutedAt,
+		schedule, nextRunAt,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить пайплайн", err)
+	}
+	return r.expectNotRunning(ctx, res, pipeline.ID, "Пайплайн выполняется и не может быть изменен")
+}
+
+// DeletePipeline удаляет пайплайн, если он не выполняется
+func (r *pipelineRepository) DeletePipeline(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM pipelines WHERE id = $1 AND status <> 'running'`, id)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось удалить пайплайн", err)
+	}
+	return r.expectNotRunning(ctx, res, id, "Пайплайн выполняется и не может быть удален")
+}
+
+// expectNotRunning проверяет результат изменения с условием status <> 'running':
+// если строка не затронута, пайплайн либо не найден, либо выполняется
+func (r *pipelineRepository) expectNotRunning(ctx context.Context, res sql.Result, id, conflict string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.NewDatabaseError("Не удалось проверить результат запроса", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pipelines WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return models.NewDatabaseError("Не удалось получить пайплайн", err)
+	}
+	if !exists {
+		return models.NewPipelineNotFoundError(id)
+	}
+	return models.NewConflictError(conflict)
+}
+
+// GetPipelinesByStatus возвращает пайплайны в указанном статусе, начиная с самых старых
+func (r *pipelineRepository) GetPipelinesByStatus(ctx context.Context, status models.PipelineStatus) ([]*models.Pipeline, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+pipelineColumns+` FROM pipelines WHERE status = $1 ORDER BY created_at, id`, status)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить список пайплайнов", err)
+	}
+	return scanPipelines(rows)
+}
+
+// UpdatePipelineState обновляет статус, шаги и время выполнения пайплайна
+func (r *pipelineRepository) UpdatePipelineState(ctx context.Context, pipeline *models.Pipeline) error {
+	_, _, _, steps, err := marshalPipeline(pipeline)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE pipelines SET status = $2, steps = $3, updated_at = $4, executed_at = $5 WHERE id = $1`,
+		pipeline.ID, pipeline.Status, steps, pipeline.UpdatedAt, pipeline.ExecutedAt,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить состояние пайплайна", err)
+	}
+	return expectAffected(res, models.NewPipelineNotFoundError(pipeline.ID))
+}
+
+// StartPipeline сохраняет состояние пайплайна, если он не выполняется. Условие в UPDATE
+// гарантирует, что пайплайн запустит только один запрос среди всех экземпляров сервиса
+func (r *pipelineRepository) StartPipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	_, _, _, steps, err := marshalPipeline(pipeline)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE pipelines SET status = $2, steps = $3, updated_at = $4, executed_at = $5
+		WHERE id = $1 AND status <> 'running'`,
+		pipeline.ID, pipeline.Status, steps, pipeline.UpdatedAt, pipeline.ExecutedAt,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить состояние пайплайна", err)
+	}
+	return r.expectNotRunning(ctx, res, pipeline.ID, "Пайплайн уже выполняется")
+}
+
+// GetDuePipelines возвращает пайплайны, следующий запуск которых не позже before, начиная с самых ранних
+func (r *pipelineRepository) GetDuePipelines(ctx context.Context, before time.Time) ([]*models.Pipeline, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+pipelineColumns+` FROM pipelines WHERE next_run_at <= $1 ORDER BY next_run_at, id`, before)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить пайплайны по расписанию", err)
+	}
+	return scanPipelines(rows)
+}
+
+// AdvanceSchedule заменяет расписание, если следующий запуск равен expected.
+// Условие в UPDATE гарантирует, что запуск обработает только один экземпляр сервиса
+func (r *pipelineRepository) AdvanceSchedule(ctx context.Context, id string, expected time.Time, schedule *models.PipelineSchedule) (bool, error) {
+	data, nextRunAt, err := marshalSchedule(schedule)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE pipelines SET schedule = $3, next_run_at = $4 WHERE id = $1 AND next_run_at = $2`,
+		id, expected, data, nextRunAt,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить расписание пайплайна", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить расписание пайплайна", err)
+	}
+	return affected == 1, nil
+}
+
+// marshalSchedule сериализует расписание и возвращает время следующего запуска для колонки next_run_at
+func marshalSchedule(schedule *models.PipelineSchedule) (sql.NullString, *time.Time, error) {
+	if schedule == nil {
+		return sql.NullString{}, nil, nil
+	}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return sql.NullString{}, nil, models.NewInternalError("Не удалось сериализовать расписание пайплайна", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, schedule.NextRunAt, nil
+}
+
+// marshalPipeline сериализует JSONB поля пайплайна
+func marshalPipeline(pipeline *models.Pipeline) (config sql.NullString, source, target, steps string, err error) {
+	if pipeline.Config != nil {
+		data, err := json.Marshal(pipeline.Config)
+		if err != nil {
+			return config, "", "", "", models.NewInternalError("Не удалось сериализовать конфигурацию пайплайна", err)
+		}
+		config = sql.NullString{String: string(data), Valid: true}
+	}
+	stepList := pipeline.Steps
+	if stepList == nil {
+		stepList = []models.PipelineStep{}
+	}
+	fields := []interface{}{pipeline.Source, pipeline.Target, stepList}
+	encoded := make([]string, len(fields))
+	for i, field := range fields {
+		data, err := json.Marshal(field)
+		if err != nil {
+			return config, "", "", "", models.NewInternalError("Не удалось сериализовать пайплайн", err)
+		}
+		encoded[i] = string(data)
+	}
+	return config, encoded[0], encoded[1], encoded[2], nil
+}
+
+func scanPipeline(row rowScanner) (*models.Pipeline, error) {
+	var p models.Pipeline
+	var config, source, target, steps, schedule []byte
+	var executedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.Status, &config, &source, &target,
+		&steps, &p.CreatedAt, &p.UpdatedAt, &executedAt, &schedule)
+	if err != nil {
+		return nil, err
+	}
+	if executedAt.Valid {
+		p.ExecutedAt = &executedAt.Time
+	}
+	if config != nil {
+		if err := json.Unmarshal(config, &p.Config); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(source, &p.Source); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(target, &p.Target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &p.Steps); err != nil {
+		return nil, err
+	}
+	if schedule != nil {
+		if err := json.Unmarshal(schedule, &p.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func scanPipelines(rows *sql.Rows) ([]*models.Pipeline, error) {
+	defer rows.Close()
+	pipelines := make([]*models.Pipeline, 0)
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать пайплайн", err)
+		}
+		pipelines = append(pipelines, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать пайплайн", err)
+	}
+	return pipelines, nil
+}
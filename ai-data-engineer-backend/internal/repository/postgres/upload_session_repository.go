@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const uploadSessionColumns = `id, user_id, filename, content_type, size, part_size, bucket, path, upload_id, status, file_id, checksum, expires_at, created_at, updated_at, blob_acquired, owner`
+
+// uploadSessionRepository реализация UploadSessionRepository на PostgreSQL.
+// Части хранятся в отдельной таблице, чтобы параллельная загрузка частей не перезаписывала друг друга
+type uploadSessionRepository struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+// NewUploadSessionRepository создает UploadSessionRepository на PostgreSQL
+func NewUploadSessionRepository(db *sql.DB, logger logger.Logger) repository.UploadSessionRepository {
+	return &uploadSessionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveSession сохраняет новую сессию загрузки
+func (r *uploadSessionRepository) SaveSession(ctx context.Context, session *models.UploadSession) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO upload_sessions (`+uploadSessionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		session.ID, session.UserID, session.Filename, session.ContentType, session.Size, session.PartSize,
+		session.Bucket, session.Path, session.UploadID, session.Status, session.FileID, session.Checksum,
+		session.ExpiresAt, session.CreatedAt, session.UpdatedAt, session.BlobAcquired, session.Owner,
+	)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Error("Failed to save upload session")
+		return models.NewDatabaseError("Не удалось сохранить сессию загрузки", err)
+	}
+	return nil
+}
+
+// GetSession возвращает сессию загрузки вместе с загруженными частями
+func (r *uploadSessionRepository) GetSession(ctx context.Context, id string) (*models.UploadSession, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+uploadSessionColumns+` FROM upload_sessions WHERE id = $1`, id)
+	session, err := scanUploadSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewUploadSessionNotFoundError(id)
+	}
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить сессию загрузки", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT number, size, etag, uploaded_at FROM upload_parts WHERE session_id = $1 ORDER BY number`, id)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить части загрузки", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var part models.UploadPart
+		if err := rows.Scan(&part.Number, &part.Size, &part.ETag, &part.UploadedAt); err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать часть загрузки", err)
+		}
+		session.Parts = append(session.Parts, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать части загрузки", err)
+	}
+	return session, nil
+}
+
+// UpdateSession обновляет поля сессии
+func (r *uploadSessionRepository) UpdateSession(ctx context.Context, session *models.UploadSession) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE upload_sessions SET status = $2, file_id = $3, checksum = $4, expires_at = $5, updated_at = $6,
+			blob_acquired = $7, owner = $8
+		WHERE id = $1`,
+		session.ID, session.Status, session.FileID, session.Checksum, session.ExpiresAt, session.UpdatedAt,
+		session.BlobAcquired, session.Owner,
+	)
+	if err != nil {
+		return models.NewDatabaseError("Не удалось обновить сессию загрузки", err)
+	}
+	return expectAffected(res, models.NewUploadSessionNotFoundError(session.ID))
+}
+
+// TransitionSession обновляет поля сессии, если ее статус равен from
+func (r *uploadSessionRepository) TransitionSession(ctx context.Context, session *models.UploadSession, from models.UploadSessionStatus) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE upload_sessions SET status = $3, file_id = $4, checksum = $5, expires_at = $6, updated_at = $7,
+			blob_acquired = $8, owner = $9
+		WHERE id = $1 AND status = $2`,
+		session.ID, from, session.Status, session.FileID, session.Checksum, session.ExpiresAt, session.UpdatedAt,
+		session.BlobAcquired, session.Owner,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить сессию загрузки", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось обновить сессию загрузки", err)
+	}
+	return affected > 0, nil
+}
+
+// ClaimSession передает сессию в статусе completing экземпляру owner, если владелец не изменился
+func (r *uploadSessionRepository) ClaimSession(ctx context.Context, id, owner, previousOwner string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE upload_sessions SET owner = $2, updated_at = now() WHERE id = $1 AND owner = $3 AND status = $4`,
+		id, owner, previousOwner, models.UploadSessionStatusCompleting,
+	)
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать сессию загрузки", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, models.NewDatabaseError("Не удалось передать сессию загрузки", err)
+	}
+	return affected > 0, nil
+}
+
+// SavePart добавляет или заменяет часть сессии
+func (r *uploadSessionRepository) SavePart(ctx context.Context, sessionID string, part models.UploadPart) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO upload_parts (session_id, number, size, etag, uploaded_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (session_id, number) DO UPDATE SET size = EXCLUDED.size, etag = EXCLUDED.etag, uploaded_at = EXCLUDED.uploaded_at`,
+		sessionID, part.Number, part.Size, part.ETag, part.UploadedAt,
+	)
+	if err != nil {
+		r.logger.WithField("error", err.Error()).WithField("upload_id", sessionID).Error("Failed to save upload part")
+		return models.NewDatabaseError("Не удалось сохранить часть загрузки", err)
+	}
+	return nil
+}
+
+// GetExpiredSessions возвращает активные сессии, истекшие до before. Части не загружаются
+func (r *uploadSessionRepository) GetExpiredSessions(ctx context.Context, before time.Time) ([]*models.UploadSession, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+uploadSessionColumns+` FROM upload_sessions WHERE status = $1 AND expires_at < $2 ORDER BY expires_at`,
+		models.UploadSessionStatusActive, before,
+	)
+	if err != nil {
+		return nil, models.NewDatabaseError("Не удалось получить истекшие сессии загрузки", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.UploadSession, 0)
+	for rows.Next() {
+		session, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, models.NewDatabaseError("Не удалось прочитать сессию загрузки", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.NewDatabaseError("Не удалось прочитать сессии загрузки", err)
+	}
+	return sessions, nil
+}
+
+func scanUploadSession(row rowScanner) (*models.UploadSession, error) {
+	var s models.UploadSession
+	err := row.Scan(&s.ID, &s.UserID, &s.Filename, &s.ContentType, &s.Size, &s.PartSize,
+		&s.Bucket, &s.Path, &s.UploadID, &s.Status, &s.FileID, &s.Checksum, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt,
+		&s.BlobAcquired, &s.Owner)
+	if err != nil {
+		return nil, err
+	}
+	s.Parts = make([]models.UploadPart, 0)
+	return &s, nil
+}
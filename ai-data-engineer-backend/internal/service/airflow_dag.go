@@ -0,0 +1,376 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// airflowDAGTemplate DAG Airflow 2.4+: задача PythonOperator на каждый шаг пайплайна,
+// зависимости по DependsOn, повторы и ограничение времени из политики шага
+var airflowDAGTemplate = template.Must(template.New("dag").Funcs(template.FuncMap{"py": pyLiteral}).Parse(`"""
+{{ .Title }}
+
+Сгенерировано AI Data Engineer по пайплайну {{ .PipelineID }}.
+Файл перезаписывается при публикации DAG; изменения вносите в пайплайн.
+"""
+import logging
+from datetime import timedelta
+
+import pendulum
+from airflow import DAG
+from airflow.hooks.base import BaseHook
+from airflow.operators.python import PythonOperator
+
+log = logging.getLogger(__name__)
+
+PIPELINE = {{ py .Pipeline }}
+
+
+def target_connection():
+    """Подключение цели: учетные данные хранятся в Airflow Connection PIPELINE["target"]["conn_id"]."""
+    return BaseHook.get_connection(PIPELINE["target"]["conn_id"])
+{{ range .Types }}
+
+def run_{{ . }}(step, **context):
+    """Шаг {{ . }}: параметры шага в step["config"], источник и цель в PIPELINE."""
+    log.info("Шаг %s (%s): %s", step["id"], step["type"], step["config"])
+    return step["config"]
+{{ end }}
+
+default_args = {
+    "owner": {{ py .Owner }},
+    "depends_on_past": False,
+    "retries": 0,
+}
+
+with DAG(
+    dag_id={{ py .DAGID }},
+    description={{ py .Description }},
+    default_args=default_args,
+    schedule={{ if .Cron }}{{ py .Cron }}{{ else }}None{{ end }},
+    start_date=pendulum.datetime({{ .Start.Year }}, {{ printf "%d" .Start.Month }}, {{ .Start.Day }}, tz={{ py .Timezone }}),
+    catchup={{ if .CatchUp }}True{{ else }}False{{ end }},
+    max_active_runs=1,
+    tags=["ai-data-engineer", "generated"],
+) as dag:
+{{- range .Tasks }}
+    {{ .Var }} = PythonOperator(
+        task_id={{ py .TaskID }},
+        python_callable=run_{{ .Type }},
+        op_kwargs={"step": {{ py .Step }}},
+{{- if .Retries }}
+        retries={{ .Retries }},
+        retry_delay=timedelta(seconds={{ .RetryDelay }}),
+{{- if .MaxRetryDelay }}
+        retry_exponential_backoff=True,
+        max_retry_delay=timedelta(seconds={{ .MaxRetryDelay }}),
+{{- end }}
+{{- end }}
+{{- if .Timeout }}
+        execution_timeout=timedelta(seconds={{ .Timeout }}),
+{{- end }}
+    )
+{{- end }}
+{{ range .Tasks }}{{ if .Upstream }}
+    {{ .Upstream }} >> {{ .Var }}{{ end }}{{ end }}
+`))
+
+// airflowDAG данные шаблона DAG
+type airflowDAG struct {
+	Title       string
+	PipelineID  string
+	DAGID       string
+	Description string
+	Owner       string
+	Pipeline    map[string]interface{}
+	Types       []string
+	Cron        string
+	Timezone    string
+	Start       time.Time
+	CatchUp     bool
+	Tasks       []airflowTask
+}
+
+// airflowTask задача DAG для шага пайплайна
+type airflowTask struct {
+	Var    string
+	TaskID string
+	Type   string
+	Step   map[string]interface{}
+	// Upstream задачи, от которых зависит шаг, в синтаксисе Python
+	Upstream string
+	// Retries число повторов; длительности — в секундах
+	Retries       int
+	RetryDelay    string
+	MaxRetryDelay string
+	Timeout       string
+}
+
+var airflowNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// airflowConnectionName имя подключения или ID сохраненного подключения; строки подключения
+// с адресом и учетными данными ему не соответствуют
+var airflowConnectionName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// pyDocstring экранирует текст для строки документации Python
+var pyDocstring = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// AirflowDAGID идентификатор DAG пайплайна
+func AirflowDAGID(pipeline *models.Pipeline) string {
+	return "pipeline_" + airflowNameInvalid.ReplaceAllString(pipeline.ID, "_")
+}
+
+// AirflowTaskIDs сопоставляет ID задач DAG пайплайна с ID шагов. ID шагов приводятся
+// к идентификаторам Python, поэтому ID задачи может отличаться от ID шага
+func AirflowTaskIDs(pipeline *models.Pipeline) (map[string]string, error) {
+	plan, problems := planSteps(pipeline.Steps)
+	if len(problems) > 0 {
+		return nil, stepsError(problems)
+	}
+	steps := make(map[string]string, len(plan.Order))
+	for stepID, taskID := range airflowTaskNames(plan.Order) {
+		steps[taskID] = stepID
+	}
+	return steps, nil
+}
+
+// AirflowConnID идентификатор Airflow Connection с учетными данными цели пайплайна:
+// ai_data_engineer_ и имя подключения цели (ConnectionString, а без него — тип цели).
+// Цель, заданная строкой подключения, а не именем, в DAG не переносится
+func AirflowConnID(target *models.DataTarget) (string, error) {
+	conn := target.ConnectionString
+	if conn == "" {
+		conn = target.Type
+	}
+	if !airflowConnectionName.MatchString(conn) {
+		return "", models.NewValidationError("Цель пайплайна для Airflow задается именем или ID сохраненного подключения: строки подключения в DAG не передаются", map[string]interface{}{
+			"target_type": target.Type,
+		})
+	}
+	return "ai_data_engineer_" + airflowNameInvalid.ReplaceAllString(conn, "_"), nil
+}
+
+// renderAirflowDAG формирует исходный код DAG Airflow по пайплайну. Шаги становятся задачами
+// PythonOperator, DependsOn — зависимостями задач, config.retry и config.timeout — повторами
+// и execution_timeout, расписание — schedule и start_date в часовом поясе расписания.
+// Экспоненциальная пауза Airflow удваивается, поэтому retry.multiplier передается приближенно.
+// Учетные данные цели в DAG не попадают: DAG получает их из Airflow Connection по AirflowConnID
+func renderAirflowDAG(pipeline *models.Pipeline) ([]byte, error) {
+	plan, problems := planSteps(pipeline.Steps)
+	if len(problems) > 0 {
+		return nil, stepsError(problems)
+	}
+	connID, err := AirflowConnID(&pipeline.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	dag := airflowDAG{
+		Title:       pipeline.Name,
+		PipelineID:  pipeline.ID,
+		DAGID:       AirflowDAGID(pipeline),
+		Description: pipeline.Description,
+		Owner:       pipeline.UserID,
+		Pipeline: map[string]interface{}{
+			"id":   pipeline.ID,
+			"name": pipeline.Name,
+			"source": map[string]interface{}{
+				"type": pipeline.Source.Type, "path": pipeline.Source.Path, "config": pipeline.Source.Config,
+			},
+			"target": map[string]interface{}{
+				"type": pipeline.Target.Type, "conn_id": connID,
+				"schema": pipeline.Target.Schema, "table_name": pipeline.Target.TableName,
+			},
+		},
+		Timezone: "UTC",
+		Start:    pipeline.CreatedAt.UTC(),
+	}
+	if dag.Title == "" {
+		dag.Title = dag.DAGID
+	}
+	if dag.Description == "" {
+		dag.Description = dag.Title
+	}
+	dag.Title = pyDocstring.Replace(dag.Title)
+	if dag.Owner == "" {
+		dag.Owner = "airflow"
+	}
+	if schedule := pipeline.Schedule; schedule != nil {
+		_, location, err := parseSchedule(schedule)
+		if err != nil {
+			return nil, models.NewValidationError("Некорректное расписание", map[string]interface{}{
+				"cron": schedule.Cron, "timezone": schedule.Timezone, "error": err.Error(),
+			})
+		}
+		dag.Cron = schedule.Cron
+		dag.Timezone = location.String()
+		dag.Start = pipeline.CreatedAt.In(location)
+		dag.CatchUp = schedule.CatchUp == models.CatchUpAll
+	}
+
+	names := airflowTaskNames(plan.Order)
+	types := make(map[string]bool)
+	for _, id := range plan.Order {
+		step := stepByID(pipeline.Steps, id)
+		task, err := newAirflowTask(step, names)
+		if err != nil {
+			return nil, err
+		}
+		dag.Tasks = append(dag.Tasks, task)
+		types[task.Type] = true
+	}
+	for stepType := range types {
+		dag.Types = append(dag.Types, stepType)
+	}
+	sort.Strings(dag.Types)
+
+	var source bytes.Buffer
+	if err := airflowDAGTemplate.Execute(&source, dag); err != nil {
+		return nil, models.NewInternalError("Не удалось сформировать DAG Airflow", err)
+	}
+	return source.Bytes(), nil
+}
+
+// newAirflowTask описывает задачу DAG для шага; names — имена переменных задач по ID шагов
+func newAirflowTask(step models.PipelineStep, names map[string]string) (airflowTask, error) {
+	policy, err := newStepPolicy(step)
+	if err != nil {
+		return airflowTask{}, err
+	}
+	task := airflowTask{
+		Var:    names[step.ID] + "_task",
+		TaskID: names[step.ID],
+		Type:   airflowNameInvalid.ReplaceAllString(string(step.Type), "_"),
+		Step: map[string]interface{}{
+			"id": step.ID, "name": step.Name, "type": string(step.Type), "config": step.Config,
+		},
+	}
+	if policy.timeout > 0 {
+		task.Timeout = airflowSeconds(policy.timeout)
+	}
+	if policy.maxAttempts > 1 {
+		task.Retries = policy.maxAttempts - 1
+		task.RetryDelay = airflowSeconds(policy.backoff)
+		if policy.multiplier > 1 {
+			task.MaxRetryDelay = airflowSeconds(policy.maxBackoff)
+		}
+	}
+
+	upstream := make([]string, len(step.DependsOn))
+	for i, dependency := range step.DependsOn {
+		upstream[i] = names[dependency] + "_task"
+	}
+	switch len(upstream) {
+	case 0:
+	case 1:
+		task.Upstream = upstream[0]
+	default:
+		task.Upstream = "[" + strings.Join(upstream, ", ") + "]"
+	}
+	return task, nil
+}
+
+// airflowTaskNames возвращает ID задач по ID шагов в порядке плана
+func airflowTaskNames(order []string) map[string]string {
+	names := make(map[string]string, len(order))
+	used := make(map[string]bool, len(order))
+	for _, id := range order {
+		names[id] = uniqueAirflowName(id, used)
+	}
+	return names
+}
+
+// uniqueAirflowName приводит ID шага к идентификатору Python, уникальному среди used
+func uniqueAirflowName(id string, used map[string]bool) string {
+	base := strings.Trim(airflowNameInvalid.ReplaceAllString(id, "_"), "_")
+	if base == "" || (base[0] >= '0' && base[0] <= '9') {
+		base = "step_" + base
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// stepByID возвращает шаг по ID; шаг существует, так как ID взят из плана
+func stepByID(steps []models.PipelineStep, id string) models.PipelineStep {
+	for _, step := range steps {
+		if step.ID == id {
+			return step
+		}
+	}
+	return models.PipelineStep{ID: id}
+}
+
+func airflowSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// pyLiteral записывает значение из JSON конфигурации литералом Python.
+// Ключи словарей сортируются, чтобы одинаковый пайплайн давал одинаковый файл
+func pyLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "None"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "None"
+		}
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = pyLiteral(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = strconv.Quote(key) + ": " + pyLiteral(v[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case map[string]string:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = item
+		}
+		return pyLiteral(converted)
+	}
+	return strconv.Quote(fmt.Sprint(value))
+}
@@ -0,0 +1,553 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// AirflowOptions место публикации DAG. Если задан DAGsBucket, DAG сохраняются в хранилище
+// под префиксом DAGsPrefix, иначе — в каталог DAGsPath
+type AirflowOptions struct {
+	DAGsPath   string
+	DAGsBucket string
+	DAGsPrefix string
+	// PollInterval период опроса состояния запусков DAG
+	PollInterval time.Duration
+	// Instance экземпляр сервиса, который отслеживает запуски; по умолчанию единственный экземпляр
+	Instance *Instance
+}
+
+// AirflowService формирует DAG Airflow по пайплайнам, публикует и запускает их.
+// Запуски DAG сохраняются как выполнения пайплайна
+type AirflowService struct {
+	pipelines  repository.PipelineRepository
+	executions repository.ExecutionRepository
+	storage    StorageClient
+	airflow    client.AirflowClient
+	broker     *EventBroker
+	options    AirflowOptions
+	logger     logger.Logger
+
+	// mu защищает ctx; запросы к Airflow и репозиториям под mu не выполняются
+	mu sync.Mutex
+	// ctx контекст отслеживания запусков; отменяется при остановке сервера
+	ctx context.Context
+}
+
+// airflowRun отслеживаемый запуск DAG и выполнение пайплайна, которое ему соответствует
+type airflowRun struct {
+	pipeline  *models.Pipeline
+	execution *models.PipelineExecution
+	dagID     string
+	runID     string
+	// steps ID шагов по ID задач DAG
+	steps map[string]string
+}
+
+// NewAirflowService создает AirflowService. storage используется, только если задан DAGsBucket;
+// без airflow запуск DAG недоступен
+func NewAirflowService(
+	pipelines repository.PipelineRepository,
+	executions repository.ExecutionRepository,
+	storage StorageClient,
+	airflow client.AirflowClient,
+	broker *EventBroker,
+	options AirflowOptions,
+	logger logger.Logger,
+) *AirflowService {
+	if options.PollInterval <= 0 {
+		options.PollInterval = 10 * time.Second
+	}
+	if options.Instance == nil {
+		options.Instance = NewInstance(nil, InstanceOptions{}, logger)
+	}
+	return &AirflowService{
+		pipelines:  pipelines,
+		executions: executions,
+		storage:    storage,
+		airflow:    airflow,
+		broker:     broker,
+		options:    options,
+		logger:     logger,
+		ctx:        context.Background(),
+	}
+}
+
+// Start задает контекст отслеживания запусков DAG и возобновляет отслеживание, прерванное
+// остановкой экземпляра сервиса. Запуски экземпляров с истекшей арендой проверяются и дальше,
+// раз в срок аренды (см. Instance)
+func (s *AirflowService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	if err := s.resume(ctx, true); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(s.options.Instance.LeaseTTL())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.resume(ctx, false); err != nil {
+					s.logger.WithField("error", err.Error()).Error("Failed to resume Airflow DAG run tracking")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// resume передает этому экземпляру выполнения с запуском DAG, владелец которых остановился,
+// и возобновляет их отслеживание. Выполнение передается условным обновлением владельца, поэтому
+// один запуск не отслеживают два экземпляра. При запуске (restart) передаются и выполнения
+// с ID этого экземпляра: их вел предыдущий процесс
+func (s *AirflowService) resume(ctx context.Context, restart bool) error {
+	if s.airflow == nil {
+		return nil
+	}
+	for _, status := range []models.ExecutionStatus{models.ExecutionStatusRunning, models.ExecutionStatusCancelling} {
+		executions, err := s.executions.GetExecutionsByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		for _, execution := range executions {
+			if execution.DAGRunID == "" {
+				continue
+			}
+			if execution.Owner == s.options.Instance.ID() {
+				if !restart {
+					continue
+				}
+			} else if alive, err := s.options.Instance.Alive(ctx, execution.Owner); err != nil {
+				return err
+			} else if alive {
+				continue
+			}
+
+			log := s.logger.WithField("execution_id", execution.ID).WithField("dag_run_id", execution.DAGRunID)
+			// Пайплайн читается до передачи выполнения: если он недоступен, выполнение
+			// остается за остановленным владельцем и передается при следующей проверке
+			pipeline, err := s.pipelines.GetPipeline(ctx, execution.PipelineID)
+			if err != nil {
+				log.WithField("error", err.Error()).Error("Failed to load pipeline of Airflow DAG run")
+				continue
+			}
+			claimed, err := s.executions.ClaimExecution(ctx, execution.ID, s.options.Instance.ID(), execution.Owner)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			steps, err := AirflowTaskIDs(pipeline)
+			if err != nil {
+				log.WithField("error", err.Error()).Warn("Failed to map Airflow tasks to pipeline steps")
+			}
+			previousOwner := execution.Owner
+			execution.Owner = s.options.Instance.ID()
+			run := &airflowRun{
+				pipeline:  pipeline,
+				execution: execution,
+				dagID:     AirflowDAGID(pipeline),
+				runID:     execution.DAGRunID,
+				steps:     steps,
+			}
+			s.log(run, models.LogLevelInfo, "", "Отслеживание запуска DAG "+run.dagID+" возобновлено")
+			log.WithField("previous_owner", previousOwner).Info("Airflow DAG run tracking resumed")
+			go s.track(ctx, run)
+		}
+	}
+	return nil
+}
+
+// RenderDAG возвращает исходный код DAG пайплайна пользователя userID без публикации
+func (s *AirflowService) RenderDAG(ctx context.Context, userID, pipelineID string) (*models.AirflowDAGResponse, error) {
+	pipeline, err := s.pipelines.GetPipeline(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.UserID != userID {
+		return nil, errPipelineForbidden()
+	}
+	source, err := renderAirflowDAG(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	dagID := AirflowDAGID(pipeline)
+	return &models.AirflowDAGResponse{
+		PipelineID: pipeline.ID,
+		DAGID:      dagID,
+		FileName:   dagID + ".py",
+		Source:     string(source),
+	}, nil
+}
+
+// PublishDAG формирует DAG пайплайна пользователя userID и атомарно заменяет им файл в каталоге DAG или в хранилище:
+// Airflow не увидит частично записанный файл
+func (s *AirflowService) PublishDAG(ctx context.Context, userID, pipelineID string) (*models.AirflowDAGResponse, error) {
+	dag, err := s.RenderDAG(ctx, userID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	log := s.logger.WithField("pipeline_id", pipelineID).WithField("dag_id", dag.DAGID)
+
+	switch {
+	case s.options.DAGsBucket != "":
+		objectName := path.Join(s.options.DAGsPrefix, dag.FileName)
+		source := []byte(dag.Source)
+		if err := s.storage.UploadFile(ctx, s.options.DAGsBucket, objectName, bytes.NewReader(source), int64(len(source)), "text/x-python"); err != nil {
+			log.WithField("error", err.Error()).Error("Failed to upload Airflow DAG")
+			return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось сохранить DAG в хранилище", http.StatusBadGateway, err)
+		}
+		dag.Location = "s3://" + s.options.DAGsBucket + "/" + objectName
+	case s.options.DAGsPath != "":
+		filePath := filepath.Join(s.options.DAGsPath, dag.FileName)
+		if err := writeFileAtomic(filePath, []byte(dag.Source)); err != nil {
+			log.WithField("error", err.Error()).Error("Failed to write Airflow DAG")
+			return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось записать DAG в каталог Airflow", http.StatusBadGateway, err)
+		}
+		dag.Location = filePath
+	default:
+		return nil, models.NewServiceUnavailableError("Публикация DAG не настроена: задайте airflow.dags_path или airflow.dags_bucket")
+	}
+
+	log.WithField("location", dag.Location).Info("Airflow DAG published")
+	return dag, nil
+}
+
+// RunDAG снимает опубликованный DAG пайплайна пользователя userID с паузы и запускает его
+// с parameters в conf. Пустой userID — запуск от имени владельца.
+// Запуск сохраняется как выполнение пайплайна в статусе running с ID запуска DAG, равным
+// ID выполнения, до создания запуска, поэтому отслеживание возобновляется после остановки
+// экземпляра сервиса (см. Start); статусы выполнения, пайплайна и шагов обновляются в фоне по состоянию
+// запуска и задач Airflow. Ход выполнения доступен через /executions/:id/events
+func (s *AirflowService) RunDAG(ctx context.Context, pipelineID, userID string, parameters map[string]interface{}) (*models.PipelineExecution, error) {
+	if s.airflow == nil {
+		return nil, models.NewServiceUnavailableError("Запуск в Airflow не настроен: задайте airflow.base_url")
+	}
+
+	pipeline, err := s.pipelines.GetPipeline(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" && pipeline.UserID != userID {
+		return nil, errPipelineForbidden()
+	}
+	switch pipeline.Status {
+	case models.PipelineStatusDraft:
+		return nil, models.NewValidationError("Пайплайн не готов к выполнению: профиль анализа еще не построен", map[string]interface{}{
+			"pipeline_id": pipelineID, "status": pipeline.Status,
+		})
+	case models.PipelineStatusRunning:
+		return nil, models.NewConflictError("Пайплайн уже выполняется")
+	}
+	steps, err := AirflowTaskIDs(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	execution := newExecution(pipeline, userID, s.options.Instance.ID(), models.ExecutionStatusRunning, parameters)
+	execution.DAGRunID = execution.ID
+	run := &airflowRun{
+		pipeline:  pipeline,
+		execution: execution,
+		dagID:     AirflowDAGID(pipeline),
+		runID:     execution.ID,
+		steps:     steps,
+	}
+	// Пайплайн переводится в статус running тем же условным обновлением, что и при локальном
+	// выполнении, до запуска DAG: параллельный запуск в Airflow или в PipelineExecutor, в том
+	// числе на другом экземпляре сервиса, получит ConflictError
+	previous, err := startPipeline(ctx, s.pipelines, pipeline, execution.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.executions.SaveExecution(ctx, execution); err != nil {
+		pipeline.Status = previous
+		s.savePipeline(context.WithoutCancel(ctx), pipeline)
+		return nil, err
+	}
+
+	dagRun, err := s.trigger(ctx, run, parameters)
+	if err != nil {
+		s.finish(ctx, run, models.ExecutionStatusFailed, "Не удалось запустить DAG в Airflow: "+err.Error())
+		return nil, models.NewAppErrorWithCause(models.ErrorCodePipelineFailed, "Не удалось запустить DAG в Airflow", http.StatusBadGateway, err)
+	}
+	run.runID = dagRun.DAGRunID
+	execution.DAGRunID = run.runID
+	s.log(run, models.LogLevelInfo, "", "Запуск DAG "+run.dagID+" создан в Airflow: "+run.runID)
+	s.saveExecution(ctx, run)
+	s.broker.Publish(ExecutionTopic(execution.ID), models.EventTypeStatus, models.StatusEvent{Status: string(execution.Status)})
+	s.logger.WithField("pipeline_id", pipeline.ID).WithField("execution_id", execution.ID).
+		WithField("dag_run_id", run.runID).Info("Airflow DAG run triggered")
+
+	started := *execution
+	started.Logs = nil
+	s.mu.Lock()
+	trackCtx := s.ctx
+	s.mu.Unlock()
+	go s.track(trackCtx, run)
+	return &started, nil
+}
+
+// trigger снимает DAG с паузы, иначе запуск не начнется, и создает запуск DAG
+func (s *AirflowService) trigger(ctx context.Context, run *airflowRun, parameters map[string]interface{}) (*client.AirflowDAGRun, error) {
+	if err := s.airflow.SetPaused(ctx, run.dagID, false); err != nil {
+		return nil, err
+	}
+	return s.airflow.TriggerDAGRun(ctx, run.dagID, run.runID, parameters)
+}
+
+// track ожидает завершения запуска DAG, переносит состояния задач на шаги пайплайна
+// и сохраняет итоговый статус выполнения. Пока запуск выполняется, отмена выполнения
+// передается в Airflow (см. watchCancel). Выполнение завершается ошибкой без итогового
+// состояния, только если Airflow сообщает, что запуска нет. При остановке сервера выполнение
+// остается в статусе running, и его отслеживание возобновляется (см. Start)
+func (s *AirflowService) track(ctx context.Context, run *airflowRun) {
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	var cancelled atomic.Bool
+	go s.watchCancel(watchCtx, run, &cancelled)
+	dagRun, err := s.wait(ctx, run)
+	stopWatch()
+	if err != nil {
+		if ctx.Err() != nil {
+			s.logger.WithField("execution_id", run.execution.ID).WithField("dag_run_id", run.runID).
+				Info("Airflow DAG run tracking stopped")
+			return
+		}
+		s.finish(ctx, run, models.ExecutionStatusFailed, "Запуск DAG "+run.runID+" не найден в Airflow")
+		return
+	}
+
+	tasks, err := s.airflow.ListTaskInstances(ctx, run.dagID, run.runID)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).WithField("execution_id", run.execution.ID).Warn("Failed to list Airflow task instances")
+		s.log(run, models.LogLevelWarn, "", "Не удалось получить задачи запуска Airflow: "+err.Error())
+	}
+	failed := s.applyTasks(run, tasks, err == nil)
+
+	var failure string
+	status := dagRun.ExecutionStatus()
+	switch {
+	case dagRun.State == client.AirflowStateSuccess:
+	case cancelled.Load():
+		status, failure = models.ExecutionStatusCancelled, "выполнение отменено"
+	default:
+		failure = "Запуск DAG завершился со статусом " + dagRun.State
+		if len(failed) > 0 {
+			failure += ": ошибка в шагах " + strings.Join(failed, ", ")
+		}
+	}
+	s.finish(ctx, run, status, failure)
+}
+
+// wait ожидает завершения запуска DAG. Ошибки, кроме отсутствия запуска в Airflow, повторяются
+// через PollInterval до остановки сервера
+func (s *AirflowService) wait(ctx context.Context, run *airflowRun) (*client.AirflowDAGRun, error) {
+	for {
+		dagRun, err := s.airflow.WaitDAGRun(ctx, run.dagID, run.runID, s.options.PollInterval)
+		var apiErr *client.AirflowError
+		if err == nil || ctx.Err() != nil || (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound) {
+			return dagRun, err
+		}
+		s.logger.WithField("error", err.Error()).WithField("execution_id", run.execution.ID).
+			Warn("Failed to get Airflow DAG run state, retrying")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.options.PollInterval):
+		}
+	}
+}
+
+// watchCancel раз в PollInterval проверяет статус выполнения. Когда запрошена отмена (статус
+// cancelling), запуск DAG завершается в Airflow состоянием failed, и ожидание в track заканчивается
+func (s *AirflowService) watchCancel(ctx context.Context, run *airflowRun, cancelled *atomic.Bool) {
+	log := s.logger.WithField("execution_id", run.execution.ID).WithField("dag_run_id", run.runID)
+	ticker := time.NewTicker(s.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		execution, err := s.executions.GetExecution(ctx, run.execution.ID)
+		if err != nil {
+			log.WithField("error", err.Error()).Warn("Failed to check execution cancellation")
+			continue
+		}
+		if execution.Status != models.ExecutionStatusCancelling {
+			continue
+		}
+		if err := s.airflow.SetDAGRunState(ctx, run.dagID, run.runID, client.AirflowStateFailed); err != nil {
+			log.WithField("error", err.Error()).Warn("Failed to stop Airflow DAG run, retrying")
+			continue
+		}
+		cancelled.Store(true)
+		log.Info("Airflow DAG run stopped on cancellation")
+		return
+	}
+}
+
+// applyTasks переносит состояния задач Airflow на шаги пайплайна и возвращает
+// ID шагов, завершившихся ошибкой. Если список задач получен, шаги без задачи
+// в запуске считаются пропущенными
+func (s *AirflowService) applyTasks(run *airflowRun, tasks []client.AirflowTaskInstance, complete bool) []string {
+	seen := make(map[string]bool, len(tasks))
+	var failed []string
+	for i := range tasks {
+		task := &tasks[i]
+		stepID, ok := run.steps[task.TaskID]
+		if !ok {
+			continue
+		}
+		step := pipelineStep(run.pipeline, stepID)
+		if step == nil {
+			continue
+		}
+		seen[stepID] = true
+		step.Status = task.StepStatus()
+		step.StartedAt, step.CompletedAt = task.StartDate, task.EndDate
+		step.Error = ""
+		if step.Status == models.StepStatusFailed {
+			step.Error = "Задача Airflow " + task.TaskID + " завершилась со статусом " + task.State
+			failed = append(failed, stepID)
+			s.log(run, models.LogLevelError, stepID, step.Error)
+		}
+		s.stepEvent(run, *step)
+	}
+	if complete {
+		for i := range run.pipeline.Steps {
+			step := &run.pipeline.Steps[i]
+			if !seen[step.ID] && step.Status == models.StepStatusPending {
+				step.Status = models.StepStatusSkipped
+				s.stepEvent(run, *step)
+			}
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// finish сохраняет итоговый статус выполнения и пайплайна и закрывает поток событий.
+// Сохранение выполняется и после отмены ctx
+func (s *AirflowService) finish(ctx context.Context, run *airflowRun, status models.ExecutionStatus, failure string) {
+	ctx = context.WithoutCancel(ctx)
+	pipelineStatus := models.PipelineStatusCompleted
+	level, message := models.LogLevelInfo, "Выполнение завершено"
+	switch status {
+	case models.ExecutionStatusCompleted:
+	case models.ExecutionStatusCancelled:
+		pipelineStatus = models.PipelineStatusCancelled
+		level, message = models.LogLevelWarn, "Выполнение отменено"
+	default:
+		status, pipelineStatus = models.ExecutionStatusFailed, models.PipelineStatusFailed
+		level, message = models.LogLevelError, "Выполнение завершилось ошибкой: "+failure
+	}
+	s.log(run, level, "", message)
+
+	now := time.Now()
+	run.execution.Status = status
+	run.execution.Error = failure
+	run.execution.CompletedAt = &now
+	run.pipeline.Status = pipelineStatus
+	run.pipeline.UpdatedAt = now
+	s.savePipeline(ctx, run.pipeline)
+	s.saveExecution(ctx, run)
+
+	log := s.logger.WithField("pipeline_id", run.pipeline.ID).WithField("execution_id", run.execution.ID).WithField("dag_run_id", run.runID)
+	if failure != "" {
+		log.WithField("error", failure).Warn("Airflow DAG run failed")
+	} else {
+		log.Info("Airflow DAG run completed")
+	}
+	s.broker.Finish(ExecutionTopic(run.execution.ID), models.StatusEvent{Status: string(status), Error: failure})
+}
+
+// log добавляет запись в журнал выполнения и публикует ее
+func (s *AirflowService) log(run *airflowRun, level, stepID, message string) {
+	entry := models.ExecutionLog{Timestamp: time.Now(), Level: level, Message: message, StepID: stepID}
+	run.execution.Logs = append(run.execution.Logs, entry)
+	s.broker.Publish(ExecutionTopic(run.execution.ID), models.EventTypeLog, entry)
+}
+
+// stepEvent публикует смену статуса шага
+func (s *AirflowService) stepEvent(run *airflowRun, step models.PipelineStep) {
+	s.broker.Publish(ExecutionTopic(run.execution.ID), models.EventTypeStage, models.StageEvent{
+		Stage:   string(step.Status),
+		Message: step.Error,
+		StepID:  step.ID,
+	})
+}
+
+func (s *AirflowService) savePipeline(ctx context.Context, pipeline *models.Pipeline) {
+	if err := s.pipelines.UpdatePipelineState(ctx, pipeline); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("pipeline_id", pipeline.ID).Error("Failed to save pipeline state")
+	}
+}
+
+func (s *AirflowService) saveExecution(ctx context.Context, run *airflowRun) {
+	if err := s.executions.UpdateExecution(ctx, run.execution); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("execution_id", run.execution.ID).Error("Failed to save pipeline execution")
+	}
+}
+
+// pipelineStep возвращает шаг пайплайна по ID
+func pipelineStep(pipeline *models.Pipeline, id string) *models.PipelineStep {
+	for i := range pipeline.Steps {
+		if pipeline.Steps[i].ID == id {
+			return &pipeline.Steps[i]
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic записывает файл во временный файл того же каталога и переименовывает его.
+// Имя временного файла начинается с точки и не оканчивается на .py, поэтому Airflow его не читает
+func writeFileAtomic(filePath string, data []byte) error {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
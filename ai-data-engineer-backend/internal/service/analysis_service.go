@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// AnalysisOptions параметры фонового анализа
+type AnalysisOptions struct {
+	// Workers количество одновременно выполняемых анализов
+	Workers int
+	// QueueSize максимальное количество анализов, ожидающих выполнения
+	QueueSize int
+	// Timeout ограничение времени одного анализа
+	Timeout time.Duration
+	// Instance экземпляр сервиса, в очередь которого попадают анализы; по умолчанию единственный экземпляр
+	Instance *Instance
+}
+
+// AnalysisService выполняет анализ файлов в фоне: запрос сохраняется в статусе pending,
+// пул воркеров переводит его в running и сохраняет результат в AnalysisRepository.
+// Ход анализа публикуется в EventBroker
+type AnalysisService struct {
+	analyzer *DataAnalyzer
+	repo     repository.AnalysisRepository
+	fileRepo repository.FileRepository
+	broker   *EventBroker
+	options  AnalysisOptions
+	queue    chan *models.AnalysisResult
+	logger   logger.Logger
+}
+
+// NewAnalysisService создает новый AnalysisService. Воркеры запускаются методом Start
+func NewAnalysisService(
+	analyzer *DataAnalyzer,
+	repo repository.AnalysisRepository,
+	fileRepo repository.FileRepository,
+	broker *EventBroker,
+	options AnalysisOptions,
+	logger logger.Logger,
+) *AnalysisService {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 100
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Minute
+	}
+	if options.Instance == nil {
+		options.Instance = NewInstance(nil, InstanceOptions{}, logger)
+	}
+	return &AnalysisService{
+		analyzer: analyzer,
+		repo:     repo,
+		fileRepo: fileRepo,
+		broker:   broker,
+		options:  options,
+		queue:    make(chan *models.AnalysisResult, options.QueueSize),
+		logger:   logger,
+	}
+}
+
+// Start запускает воркеры до отмены ctx и возвращает в очередь незавершенные анализы
+// остановленных экземпляров сервиса. При запуске возвращаются и анализы с ID этого экземпляра:
+// их очередь потеряна вместе с предыдущим процессом. Анализы экземпляров с истекшей арендой
+// проверяются и дальше, раз в срок аренды (см. Instance)
+func (s *AnalysisService) Start(ctx context.Context) error {
+	recovered, err := s.recover(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < s.options.Workers; i++ {
+		go s.worker(ctx)
+	}
+
+	go func() {
+		s.requeue(ctx, recovered)
+		ticker := time.NewTicker(s.options.Instance.LeaseTTL())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				recovered, err := s.recover(ctx, false)
+				if err != nil {
+					s.logger.WithField("error", err.Error()).Error("Failed to recover unfinished analyses")
+				}
+				s.requeue(ctx, recovered)
+			}
+		}
+	}()
+	return nil
+}
+
+// recover передает этому экземпляру незавершенные анализы остановленных экземпляров и возвращает их.
+// Анализ передается условным обновлением владельца, поэтому один анализ не забирают два экземпляра
+func (s *AnalysisService) recover(ctx context.Context, restart bool) ([]*models.AnalysisResult, error) {
+	var recovered []*models.AnalysisResult
+	for _, status := range []models.AnalysisStatus{models.AnalysisStatusRunning, models.AnalysisStatusPending} {
+		analyses, err := s.repo.GetAnalysesByStatus(ctx, status)
+		if err != nil {
+			return recovered, err
+		}
+		for _, analysis := range analyses {
+			if analysis.Owner == s.options.Instance.ID() {
+				if !restart {
+					continue
+				}
+			} else if alive, err := s.options.Instance.Alive(ctx, analysis.Owner); err != nil {
+				return recovered, err
+			} else if alive {
+				continue
+			}
+
+			claimed, err := s.repo.ClaimAnalysis(ctx, analysis.ID, s.options.Instance.ID(), analysis.Owner)
+			if err != nil {
+				return recovered, err
+			}
+			if claimed {
+				analysis.Owner = s.options.Instance.ID()
+				analysis.Status = models.AnalysisStatusPending
+				recovered = append(recovered, analysis)
+			}
+		}
+	}
+	return recovered, nil
+}
+
+// requeue ставит переданные экземпляру анализы в очередь, дожидаясь свободного места
+func (s *AnalysisService) requeue(ctx context.Context, analyses []*models.AnalysisResult) {
+	if len(analyses) == 0 {
+		return
+	}
+	s.logger.WithField("count", len(analyses)).Info("Resuming unfinished analyses")
+	for _, analysis := range analyses {
+		select {
+		case s.queue <- analysis:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SubmitAnalysis сохраняет анализ в статусе pending и ставит его в очередь.
+// Анализировать можно только файлы пользователя req.UserID
+func (s *AnalysisService) SubmitAnalysis(ctx context.Context, req *models.AnalysisRequest) (*models.AnalysisResult, error) {
+	if req.FilePath != "" && req.FileID == "" {
+		filePath, ok := userFilePath(req.UserID, req.FilePath)
+		if !ok {
+			return nil, errAnalysisForbidden()
+		}
+		req.FilePath = filePath
+	}
+	// Несуществующий или чужой файл отклоняем сразу, не дожидаясь воркера
+	if req.FileID != "" {
+		metadata, err := s.fileRepo.GetFile(ctx, req.FileID)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.UserID != req.UserID {
+			return nil, models.NewAppError(models.ErrorCodeForbidden, "Нет доступа к файлу", http.StatusForbidden)
+		}
+		reused, err := s.reuseAnalysis(ctx, req, metadata)
+		if err != nil || reused != nil {
+			return reused, err
+		}
+	}
+
+	now := time.Now()
+	analysis := &models.AnalysisResult{
+		ID:        uuid.New().String(),
+		UserId:    req.UserID,
+		FileID:    req.FileID,
+		FilePath:  req.FilePath,
+		Status:    models.AnalysisStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Owner:     s.options.Instance.ID(),
+	}
+	if err := s.repo.SaveAnalysis(ctx, analysis); err != nil {
+		return nil, err
+	}
+	submitted := *analysis
+	s.publishStatus(analysis)
+
+	select {
+	case s.queue <- analysis:
+	default:
+		s.finish(ctx, analysis, models.AnalysisStatusFailed, "Очередь анализа переполнена")
+		return nil, models.NewServiceUnavailableError("Очередь анализа переполнена, повторите запрос позже")
+	}
+
+	s.logger.WithField("analysis_id", analysis.ID).WithField("user_id", analysis.UserId).Info("Analysis queued")
+	return &submitted, nil
+}
+
+// reuseAnalysis копирует завершенный анализ файла пользователя с тем же содержимым, чтобы повторно
+// выгруженный файл не анализировался заново. Возвращает nil без ошибки, если анализа нет
+func (s *AnalysisService) reuseAnalysis(ctx context.Context, req *models.AnalysisRequest, file *models.FileMetadata) (*models.AnalysisResult, error) {
+	if file.Checksum == "" {
+		return nil, nil
+	}
+	files, err := s.fileRepo.GetFilesByChecksum(ctx, file.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	fileIDs := make([]string, 0, len(files))
+	for _, f := range files {
+		if f.UserID == req.UserID {
+			fileIDs = append(fileIDs, f.ID)
+		}
+	}
+	previous, err := s.repo.GetCompletedAnalysisByFiles(ctx, req.UserID, fileIDs)
+	if err != nil || previous == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	analysis := &models.AnalysisResult{
+		ID:             uuid.New().String(),
+		UserId:         req.UserID,
+		FileID:         file.ID,
+		FilePath:       file.Path,
+		Status:         models.AnalysisStatusCompleted,
+		Profile:        previous.Profile,
+		AnalysisResult: previous.AnalysisResult,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.SaveAnalysis(ctx, analysis); err != nil {
+		return nil, err
+	}
+	s.publishStatus(analysis)
+
+	s.logger.WithField("analysis_id", analysis.ID).WithField("source_analysis_id", previous.ID).
+		WithField("checksum", file.Checksum).Info("Analysis reused for identical content")
+	return analysis, nil
+}
+
+// GetAnalysis возвращает текущее состояние анализа пользователя
+func (s *AnalysisService) GetAnalysis(ctx context.Context, userID, id string) (*models.AnalysisResult, error) {
+	analysis, err := s.repo.GetAnalysis(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.UserId != userID {
+		return nil, errAnalysisForbidden()
+	}
+	return analysis, nil
+}
+
+func (s *AnalysisService) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case analysis := <-s.queue:
+			s.run(ctx, analysis)
+		}
+	}
+}
+
+// run выполняет анализ и сохраняет результат
+func (s *AnalysisService) run(ctx context.Context, analysis *models.AnalysisResult) {
+	log := s.logger.WithField("analysis_id", analysis.ID)
+	analysis.Status = models.AnalysisStatusRunning
+	analysis.UpdatedAt = time.Now()
+	if err := s.repo.UpdateAnalysis(ctx, analysis); err != nil {
+		log.WithField("error", err.Error()).Error("Failed to mark analysis as running")
+		return
+	}
+	s.publishStatus(analysis)
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.options.Timeout)
+	topic := AnalysisTopic(analysis.ID)
+	result, err := s.analyzer.AnalyzeFileWithProgress(jobCtx, &models.AnalysisRequest{
+		FileID:   analysis.FileID,
+		UserID:   analysis.UserId,
+		FilePath: analysis.FilePath,
+	}, func(eventType models.EventType, data interface{}) {
+		s.broker.Publish(topic, eventType, data)
+	})
+	cancel()
+
+	// Сервер останавливается: анализ будет выполнен заново после запуска
+	if ctx.Err() != nil {
+		s.finish(ctx, analysis, models.AnalysisStatusPending, "")
+		return
+	}
+
+	analysis.Profile = result.Profile
+	analysis.AnalysisResult = result.AnalysisResult
+	if result.FilePath != "" {
+		analysis.FilePath = result.FilePath
+	}
+	if err != nil {
+		message := err.Error()
+		if appErr, ok := models.IsAppError(err); ok {
+			message = appErr.Message
+		}
+		log.WithField("error", err.Error()).Warn("Analysis failed")
+		s.finish(ctx, analysis, models.AnalysisStatusFailed, message)
+		return
+	}
+	log.Info("Analysis completed")
+	s.finish(ctx, analysis, models.AnalysisStatusCompleted, "")
+}
+
+// finish сохраняет итоговый статус анализа, в том числе после отмены ctx
+func (s *AnalysisService) finish(ctx context.Context, analysis *models.AnalysisResult, status models.AnalysisStatus, message string) {
+	analysis.Status = status
+	analysis.Error = message
+	analysis.UpdatedAt = time.Now()
+	if err := s.repo.UpdateAnalysis(context.WithoutCancel(ctx), analysis); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("analysis_id", analysis.ID).Error("Failed to save analysis result")
+	}
+	s.publishStatus(analysis)
+}
+
+// userFilePath нормализует путь файла и проверяет, что он лежит в каталоге users/<userID>/.
+// Путь с сегментом ".." отклоняется до нормализации, поэтому users/<userID>/../<другой>/...
+// не проходит проверку префикса
+func userFilePath(userID, filePath string) (string, bool) {
+	if userID == "" || strings.Contains(userID, "/") || userID == "." || userID == ".." {
+		return "", false
+	}
+	for _, segment := range strings.Split(filePath, "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+	cleaned := path.Clean(filePath)
+	return cleaned, strings.HasPrefix(cleaned, "users/"+userID+"/")
+}
+
+func errAnalysisForbidden() error {
+	return models.NewAppError(models.ErrorCodeForbidden, "Нет доступа к анализу", http.StatusForbidden)
+}
+
+// publishStatus публикует статус анализа; для завершенного анализа поток событий закрывается
+func (s *AnalysisService) publishStatus(analysis *models.AnalysisResult) {
+	event := models.StatusEvent{Status: string(analysis.Status), Error: analysis.Error}
+	switch analysis.Status {
+	case models.AnalysisStatusCompleted, models.AnalysisStatusFailed:
+		s.broker.Finish(AnalysisTopic(analysis.ID), event)
+	default:
+		s.broker.Publish(AnalysisTopic(analysis.ID), models.EventTypeStatus, event)
+	}
+}
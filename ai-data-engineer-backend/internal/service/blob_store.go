@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// BlobStore хранит содержимое файлов по ключу SHA-256: одинаковые файлы
+// хранятся в одном объекте, а FileMetadata ссылаются на него. Счетчик ссылок и решение
+// об удалении объекта принимаются в BlobRepository, поэтому несколько экземпляров сервиса
+// работают с одними blob без общей блокировки в памяти
+type BlobStore struct {
+	storage StorageClient
+	repo    repository.BlobRepository
+	logger  logger.Logger
+}
+
+// NewBlobStore создает BlobStore
+func NewBlobStore(storage StorageClient, repo repository.BlobRepository, logger logger.Logger) *BlobStore {
+	return &BlobStore{
+		storage: storage,
+		repo:    repo,
+		logger:  logger,
+	}
+}
+
+// blobPath возвращает ключ объекта для содержимого с указанной суммой
+func blobPath(checksum string) string {
+	return fmt.Sprintf("blobs/sha256/%s/%s", checksum[:2], checksum)
+}
+
+// Promote переносит загруженный во временный объект файл в blob и удаляет временный объект.
+// Если blob с таким содержимым уже есть, увеличивается только счетчик ссылок.
+// При ошибке ссылка не удерживается, а временный объект остается для повтора.
+// Параллельные Promote одного содержимого могут оба записать объект: содержимое у них одинаковое
+func (b *BlobStore) Promote(ctx context.Context, bucket, tempPath, checksum string, size int64) (*models.Blob, error) {
+	blob, created, err := b.Acquire(ctx, bucket, checksum, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Store(ctx, bucket, tempPath, blob, created); err != nil {
+		if _, releaseErr := b.repo.ReleaseBlob(ctx, checksum, b.deleteObject(ctx)); releaseErr != nil {
+			b.logger.WithField("error", releaseErr.Error()).WithField("checksum", checksum).Warn("Failed to release blob")
+		}
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Acquire берет ссылку на blob содержимого checksum, создавая запись, если ее нет. Объект
+// записывается методом Store; created сообщает, что blob создан этим вызовом
+func (b *BlobStore) Acquire(ctx context.Context, bucket, checksum string, size int64) (*models.Blob, bool, error) {
+	blob, created, err := b.repo.AcquireBlob(ctx, &models.Blob{
+		Checksum: checksum,
+		Bucket:   bucket,
+		Path:     blobPath(checksum),
+		Size:     size,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	b.logger.WithField("checksum", checksum).WithField("ref_count", blob.RefCount).WithField("deduplicated", !created).Info("Blob acquired")
+	return blob, created, nil
+}
+
+// Store копирует временный объект в объект blob, если его нет, и удаляет временный объект.
+// Объект существующего blob мог не записаться, если процесс упал между Acquire и Store.
+// При ошибке временный объект остается для повтора, а ссылка на blob не освобождается
+func (b *BlobStore) Store(ctx context.Context, bucket, tempPath string, blob *models.Blob, created bool) error {
+	missing := created
+	if !created {
+		exists, err := b.storage.FileExists(ctx, blob.Bucket, blob.Path)
+		if err != nil {
+			return models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось проверить файл в хранилище", http.StatusBadGateway, err)
+		}
+		missing = !exists
+	}
+	if missing {
+		if err := b.storage.CopyFile(ctx, bucket, tempPath, blob.Path); err != nil {
+			b.logger.WithField("error", err.Error()).WithField("checksum", blob.Checksum).Error("Failed to store blob")
+			return models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось сохранить файл в хранилище", http.StatusBadGateway, err)
+		}
+	}
+	b.removeTemp(ctx, bucket, tempPath)
+	return nil
+}
+
+// Reuse добавляет ссылку на существующий blob без повторной загрузки содержимого.
+// Ссылка берется до проверки объекта: blob, удаляемый параллельно, уже не будет найден
+func (b *BlobStore) Reuse(ctx context.Context, checksum string) (*models.Blob, error) {
+	blob, err := b.repo.ReferenceBlob(ctx, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if exists, err := b.storage.FileExists(ctx, blob.Bucket, blob.Path); err != nil || !exists {
+		if _, releaseErr := b.repo.ReleaseBlob(ctx, checksum, b.deleteObject(ctx)); releaseErr != nil {
+			b.logger.WithField("error", releaseErr.Error()).WithField("checksum", checksum).Warn("Failed to release blob")
+		}
+		return nil, models.NewBlobNotFoundError(checksum)
+	}
+	return blob, nil
+}
+
+// Release освобождает ссылку файла на содержимое. Объект удаляется вместе с последней ссылкой;
+// файлы, загруженные до появления blob, удаляются напрямую
+func (b *BlobStore) Release(ctx context.Context, file *models.FileMetadata) error {
+	if file.Checksum == "" || file.Path != blobPath(file.Checksum) {
+		return b.storage.DeleteFile(ctx, file.Bucket, file.Path)
+	}
+	_, err := b.repo.ReleaseBlob(ctx, file.Checksum, b.deleteObject(ctx))
+	return err
+}
+
+// deleteObject удаляет объект blob, освобожденного последней ссылкой. Вызывается
+// BlobRepository до удаления записи: при ошибке ссылка остается
+func (b *BlobStore) deleteObject(ctx context.Context) func(*models.Blob) error {
+	return func(blob *models.Blob) error {
+		b.logger.WithField("checksum", blob.Checksum).Info("Last reference released, deleting blob")
+		return b.storage.DeleteFile(ctx, blob.Bucket, blob.Path)
+	}
+}
+
+// removeTemp удаляет временный объект; ошибка только логируется
+func (b *BlobStore) removeTemp(ctx context.Context, bucket, tempPath string) {
+	if err := b.storage.DeleteFile(ctx, bucket, tempPath); err != nil {
+		b.logger.WithField("error", err.Error()).WithField("object", tempPath).Warn("Failed to remove temporary object")
+	}
+}
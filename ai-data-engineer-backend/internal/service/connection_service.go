@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+	"ai-data-engineer-backend/pkg/secrets"
+
+	"github.com/google/uuid"
+)
+
+// ConnectionService управляет сохраненными подключениями к целевым базам.
+// Пароль шифруется при сохранении и расшифровывается только для подключения к базе
+type ConnectionService struct {
+	repo   repository.ConnectionRepository
+	cipher *secrets.Cipher
+	health *HealthService
+	logger logger.Logger
+}
+
+// NewConnectionService создает новый ConnectionService
+func NewConnectionService(repo repository.ConnectionRepository, cipher *secrets.Cipher, health *HealthService, logger logger.Logger) *ConnectionService {
+	return &ConnectionService{
+		repo:   repo,
+		cipher: cipher,
+		health: health,
+		logger: logger,
+	}
+}
+
+// CreateConnection сохраняет подключение пользователя с зашифрованным паролем
+func (s *ConnectionService) CreateConnection(ctx context.Context, userID string, req *models.ConnectionRequest) (*models.ConnectionProfile, error) {
+	now := time.Now()
+	profile := &models.ConnectionProfile{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.apply(profile, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveConnection(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.logger.WithField("connection_id", profile.ID).WithField("type", profile.Type).Info("Connection saved")
+	return profile, nil
+}
+
+// GetConnection возвращает подключение пользователя по ID
+func (s *ConnectionService) GetConnection(ctx context.Context, userID, id string) (*models.ConnectionProfile, error) {
+	return s.ownedConnection(ctx, userID, id)
+}
+
+// ListConnections возвращает подключения пользователя
+func (s *ConnectionService) ListConnections(ctx context.Context, userID string, limit, offset int) ([]*models.ConnectionProfile, error) {
+	return s.repo.GetConnectionsByUser(ctx, userID, limit, offset)
+}
+
+// UpdateConnection изменяет подключение. Пустой пароль в запросе сохраняет текущий
+func (s *ConnectionService) UpdateConnection(ctx context.Context, userID, id string, req *models.ConnectionRequest) (*models.ConnectionProfile, error) {
+	profile, err := s.ownedConnection(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(profile, req); err != nil {
+		return nil, err
+	}
+	profile.UpdatedAt = time.Now()
+	if err := s.repo.UpdateConnection(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.logger.WithField("connection_id", id).Info("Connection updated")
+	return profile, nil
+}
+
+// DeleteConnection удаляет подключение
+func (s *ConnectionService) DeleteConnection(ctx context.Context, userID, id string) error {
+	if _, err := s.ownedConnection(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteConnection(ctx, id); err != nil {
+		return err
+	}
+	s.logger.WithField("connection_id", id).Info("Connection deleted")
+	return nil
+}
+
+// TestConnection проверяет сохраненное подключение так же, как POST /databases/test
+func (s *ConnectionService) TestConnection(ctx context.Context, userID, id string) (*models.DatabaseTestResponse, error) {
+	_, params, err := s.ResolveConnection(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.health.TestDatabaseConnection(ctx, params)
+}
+
+// ResolveConnection возвращает профиль и параметры подключения пользователя с расшифрованным паролем
+func (s *ConnectionService) ResolveConnection(ctx context.Context, userID, id string) (*models.ConnectionProfile, *models.DatabaseTestRequest, error) {
+	profile, err := s.ownedConnection(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var password []byte
+	if len(profile.Secret) > 0 {
+		password, err = s.cipher.Decrypt(profile.Secret, []byte(profile.ID))
+		if err != nil {
+			s.logger.WithField("connection_id", id).Error("Failed to decrypt connection secret")
+			return nil, nil, models.NewInternalError("Не удалось расшифровать пароль подключения", err)
+		}
+	}
+	return profile, profile.Params(string(password)), nil
+}
+
+// ownedConnection возвращает подключение, если оно принадлежит пользователю.
+// Чужое подключение не найдено: его существование не раскрывается
+func (s *ConnectionService) ownedConnection(ctx context.Context, userID, id string) (*models.ConnectionProfile, error) {
+	profile, err := s.repo.GetConnection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if profile.UserID != userID {
+		return nil, models.NewConnectionNotFoundError(id)
+	}
+	return profile, nil
+}
+
+// apply переносит параметры запроса в профиль и шифрует новый пароль
+func (s *ConnectionService) apply(profile *models.ConnectionProfile, req *models.ConnectionRequest) error {
+	profile.Name = req.Name
+	profile.Type = req.Type
+	profile.Host = req.Host
+	profile.Port = req.Port
+	profile.User = req.User
+	profile.DBName = req.DBName
+	profile.SSLMode = req.SSLMode
+	profile.Secure = req.Secure
+	if req.Password != "" {
+		secret, err := s.cipher.Encrypt([]byte(req.Password), []byte(profile.ID))
+		if err != nil {
+			return models.NewInternalError("Не удалось зашифровать пароль подключения", err)
+		}
+		profile.Secret = secret
+	}
+	profile.HasPassword = len(profile.Secret) > 0
+	return nil
+}
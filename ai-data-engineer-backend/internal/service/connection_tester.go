@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultConnectionTestTimeout время на проверку подключения, если запрос его не задает
+	defaultConnectionTestTimeout = 10 * time.Second
+	// probeTablePrefix префикс таблицы, которой проверяются права на создание и вставку
+	probeTablePrefix = "aien_probe_"
+)
+
+// connectionProbe результат проверки подключения
+type connectionProbe struct {
+	connect     time.Duration
+	latency     time.Duration
+	version     string
+	ssl         bool
+	createTable bool
+	insert      bool
+	// permissionError ошибка первой не пройденной проверки прав
+	permissionError string
+}
+
+// details возвращает результат проверки для DatabaseTestResponse.Details
+func (p *connectionProbe) details() map[string]interface{} {
+	details := map[string]interface{}{
+		"connect_ms":     p.connect.Milliseconds(),
+		"latency_ms":     p.latency.Milliseconds(),
+		"server_version": p.version,
+		"ssl":            p.ssl,
+		"permissions": map[string]bool{
+			"create_table": p.createTable,
+			"insert":       p.insert,
+		},
+	}
+	if p.permissionError != "" {
+		details["permissions_error"] = p.permissionError
+	}
+	return details
+}
+
+// probePostgres подключается к PostgreSQL, измеряет задержку, читает версию и статус SSL
+// соединения и проверяет права на создание таблицы и вставку
+func probePostgres(ctx context.Context, req *models.DatabaseTestRequest) (*connectionProbe, error) {
+	dsn := req.PostgresDSN()
+	if deadline, ok := ctx.Deadline(); ok {
+		dsn += fmt.Sprintf(" connect_timeout='%d'", max(int(time.Until(deadline).Seconds()), 1))
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// Все проверки выполняются в одном соединении: статус SSL относится к нему
+	db.SetMaxOpenConns(1)
+
+	probe := &connectionProbe{}
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	probe.connect = time.Since(start)
+
+	start = time.Now()
+	if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&probe.version); err != nil {
+		return nil, err
+	}
+	probe.latency = time.Since(start)
+
+	err = db.QueryRowContext(ctx,
+		`SELECT COALESCE((SELECT ssl FROM pg_stat_ssl WHERE pid = pg_backend_pid()), false)`).Scan(&probe.ssl)
+	if err != nil {
+		return nil, err
+	}
+
+	probe.createTable, probe.insert, probe.permissionError = probePostgresPermissions(ctx, db)
+	return probe, nil
+}
+
+// probePostgresPermissions создает таблицу и вставляет строку в транзакции,
+// которая всегда откатывается: в базе не остается следов проверки
+func probePostgresPermissions(ctx context.Context, db *sql.DB) (bool, bool, string) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, false, err.Error()
+	}
+	defer tx.Rollback()
+
+	table := pq.QuoteIdentifier(probeTableName())
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE "+table+" (id INTEGER)"); err != nil {
+		return false, false, err.Error()
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+table+" (id) VALUES (1)"); err != nil {
+		return true, false, err.Error()
+	}
+	return true, true, ""
+}
+
+// probeClickHouse подключается к HTTP интерфейсу ClickHouse, измеряет задержку,
+// читает версию и проверяет права на создание таблицы и вставку. SSL — это HTTPS (req.Secure)
+func probeClickHouse(ctx context.Context, req *models.DatabaseTestRequest, logger logger.Logger) (*connectionProbe, error) {
+	clickhouseClient := client.NewClickHouseClient(client.ClickHouseOptions{
+		URL:      req.ClickHouseURL(),
+		User:     req.User,
+		Password: req.Password,
+		Database: req.DBName,
+	}, logger)
+
+	probe := &connectionProbe{ssl: req.Secure}
+	start := time.Now()
+	if err := clickhouseClient.Ping(ctx); err != nil {
+		return nil, err
+	}
+	probe.connect = time.Since(start)
+
+	// В отличие от /ping запрос проверяет учетные данные и существование базы
+	start = time.Now()
+	rows, err := clickhouseClient.Query(ctx, "SELECT version() AS version", nil)
+	if err != nil {
+		return nil, err
+	}
+	probe.latency = time.Since(start)
+	if len(rows) > 0 {
+		probe.version = fmt.Sprint(rows[0]["version"])
+	}
+
+	probe.createTable, probe.insert, probe.permissionError = probeClickHousePermissions(ctx, clickhouseClient)
+	return probe, nil
+}
+
+// probeClickHousePermissions создает таблицу Memory и вставляет строку. DDL в ClickHouse
+// не транзакционный, поэтому таблица удаляется после проверки
+func probeClickHousePermissions(ctx context.Context, clickhouseClient client.ClickHouseClient) (bool, bool, string) {
+	table := "`" + probeTableName() + "`"
+	if err := clickhouseClient.Exec(ctx, "CREATE TABLE "+table+" (id UInt8) ENGINE = Memory", nil, nil); err != nil {
+		return false, false, err.Error()
+	}
+	defer func() {
+		dropCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		clickhouseClient.Exec(dropCtx, "DROP TABLE IF EXISTS "+table, nil, nil)
+	}()
+	if err := clickhouseClient.Exec(ctx, "INSERT INTO "+table+" VALUES (1)", nil, nil); err != nil {
+		return true, false, err.Error()
+	}
+	return true, true, ""
+}
+
+func probeTableName() string {
+	return probeTablePrefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+}
+
+// connectionFailed оборачивает ошибку подключения в AppError с причиной:
+// timeout, authentication_failed, database_not_found или unreachable
+func connectionFailed(ctx context.Context, req *models.DatabaseTestRequest, err error) *models.AppError {
+	reason, httpCode := "unreachable", http.StatusBadGateway
+	var netErr net.Error
+	var pqErr *pq.Error
+	var clickhouseErr *client.ClickHouseError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		reason, httpCode = "timeout", http.StatusGatewayTimeout
+	case errors.As(err, &pqErr) && (pqErr.Code == "28P01" || pqErr.Code == "28000"),
+		errors.As(err, &clickhouseErr) && (clickhouseErr.Code == "516" || clickhouseErr.StatusCode == http.StatusUnauthorized):
+		reason = "authentication_failed"
+	case errors.As(err, &pqErr) && pqErr.Code == "3D000",
+		errors.As(err, &clickhouseErr) && clickhouseErr.Code == "81":
+		reason = "database_not_found"
+	}
+
+	appErr := models.NewAppErrorWithCause(models.ErrorCodeConnectionFailed, "Не удалось подключиться к базе данных", httpCode, err)
+	appErr.Details = map[string]interface{}{
+		"type":   req.Type,
+		"host":   req.Host,
+		"port":   req.Port,
+		"dbname": req.DBName,
+		"reason": reason,
+		"error":  err.Error(),
+	}
+	return appErr
+}
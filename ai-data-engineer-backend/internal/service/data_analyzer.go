@@ -2,42 +2,161 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
 
 	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
 	"ai-data-engineer-backend/pkg/client"
 	"ai-data-engineer-backend/pkg/logger"
 )
 
 // DataAnalyzer реализация DataAnalyzer
 type DataAnalyzer struct {
-	logger    logger.Logger
-	llmClient client.LLMClient
+	logger        logger.Logger
+	llmClient     client.LLMClient
+	storageClient StorageClient
+	fileRepo      repository.FileRepository
+	profiler      *DataProfiler
 }
 
 // NewDataAnalyzer создает новый анализатор данных
-func NewDataAnalyzer(logger logger.Logger, llmClient client.LLMClient) *DataAnalyzer {
+func NewDataAnalyzer(logger logger.Logger, llmClient client.LLMClient, storageClient StorageClient, fileRepo repository.FileRepository) *DataAnalyzer {
 	return &DataAnalyzer{
-		logger:    logger,
-		llmClient: llmClient,
+		logger:        logger,
+		llmClient:     llmClient,
+		storageClient: storageClient,
+		fileRepo:      fileRepo,
+		profiler:      NewDataProfiler(storageClient, logger),
 	}
 }
 
-// отправка запроса на анализ файла в LLM
-func (d *DataAnalyzer) AnalyzeFile(ctx context.Context, userID string) (models.AnalysisResult, error) {
-	d.logger.WithField("user_id", userID).Info("DataAnalyzer.AnalyzeFile: Starting")
+// ProgressFunc получает события хода анализа: этапы и промежуточные результаты
+type ProgressFunc func(eventType models.EventType, data interface{})
 
-	resp, err := d.llmClient.AnalyzeFile(ctx, userID)
+// AnalyzeFile строит профиль файла и отправляет его на анализ в LLM.
+// Если LLM недоступен, возвращается результат только с профилем данных
+func (d *DataAnalyzer) AnalyzeFile(ctx context.Context, req *models.AnalysisRequest) (models.AnalysisResult, error) {
+	return d.AnalyzeFileWithProgress(ctx, req, nil)
+}
+
+// AnalyzeFileWithProgress выполняет AnalyzeFile, сообщая о ходе анализа через progress
+func (d *DataAnalyzer) AnalyzeFileWithProgress(ctx context.Context, req *models.AnalysisRequest, progress ProgressFunc) (models.AnalysisResult, error) {
+	log := d.logger.WithField("user_id", req.UserID)
+	log.Info("DataAnalyzer.AnalyzeFile: Starting")
+	if progress == nil {
+		progress = func(models.EventType, interface{}) {}
+	}
+
+	progress(models.EventTypeStage, models.StageEvent{Stage: "resolve", Message: "Поиск файла"})
+	metadata, bucket, objectName, err := d.resolveObject(ctx, req)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to resolve file for analysis")
+		return models.AnalysisResult{UserId: req.UserID, FileID: req.FileID, Status: models.AnalysisStatusFailed}, err
+	}
+
+	// Файлы, загруженные через FileService, проходят статусы processing → processed/error
+	if metadata == nil {
+		return d.analyze(ctx, req, bucket, objectName, path.Base(objectName), progress)
+	}
+	d.setFileStatus(ctx, metadata, models.FileStatusProcessing)
+	result, err := d.analyze(ctx, req, bucket, objectName, metadata.Filename, progress)
+	if err != nil {
+		d.setFileStatus(ctx, metadata, models.FileStatusError)
+	} else {
+		d.setFileStatus(ctx, metadata, models.FileStatusProcessed)
+	}
+	return result, err
+}
+
+// analyze профилирует объект и запрашивает анализ у LLM. filename — исходное имя файла
+func (d *DataAnalyzer) analyze(ctx context.Context, req *models.AnalysisRequest, bucket, objectName, filename string, progress ProgressFunc) (models.AnalysisResult, error) {
+	log := d.logger.WithField("user_id", req.UserID).WithField("object", objectName)
+	result := models.AnalysisResult{
+		UserId:    req.UserID,
+		FileID:    req.FileID,
+		FilePath:  objectName,
+		Status:    models.AnalysisStatusFailed,
+		CreatedAt: time.Now(),
+	}
+
+	progress(models.EventTypeStage, models.StageEvent{Stage: "profile", Message: "Профилирование данных"})
+	profile, profileErr := d.profiler.ProfileFile(ctx, bucket, objectName, filename)
+	if profileErr != nil {
+		log.WithField("error", profileErr.Error()).Warn("Native profiling failed, falling back to LLM only")
+	} else {
+		progress(models.EventTypeProfile, profile)
+	}
+	result.Profile = profile
+
+	progress(models.EventTypeStage, models.StageEvent{Stage: "llm", Message: "Анализ в LLM"})
+
+	resp, err := d.llmClient.AnalyzeFile(ctx, &models.LLMRequest{
+		UserID:      req.UserID,
+		FileName:    filename,
+		DataProfile: profile,
+	})
 	if err != nil {
-		d.logger.WithField("error", err.Error()).Error("Failed to analyze file")
-		return models.AnalysisResult{
-			UserId:         userID,
-			AnalysisResult: resp,
-			Status:         "failed",
-		}, err
-	}
-	return models.AnalysisResult{
-		UserId:         userID,
-		AnalysisResult: resp,
-		Status:         "completed",
-	}, nil
+		if profile == nil {
+			log.WithField("error", err.Error()).Error("Failed to analyze file")
+			return result, err
+		}
+		log.WithField("error", err.Error()).Warn("LLM analysis failed, returning native profile only")
+	}
+	result.AnalysisResult = resp
+	result.Status = models.AnalysisStatusCompleted
+	return result, nil
+}
+
+// resolveObject определяет объект для анализа: файл по ID, явный путь
+// или последний загруженный файл пользователя. Для явного пути метаданные не возвращаются
+func (d *DataAnalyzer) resolveObject(ctx context.Context, req *models.AnalysisRequest) (*models.FileMetadata, string, string, error) {
+	if req.FileID != "" {
+		metadata, err := d.fileRepo.GetFile(ctx, req.FileID)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if req.UserID == "" {
+			req.UserID = metadata.UserID
+		}
+		return metadata, metadata.Bucket, metadata.Path, nil
+	}
+	if req.FilePath != "" {
+		return nil, defaultBucket, req.FilePath, nil
+	}
+
+	// Последний зарегистрированный файл пользователя
+	files, err := d.fileRepo.GetFilesByUser(ctx, req.UserID, 1, 0)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if len(files) > 0 {
+		return files[0], files[0].Bucket, files[0].Path, nil
+	}
+
+	// Объекты, загруженные в хранилище в обход FileService
+	prefix := fmt.Sprintf("users/%s/files/", req.UserID)
+	objects, err := d.storageClient.ListFiles(ctx, defaultBucket, prefix)
+	if err != nil {
+		return nil, "", "", models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось получить список файлов", http.StatusBadGateway, err)
+	}
+	if len(objects) == 0 {
+		return nil, "", "", models.NewFileNotFoundError(prefix)
+	}
+
+	// Имена файлов начинаются с временной метки, поэтому последний по порядку — самый свежий
+	sort.Strings(objects)
+	return nil, defaultBucket, objects[len(objects)-1], nil
+}
+
+// setFileStatus обновляет статус файла; ошибка обновления не прерывает анализ
+func (d *DataAnalyzer) setFileStatus(ctx context.Context, metadata *models.FileMetadata, status models.FileStatus) {
+	metadata.Status = status
+	metadata.UpdatedAt = time.Now()
+	if err := d.fileRepo.UpdateFile(ctx, metadata); err != nil {
+		d.logger.WithField("error", err.Error()).WithField("file_id", metadata.ID).Warn("Failed to update file status")
+	}
 }
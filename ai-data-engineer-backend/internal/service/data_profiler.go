@@ -0,0 +1,633 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const (
+	// profileSniffSize объем начала файла для определения кодировки и разделителя
+	profileSniffSize = 64 * 1024
+	// profileHeaderProbeRows количество строк для определения заголовка
+	profileHeaderProbeRows = 100
+	// profileDistinctLimit предел подсчета уникальных значений в колонке
+	profileDistinctLimit = 10000
+	// profileCtxCheckEvery как часто проверять отмену контекста (в строках)
+	profileCtxCheckEvery = 10000
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+
+	candidateDelimiters = []rune{',', ';', '\t', '|'}
+
+	nullTokens = map[string]struct{}{
+		"": {}, "null": {}, "none": {}, "nan": {}, "n/a": {}, "na": {}, "nil": {}, `\n`: {},
+	}
+
+	boolTokens = map[string]struct{}{
+		"true": {}, "false": {}, "t": {}, "f": {}, "yes": {}, "no": {}, "y": {}, "n": {},
+	}
+
+	dateLayouts = []string{
+		"2006-01-02",
+		"02.01.2006",
+		"2006/01/02",
+		"01/02/2006",
+	}
+
+	timestampLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04",
+		"02.01.2006 15:04:05",
+		"02.01.2006 15:04",
+	}
+)
+
+// ProfileOptions параметры профилирования
+type ProfileOptions struct {
+	// SampleRows количество строк, попадающих в DataProfile.SampleData
+	SampleRows int
+}
+
+// DataProfiler строит профиль данных файла из хранилища без обращения к LLM
+type DataProfiler struct {
+	storageClient StorageClient
+	logger        logger.Logger
+	options       ProfileOptions
+}
+
+// NewDataProfiler создает новый DataProfiler
+func NewDataProfiler(storageClient StorageClient, logger logger.Logger) *DataProfiler {
+	return &DataProfiler{
+		storageClient: storageClient,
+		logger:        logger,
+		options:       ProfileOptions{SampleRows: 10},
+	}
+}
+
+// ProfileFile потоково читает файл из хранилища и строит его профиль.
+// Формат определяется по расширению filename (ключи blob расширения не содержат);
+// если имя не передано, используется имя объекта
+func (p *DataProfiler) ProfileFile(ctx context.Context, bucket, objectName, filename string) (*models.DataProfile, error) {
+	log := p.logger.WithField("bucket", bucket).WithField("object", objectName)
+	log.Info("DataProfiler.ProfileFile: Starting")
+
+	if filename == "" {
+		filename = objectName
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".csv" && ext != ".tsv" && ext != ".txt" {
+		return nil, models.NewAppError(models.ErrorCodeUnsupportedType,
+			fmt.Sprintf("Профилирование файлов %s не поддерживается", ext), http.StatusBadRequest)
+	}
+
+	reader, err := p.storageClient.DownloadFile(ctx, bucket, objectName)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to download file for profiling")
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось прочитать файл", http.StatusBadGateway, err)
+	}
+	defer reader.Close()
+
+	profile, err := ProfileCSV(ctx, reader, p.options)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to profile file")
+		return nil, err
+	}
+
+	log.WithField("rows", profile.TotalRows).WithField("columns", len(profile.Fields)).Info("DataProfiler.ProfileFile: Ending")
+	return profile, nil
+}
+
+// ProfileCSV строит профиль CSV данных за один проход по потоку
+func ProfileCSV(ctx context.Context, r io.Reader, opts ProfileOptions) (*models.DataProfile, error) {
+	counter := &countingReader{r: r}
+	br := bufio.NewReaderSize(counter, profileSniffSize)
+
+	head, err := br.Peek(profileSniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read file head: %w", err)
+	}
+	atEOF := err == io.EOF
+
+	encoding, bomSize, err := detectEncoding(head, atEOF)
+	if err != nil {
+		return nil, err
+	}
+	if bomSize > 0 {
+		if _, err := br.Discard(bomSize); err != nil {
+			return nil, fmt.Errorf("failed to skip BOM: %w", err)
+		}
+		head = head[bomSize:]
+	}
+
+	delimiter := detectDelimiter(head, atEOF)
+
+	csvReader := csv.NewReader(br)
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	// Буферизуем первые строки, чтобы определить наличие заголовка
+	var probe [][]string
+	for len(probe) < profileHeaderProbeRows {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, models.NewAppErrorWithCause(models.ErrorCodeInvalidFormat, "Не удалось разобрать CSV", http.StatusUnprocessableEntity, err)
+		}
+		probe = append(probe, record)
+	}
+	if len(probe) == 0 {
+		return nil, models.NewAppError(models.ErrorCodeInvalidFormat, "Файл не содержит данных", http.StatusUnprocessableEntity)
+	}
+
+	hasHeaders := detectHeader(probe)
+	var names []string
+	rows := probe
+	if hasHeaders {
+		names = normalizeColumnNames(probe[0])
+		rows = probe[1:]
+	} else {
+		names = make([]string, len(probe[0]))
+		for i := range names {
+			names[i] = fmt.Sprintf("column_%d", i+1)
+		}
+	}
+
+	columns := make([]*columnStats, len(names))
+	for i, name := range names {
+		columns[i] = newColumnStats(name)
+	}
+
+	sampleRows := opts.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = 10
+	}
+	var sample bytes.Buffer
+	sampleWriter := csv.NewWriter(&sample)
+	sampleWriter.Comma = delimiter
+	if hasHeaders {
+		_ = sampleWriter.Write(probe[0])
+	}
+
+	totalRows, raggedRows := 0, 0
+	consume := func(record []string) {
+		if totalRows < sampleRows {
+			_ = sampleWriter.Write(record)
+		}
+		totalRows++
+		if len(record) != len(columns) {
+			raggedRows++
+		}
+		for i, col := range columns {
+			if i < len(record) {
+				col.observe(record[i])
+			} else {
+				col.observe("")
+			}
+		}
+	}
+
+	for _, record := range rows {
+		consume(record)
+	}
+	for {
+		if totalRows%profileCtxCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, models.NewAppErrorWithCause(models.ErrorCodeInvalidFormat,
+					fmt.Sprintf("Ошибка разбора CSV в строке %d", parseErr.Line), http.StatusUnprocessableEntity, err)
+			}
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		consume(record)
+	}
+	sampleWriter.Flush()
+
+	fields := make([]models.DataField, len(columns))
+	nullCells := 0
+	for i, col := range columns {
+		fields[i] = col.field()
+		nullCells += col.nulls
+	}
+
+	return &models.DataProfile{
+		DataType:         "csv",
+		TotalRows:        totalRows,
+		SampledRows:      totalRows,
+		Fields:           fields,
+		SampleData:       sample.String(),
+		DataQualityScore: qualityScore(totalRows, len(columns), nullCells, raggedRows),
+		FileSize:         counter.n,
+		Encoding:         encoding,
+		Delimiter:        string(delimiter),
+		HasHeaders:       hasHeaders,
+		CreatedAt:        time.Now(),
+	}, nil
+}
+
+// countingReader считает прочитанные байты
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// detectEncoding определяет кодировку по BOM и валидности UTF-8
+func detectEncoding(head []byte, atEOF bool) (string, int, error) {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return "UTF-8-BOM", len(utf8BOM), nil
+	case bytes.HasPrefix(head, utf16LEBOM), bytes.HasPrefix(head, utf16BEBOM):
+		return "", 0, models.NewAppError(models.ErrorCodeUnsupportedType, "Кодировка UTF-16 не поддерживается", http.StatusUnprocessableEntity)
+	}
+
+	// Буфер мог оборвать многобайтовый символ, отбрасываем неполный хвост
+	check := head
+	if !atEOF {
+		if i := lastRuneStart(check); i < len(check) && !utf8.FullRune(check[i:]) {
+			check = check[:i]
+		}
+	}
+	if utf8.Valid(check) {
+		return "UTF-8", 0, nil
+	}
+	return "unknown", 0, nil
+}
+
+func lastRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// detectDelimiter выбирает разделитель, встречающийся одинаковое число раз в первых строках
+func detectDelimiter(head []byte, atEOF bool) rune {
+	lines := strings.Split(strings.ReplaceAll(string(head), "\r\n", "\n"), "\n")
+	if !atEOF && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 20 {
+		lines = lines[:20]
+	}
+
+	best, bestScore := ',', 0.0
+	for _, delim := range candidateDelimiters {
+		counts := make([]int, 0, len(lines))
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			counts = append(counts, countOutsideQuotes(line, delim))
+		}
+		if len(counts) == 0 || counts[0] == 0 {
+			continue
+		}
+		consistent := 0
+		for _, c := range counts {
+			if c == counts[0] {
+				consistent++
+			}
+		}
+		// Стабильность количества важнее частоты
+		score := float64(consistent)/float64(len(counts))*100 + float64(counts[0])
+		if score > bestScore {
+			best, bestScore = delim, score
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, delim rune) int {
+	count, quoted := 0, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == delim && !quoted:
+			count++
+		}
+	}
+	return count
+}
+
+// detectHeader сравнивает типы значений первой строки с типами остальных строк
+func detectHeader(probe [][]string) bool {
+	first := probe[0]
+	seen := make(map[string]struct{}, len(first))
+	for _, cell := range first {
+		name := strings.TrimSpace(cell)
+		if isNullToken(name) {
+			return false
+		}
+		if _, dup := seen[name]; dup {
+			return false
+		}
+		seen[name] = struct{}{}
+	}
+	if len(probe) == 1 {
+		return detectSingleRowHeader(first)
+	}
+
+	headerVotes, dataVotes := 0, 0
+	for i, cell := range first {
+		col := newColumnStats("")
+		for _, record := range probe[1:] {
+			if i < len(record) {
+				col.observe(record[i])
+			}
+		}
+		dataType := col.inferType()
+		if dataType == models.FieldTypeString {
+			continue
+		}
+		if valueMatchesType(strings.TrimSpace(cell), dataType) {
+			dataVotes++
+		} else {
+			headerVotes++
+		}
+	}
+	if headerVotes+dataVotes > 0 {
+		return headerVotes > dataVotes
+	}
+
+	// Все колонки строковые: считаем заголовком строку из идентификаторов
+	for _, cell := range first {
+		if !looksLikeIdentifier(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// detectSingleRowHeader решает по единственной строке: значения с типом голосуют за данные,
+// идентификаторы — за заголовок
+func detectSingleRowHeader(row []string) bool {
+	headerVotes, dataVotes := 0, 0
+	for _, cell := range row {
+		col := newColumnStats("")
+		col.observe(cell)
+		switch {
+		case col.inferType() != models.FieldTypeString:
+			dataVotes++
+		case looksLikeIdentifier(cell):
+			headerVotes++
+		default:
+			dataVotes++
+		}
+	}
+	return headerVotes > dataVotes
+}
+
+func looksLikeIdentifier(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != ' ' && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return unicode.IsLetter([]rune(s)[0]) || s[0] == '_'
+}
+
+// normalizeColumnNames убирает пробелы и заполняет пустые имена колонок
+func normalizeColumnNames(header []string) []string {
+	names := make([]string, len(header))
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func isNullToken(v string) bool {
+	_, ok := nullTokens[strings.ToLower(v)]
+	return ok
+}
+
+func valueMatchesType(v, fieldType string) bool {
+	col := newColumnStats("")
+	col.observe(v)
+	switch fieldType {
+	case models.FieldTypeBoolean:
+		return col.isBool
+	case models.FieldTypeInteger:
+		return col.isInt
+	case models.FieldTypeFloat:
+		return col.isFloat
+	case models.FieldTypeDate:
+		return col.isDate
+	case models.FieldTypeTimestamp:
+		return col.isTimestamp
+	}
+	return true
+}
+
+// columnStats накапливает статистику одной колонки
+type columnStats struct {
+	name     string
+	values   int
+	nulls    int
+	sample   string
+	distinct map[string]struct{}
+	overflow bool
+
+	isBool, isInt, isFloat, isDate, isTimestamp bool
+
+	minNum, maxNum   float64
+	minTime, maxTime time.Time
+	minLen, maxLen   int
+}
+
+func newColumnStats(name string) *columnStats {
+	return &columnStats{
+		name:        name,
+		distinct:    make(map[string]struct{}),
+		isBool:      true,
+		isInt:       true,
+		isFloat:     true,
+		isDate:      true,
+		isTimestamp: true,
+		minNum:      math.Inf(1),
+		maxNum:      math.Inf(-1),
+		minLen:      math.MaxInt,
+	}
+}
+
+func (c *columnStats) observe(raw string) {
+	v := strings.TrimSpace(raw)
+	if isNullToken(v) {
+		c.nulls++
+		return
+	}
+	c.values++
+	if c.sample == "" {
+		c.sample = v
+	}
+	if !c.overflow {
+		if len(c.distinct) < profileDistinctLimit {
+			c.distinct[v] = struct{}{}
+		} else if _, ok := c.distinct[v]; !ok {
+			c.overflow = true
+		}
+	}
+
+	n := utf8.RuneCountInString(v)
+	c.minLen = min(c.minLen, n)
+	c.maxLen = max(c.maxLen, n)
+
+	if c.isBool {
+		_, c.isBool = boolTokens[strings.ToLower(v)]
+	}
+	if c.isInt || c.isFloat {
+		// Значения с ведущими нулями (коды, индексы) считаем строками
+		if len(v) > 1 && v[0] == '0' && v[1] != '.' {
+			c.isInt, c.isFloat = false, false
+		}
+	}
+	if c.isInt {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			c.isInt = false
+		}
+	}
+	if c.isFloat {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			c.isFloat = false
+		} else {
+			c.minNum = math.Min(c.minNum, f)
+			c.maxNum = math.Max(c.maxNum, f)
+		}
+	}
+	if c.isDate || c.isTimestamp {
+		t, isDate, ok := parseTemporal(v)
+		if !ok {
+			c.isDate, c.isTimestamp = false, false
+		} else {
+			c.isDate = c.isDate && isDate
+			if c.minTime.IsZero() || t.Before(c.minTime) {
+				c.minTime = t
+			}
+			if c.maxTime.IsZero() || t.After(c.maxTime) {
+				c.maxTime = t
+			}
+		}
+	}
+}
+
+// parseTemporal разбирает дату или метку времени; isDate=true, если времени нет
+func parseTemporal(v string) (time.Time, bool, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true, true
+		}
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+func (c *columnStats) inferType() string {
+	switch {
+	case c.values == 0:
+		return models.FieldTypeString
+	case c.isBool:
+		return models.FieldTypeBoolean
+	case c.isInt:
+		return models.FieldTypeInteger
+	case c.isFloat:
+		return models.FieldTypeFloat
+	case c.isDate:
+		return models.FieldTypeDate
+	case c.isTimestamp:
+		return models.FieldTypeTimestamp
+	default:
+		return models.FieldTypeString
+	}
+}
+
+// field формирует DataField. MinValue/MaxValue: для чисел — значения,
+// для дат — unix-время, для строк — длина в символах
+func (c *columnStats) field() models.DataField {
+	f := models.DataField{
+		Name:          c.name,
+		Type:          c.inferType(),
+		Nullable:      c.nulls > 0,
+		NullCount:     c.nulls,
+		DistinctCount: len(c.distinct),
+		SampleValue:   c.sample,
+	}
+	if c.values == 0 {
+		return f
+	}
+	switch f.Type {
+	case models.FieldTypeInteger, models.FieldTypeFloat:
+		f.MinValue, f.MaxValue = c.minNum, c.maxNum
+	case models.FieldTypeDate, models.FieldTypeTimestamp:
+		f.MinValue, f.MaxValue = float64(c.minTime.Unix()), float64(c.maxTime.Unix())
+	case models.FieldTypeString:
+		f.MinValue, f.MaxValue = float64(c.minLen), float64(c.maxLen)
+	}
+	if c.overflow {
+		f.Description = fmt.Sprintf("более %d уникальных значений", profileDistinctLimit)
+	}
+	return f
+}
+
+// qualityScore оценивает полноту и однородность данных в диапазоне [0, 1]
+func qualityScore(rows, columns, nullCells, raggedRows int) float64 {
+	if rows == 0 || columns == 0 {
+		return 0
+	}
+	completeness := 1 - float64(nullCells)/float64(rows*columns)
+	consistency := 1 - float64(raggedRows)/float64(rows)
+	return math.Round(completeness*consistency*1000) / 1000
+}
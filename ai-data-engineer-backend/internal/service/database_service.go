@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// DatabaseOpener открывает репозиторий целевой базы по параметрам подключения.
+// closeFn освобождает соединения и может быть nil
+type DatabaseOpener func(params *models.DatabaseTestRequest) (database repository.DatabaseRepository, closeFn func() error, err error)
+
+// DatabaseService работает с таблицами целевых баз данных. Базы адресуются
+// именем подключения (postgres и clickhouse из конфигурации или memory)
+// либо ID сохраненного подключения. Загрузка, интроспекция и пайплайны получают базу
+// через connection, поэтому база метаданных сервиса в connections не передается
+type DatabaseService struct {
+	connections map[string]repository.DatabaseRepository
+	profiles    *ConnectionService
+	open        DatabaseOpener
+	logger      logger.Logger
+
+	mu sync.Mutex
+	// opened базы сохраненных подключений; переоткрываются после изменения профиля
+	opened map[string]*openedDatabase
+}
+
+// openedDatabase открытая база сохраненного подключения
+type openedDatabase struct {
+	database  repository.DatabaseRepository
+	closeFn   func() error
+	updatedAt time.Time
+}
+
+// NewDatabaseService создает новый DatabaseService. Без profiles доступны
+// только подключения из connections
+func NewDatabaseService(
+	connections map[string]repository.DatabaseRepository,
+	profiles *ConnectionService,
+	open DatabaseOpener,
+	logger logger.Logger,
+) *DatabaseService {
+	return &DatabaseService{
+		connections: connections,
+		profiles:    profiles,
+		open:        open,
+		logger:      logger,
+		opened:      make(map[string]*openedDatabase),
+	}
+}
+
+// GetTableSchema возвращает схему существующей таблицы в базе подключения conn
+func (s *DatabaseService) GetTableSchema(ctx context.Context, userID, conn, tableName string) (*models.TableSchema, error) {
+	database, err := s.connection(ctx, userID, conn)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := database.GetTableSchema(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithField("connection", conn).WithField("table", tableName).
+		WithField("columns", len(schema.Fields)).Debug("Table schema introspected")
+	return schema, nil
+}
+
+// TargetDatabase возвращает базу цели пайплайна: подключение из ConnectionString
+// (имя или ID сохраненного подключения владельца пайплайна), а без него — подключение по типу цели
+func (s *DatabaseService) TargetDatabase(ctx context.Context, userID string, target *models.DataTarget) (repository.DatabaseRepository, error) {
+	conn := target.ConnectionString
+	if conn == "" {
+		conn = target.Type
+	}
+	return s.connection(ctx, userID, conn)
+}
+
+// Close закрывает соединения баз сохраненных подключений
+func (s *DatabaseService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for id, opened := range s.opened {
+		if err := opened.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.opened, id)
+	}
+	return firstErr
+}
+
+// connection возвращает репозиторий базы подключения по имени или ID сохраненного
+// подключения пользователя userID
+func (s *DatabaseService) connection(ctx context.Context, userID, conn string) (repository.DatabaseRepository, error) {
+	if database, ok := s.connections[conn]; ok {
+		return database, nil
+	}
+	if s.profiles != nil {
+		database, err := s.profileDatabase(ctx, userID, conn)
+		if !isNotFound(err) {
+			return database, err
+		}
+	}
+
+	err := models.NewConnectionNotFoundError(conn)
+	names := make([]string, 0, len(s.connections))
+	for name := range s.connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	err.Details["available"] = names
+	return nil, err
+}
+
+// profileDatabase открывает базу сохраненного подключения пользователя или возвращает уже открытую.
+// Чужое подключение не найдено, открытая база при этом не закрывается
+func (s *DatabaseService) profileDatabase(ctx context.Context, userID, id string) (repository.DatabaseRepository, error) {
+	profile, params, err := s.profiles.ResolveConnection(ctx, userID, id)
+	if isNotFound(err) {
+		// Профиль мог быть удален: ищем без проверки владельца, только чтобы закрыть соединения
+		if _, lookupErr := s.profiles.repo.GetConnection(ctx, id); lookupErr == nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opened, ok := s.opened[id]
+	if ok && (err != nil || !opened.updatedAt.Equal(profile.UpdatedAt)) {
+		// Профиль удален или изменен: старые соединения больше не нужны
+		if closeErr := opened.close(); closeErr != nil {
+			s.logger.WithField("connection_id", id).WithField("error", closeErr.Error()).Warn("Failed to close connection")
+		}
+		delete(s.opened, id)
+		ok = false
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return opened.database, nil
+	}
+
+	database, closeFn, err := s.open(params)
+	if err != nil {
+		return nil, err
+	}
+	s.opened[id] = &openedDatabase{database: database, closeFn: closeFn, updatedAt: profile.UpdatedAt}
+	s.logger.WithField("connection_id", id).WithField("type", profile.Type).Debug("Connection opened")
+	return database, nil
+}
+
+func (o *openedDatabase) close() error {
+	if o.closeFn == nil {
+		return nil
+	}
+	return o.closeFn()
+}
+
+// isNotFound сообщает, что ошибка — AppError с кодом not_found
+func isNotFound(err error) bool {
+	appErr, ok := models.IsAppError(err)
+	return ok && appErr.Code == models.ErrorCodeNotFound
+}
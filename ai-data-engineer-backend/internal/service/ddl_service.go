@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/ddl"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// DDLService генерирует DDL целевых таблиц и миграции схем без LLM
+type DDLService struct {
+	analyses  repository.AnalysisRepository
+	databases *DatabaseService
+	logger    logger.Logger
+}
+
+// NewDDLService создает новый DDLService. databases необязателен:
+// без него сравнение с живой таблицей недоступно
+func NewDDLService(analyses repository.AnalysisRepository, databases *DatabaseService, logger logger.Logger) *DDLService {
+	return &DDLService{
+		analyses:  analyses,
+		databases: databases,
+		logger:    logger,
+	}
+}
+
+// GenerateDDL строит TableSchema из запроса и генерирует DDL для req.Database
+// (или req.Target.Type, по умолчанию PostgreSQL)
+func (s *DDLService) GenerateDDL(ctx context.Context, req *models.GenerateDDLRequest) (*models.GenerateDDLResponse, error) {
+	database := req.Database
+	if database == "" && req.Target != nil {
+		database = req.Target.Type
+	}
+	generator, err := ddl.NewGenerator(database)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := req.DataProfile
+	if req.AnalysisID != "" {
+		analysis, err := s.analyses.GetAnalysis(ctx, req.AnalysisID)
+		if err != nil {
+			return nil, err
+		}
+		if analysis.Profile == nil {
+			return nil, models.NewValidationError("Анализ не содержит профиля данных", map[string]interface{}{
+				"analysis_id": req.AnalysisID, "status": analysis.Status,
+			})
+		}
+		profile = analysis.Profile
+	}
+
+	schema, err := tableSchema(req, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := generator.Generate(schema, profile, ddl.OptionsFromMap(req.Options))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithField("database", response.Database).WithField("table", schema.TableName).Info("DDL generated")
+	return response, nil
+}
+
+// DiffSchemas сравнивает исходную и целевую схемы таблицы и возвращает
+// упорядоченные ALTER для req.Database (по умолчанию PostgreSQL)
+func (s *DDLService) DiffSchemas(ctx context.Context, userID string, req *models.SchemaDiffRequest) (*models.SchemaDiffResponse, error) {
+	var from *models.TableSchema
+	var fromProfile *models.DataProfile
+	var err error
+	switch {
+	case req.From != nil || req.FromAnalysisID != "":
+		from, fromProfile, err = s.diffSide(ctx, req.TableName, req.From, req.FromAnalysisID, "from")
+	case req.LiveTable:
+		from, err = s.liveTable(ctx, userID, req.Connection, req.TableName)
+	default:
+		err = models.NewValidationError("Не указана исходная схема: from, from_analysis_id или live_table", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.To == nil && req.ToAnalysisID == "" {
+		return nil, models.NewValidationError("Не указана целевая схема: to или to_analysis_id", nil)
+	}
+	tableName := req.TableName
+	if tableName == "" {
+		tableName = from.TableName
+	}
+	to, toProfile, err := s.diffSide(ctx, tableName, req.To, req.ToAnalysisID, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ddl.Diff(req.Database, from, to, fromProfile, toProfile, ddl.Options{
+		Schema:             req.Schema,
+		ApproveDestructive: req.ApproveDestructive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithField("database", response.Database).WithField("table", response.Table).
+		WithField("changes", len(response.Changes)).WithField("destructive", response.Destructive).
+		Info("Schema diff generated")
+	return response, nil
+}
+
+// diffSide возвращает схему из запроса или строит ее по профилю анализа
+func (s *DDLService) diffSide(ctx context.Context, tableName string, schema *models.TableSchema, analysisID, field string) (*models.TableSchema, *models.DataProfile, error) {
+	if schema != nil {
+		copied := *schema
+		if copied.TableName == "" {
+			copied.TableName = tableName
+		}
+		return &copied, nil, nil
+	}
+
+	analysis, err := s.analyses.GetAnalysis(ctx, analysisID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if analysis.Profile == nil {
+		return nil, nil, models.NewValidationError("Анализ не содержит профиля данных", map[string]interface{}{
+			"analysis_id": analysisID, "status": analysis.Status,
+		})
+	}
+	if tableName == "" {
+		return nil, nil, models.NewValidationError("Не указано имя таблицы", map[string]interface{}{"field": "table_name", "side": field})
+	}
+	return ddl.SchemaFromProfile(tableName, analysis.Profile), analysis.Profile, nil
+}
+
+// liveTable читает схему существующей таблицы в базе подключения conn пользователя userID
+func (s *DDLService) liveTable(ctx context.Context, userID, conn, tableName string) (*models.TableSchema, error) {
+	if tableName == "" || conn == "" {
+		return nil, models.NewValidationError("Для live_table нужны connection и table_name", map[string]interface{}{
+			"connection": conn, "table_name": tableName,
+		})
+	}
+	if s.databases == nil {
+		return nil, models.NewServiceUnavailableError("Чтение схемы живой таблицы не настроено")
+	}
+	return s.databases.GetTableSchema(ctx, userID, conn, tableName)
+}
+
+// tableSchema возвращает схему из запроса или строит ее по профилю данных
+func tableSchema(req *models.GenerateDDLRequest, profile *models.DataProfile) (*models.TableSchema, error) {
+	var tableName string
+	if req.Target != nil {
+		tableName = req.Target.TableName
+	}
+
+	if req.TableSchema != nil {
+		schema := *req.TableSchema
+		if schema.TableName == "" {
+			schema.TableName = tableName
+		}
+		return &schema, nil
+	}
+
+	if tableName == "" {
+		return nil, models.NewValidationError("Не указано имя таблицы", map[string]interface{}{"field": "target.table_name"})
+	}
+	switch {
+	case profile != nil:
+		return ddl.SchemaFromProfile(tableName, profile), nil
+	case req.Schema != nil:
+		return ddl.SchemaFromProfile(tableName, &models.DataProfile{Fields: req.Schema.Fields}), nil
+	default:
+		return nil, models.NewValidationError("Не указан источник схемы: table_schema, analysis_id, data_profile или schema", nil)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+const (
+	// defaultEventHistory количество событий потока, доступных для повтора после переподключения
+	defaultEventHistory = 1000
+	// defaultEventRetention время хранения завершенного потока
+	defaultEventRetention = 10 * time.Minute
+	// subscriberBuffer размер буфера подписчика; отставший подписчик отключается
+	// и переподключается с Last-Event-ID
+	subscriberBuffer = 64
+)
+
+// AnalysisTopic возвращает имя потока событий анализа
+func AnalysisTopic(analysisID string) string {
+	return "analysis:" + analysisID
+}
+
+// ExecutionTopic возвращает имя потока событий выполнения пайплайна
+func ExecutionTopic(executionID string) string {
+	return "execution:" + executionID
+}
+
+// EventBroker рассылает события хода выполнения подписчикам SSE.
+// Для каждого потока хранится история, чтобы клиент мог продолжить с Last-Event-ID
+type EventBroker struct {
+	mu          sync.Mutex
+	topics      map[string]*eventTopic
+	historySize int
+	retention   time.Duration
+}
+
+type eventTopic struct {
+	nextID      int64
+	history     []models.ProgressEvent
+	subscribers map[chan models.ProgressEvent]struct{}
+	finished    bool
+}
+
+// NewEventBroker создает EventBroker. Нулевые параметры заменяются значениями по умолчанию
+func NewEventBroker(historySize int, retention time.Duration) *EventBroker {
+	if historySize <= 0 {
+		historySize = defaultEventHistory
+	}
+	if retention <= 0 {
+		retention = defaultEventRetention
+	}
+	return &EventBroker{
+		topics:      make(map[string]*eventTopic),
+		historySize: historySize,
+		retention:   retention,
+	}
+}
+
+// Publish отправляет событие подписчикам потока. События завершенного потока игнорируются
+func (b *EventBroker) Publish(topic string, eventType models.EventType, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publish(b.topic(topic), eventType, data)
+}
+
+// Finish отправляет завершающее событие и закрывает поток.
+// История завершенного потока хранится retention, затем удаляется
+func (b *EventBroker) Finish(topic string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	if t.finished {
+		return
+	}
+	b.publish(t, models.EventTypeDone, data)
+	t.finished = true
+	for ch := range t.subscribers {
+		close(ch)
+	}
+	t.subscribers = nil
+
+	time.AfterFunc(b.retention, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.topics[topic] == t {
+			delete(b.topics, topic)
+		}
+	})
+}
+
+// Has сообщает, есть ли у потока события в истории
+func (b *EventBroker) Has(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.topics[topic]
+	return ok && len(t.history) > 0
+}
+
+// Subscribe подписывается на поток и возвращает события с ID больше lastEventID
+func (b *EventBroker) Subscribe(topic string, lastEventID int64) *models.EventSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	var replay []models.ProgressEvent
+	for _, event := range t.history {
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+
+	ch := make(chan models.ProgressEvent, subscriberBuffer)
+	if t.finished {
+		close(ch)
+		return &models.EventSubscription{Replay: replay, Events: ch, Finished: true, Cancel: func() {}}
+	}
+	t.subscribers[ch] = struct{}{}
+
+	return &models.EventSubscription{
+		Replay: replay,
+		Events: ch,
+		Cancel: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := t.subscribers[ch]; ok {
+				delete(t.subscribers, ch)
+				close(ch)
+			}
+			// Поток без событий и подписчиков не хранится
+			if len(t.history) == 0 && len(t.subscribers) == 0 && b.topics[topic] == t {
+				delete(b.topics, topic)
+			}
+		},
+	}
+}
+
+// topic возвращает поток, создавая его при необходимости. Вызывается под b.mu
+func (b *EventBroker) topic(name string) *eventTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &eventTopic{subscribers: make(map[chan models.ProgressEvent]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// publish добавляет событие в историю и рассылает его. Вызывается под b.mu
+func (b *EventBroker) publish(t *eventTopic, eventType models.EventType, data interface{}) {
+	if t.finished {
+		return
+	}
+	t.nextID++
+	event := models.ProgressEvent{ID: t.nextID, Type: eventType, Data: data, Timestamp: time.Now()}
+	if eventType == models.EventTypeDone {
+		event.ID = models.DoneEventID
+	}
+
+	t.history = append(t.history, event)
+	if len(t.history) > b.historySize {
+		t.history = append(t.history[:0:0], t.history[len(t.history)-b.historySize:]...)
+	}
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Подписчик не успевает читать: отключаем, пропущенное он получит из истории
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+}
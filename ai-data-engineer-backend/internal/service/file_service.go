@@ -2,89 +2,401 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/client"
 	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
+// defaultBucket bucket для пользовательских файлов
+const defaultBucket = "ai-data-engineer"
+
 type StorageClient interface {
 	UploadFile(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, contentType string) error
 	DownloadFile(ctx context.Context, bucket, objectName string) (io.ReadCloser, error)
 	DownloadFileAsBytes(ctx context.Context, bucket, objectName string) ([]byte, error)
 	DeleteFile(ctx context.Context, bucket, objectName string) error
+	CopyFile(ctx context.Context, bucket, srcObject, dstObject string) error
 	ListFiles(ctx context.Context, bucket, prefix string) ([]string, error)
 	FileExists(ctx context.Context, bucket, objectName string) (bool, error)
 }
 
+// PresignStorage хранилище, умеющее выдавать временные ссылки для прямого доступа клиента
+type PresignStorage interface {
+	PresignedGetURL(ctx context.Context, bucket, objectName, filename string, expires time.Duration) (string, error)
+	PresignedPutURL(ctx context.Context, bucket, objectName string, expires time.Duration) (string, error)
+}
+
+// FileServiceOptions параметры FileService
+type FileServiceOptions struct {
+	// MaxUploadSize максимальный размер файла, <= 0 — без ограничения
+	MaxUploadSize int64
+	// PresignExpiry время действия временных ссылок
+	PresignExpiry time.Duration
+}
+
 // FileService реализация FileService
 type FileService struct {
 	storageClient StorageClient
-	logger        logger.Logger
+	// presign nil, если хранилище не поддерживает временные ссылки
+	presign  PresignStorage
+	blobs    *BlobStore
+	fileRepo repository.FileRepository
+	options  FileServiceOptions
+	logger   logger.Logger
 }
 
 // NewFileService создает новый FileService
 func NewFileService(
 	storageClient StorageClient,
+	fileRepo repository.FileRepository,
+	blobs *BlobStore,
+	options FileServiceOptions,
 	logger logger.Logger,
 ) *FileService {
+	if options.PresignExpiry <= 0 {
+		options.PresignExpiry = 15 * time.Minute
+	}
+	presign, _ := storageClient.(PresignStorage)
 	return &FileService{
 		storageClient: storageClient,
+		presign:       presign,
+		blobs:         blobs,
+		fileRepo:      fileRepo,
+		options:       options,
 		logger:        logger,
 	}
 }
 
-// * UploadFile загружает файл в MinIO
-func (s *FileService) UploadFile(ctx context.Context, userID, filename string, file io.Reader) (string, error) {
-	s.logger.WithField("user_id", userID).WithField("filename", filename).Info("Starting analyze file")
-
-	//! Генерируем уникальное имя файла для MinIO
-	s.logger.Info("Generating unique object name for MinIO")
-	objectName := generateFileName(userID, filename)
+// * UploadFile потоково загружает файл в хранилище и сохраняет его метаданные.
+// Содержимое не буферизуется: размер и SHA-256 вычисляются по ходу чтения,
+// после чего временный объект переносится в blob с ключом по SHA-256
+func (s *FileService) UploadFile(ctx context.Context, userID, filename string, file io.Reader) (*models.FileMetadata, error) {
+	s.logger.WithField("user_id", userID).WithField("filename", filename).Info("Starting upload file")
 
-	// Читаем содержимое файла для получения размера
-	content, err := io.ReadAll(file)
+	metadata, err := s.createMetadata(ctx, userID, filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return nil, err
+	}
+
+	hasher := sha256.New()
+	source := &limitedReader{r: file, remaining: s.options.MaxUploadSize, unlimited: s.options.MaxUploadSize <= 0}
+	counter := &countingReader{r: io.TeeReader(source, hasher)}
+
+	uploadErr := s.storageClient.UploadFile(ctx, metadata.Bucket, metadata.Path, counter, -1, metadata.ContentType)
+	if source.exceeded {
+		// Часть объекта могла остаться в хранилище, удаляем ее
+		if err := s.storageClient.DeleteFile(ctx, metadata.Bucket, metadata.Path); err != nil {
+			s.logger.WithField("error", err.Error()).WithField("file_id", metadata.ID).Warn("Failed to remove oversized upload")
+		}
+		s.setStatus(ctx, metadata, models.FileStatusError)
+		return nil, s.tooLarge()
 	}
-	userBucket := "ai-data-engineer"
-	userfilePath := fmt.Sprintf("users/%s/files/%s", userID, objectName)
-	uploadErr := s.storageClient.UploadFile(ctx, userBucket, userfilePath, strings.NewReader(string(content)), int64(len(content)), "application/octet-stream")
 	if uploadErr != nil {
 		s.logger.WithField("error", uploadErr.Error()).Error("Failed to save file to MinIO")
-		return "", fmt.Errorf("failed to save file to MinIO: %w", uploadErr)
+		s.setStatus(ctx, metadata, models.FileStatusError)
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeUploadFailed, "Не удалось сохранить файл в хранилище", http.StatusBadGateway, uploadErr)
+	}
+
+	if err := s.attachBlob(ctx, metadata, hex.EncodeToString(hasher.Sum(nil)), counter.n); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// PresignUpload регистрирует файл и возвращает ссылку для загрузки напрямую в хранилище.
+// После загрузки клиент должен вызвать ConfirmUpload. Если передан checksum файла,
+// который пользователь уже загружал, файл регистрируется сразу, а ссылка не возвращается
+func (s *FileService) PresignUpload(ctx context.Context, userID, filename, checksum string) (*models.FileMetadata, *models.PresignedURL, error) {
+	if filename == "" {
+		return nil, nil, models.NewValidationError("Имя файла обязательно", nil)
+	}
+	if checksum != "" {
+		metadata, err := s.reuseContent(ctx, userID, filename, checksum)
+		if err != nil || metadata != nil {
+			return metadata, nil, err
+		}
+	}
+	if s.presign == nil {
+		return nil, nil, errPresignUnsupported()
+	}
+
+	metadata, err := s.createMetadata(ctx, userID, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	expiresAt := time.Now().Add(s.options.PresignExpiry)
+	url, err := s.presign.PresignedPutURL(ctx, metadata.Bucket, metadata.Path, s.options.PresignExpiry)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).WithField("file_id", metadata.ID).Error("Failed to presign upload")
+		s.setStatus(ctx, metadata, models.FileStatusError)
+		return nil, nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось создать ссылку для загрузки", http.StatusBadGateway, err)
+	}
+	return metadata, &models.PresignedURL{URL: url, Method: http.MethodPut, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmUpload завершает загрузку по временной ссылке: проверяет наличие объекта,
+// вычисляет размер и SHA-256 и переводит файл в статус uploaded
+func (s *FileService) ConfirmUpload(ctx context.Context, fileID, userID string) (*models.FileMetadata, error) {
+	metadata, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Status != models.FileStatusUploading {
+		return nil, models.NewConflictError(fmt.Sprintf("Файл в статусе %s", metadata.Status))
+	}
+
+	reader, err := s.storageClient.DownloadFile(ctx, metadata.Bucket, metadata.Path)
+	if err != nil {
+		return nil, models.NewConflictError("Файл еще не загружен в хранилище")
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	source := &limitedReader{r: reader, remaining: s.options.MaxUploadSize, unlimited: s.options.MaxUploadSize <= 0}
+	size, err := io.Copy(hasher, source)
+	if source.exceeded {
+		// Ограничение размера нельзя задать в ссылке на PUT, поэтому проверяем после загрузки
+		if err := s.storageClient.DeleteFile(ctx, metadata.Bucket, metadata.Path); err != nil {
+			s.logger.WithField("error", err.Error()).WithField("file_id", metadata.ID).Warn("Failed to remove oversized upload")
+		}
+		s.setStatus(ctx, metadata, models.FileStatusError)
+		return nil, s.tooLarge()
+	}
+	if err != nil {
+		return nil, models.NewConflictError("Файл еще не загружен в хранилище")
 	}
 
-	return objectName, nil
+	if err := s.attachBlob(ctx, metadata, hex.EncodeToString(hasher.Sum(nil)), size); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// PresignDownload возвращает ссылку на скачивание файла владельцем
+func (s *FileService) PresignDownload(ctx context.Context, fileID, userID string) (*models.PresignedURL, error) {
+	if s.presign == nil {
+		return nil, errPresignUnsupported()
+	}
+	metadata, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Status == models.FileStatusUploading || metadata.Status == models.FileStatusError {
+		return nil, models.NewConflictError(fmt.Sprintf("Файл в статусе %s недоступен для скачивания", metadata.Status))
+	}
+
+	expiresAt := time.Now().Add(s.options.PresignExpiry)
+	url, err := s.presign.PresignedGetURL(ctx, metadata.Bucket, metadata.Path, metadata.Filename, s.options.PresignExpiry)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to presign download")
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось создать ссылку для скачивания", http.StatusBadGateway, err)
+	}
+	return &models.PresignedURL{URL: url, Method: http.MethodGet, ExpiresAt: expiresAt}, nil
 }
 
-// GetFileInfo получает информацию о файле
-func (s *FileService) GetFileInfo(ctx context.Context, fileID string) (interface{}, error) {
-	// TODO: Implement file info retrieval
-	return map[string]interface{}{"file_id": fileID}, nil
+// GetFileInfo получает информацию о файле пользователя
+func (s *FileService) GetFileInfo(ctx context.Context, fileID, userID string) (*models.FileMetadata, error) {
+	return s.ownedFile(ctx, fileID, userID)
 }
 
-// DeleteFile удаляет файл
-func (s *FileService) DeleteFile(ctx context.Context, fileID string) error {
-	// TODO: Implement file deletion
+// DeleteFile удаляет метаданные файла пользователя и освобождает ссылку на его содержимое.
+// Объект в хранилище удаляется, только если на него не ссылаются другие файлы
+func (s *FileService) DeleteFile(ctx context.Context, fileID, userID string) error {
+	metadata, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return err
+	}
+
+	// Сначала удаляем метаданные: при сбое остается лишний объект, но не ссылка на удаленный
+	if err := s.fileRepo.DeleteFile(ctx, fileID); err != nil {
+		return err
+	}
+	if err := s.blobs.Release(ctx, metadata); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("file_id", fileID).Error("Failed to release file content")
+	}
 	return nil
 }
 
-// ListFiles получает список файлов
-func (s *FileService) ListFiles(ctx context.Context, userID string, limit, offset int) ([]interface{}, error) {
-	// TODO: Implement file listing
-	return []interface{}{}, nil
+// ListFiles получает список файлов пользователя
+func (s *FileService) ListFiles(ctx context.Context, userID string, limit, offset int) ([]*models.FileMetadata, error) {
+	return s.fileRepo.GetFilesByUser(ctx, userID, limit, offset)
+}
+
+// createMetadata регистрирует новый файл в статусе uploading
+func (s *FileService) createMetadata(ctx context.Context, userID, filename string) (*models.FileMetadata, error) {
+	//! Генерируем уникальное имя файла для MinIO
+	id := uuid.New().String()
+	objectName := generateFileName(id, filename)
+
+	now := time.Now()
+	metadata := &models.FileMetadata{
+		ID:          id,
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: client.GetContentType(filename),
+		Path:        fmt.Sprintf("users/%s/files/%s", userID, objectName),
+		Bucket:      defaultBucket,
+		Status:      models.FileStatusUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.fileRepo.SaveFile(ctx, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// attachBlob переносит загруженное содержимое в blob и переводит файл в статус uploaded
+func (s *FileService) attachBlob(ctx context.Context, metadata *models.FileMetadata, checksum string, size int64) error {
+	blob, err := s.blobs.Promote(ctx, metadata.Bucket, metadata.Path, checksum, size)
+	if err != nil {
+		s.blobs.removeTemp(ctx, metadata.Bucket, metadata.Path)
+		s.setStatus(ctx, metadata, models.FileStatusError)
+		return err
+	}
+	metadata.Bucket = blob.Bucket
+	metadata.Path = blob.Path
+	metadata.Size = size
+	metadata.Checksum = checksum
+	return s.setStatus(ctx, metadata, models.FileStatusUploaded)
 }
 
-func generateFileName(userID, filename string) string {
-	timestamp := time.Now().Format("20060102_150405")
+// reuseContent регистрирует файл с уже загруженным пользователем содержимым.
+// Возвращает nil без ошибки, если содержимого нет. Проверка владельца не позволяет
+// получить чужой файл, зная только его хеш
+func (s *FileService) reuseContent(ctx context.Context, userID, filename, checksum string) (*models.FileMetadata, error) {
+	checksum = strings.ToLower(checksum)
+	files, err := s.fileRepo.GetFilesByChecksum(ctx, checksum)
+	if err != nil {
+		return nil, err
+	}
+	owned := false
+	for _, f := range files {
+		if f.UserID == userID && f.Status != models.FileStatusUploading && f.Status != models.FileStatusError {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		return nil, nil
+	}
+
+	blob, err := s.blobs.Reuse(ctx, checksum)
+	if appErr, ok := models.IsAppError(err); ok && appErr.Code == models.ErrorCodeNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	metadata := &models.FileMetadata{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: client.GetContentType(filename),
+		Size:        blob.Size,
+		Checksum:    checksum,
+		Path:        blob.Path,
+		Bucket:      blob.Bucket,
+		Status:      models.FileStatusUploaded,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.fileRepo.SaveFile(ctx, metadata); err != nil {
+		s.blobs.Release(ctx, metadata)
+		return nil, err
+	}
+	s.logger.WithField("file_id", metadata.ID).WithField("checksum", checksum).Info("File registered from existing content")
+	return metadata, nil
+}
+
+// ownedFile возвращает метаданные файла, если он принадлежит пользователю
+func (s *FileService) ownedFile(ctx context.Context, fileID, userID string) (*models.FileMetadata, error) {
+	metadata, err := s.fileRepo.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.UserID != userID {
+		return nil, models.NewAppError(models.ErrorCodeForbidden, "Нет доступа к файлу", http.StatusForbidden)
+	}
+	return metadata, nil
+}
+
+func (s *FileService) tooLarge() error {
+	return models.NewAppError(models.ErrorCodeFileTooLarge,
+		fmt.Sprintf("Размер файла превышает допустимый (%d байт)", s.options.MaxUploadSize), http.StatusRequestEntityTooLarge)
+}
+
+func errPresignUnsupported() error {
+	return models.NewAppError(models.ErrorCodeStorageError, "Хранилище не поддерживает временные ссылки", http.StatusNotImplemented)
+}
+
+// setStatus переводит файл в новый статус жизненного цикла
+func (s *FileService) setStatus(ctx context.Context, metadata *models.FileMetadata, status models.FileStatus) error {
+	metadata.Status = status
+	metadata.UpdatedAt = time.Now()
+	if err := s.fileRepo.UpdateFile(ctx, metadata); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("file_id", metadata.ID).WithField("status", status).Error("Failed to update file status")
+		return err
+	}
+	return nil
+}
+
+// generateFileName возвращает имя объекта с уникальным id: одновременные загрузки
+// одноименных файлов не пишут в один объект
+func generateFileName(id, filename string) string {
 	ext := filepath.Ext(filename)
 	nameWithoutExt := strings.TrimSuffix(filename, ext)
 	cleanName := strings.ReplaceAll(nameWithoutExt, " ", "_")
 	cleanName = strings.ReplaceAll(cleanName, "/", "_")
 	cleanName = strings.ReplaceAll(cleanName, "\\", "_")
-	return fmt.Sprintf("%s_%s%s", timestamp, cleanName, ext)
+	return fmt.Sprintf("%s_%s%s", id, cleanName, ext)
+}
+
+// errUploadTooLarge возвращается limitedReader при превышении лимита
+var errUploadTooLarge = errors.New("upload exceeds maximum size")
+
+// limitedReader ограничивает объем читаемых данных. В отличие от io.LimitReader
+// превышение лимита возвращается как ошибка, а не как EOF, чтобы не сохранить усеченный файл
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	unlimited bool
+	exceeded  bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.unlimited {
+		return l.r.Read(p)
+	}
+	if l.remaining <= 0 {
+		// Лимит исчерпан: проверяем, остались ли еще данные
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, errUploadTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
 }
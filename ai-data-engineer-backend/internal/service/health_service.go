@@ -4,6 +4,7 @@ import (
 	"ai-data-engineer-backend/domain/models"
 	"ai-data-engineer-backend/pkg/logger"
 	"context"
+	"time"
 )
 
 // HealthService сервис для проверки здоровья системы
@@ -35,8 +36,42 @@ func (h *HealthService) CheckLLM(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-// TestDatabaseConnection тестирует подключение к базе данных
-func (h *HealthService) TestDatabaseConnection(ctx context.Context, req *models.DatabaseTestRequest) (bool, error) {
-	// TODO: Implement database connection test
-	return true, nil
+// TestDatabaseConnection подключается к базе из запроса и проверяет задержку, версию сервера,
+// SSL и права на создание таблицы и вставку. Недоступная база — ошибка ErrorCodeConnectionFailed
+func (h *HealthService) TestDatabaseConnection(ctx context.Context, req *models.DatabaseTestRequest) (*models.DatabaseTestResponse, error) {
+	timeout := defaultConnectionTestTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var probe *connectionProbe
+	var err error
+	switch req.Type {
+	case "postgres":
+		probe, err = probePostgres(ctx, req)
+	case "clickhouse":
+		probe, err = probeClickHouse(ctx, req, h.logger)
+	default:
+		return nil, models.NewValidationError("Неподдерживаемый тип базы данных", map[string]interface{}{"type": req.Type})
+	}
+	if err != nil {
+		h.logger.WithField("type", req.Type).WithField("host", req.Host).WithField("error", err.Error()).
+			Warn("Database connection test failed")
+		return nil, connectionFailed(ctx, req, err)
+	}
+
+	response := &models.DatabaseTestResponse{
+		Status:    "healthy",
+		Message:   "Подключение установлено",
+		Connected: true,
+		TestedAt:  time.Now(),
+		Details:   probe.details(),
+	}
+	if !probe.createTable || !probe.insert {
+		response.Status = "limited"
+		response.Message = "Подключение установлено, но недостаточно прав для создания таблиц или вставки данных"
+	}
+	return response, nil
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// instanceLeasePrefix префикс аренды, которую удерживает работающий экземпляр сервиса
+const instanceLeasePrefix = "instance:"
+
+// InstanceOptions параметры экземпляра сервиса
+type InstanceOptions struct {
+	// ID имя экземпляра; по умолчанию имя хоста и случайный суффикс. Одновременно
+	// работающие экземпляры должны иметь разные имена
+	ID string
+	// LeaseTTL срок аренды экземпляра: экземпляр, не продливший аренду за это время,
+	// считается остановленным, и его фоновые задачи восстанавливают другие экземпляры
+	LeaseTTL time.Duration
+}
+
+// Instance экземпляр сервиса. Пока процесс работает, он продлевает аренду "instance:<ID>".
+// Фоновые задачи (выполнения пайплайнов, анализы, загрузки) сохраняют ID экземпляра-владельца,
+// и восстанавливаются только задачи экземпляров, аренда которых истекла.
+// Без репозитория аренд экземпляр считается единственным
+type Instance struct {
+	leases  repository.LeaseRepository
+	options InstanceOptions
+	logger  logger.Logger
+	// holder держатель аренды; уникален для процесса, поэтому процесс с тем же ID не продлит
+	// чужую аренду
+	holder string
+}
+
+// NewInstance создает Instance. leases может быть nil
+func NewInstance(leases repository.LeaseRepository, options InstanceOptions, logger logger.Logger) *Instance {
+	if options.ID == "" {
+		host, _ := os.Hostname()
+		options.ID = host + "-" + uuid.New().String()[:8]
+	}
+	if options.LeaseTTL <= 0 {
+		options.LeaseTTL = time.Minute
+	}
+	return &Instance{
+		leases:  leases,
+		options: options,
+		logger:  logger,
+		holder:  uuid.New().String(),
+	}
+}
+
+// ID имя экземпляра
+func (i *Instance) ID() string {
+	return i.options.ID
+}
+
+// LeaseTTL срок аренды экземпляра; с этим периодом фоновые задачи проверяют,
+// не остановились ли владельцы незавершенных задач
+func (i *Instance) LeaseTTL() time.Duration {
+	return i.options.LeaseTTL
+}
+
+// Start захватывает аренду экземпляра и продлевает ее до отмены ctx; при остановке аренда
+// освобождается. Аренда, которую удерживает работающий экземпляр с тем же ID, не захватывается
+func (i *Instance) Start(ctx context.Context) error {
+	if i.leases == nil {
+		return nil
+	}
+	name := instanceLeasePrefix + i.options.ID
+	acquired, err := i.leases.AcquireLease(ctx, name, i.holder, i.options.LeaseTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return fmt.Errorf("экземпляр %q уже работает", i.options.ID)
+	}
+
+	go func() {
+		ticker := time.NewTicker(i.options.LeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := i.leases.ReleaseLease(context.WithoutCancel(ctx), name, i.holder); err != nil {
+					i.logger.WithField("error", err.Error()).Warn("Failed to release instance lease")
+				}
+				return
+			case <-ticker.C:
+				if acquired, err := i.leases.AcquireLease(ctx, name, i.holder, i.options.LeaseTTL); err != nil {
+					i.logger.WithField("error", err.Error()).Error("Failed to renew instance lease")
+				} else if !acquired {
+					i.logger.WithField("instance", i.options.ID).Error("Instance lease taken over by another process")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Alive сообщает, работает ли экземпляр owner. Этот экземпляр работает всегда; задачи без
+// владельца и задачи экземпляров с истекшей арендой считаются прерванными
+func (i *Instance) Alive(ctx context.Context, owner string) (bool, error) {
+	if owner == i.options.ID {
+		return true, nil
+	}
+	if owner == "" || i.leases == nil {
+		return false, nil
+	}
+	holder, err := i.leases.LeaseHolder(ctx, instanceLeasePrefix+owner)
+	if err != nil {
+		return false, err
+	}
+	return holder != "", nil
+}
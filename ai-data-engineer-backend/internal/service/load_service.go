@@ -0,0 +1,381 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/ddl"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// loadCtxCheckEvery как часто проверять отмену контекста при загрузке (в строках)
+const loadCtxCheckEvery = 10000
+
+// LoadService загружает проанализированные файлы из хранилища в таблицы целевых баз.
+// Значения приводятся к типам колонок на стороне сервиса: строки, которые не удалось
+// привести, не прерывают загрузку и сохраняются в файл отклоненных строк рядом с исходным
+type LoadService struct {
+	storage   StorageClient
+	analyses  repository.AnalysisRepository
+	files     repository.FileRepository
+	databases *DatabaseService
+	logger    logger.Logger
+}
+
+// NewLoadService создает новый LoadService
+func NewLoadService(
+	storage StorageClient,
+	analyses repository.AnalysisRepository,
+	files repository.FileRepository,
+	databases *DatabaseService,
+	logger logger.Logger,
+) *LoadService {
+	return &LoadService{
+		storage:   storage,
+		analyses:  analyses,
+		files:     files,
+		databases: databases,
+		logger:    logger,
+	}
+}
+
+// Load потоково читает CSV файл анализа и загружает его в существующую таблицу.
+// Анализ и сохраненное подключение должны принадлежать пользователю userID
+func (s *LoadService) Load(ctx context.Context, userID string, req *models.LoadRequest) (*models.LoadResult, error) {
+	started := time.Now()
+	result := &models.LoadResult{
+		ID:         uuid.New().String(),
+		AnalysisID: req.AnalysisID,
+		Connection: req.Connection,
+		TableName:  req.TableName,
+		StartedAt:  started,
+	}
+	log := s.logger.WithField("load_id", result.ID).WithField("analysis_id", req.AnalysisID).WithField("table", req.TableName)
+
+	analysis, err := s.analyses.GetAnalysis(ctx, req.AnalysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.UserId != userID {
+		return nil, errAnalysisForbidden()
+	}
+	if analysis.Profile == nil {
+		return nil, models.NewValidationError("Анализ не содержит профиля данных", map[string]interface{}{
+			"analysis_id": req.AnalysisID, "status": analysis.Status,
+		})
+	}
+	bucket, object, err := s.sourceObject(ctx, analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := s.databases.connection(ctx, userID, req.Connection)
+	if err != nil {
+		return nil, err
+	}
+	inserter, ok := database.(repository.BulkInserter)
+	if !ok {
+		return nil, models.NewValidationError("Подключение не поддерживает массовую загрузку", map[string]interface{}{"connection": req.Connection})
+	}
+	result.Database = inserter.Dialect()
+
+	columns, sources, err := loadColumns(inserter.Dialect(), req, analysis.Profile)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i := range columns {
+		names[i] = columns[i].Name
+		result.Columns = append(result.Columns, models.LoadColumn{
+			Column:      columns[i].Name,
+			Type:        columns[i].Type,
+			Source:      analysis.Profile.Fields[sources[i]].Name,
+			SourceIndex: sources[i],
+		})
+	}
+
+	reader, err := s.storage.DownloadFile(ctx, bucket, object)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to download file for load")
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось прочитать файл", http.StatusBadGateway, err)
+	}
+	defer reader.Close()
+
+	source, err := newLoadSource(reader, analysis.Profile, columns, sources, req.MaxRejects)
+	if err != nil {
+		return nil, err
+	}
+	defer source.close()
+
+	opts := models.BulkInsertOptions{BatchSize: req.BatchSize, DedupKey: req.IdempotencyKey}
+	if opts.DedupKey == "" {
+		opts.DedupKey = loadDedupKey(req, result.Columns)
+	}
+	result.IdempotencyKey = opts.DedupKey
+
+	loaded, err := inserter.BulkInsert(ctx, req.TableName, names, func() ([]interface{}, error) {
+		if source.read%loadCtxCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		return source.next()
+	}, opts)
+	if err != nil {
+		log.WithField("error", err.Error()).WithField("rows_read", source.read).WithField("rows_loaded", loaded).Error("Load failed")
+		if appErr, ok := models.IsAppError(err); ok && loaded > 0 {
+			// Часть батчей ClickHouse уже вставлена: повтор с тем же ключом их пропустит
+			if appErr.Details == nil {
+				appErr.Details = map[string]interface{}{}
+			}
+			appErr.Details["rows_loaded"] = loaded
+			appErr.Details["idempotency_key"] = opts.DedupKey
+		}
+		return nil, err
+	}
+
+	result.RowsRead = source.read
+	result.RowsLoaded = loaded
+	result.RowsRejected = source.rejected
+	result.BytesRead = source.counter.n
+	if source.rejected > 0 {
+		path := fmt.Sprintf("loads/%s/rejects.csv", result.ID)
+		if err := source.uploadRejects(ctx, s.storage, bucket, path); err != nil {
+			// Данные уже загружены: отсутствие файла отклоненных строк не отменяет загрузку
+			log.WithField("error", err.Error()).Error("Failed to upload rejected rows")
+		} else {
+			result.RejectsBucket, result.RejectsPath = bucket, path
+		}
+	}
+
+	result.CompletedAt = time.Now()
+	elapsed := result.CompletedAt.Sub(started)
+	result.DurationMs = elapsed.Milliseconds()
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		result.RowsPerSecond = float64(result.RowsLoaded) / seconds
+		result.BytesPerSecond = float64(result.BytesRead) / seconds
+	}
+	log.WithField("rows_loaded", result.RowsLoaded).WithField("rows_rejected", result.RowsRejected).
+		WithField("duration_ms", result.DurationMs).Info("File loaded")
+	return result, nil
+}
+
+// sourceObject возвращает объект файла анализа в хранилище
+func (s *LoadService) sourceObject(ctx context.Context, analysis *models.AnalysisResult) (string, string, error) {
+	if analysis.FileID != "" {
+		metadata, err := s.files.GetFile(ctx, analysis.FileID)
+		if err != nil {
+			return "", "", err
+		}
+		return metadata.Bucket, metadata.Path, nil
+	}
+	if analysis.FilePath == "" {
+		return "", "", models.NewValidationError("Анализ не связан с файлом", map[string]interface{}{"analysis_id": analysis.ID})
+	}
+	return defaultBucket, analysis.FilePath, nil
+}
+
+// loadDedupKey строит ключ идемпотентности из параметров, определяющих содержимое батчей:
+// повтор той же загрузки дает те же батчи и те же токены дедупликации
+func loadDedupKey(req *models.LoadRequest, columns []models.LoadColumn) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%d", req.AnalysisID, req.TableName, req.BatchSize)
+	for _, column := range columns {
+		fmt.Fprintf(hash, "\x00%s=%d", column.Column, column.SourceIndex)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// loadColumns возвращает типы колонок таблицы и номера соответствующих колонок файла.
+// Колонка таблицы сопоставляется с колонкой файла по Mapping, иначе по имени:
+// исходному заголовку или имени колонки, построенному из него при генерации схемы
+func loadColumns(dialect string, req *models.LoadRequest, profile *models.DataProfile) ([]ddl.ColumnType, []int, error) {
+	generated := ddl.SchemaFromProfile(req.TableName, profile)
+	schema := req.TableSchema
+	if schema == nil {
+		schema = generated
+	}
+	columns, err := ddl.ResolveColumns(dialect, schema, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := make(map[string]int, 2*len(profile.Fields))
+	for i := len(profile.Fields) - 1; i >= 0; i-- {
+		index[generated.Fields[i].Name] = i
+		index[profile.Fields[i].Name] = i
+	}
+	sources := make([]int, len(columns))
+	var missing []string
+	for i, column := range columns {
+		source := column.Name
+		if mapped, ok := req.Mapping[column.Name]; ok {
+			source = mapped
+		}
+		position, ok := index[source]
+		if !ok {
+			missing = append(missing, column.Name)
+			continue
+		}
+		sources[i] = position
+	}
+	if len(missing) > 0 {
+		return nil, nil, models.NewValidationError("Для колонок таблицы не найдены колонки файла", map[string]interface{}{
+			"columns": missing,
+			"hint":    "укажите соответствие в mapping: колонка таблицы → колонка файла",
+		})
+	}
+	return columns, sources, nil
+}
+
+// loadSource читает строки CSV и приводит их к типам колонок, отклоняя неподходящие
+type loadSource struct {
+	counter    *countingReader
+	csv        *csv.Reader
+	header     []string
+	columns    []ddl.ColumnType
+	sources    []int
+	maxRejects int64
+
+	read     int64
+	rejected int64
+	rejects  *os.File
+	writer   *csv.Writer
+}
+
+func newLoadSource(r io.Reader, profile *models.DataProfile, columns []ddl.ColumnType, sources []int, maxRejects int64) (*loadSource, error) {
+	counter := &countingReader{r: r}
+	br := bufio.NewReaderSize(counter, profileSniffSize)
+	if head, _ := br.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+
+	csvReader := csv.NewReader(br)
+	if delimiter := []rune(profile.Delimiter); len(delimiter) == 1 {
+		csvReader.Comma = delimiter[0]
+	}
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	header := make([]string, len(profile.Fields))
+	for i, field := range profile.Fields {
+		header[i] = field.Name
+	}
+	if profile.HasHeaders {
+		if _, err := csvReader.Read(); err != nil && err != io.EOF {
+			return nil, models.NewAppErrorWithCause(models.ErrorCodeInvalidFormat, "Не удалось прочитать заголовок CSV", http.StatusUnprocessableEntity, err)
+		}
+	}
+	return &loadSource{
+		counter:    counter,
+		csv:        csvReader,
+		header:     header,
+		columns:    columns,
+		sources:    sources,
+		maxRejects: maxRejects,
+	}, nil
+}
+
+// next возвращает следующую приведенную строку или io.EOF
+func (s *loadSource) next() ([]interface{}, error) {
+	for {
+		record, err := s.csv.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		s.read++
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := s.reject(parseErr.Line, record, "ошибка разбора CSV: "+parseErr.Err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		row, reason := s.coerce(record)
+		if reason == "" {
+			return row, nil
+		}
+		line, _ := s.csv.FieldPos(0)
+		if err := s.reject(line, record, reason); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// coerce приводит значения строки; возвращает причину отклонения для первой неподходящей колонки
+func (s *loadSource) coerce(record []string) ([]interface{}, string) {
+	row := make([]interface{}, len(s.columns))
+	for i := range s.columns {
+		source := s.sources[i]
+		if source >= len(record) {
+			return nil, fmt.Sprintf("в строке %d колонок, колонка %s не заполнена", len(record), s.header[source])
+		}
+		value, err := coerceValue(&s.columns[i], record[source])
+		if err != nil {
+			return nil, fmt.Sprintf("колонка %s: %s", s.columns[i].Name, err.Error())
+		}
+		row[i] = value
+	}
+	return row, ""
+}
+
+// reject записывает строку в файл отклоненных строк. Превышение maxRejects прерывает загрузку
+func (s *loadSource) reject(line int, record []string, reason string) error {
+	s.rejected++
+	if s.maxRejects > 0 && s.rejected > s.maxRejects {
+		return models.NewValidationError("Превышено допустимое число отклоненных строк", map[string]interface{}{
+			"max_rejects": s.maxRejects, "line": line, "reason": reason,
+		})
+	}
+	if s.rejects == nil {
+		file, err := os.CreateTemp("", "load-rejects-*.csv")
+		if err != nil {
+			return fmt.Errorf("failed to create rejects file: %w", err)
+		}
+		s.rejects, s.writer = file, csv.NewWriter(file)
+		s.writer.Write(append([]string{"line", "error"}, s.header...))
+	}
+	return s.writer.Write(append([]string{strconv.Itoa(line), reason}, record...))
+}
+
+// uploadRejects сохраняет файл отклоненных строк в хранилище
+func (s *loadSource) uploadRejects(ctx context.Context, storage StorageClient, bucket, path string) error {
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write rejects file: %w", err)
+	}
+	size, err := s.rejects.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.rejects.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return storage.UploadFile(ctx, bucket, path, s.rejects, size, "text/csv")
+}
+
+func (s *loadSource) close() {
+	if s.rejects != nil {
+		s.rejects.Close()
+		os.Remove(s.rejects.Name())
+	}
+}
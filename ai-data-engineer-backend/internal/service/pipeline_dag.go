@@ -0,0 +1,281 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// stepRanks порядок стадий: шаг не может зависеть от шага более поздней стадии.
+// Шаги validate допустимы на любой стадии и в проверке порядка не участвуют
+var stepRanks = map[models.StepType]int{
+	models.StepTypeExtract:   0,
+	models.StepTypeTransform: 1,
+	models.StepTypeLoad:      2,
+	models.StepTypeValidate:  -1,
+}
+
+// stepGraph граф зависимостей шагов по их номерам в пайплайне.
+// Повторяющиеся ID и отсутствующие зависимости в граф не попадают
+type stepGraph struct {
+	steps []models.PipelineStep
+	// nodes номера шагов графа в порядке пайплайна
+	nodes      []int
+	deps       [][]int
+	dependents [][]int
+}
+
+// planSteps проверяет, что шаги образуют ациклический граф с допустимым порядком стадий,
+// и раскладывает их по уровням выполнения. План возвращается только без нарушений
+func planSteps(steps []models.PipelineStep) (*models.PipelinePlan, []models.StepProblem) {
+	graph, problems := newStepGraph(steps)
+	levels, placed := graph.levels()
+	if len(placed) < len(graph.nodes) {
+		problems = append(problems, graph.cycles(placed)...)
+	} else {
+		problems = append(problems, graph.orderProblems(levels)...)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	plan := &models.PipelinePlan{Order: []string{}, Levels: make([][]string, len(levels))}
+	for i, level := range levels {
+		plan.Levels[i] = make([]string, len(level))
+		for j, node := range level {
+			plan.Levels[i][j] = steps[node].ID
+		}
+		plan.Order = append(plan.Order, plan.Levels[i]...)
+	}
+	return plan, nil
+}
+
+func newStepGraph(steps []models.PipelineStep) (*stepGraph, []models.StepProblem) {
+	var problems []models.StepProblem
+	graph := &stepGraph{
+		steps:      steps,
+		deps:       make([][]int, len(steps)),
+		dependents: make([][]int, len(steps)),
+	}
+
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if _, ok := stepRanks[step.Type]; !ok {
+			problems = append(problems, models.StepProblem{
+				Code:    models.StepProblemUnknownType,
+				StepID:  step.ID,
+				Message: fmt.Sprintf("неизвестный тип шага %q", step.Type),
+			})
+		}
+		if _, ok := index[step.ID]; ok {
+			problems = append(problems, models.StepProblem{
+				Code:    models.StepProblemDuplicateID,
+				StepID:  step.ID,
+				Message: fmt.Sprintf("ID шага %q повторяется", step.ID),
+			})
+			continue
+		}
+		index[step.ID] = i
+		graph.nodes = append(graph.nodes, i)
+	}
+
+	for _, i := range graph.nodes {
+		seen := make(map[int]bool, len(steps[i].DependsOn))
+		for _, dependency := range steps[i].DependsOn {
+			j, ok := index[dependency]
+			if !ok {
+				problems = append(problems, models.StepProblem{
+					Code:    models.StepProblemMissingDependency,
+					StepID:  steps[i].ID,
+					Steps:   []string{dependency},
+					Message: fmt.Sprintf("шаг %q зависит от несуществующего шага %q", steps[i].ID, dependency),
+				})
+				continue
+			}
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			graph.deps[i] = append(graph.deps[i], j)
+			graph.dependents[j] = append(graph.dependents[j], i)
+		}
+	}
+	return graph, problems
+}
+
+// levels раскладывает шаги по уровням алгоритмом Кана: уровень шага на единицу больше
+// наибольшего уровня его зависимостей. Шаги циклов и зависящие от них не размещаются
+func (g *stepGraph) levels() ([][]int, map[int]bool) {
+	pending := make(map[int]int, len(g.nodes))
+	var level []int
+	for _, node := range g.nodes {
+		pending[node] = len(g.deps[node])
+		if pending[node] == 0 {
+			level = append(level, node)
+		}
+	}
+
+	var levels [][]int
+	placed := make(map[int]bool, len(g.nodes))
+	for len(level) > 0 {
+		levels = append(levels, level)
+		var next []int
+		for _, node := range level {
+			placed[node] = true
+			for _, dependent := range g.dependents[node] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Ints(next)
+		level = next
+	}
+	return levels, placed
+}
+
+// cycles находит циклы среди неразмещенных шагов как сильно связные компоненты (Тарьян)
+func (g *stepGraph) cycles(placed map[int]bool) []models.StepProblem {
+	var problems []models.StepProblem
+	order := make(map[int]int)
+	low := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	counter := 0
+	// first номер первого шага каждого цикла: циклы перечисляются в порядке пайплайна
+	var first []int
+
+	var visit func(node int)
+	visit = func(node int) {
+		order[node], low[node] = counter, counter
+		counter++
+		stack = append(stack, node)
+		onStack[node] = true
+		for _, dependency := range g.deps[node] {
+			if placed[dependency] {
+				continue
+			}
+			if _, seen := order[dependency]; !seen {
+				visit(dependency)
+				low[node] = min(low[node], low[dependency])
+			} else if onStack[dependency] {
+				low[node] = min(low[node], order[dependency])
+			}
+		}
+		if low[node] != order[node] {
+			return
+		}
+
+		var component []int
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		if len(component) == 1 && !g.dependsOn(node, node) {
+			// Шаг не в цикле, а лишь зависит от шага цикла
+			return
+		}
+		sort.Ints(component)
+		first = append(first, component[0])
+		ids := make([]string, len(component))
+		for i, member := range component {
+			ids[i] = g.steps[member].ID
+		}
+		problems = append(problems, models.StepProblem{
+			Code:    models.StepProblemCycle,
+			StepID:  ids[0],
+			Steps:   ids,
+			Message: "шаги образуют цикл: " + strings.Join(ids, ", "),
+		})
+	}
+
+	for _, node := range g.nodes {
+		if _, seen := order[node]; !seen && !placed[node] {
+			visit(node)
+		}
+	}
+	sort.Sort(byPosition{problems, first})
+	return problems
+}
+
+// byPosition упорядочивает нарушения по номерам шагов
+type byPosition struct {
+	problems  []models.StepProblem
+	positions []int
+}
+
+func (b byPosition) Len() int           { return len(b.problems) }
+func (b byPosition) Less(i, j int) bool { return b.positions[i] < b.positions[j] }
+func (b byPosition) Swap(i, j int) {
+	b.problems[i], b.problems[j] = b.problems[j], b.problems[i]
+	b.positions[i], b.positions[j] = b.positions[j], b.positions[i]
+}
+
+// orderProblems проверяет порядок стадий: шаг не выполняется после шага более поздней
+// стадии (extract после load), а при наличии шагов extract каждый transform и load следует за одним из них
+func (g *stepGraph) orderProblems(levels [][]int) []models.StepProblem {
+	var problems []models.StepProblem
+	hasExtract := false
+	for _, node := range g.nodes {
+		hasExtract = hasExtract || g.steps[node].Type == models.StepTypeExtract
+	}
+
+	// latest самый поздний по стадии предшествующий шаг, afterExtract — есть ли extract среди предшествующих
+	latest := make(map[int]int, len(g.nodes))
+	afterExtract := make(map[int]bool, len(g.nodes))
+	for _, level := range levels {
+		for _, node := range level {
+			latest[node] = -1
+			for _, dependency := range g.deps[node] {
+				for _, candidate := range []int{dependency, latest[dependency]} {
+					if candidate >= 0 && (latest[node] < 0 || stepRanks[g.steps[candidate].Type] > stepRanks[g.steps[latest[node]].Type]) {
+						latest[node] = candidate
+					}
+				}
+				afterExtract[node] = afterExtract[node] || afterExtract[dependency] || g.steps[dependency].Type == models.StepTypeExtract
+			}
+		}
+	}
+
+	for _, node := range g.nodes {
+		step := g.steps[node]
+		rank, known := stepRanks[step.Type]
+		if !known || rank < 0 {
+			continue
+		}
+		if previous := latest[node]; previous >= 0 && stepRanks[g.steps[previous].Type] > rank {
+			problems = append(problems, models.StepProblem{
+				Code:   models.StepProblemOrder,
+				StepID: step.ID,
+				Steps:  []string{g.steps[previous].ID},
+				Message: fmt.Sprintf("шаг %q (%s) выполняется после шага %q (%s)",
+					step.ID, step.Type, g.steps[previous].ID, g.steps[previous].Type),
+			})
+		}
+		if hasExtract && step.Type != models.StepTypeExtract && !afterExtract[node] {
+			problems = append(problems, models.StepProblem{
+				Code:    models.StepProblemOrder,
+				StepID:  step.ID,
+				Message: fmt.Sprintf("шаг %q (%s) не зависит ни от одного шага extract", step.ID, step.Type),
+			})
+		}
+	}
+	return problems
+}
+
+func (g *stepGraph) dependsOn(node, dependency int) bool {
+	for _, d := range g.deps[node] {
+		if d == dependency {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,965 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// StepRunner выполняет шаги пайплайна одного типа и возвращает результат шага,
+// который получают зависящие от него шаги
+type StepRunner interface {
+	RunStep(ctx context.Context, run *StepRun) (interface{}, error)
+}
+
+// StepRunnerFunc функция, реализующая StepRunner
+type StepRunnerFunc func(ctx context.Context, run *StepRun) (interface{}, error)
+
+// RunStep вызывает f
+func (f StepRunnerFunc) RunStep(ctx context.Context, run *StepRun) (interface{}, error) {
+	return f(ctx, run)
+}
+
+// StepRun выполняемый шаг пайплайна
+type StepRun struct {
+	ExecutionID string
+	// Pipeline пайплайн на момент запуска выполнения; не изменяется шагами
+	Pipeline   *models.Pipeline
+	Step       models.PipelineStep
+	Parameters map[string]interface{}
+	// Inputs результаты шагов из DependsOn по их ID
+	Inputs map[string]interface{}
+	log    func(level, message string)
+}
+
+// Log добавляет запись в журнал выполнения от имени шага
+func (r *StepRun) Log(level, message string) {
+	r.log(level, message)
+}
+
+// ExecutorOptions параметры выполнения пайплайнов
+type ExecutorOptions struct {
+	// Parallelism максимальное число одновременно выполняемых шагов одного уровня
+	Parallelism int
+	// Instance экземпляр сервиса, который ведет выполнения; по умолчанию единственный экземпляр
+	Instance *Instance
+	// CancelPollInterval период проверки отмены выполнений, запрошенной через другой экземпляр сервиса
+	CancelPollInterval time.Duration
+}
+
+// PipelineExecutor выполняет пайплайны в процессе сервера. Шаги выполняются по уровням
+// плана: шаги уровня запускаются параллельно, следующий уровень начинается после
+// завершения предыдущего. Шаги, зависящие от невыполненного шага, пропускаются.
+// Ограничение времени и повторы шага задаются его конфигурацией (см. stepPolicy).
+// Состояние шагов сохраняется в пайплайне, журнал — в PipelineExecution, ход выполнения
+// публикуется в EventBroker
+type PipelineExecutor struct {
+	pipelines  repository.PipelineRepository
+	executions repository.ExecutionRepository
+	broker     *EventBroker
+	runners    map[models.StepType]StepRunner
+	options    ExecutorOptions
+	logger     logger.Logger
+
+	// mu защищает поля ниже; обращения к репозиториям под mu не выполняются
+	mu sync.Mutex
+	// ctx контекст фоновых задач; отменяется при остановке сервера
+	ctx context.Context
+	// active выполняемые пайплайны по ID пайплайна; nil — запуск зарезервирован, но еще не начат
+	active map[string]*pipelineRun
+}
+
+// pipelineRun состояние одного выполнения. Пайплайн и выполнение изменяются под mu
+type pipelineRun struct {
+	mu        sync.Mutex
+	pipeline  *models.Pipeline
+	snapshot  *models.Pipeline
+	execution *models.PipelineExecution
+	outputs   map[string]interface{}
+	// cancel отменяет контекст выполняемых шагов; cancelled — отмена запрошена пользователем
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// NewPipelineExecutor создает PipelineExecutor. Исполнители шагов регистрируются методом Register
+func NewPipelineExecutor(
+	pipelines repository.PipelineRepository,
+	executions repository.ExecutionRepository,
+	broker *EventBroker,
+	options ExecutorOptions,
+	logger logger.Logger,
+) *PipelineExecutor {
+	if options.Parallelism <= 0 {
+		options.Parallelism = 4
+	}
+	if options.Instance == nil {
+		options.Instance = NewInstance(nil, InstanceOptions{}, logger)
+	}
+	if options.CancelPollInterval <= 0 {
+		options.CancelPollInterval = 5 * time.Second
+	}
+	return &PipelineExecutor{
+		pipelines:  pipelines,
+		executions: executions,
+		broker:     broker,
+		runners:    make(map[models.StepType]StepRunner),
+		options:    options,
+		logger:     logger,
+		ctx:        context.Background(),
+		active:     make(map[string]*pipelineRun),
+	}
+}
+
+// Register задает исполнителя шагов типа stepType
+func (e *PipelineExecutor) Register(stepType models.StepType, runner StepRunner) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runners[stepType] = runner
+}
+
+// Start задает контекст фоновых выполнений, завершает с ошибкой выполнения, прерванные
+// остановкой их экземпляра сервиса, и запускает ожидающие выполнения. Выполнения экземпляров
+// с истекшей арендой проверяются и дальше, раз в срок аренды (см. Instance)
+func (e *PipelineExecutor) Start(ctx context.Context) error {
+	e.mu.Lock()
+	e.ctx = ctx
+	e.mu.Unlock()
+
+	if err := e.recover(ctx, true); err != nil {
+		return err
+	}
+	go e.watchCancellations(ctx)
+	go func() {
+		ticker := time.NewTicker(e.options.Instance.LeaseTTL())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := e.recover(ctx, false); err != nil {
+					e.logger.WithField("error", err.Error()).Error("Failed to recover interrupted pipeline executions")
+				}
+			}
+		}
+	}()
+
+	scheduled, err := e.executions.GetExecutionsByStatus(ctx, models.ExecutionStatusScheduled)
+	if err != nil {
+		return err
+	}
+	dispatched := make(map[string]bool)
+	for _, execution := range scheduled {
+		if !dispatched[execution.PipelineID] {
+			dispatched[execution.PipelineID] = true
+			e.dispatch(execution.PipelineID)
+		}
+	}
+	return nil
+}
+
+// recover завершает выполнения, владелец которых остановился, и их пайплайны: выполнение
+// получает статус failed, а выполнение с запрошенной отменой — cancelled. Прерванные шаги могли
+// выполниться частично, поэтому они не возобновляются. При запуске (restart) прерванными
+// считаются и выполнения с ID этого экземпляра: их вел предыдущий процесс. Выполнения
+// работающих экземпляров и запуски DAG, отслеживание которых возобновляет AirflowService,
+// не затрагиваются
+func (e *PipelineExecutor) recover(ctx context.Context, restart bool) error {
+	const message = "Выполнение прервано остановкой сервера"
+	// Пайплайны читаются до выполнений: пайплайн, запущенный после чтения выполнений,
+	// не попадает в список
+	pipelines, err := e.pipelines.GetPipelinesByStatus(ctx, models.PipelineStatusRunning)
+	if err != nil {
+		return err
+	}
+
+	running := make(map[string]bool)
+	interrupted := make(map[string]*models.PipelineExecution)
+	for _, status := range []models.ExecutionStatus{models.ExecutionStatusRunning, models.ExecutionStatusCancelling} {
+		executions, err := e.executions.GetExecutionsByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		for _, execution := range executions {
+			running[execution.PipelineID] = true
+			if execution.DAGRunID != "" {
+				continue
+			}
+			if execution.Owner == e.options.Instance.ID() {
+				if !restart {
+					continue
+				}
+			} else if alive, err := e.options.Instance.Alive(ctx, execution.Owner); err != nil {
+				return err
+			} else if alive {
+				continue
+			}
+
+			now := time.Now()
+			execution.Status, execution.Error = models.ExecutionStatusFailed, message
+			if status == models.ExecutionStatusCancelling {
+				execution.Status, execution.Error = models.ExecutionStatusCancelled, "выполнение отменено"
+			}
+			execution.CompletedAt = &now
+			stopped, err := e.executions.TransitionExecution(ctx, execution, status)
+			if err != nil {
+				return err
+			}
+			if stopped {
+				interrupted[execution.PipelineID] = execution
+				e.broker.Finish(ExecutionTopic(execution.ID), models.StatusEvent{Status: string(execution.Status), Error: execution.Error})
+				e.logger.WithField("execution_id", execution.ID).WithField("owner", execution.Owner).
+					WithField("status", execution.Status).Warn("Interrupted pipeline execution finished")
+			}
+		}
+	}
+
+	// Пайплайн без выполнения остается в статусе running, если экземпляр остановился между
+	// запуском пайплайна и сохранением выполнения; такой пайплайн завершается по истечении
+	// срока аренды
+	stale := time.Now().Add(-e.options.Instance.LeaseTTL())
+	for _, pipeline := range pipelines {
+		execution := interrupted[pipeline.ID]
+		if execution == nil && (running[pipeline.ID] || pipeline.UpdatedAt.After(stale)) {
+			continue
+		}
+		pipeline.Status = models.PipelineStatusFailed
+		reason := message
+		if execution != nil && execution.Status == models.ExecutionStatusCancelled {
+			pipeline.Status, reason = models.PipelineStatusCancelled, execution.Error
+		}
+		pipeline.UpdatedAt = time.Now()
+		for i := range pipeline.Steps {
+			if pipeline.Steps[i].Status == models.StepStatusRunning {
+				pipeline.Steps[i].Status = models.StepStatusFailed
+				pipeline.Steps[i].Error = reason
+			}
+		}
+		if err := e.pipelines.UpdatePipelineState(ctx, pipeline); err != nil {
+			return err
+		}
+		e.logger.WithField("pipeline_id", pipeline.ID).WithField("status", pipeline.Status).Warn("Interrupted pipeline finished")
+		if !restart {
+			e.dispatch(pipeline.ID)
+		}
+	}
+	return nil
+}
+
+// watchCancellations раз в CancelPollInterval прерывает выполняемые в этом экземпляре запуски,
+// отмену которых запросили через другой экземпляр сервиса
+func (e *PipelineExecutor) watchCancellations(ctx context.Context) {
+	ticker := time.NewTicker(e.options.CancelPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		e.mu.Lock()
+		idle := len(e.active) == 0
+		e.mu.Unlock()
+		if idle {
+			continue
+		}
+		executions, err := e.executions.GetExecutionsByStatus(ctx, models.ExecutionStatusCancelling)
+		if err != nil {
+			e.logger.WithField("error", err.Error()).Error("Failed to load cancelling executions")
+			continue
+		}
+		for _, execution := range executions {
+			e.cancelRun(execution.ID)
+		}
+	}
+}
+
+// Execute запускает выполнение пайплайна пользователя userID в фоне и возвращает выполнение
+// в статусе running. Пустой userID — запуск от имени владельца пайплайна
+func (e *PipelineExecutor) Execute(ctx context.Context, pipelineID, userID string, parameters map[string]interface{}) (*models.PipelineExecution, error) {
+	pipeline, plan, err := e.prepare(ctx, pipelineID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.Status == models.PipelineStatusRunning || !e.reserve(pipelineID) {
+		return nil, models.NewConflictError("Пайплайн уже выполняется")
+	}
+
+	execution := newExecution(pipeline, userID, e.options.Instance.ID(), models.ExecutionStatusRunning, parameters)
+	previous, err := startPipeline(ctx, e.pipelines, pipeline, execution.StartedAt)
+	if err != nil {
+		e.release(pipelineID)
+		return nil, err
+	}
+	if err := e.executions.SaveExecution(ctx, execution); err != nil {
+		pipeline.Status = previous
+		if saveErr := e.pipelines.UpdatePipelineState(context.WithoutCancel(ctx), pipeline); saveErr != nil {
+			e.logger.WithField("error", saveErr.Error()).WithField("pipeline_id", pipelineID).Error("Failed to save pipeline state")
+		}
+		e.release(pipelineID)
+		return nil, err
+	}
+	return e.begin(pipeline, plan, execution), nil
+}
+
+// Enqueue ставит выполнение пайплайна в очередь в статусе scheduled. Выполнения одного
+// пайплайна запускаются по очереди: следующее начинается после завершения текущего.
+// Очередь хранится в ExecutionRepository и переживает перезапуск сервера.
+// Пустой userID — запуск от имени владельца пайплайна
+func (e *PipelineExecutor) Enqueue(ctx context.Context, pipelineID, userID string, parameters map[string]interface{}) (*models.PipelineExecution, error) {
+	pipeline, _, err := e.prepare(ctx, pipelineID, userID)
+	if err != nil {
+		return nil, err
+	}
+	execution := newExecution(pipeline, userID, e.options.Instance.ID(), models.ExecutionStatusScheduled, parameters)
+	if err := e.executions.SaveExecution(ctx, execution); err != nil {
+		return nil, err
+	}
+	e.broker.Publish(ExecutionTopic(execution.ID), models.EventTypeStatus, models.StatusEvent{Status: string(execution.Status)})
+	e.logger.WithField("pipeline_id", pipelineID).WithField("execution_id", execution.ID).Info("Pipeline execution scheduled")
+
+	queued := *execution
+	queued.Logs = nil
+	e.dispatch(pipelineID)
+	return &queued, nil
+}
+
+// RecordFailure сохраняет выполнение, которое не удалось поставить в очередь, со статусом
+// failed: запуск по расписанию уже сдвинут и не повторится, поэтому остается в истории
+// пайплайна с причиной ошибки
+func (e *PipelineExecutor) RecordFailure(ctx context.Context, pipeline *models.Pipeline, userID string, parameters map[string]interface{}, cause error) (*models.PipelineExecution, error) {
+	execution := newExecution(pipeline, userID, e.options.Instance.ID(), models.ExecutionStatusFailed, parameters)
+	execution.Error = errorMessage(cause)
+	execution.CompletedAt = &execution.StartedAt
+	if err := e.executions.SaveExecution(ctx, execution); err != nil {
+		return nil, err
+	}
+	e.broker.Finish(ExecutionTopic(execution.ID), models.StatusEvent{Status: string(execution.Status), Error: execution.Error})
+	e.logger.WithField("pipeline_id", pipeline.ID).WithField("execution_id", execution.ID).
+		WithField("error", execution.Error).Warn("Pipeline execution failed to enqueue")
+	return execution, nil
+}
+
+// dispatch запускает самое раннее ожидающее выполнение пайплайна, если пайплайн
+// не выполняется. Выполнение пайплайна, ставшего непригодным к запуску, завершается ошибкой
+func (e *PipelineExecutor) dispatch(pipelineID string) {
+	e.mu.Lock()
+	ctx := e.ctx
+	e.mu.Unlock()
+	// Очередь читается после резервирования: выполнение, запущенное параллельным dispatch,
+	// к этому моменту уже не в статусе scheduled
+	if ctx.Err() != nil || !e.reserve(pipelineID) {
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			e.release(pipelineID)
+		}
+	}()
+	log := e.logger.WithField("pipeline_id", pipelineID)
+
+	scheduled, err := e.executions.GetExecutionsByStatus(ctx, models.ExecutionStatusScheduled)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to load scheduled executions")
+		return
+	}
+	var execution *models.PipelineExecution
+	for _, candidate := range scheduled {
+		if candidate.PipelineID == pipelineID && (execution == nil || candidate.StartedAt.Before(execution.StartedAt)) {
+			execution = candidate
+		}
+	}
+	if execution == nil {
+		return
+	}
+	log = log.WithField("execution_id", execution.ID)
+
+	pipeline, plan, err := e.prepare(ctx, pipelineID, "")
+	if err == nil && pipeline.Status == models.PipelineStatusRunning {
+		// Пайплайн выполняет другой экземпляр сервиса; он запустит очередь после завершения
+		return
+	}
+	claimed := false
+	if err == nil {
+		queuedAt, queuedBy := execution.StartedAt, execution.Owner
+		execution.Status = models.ExecutionStatusRunning
+		execution.StartedAt = time.Now()
+		execution.Owner = e.options.Instance.ID()
+		claimed, err = e.executions.TransitionExecution(ctx, execution, models.ExecutionStatusScheduled)
+		if err == nil && !claimed {
+			// Выполнение отменено или запущено параллельно
+			return
+		}
+		if err == nil {
+			_, err = startPipeline(ctx, e.pipelines, pipeline, execution.StartedAt)
+			if appErr, ok := models.IsAppError(err); ok && appErr.Code == models.ErrorCodeConflict {
+				// Пайплайн запущен другим экземпляром сервиса или в Airflow: выполнение
+				// возвращается в очередь
+				execution.Status, execution.StartedAt, execution.Owner = models.ExecutionStatusScheduled, queuedAt, queuedBy
+				requeued, err := e.executions.TransitionExecution(context.WithoutCancel(ctx), execution, models.ExecutionStatusRunning)
+				if err == nil && !requeued {
+					// Отмена запрошена до начала выполнения
+					now := time.Now()
+					execution.Status, execution.Error, execution.CompletedAt = models.ExecutionStatusCancelled, "выполнение отменено", &now
+					var cancelled bool
+					if cancelled, err = e.executions.TransitionExecution(context.WithoutCancel(ctx), execution, models.ExecutionStatusCancelling); cancelled {
+						e.broker.Finish(ExecutionTopic(execution.ID), models.StatusEvent{Status: string(execution.Status), Error: execution.Error})
+					}
+				}
+				if err != nil {
+					log.WithField("error", err.Error()).Error("Failed to requeue pipeline execution")
+				}
+				return
+			}
+		}
+		if err == nil {
+			e.begin(pipeline, plan, execution)
+			started = true
+		}
+	}
+	if err != nil {
+		now := time.Now()
+		execution.Status = models.ExecutionStatusFailed
+		execution.Error = errorMessage(err)
+		execution.CompletedAt = &now
+		// Не захваченное выполнение по-прежнему ждет в очереди и могло быть отменено параллельно
+		var saveErr error
+		if claimed {
+			saveErr = e.executions.UpdateExecution(context.WithoutCancel(ctx), execution)
+		} else if ok, transitionErr := e.executions.TransitionExecution(context.WithoutCancel(ctx), execution, models.ExecutionStatusScheduled); transitionErr != nil {
+			saveErr = transitionErr
+		} else if !ok {
+			return
+		}
+		if saveErr != nil {
+			log.WithField("error", saveErr.Error()).Error("Failed to save pipeline execution")
+		}
+		e.broker.Finish(ExecutionTopic(execution.ID), models.StatusEvent{Status: string(execution.Status), Error: execution.Error})
+		log.WithField("error", execution.Error).Warn("Scheduled pipeline execution failed to start")
+	}
+}
+
+// prepare загружает пайплайн и проверяет, что его можно выполнить от имени пользователя userID.
+// Пустой userID — запуск от имени владельца
+func (e *PipelineExecutor) prepare(ctx context.Context, pipelineID, userID string) (*models.Pipeline, *models.PipelinePlan, error) {
+	pipeline, err := e.pipelines.GetPipeline(ctx, pipelineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userID != "" && pipeline.UserID != userID {
+		return nil, nil, errPipelineForbidden()
+	}
+	if pipeline.Status == models.PipelineStatusDraft {
+		return nil, nil, models.NewValidationError("Пайплайн не готов к выполнению: профиль анализа еще не построен", map[string]interface{}{
+			"pipeline_id": pipelineID, "status": pipeline.Status,
+		})
+	}
+	plan, problems := planSteps(pipeline.Steps)
+	if len(problems) > 0 {
+		return nil, nil, stepsError(problems)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, step := range pipeline.Steps {
+		if _, ok := e.runners[step.Type]; !ok {
+			return nil, nil, models.NewValidationError("Нет исполнителя для шага", map[string]interface{}{
+				"step_id": step.ID, "type": step.Type,
+			})
+		}
+	}
+	return pipeline, plan, nil
+}
+
+// reserve отмечает пайплайн выполняемым в этом процессе до начала выполнения.
+// false — пайплайн уже выполняется или его запуск зарезервирован
+func (e *PipelineExecutor) reserve(pipelineID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, running := e.active[pipelineID]; running {
+		return false
+	}
+	e.active[pipelineID] = nil
+	return true
+}
+
+// release снимает резервирование пайплайна, выполнение которого не началось
+func (e *PipelineExecutor) release(pipelineID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.active[pipelineID] == nil {
+		delete(e.active, pipelineID)
+	}
+}
+
+// startPipeline переводит пайплайн в статус running со сброшенными шагами условным обновлением
+// репозитория и возвращает прежний статус. Им запускают пайплайн и PipelineExecutor, и
+// AirflowService, поэтому пайплайн, уже запущенный любым из них, получает ConflictError
+func startPipeline(ctx context.Context, pipelines repository.PipelineRepository, pipeline *models.Pipeline, now time.Time) (models.PipelineStatus, error) {
+	previous := pipeline.Status
+	pipeline.Status = models.PipelineStatusRunning
+	pipeline.ExecutedAt = &now
+	pipeline.UpdatedAt = now
+	for i := range pipeline.Steps {
+		step := &pipeline.Steps[i]
+		step.Status = models.StepStatusPending
+		step.StartedAt, step.CompletedAt, step.Error = nil, nil, ""
+	}
+	if err := pipelines.StartPipeline(ctx, pipeline); err != nil {
+		pipeline.Status = previous
+		return previous, err
+	}
+	return previous, nil
+}
+
+// begin запускает сохраненное выполнение пайплайна, переведенного startPipeline в статус running,
+// в фоне и возвращает копию выполнения на момент запуска. Запуск должен быть зарезервирован reserve
+func (e *PipelineExecutor) begin(pipeline *models.Pipeline, plan *models.PipelinePlan, execution *models.PipelineExecution) *models.PipelineExecution {
+	snapshot := *pipeline
+	snapshot.Steps = append([]models.PipelineStep(nil), pipeline.Steps...)
+	e.mu.Lock()
+	runCtx, cancel := context.WithCancel(e.ctx)
+	run := &pipelineRun{
+		pipeline:  pipeline,
+		snapshot:  &snapshot,
+		execution: execution,
+		outputs:   make(map[string]interface{}),
+		cancel:    cancel,
+	}
+	e.active[pipeline.ID] = run
+	e.mu.Unlock()
+	started := *execution
+	started.Logs = nil
+
+	e.broker.Publish(ExecutionTopic(execution.ID), models.EventTypeStatus, models.StatusEvent{Status: string(execution.Status)})
+	e.logger.WithField("pipeline_id", pipeline.ID).WithField("execution_id", execution.ID).Info("Pipeline execution started")
+	go e.run(runCtx, run, plan.Levels)
+	return &started
+}
+
+// newExecution создает выполнение пайплайна, которое ведет экземпляр owner; без userID
+// выполнение принадлежит владельцу пайплайна
+func newExecution(pipeline *models.Pipeline, userID, owner string, status models.ExecutionStatus, parameters map[string]interface{}) *models.PipelineExecution {
+	if userID == "" {
+		userID = pipeline.UserID
+	}
+	return &models.PipelineExecution{
+		ID:         uuid.New().String(),
+		PipelineID: pipeline.ID,
+		UserID:     userID,
+		Status:     status,
+		Parameters: parameters,
+		StartedAt:  time.Now(),
+		Logs:       []models.ExecutionLog{},
+		Owner:      owner,
+	}
+}
+
+// Cancel отменяет выполнение пользователя userID. Ожидающее в очереди выполнение отменяется
+// без запуска. Для выполняемого сохраняется статус cancelling: экземпляр сервиса, который ведет
+// выполнение, прерывает шаги (см. watchCancellations), а запуск DAG завершает через API Airflow
+// (см. AirflowService). Выполнение этого экземпляра прерывается сразу; выполнение и пайплайн
+// получают статус cancelled, когда выполняемые шаги остановятся
+func (e *PipelineExecutor) Cancel(ctx context.Context, userID, executionID string) error {
+	execution, err := e.GetExecution(ctx, userID, executionID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		switch execution.Status {
+		case models.ExecutionStatusScheduled:
+			now := time.Now()
+			execution.Status = models.ExecutionStatusCancelled
+			execution.Error = "выполнение отменено"
+			execution.CompletedAt = &now
+			cancelled, err := e.executions.TransitionExecution(ctx, execution, models.ExecutionStatusScheduled)
+			if err != nil {
+				return err
+			}
+			if cancelled {
+				e.broker.Finish(ExecutionTopic(execution.ID), models.StatusEvent{Status: string(execution.Status), Error: execution.Error})
+				e.logger.WithField("execution_id", executionID).Info("Scheduled pipeline execution cancelled")
+				return nil
+			}
+		case models.ExecutionStatusRunning:
+			execution.Status = models.ExecutionStatusCancelling
+			requested, err := e.executions.TransitionExecution(ctx, execution, models.ExecutionStatusRunning)
+			if err != nil {
+				return err
+			}
+			if requested {
+				e.broker.Publish(ExecutionTopic(execution.ID), models.EventTypeStatus, models.StatusEvent{Status: string(execution.Status)})
+				e.logger.WithField("execution_id", executionID).WithField("owner", execution.Owner).Info("Pipeline execution cancellation requested")
+				e.cancelRun(executionID)
+				return nil
+			}
+		case models.ExecutionStatusCancelling:
+			e.cancelRun(executionID)
+			return nil
+		default:
+			return models.NewConflictError("Выполнение уже завершено")
+		}
+		// Статус изменился параллельно: выполнение запущено, отменено или завершено
+		if execution, err = e.executions.GetExecution(ctx, executionID); err != nil {
+			return err
+		}
+	}
+}
+
+// cancelRun прерывает выполняемый в этом экземпляре запуск выполнения executionID: выполняемые
+// шаги получают отмену контекста, оставшиеся пропускаются
+func (e *PipelineExecutor) cancelRun(executionID string) {
+	run := e.activeRun(executionID)
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	requested := run.cancelled
+	run.cancelled = true
+	run.mu.Unlock()
+	if !requested {
+		e.log(run, models.LogLevelWarn, "", "Отмена выполнения запрошена")
+	}
+	run.cancel()
+}
+
+// activeRun возвращает выполняемый в этом экземпляре запуск выполнения executionID
+func (e *PipelineExecutor) activeRun(executionID string) *pipelineRun {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, run := range e.active {
+		if run != nil && run.execution.ID == executionID {
+			return run
+		}
+	}
+	return nil
+}
+
+// GetExecution возвращает выполнение пользователя по ID
+func (e *PipelineExecutor) GetExecution(ctx context.Context, userID, id string) (*models.PipelineExecution, error) {
+	execution, err := e.executions.GetExecution(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if execution.UserID != userID {
+		return nil, models.NewAppError(models.ErrorCodeForbidden, "Нет доступа к выполнению", http.StatusForbidden)
+	}
+	return execution, nil
+}
+
+// ListExecutions возвращает выполнения пайплайна пользователя, начиная с последних
+func (e *PipelineExecutor) ListExecutions(ctx context.Context, userID, pipelineID string, limit, offset int) ([]*models.PipelineExecution, error) {
+	pipeline, err := e.pipelines.GetPipeline(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.UserID != userID {
+		return nil, errPipelineForbidden()
+	}
+	return e.executions.GetExecutionsByPipeline(ctx, pipelineID, limit, offset)
+}
+
+// run выполняет уровни плана и сохраняет итог выполнения
+func (e *PipelineExecutor) run(ctx context.Context, run *pipelineRun, levels [][]string) {
+	defer func() {
+		run.cancel()
+		e.mu.Lock()
+		delete(e.active, run.pipeline.ID)
+		e.mu.Unlock()
+		e.dispatch(run.pipeline.ID)
+	}()
+	e.log(run, models.LogLevelInfo, "", fmt.Sprintf("Выполнение начато: шагов %d, уровней %d", len(run.pipeline.Steps), len(levels)))
+
+	var failure string
+	for _, level := range levels {
+		var runnable []string
+		for _, id := range level {
+			if reason := e.blocked(ctx, run, id); reason != "" {
+				e.skipStep(run, id, reason)
+				continue
+			}
+			runnable = append(runnable, id)
+		}
+
+		sem := make(chan struct{}, e.options.Parallelism)
+		errs := make([]error, len(runnable))
+		var wg sync.WaitGroup
+		for i, id := range runnable {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, id string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				errs[i] = e.runStep(ctx, run, id)
+			}(i, id)
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil && failure == "" && ctx.Err() == nil {
+				failure = fmt.Sprintf("Шаг %q завершился ошибкой: %s", runnable[i], errorMessage(err))
+			}
+		}
+	}
+	e.finish(ctx, run, failure)
+}
+
+// blocked возвращает причину, по которой шаг не выполняется: выполнение остановлено
+// или одна из зависимостей не выполнена. Пустая строка — шаг можно выполнять
+func (e *PipelineExecutor) blocked(ctx context.Context, run *pipelineRun, id string) string {
+	if ctx.Err() != nil {
+		return run.stopReason()
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	step, ok := run.step(id)
+	if !ok {
+		return fmt.Sprintf("шаг %q отсутствует в пайплайне", id)
+	}
+	for _, id := range step.DependsOn {
+		if dependency, ok := run.step(id); !ok || dependency.Status != models.StepStatusCompleted {
+			return fmt.Sprintf("шаг %q не выполнен", id)
+		}
+	}
+	return ""
+}
+
+// runStep выполняет шаг исполнителем его типа
+func (e *PipelineExecutor) runStep(ctx context.Context, run *pipelineRun, id string) error {
+	run.mu.Lock()
+	step, ok := run.step(id)
+	if !ok {
+		run.mu.Unlock()
+		return models.NewInternalError(fmt.Sprintf("Шаг %q отсутствует в пайплайне", id), nil)
+	}
+	now := time.Now()
+	step.Status = models.StepStatusRunning
+	step.StartedAt = &now
+	stepRun := &StepRun{
+		ExecutionID: run.execution.ID,
+		Pipeline:    run.snapshot,
+		Step:        *step,
+		Parameters:  run.execution.Parameters,
+		Inputs:      make(map[string]interface{}, len(step.DependsOn)),
+		log:         func(level, message string) { e.log(run, level, id, message) },
+	}
+	for _, dependency := range step.DependsOn {
+		stepRun.Inputs[dependency] = run.outputs[dependency]
+	}
+	run.mu.Unlock()
+
+	e.stepEvent(run, stepRun.Step, "Шаг начат")
+	e.save(ctx, run)
+
+	output, err := e.attempt(ctx, stepRun)
+	if err != nil && ctx.Err() != nil {
+		err = models.NewAppErrorWithCause(models.ErrorCodeExecutionFailed, "Шаг прерван: "+run.stopReason(), http.StatusConflict, err)
+	}
+
+	run.mu.Lock()
+	// Шаги выполнения не меняются после запуска: шаг найден при старте
+	step, _ = run.step(id)
+	completed := time.Now()
+	step.CompletedAt = &completed
+	if err != nil {
+		step.Status = models.StepStatusFailed
+		step.Error = errorMessage(err)
+	} else {
+		step.Status = models.StepStatusCompleted
+		run.outputs[id] = output
+	}
+	finished := *step
+	run.mu.Unlock()
+
+	if err != nil {
+		e.log(run, models.LogLevelError, id, "Шаг завершился ошибкой: "+finished.Error)
+	} else {
+		e.log(run, models.LogLevelInfo, id, fmt.Sprintf("Шаг выполнен за %s", completed.Sub(now).Round(time.Millisecond)))
+	}
+	e.stepEvent(run, finished, finished.Error)
+	e.save(ctx, run)
+	return err
+}
+
+// attempt выполняет шаг с ограничением времени и повторами из его конфигурации.
+// Ошибка класса, не указанного в retry_on, и отмена выполнения не повторяются
+func (e *PipelineExecutor) attempt(ctx context.Context, run *StepRun) (interface{}, error) {
+	policy, err := newStepPolicy(run.Step)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	runner := e.runners[run.Step.Type]
+	e.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.timeout)
+		}
+		output, err := runStepSafely(attemptCtx, runner, run)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return output, err
+		}
+
+		class := stepErrorClass(err)
+		if class == StepErrorTimeout && policy.timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+			err = models.NewAppErrorWithCause(models.ErrorCodeExecutionFailed,
+				fmt.Sprintf("Шаг не завершился за %s", policy.timeout), http.StatusGatewayTimeout, err)
+		}
+		if attempt >= policy.maxAttempts || !policy.retryable(class) {
+			return nil, err
+		}
+
+		delay := policy.delay(attempt)
+		run.Log(models.LogLevelWarn, fmt.Sprintf("Попытка %d из %d завершилась ошибкой (%s): %s; повтор через %s",
+			attempt, policy.maxAttempts, class, errorMessage(err), delay))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// runStepSafely вызывает исполнителя и превращает panic в ошибку шага
+func runStepSafely(ctx context.Context, runner StepRunner, run *StepRun) (output interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = models.NewInternalError(fmt.Sprintf("Сбой исполнителя шага: %v", recovered), nil)
+		}
+	}()
+	return runner.RunStep(ctx, run)
+}
+
+// skipStep помечает шаг пропущенным
+func (e *PipelineExecutor) skipStep(run *pipelineRun, id, reason string) {
+	run.mu.Lock()
+	step, ok := run.step(id)
+	if !ok {
+		run.mu.Unlock()
+		e.log(run, models.LogLevelWarn, id, "Шаг пропущен: "+reason)
+		return
+	}
+	step.Status = models.StepStatusSkipped
+	skipped := *step
+	run.mu.Unlock()
+
+	e.log(run, models.LogLevelWarn, id, "Шаг пропущен: "+reason)
+	e.stepEvent(run, skipped, reason)
+}
+
+// finish сохраняет итоговый статус выполнения и пайплайна и закрывает поток событий
+func (e *PipelineExecutor) finish(ctx context.Context, run *pipelineRun, failure string) {
+	status, pipelineStatus := models.ExecutionStatusCompleted, models.PipelineStatusCompleted
+	level, message := models.LogLevelInfo, "Выполнение завершено"
+	switch {
+	case run.isCancelled():
+		status, pipelineStatus = models.ExecutionStatusCancelled, models.PipelineStatusCancelled
+		failure = run.stopReason()
+		level, message = models.LogLevelWarn, "Выполнение отменено"
+	case ctx.Err() != nil:
+		failure = run.stopReason()
+		fallthrough
+	case failure != "":
+		status, pipelineStatus = models.ExecutionStatusFailed, models.PipelineStatusFailed
+		level, message = models.LogLevelError, "Выполнение завершилось ошибкой: "+failure
+	}
+	e.log(run, level, "", message)
+
+	run.mu.Lock()
+	now := time.Now()
+	run.execution.Status = status
+	run.execution.Error = failure
+	run.execution.CompletedAt = &now
+	run.pipeline.Status = pipelineStatus
+	run.pipeline.UpdatedAt = now
+	run.mu.Unlock()
+	e.save(ctx, run)
+
+	log := e.logger.WithField("pipeline_id", run.pipeline.ID).WithField("execution_id", run.execution.ID)
+	if failure != "" {
+		log.WithField("error", failure).Warn("Pipeline execution failed")
+	} else {
+		log.Info("Pipeline execution completed")
+	}
+	e.broker.Finish(ExecutionTopic(run.execution.ID), models.StatusEvent{Status: string(status), Error: failure})
+}
+
+// log добавляет запись в журнал выполнения и публикует ее
+func (e *PipelineExecutor) log(run *pipelineRun, level, stepID, message string) {
+	entry := models.ExecutionLog{Timestamp: time.Now(), Level: level, Message: message, StepID: stepID}
+	run.mu.Lock()
+	run.execution.Logs = append(run.execution.Logs, entry)
+	run.mu.Unlock()
+	e.broker.Publish(ExecutionTopic(run.execution.ID), models.EventTypeLog, entry)
+}
+
+// stepEvent публикует смену статуса шага
+func (e *PipelineExecutor) stepEvent(run *pipelineRun, step models.PipelineStep, message string) {
+	e.broker.Publish(ExecutionTopic(run.execution.ID), models.EventTypeStage, models.StageEvent{
+		Stage:   string(step.Status),
+		Message: message,
+		StepID:  step.ID,
+	})
+}
+
+// save сохраняет состояние шагов и журнал, в том числе после отмены ctx
+func (e *PipelineExecutor) save(ctx context.Context, run *pipelineRun) {
+	ctx = context.WithoutCancel(ctx)
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if err := e.pipelines.UpdatePipelineState(ctx, run.pipeline); err != nil {
+		e.logger.WithField("error", err.Error()).WithField("pipeline_id", run.pipeline.ID).Error("Failed to save pipeline state")
+	}
+	if err := e.executions.UpdateExecution(ctx, run.execution); err != nil {
+		e.logger.WithField("error", err.Error()).WithField("execution_id", run.execution.ID).Error("Failed to save pipeline execution")
+	}
+}
+
+func (r *pipelineRun) isCancelled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelled
+}
+
+// stopReason причина остановки выполнения: отмена пользователем или остановка сервера
+func (r *pipelineRun) stopReason() string {
+	if r.isCancelled() {
+		return "выполнение отменено"
+	}
+	return "выполнение прервано остановкой сервера"
+}
+
+// step возвращает шаг пайплайна по ID. Вызывается под run.mu
+func (r *pipelineRun) step(id string) (*models.PipelineStep, bool) {
+	for i := range r.pipeline.Steps {
+		if r.pipeline.Steps[i].ID == id {
+			return &r.pipeline.Steps[i], true
+		}
+	}
+	return nil, false
+}
+
+// errorMessage текст ошибки для пользователя
+func errorMessage(err error) string {
+	if appErr, ok := models.IsAppError(err); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// RegisterDefaultRunners регистрирует исполнителей шагов по умолчанию:
+// extract проверяет анализ источника, transform задает сопоставление колонок,
+// load загружает файл в целевую таблицу через LoadService, validate проверяет результат загрузки
+func RegisterDefaultRunners(executor *PipelineExecutor, analyses repository.AnalysisRepository, loads *LoadService) {
+	executor.Register(models.StepTypeExtract, &extractRunner{analyses: analyses})
+	executor.Register(models.StepTypeTransform, StepRunnerFunc(runTransform))
+	executor.Register(models.StepTypeLoad, &loadRunner{loads: loads})
+	executor.Register(models.StepTypeValidate, StepRunnerFunc(runValidate))
+}
+
+// extractRunner проверяет, что анализ источника завершен и содержит профиль.
+// Результат шага — анализ источника
+type extractRunner struct {
+	analyses repository.AnalysisRepository
+}
+
+func (r *extractRunner) RunStep(ctx context.Context, run *StepRun) (interface{}, error) {
+	analysisID, err := sourceAnalysisID(run.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := r.analyses.GetAnalysis(ctx, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.Status != models.AnalysisStatusCompleted || analysis.Profile == nil {
+		return nil, models.NewValidationError("Анализ источника не завершен", map[string]interface{}{
+			"analysis_id": analysisID, "status": analysis.Status,
+		})
+	}
+	run.Log(models.LogLevelInfo, fmt.Sprintf("Источник %s: строк %d, колонок %d",
+		analysis.FilePath, analysis.Profile.TotalRows, len(analysis.Profile.Fields)))
+	return analysis, nil
+}
+
+// runTransform задает сопоставление колонок таблицы с колонками файла из config.mapping.
+// Значения приводятся к типам колонок при загрузке, поэтому результат шага — сопоставление,
+// которое применяет зависящий от него шаг load
+func runTransform(ctx context.Context, run *StepRun) (interface{}, error) {
+	mapping, err := stepMapping(run.Step)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range run.Inputs {
+		analysis, ok := input.(*models.AnalysisResult)
+		if !ok {
+			continue
+		}
+		fields := make(map[string]bool, len(analysis.Profile.Fields))
+		for _, field := range analysis.Profile.Fields {
+			fields[field.Name] = true
+		}
+		for column, source := range mapping {
+			if !fields[source] {
+				return nil, models.NewValidationError("Колонка сопоставления отсутствует в файле", map[string]interface{}{
+					"column": column, "source": source,
+				})
+			}
+		}
+	}
+	run.Log(models.LogLevelInfo, fmt.Sprintf("Сопоставлено колонок: %d", len(mapping)))
+	return mapping, nil
+}
+
+// loadRunner загружает файл анализа в целевую таблицу пайплайна.
+// Сопоставление колонок берется из предшествующих шагов transform и config.mapping шага;
+// config.max_rejects, config.batch_size и config.idempotency_key передаются в LoadRequest
+type loadRunner struct {
+	loads *LoadService
+}
+
+func (r *loadRunner) RunStep(ctx context.Context, run *StepRun) (interface{}, error) {
+	analysisID, err := sourceAnalysisID(run.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+	target := run.Pipeline.Target
+	req := &models.LoadRequest{
+		AnalysisID: analysisID,
+		Connection: target.ConnectionString,
+		TableName:  target.TableName,
+	}
+	if target.Schema != "" {
+		req.TableName = target.Schema + "." + target.TableName
+	}
+
+	// Сопоставление из config.mapping шага имеет приоритет над сопоставлением шагов transform
+	mapping, err := stepMapping(run.Step)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range run.Inputs {
+		inherited, _ := input.(map[string]string)
+		for column, source := range inherited {
+			if _, ok := mapping[column]; !ok {
+				mapping[column] = source
+			}
+		}
+	}
+	if len(mapping) > 0 {
+		req.Mapping = mapping
+	}
+
+	maxRejects, err := stepConfigInt(run.Step, "max_rejects")
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := stepConfigInt(run.Step, "batch_size")
+	if err != nil {
+		return nil, err
+	}
+	req.MaxRejects, req.BatchSize = maxRejects, int(batchSize)
+	if key, ok := run.Step.Config["idempotency_key"].(string); ok {
+		req.IdempotencyKey = key
+	}
+
+	result, err := r.loads.Load(ctx, run.Pipeline.UserID, req)
+	if err != nil {
+		return nil, err
+	}
+	run.Log(models.LogLevelInfo, fmt.Sprintf("Загружено строк в %s: %d, отклонено: %d",
+		req.TableName, result.RowsLoaded, result.RowsRejected))
+	if result.RowsRejected > 0 {
+		run.Log(models.LogLevelWarn, fmt.Sprintf("Отклоненные строки сохранены в %s/%s", result.RejectsBucket, result.RejectsPath))
+	}
+	return result, nil
+}
+
+// runValidate проверяет результаты предшествующих шагов load: загружено не меньше
+// config.min_rows строк и отклонено не больше config.max_rejected
+func runValidate(ctx context.Context, run *StepRun) (interface{}, error) {
+	minRows, err := stepConfigInt(run.Step, "min_rows")
+	if err != nil {
+		return nil, err
+	}
+	maxRejected, err := stepConfigInt(run.Step, "max_rejected")
+	if err != nil {
+		return nil, err
+	}
+	_, limitRejected := run.Step.Config["max_rejected"]
+
+	checked := 0
+	for id, input := range run.Inputs {
+		result, ok := input.(*models.LoadResult)
+		if !ok {
+			continue
+		}
+		checked++
+		if result.RowsLoaded < minRows {
+			return nil, models.NewValidationError("Загружено меньше строк, чем ожидалось", map[string]interface{}{
+				"step_id": id, "rows_loaded": result.RowsLoaded, "min_rows": minRows,
+			})
+		}
+		if limitRejected && result.RowsRejected > maxRejected {
+			return nil, models.NewValidationError("Отклонено больше строк, чем допустимо", map[string]interface{}{
+				"step_id": id, "rows_rejected": result.RowsRejected, "max_rejected": maxRejected,
+			})
+		}
+	}
+	if checked == 0 {
+		run.Log(models.LogLevelWarn, "Нет результатов загрузки для проверки")
+		return nil, nil
+	}
+	run.Log(models.LogLevelInfo, fmt.Sprintf("Проверено загрузок: %d", checked))
+	return nil, nil
+}
+
+// sourceAnalysisID возвращает анализ, по которому построен источник пайплайна
+func sourceAnalysisID(pipeline *models.Pipeline) (string, error) {
+	analysisID, _ := pipeline.Source.Config["analysis_id"].(string)
+	if analysisID == "" {
+		return "", models.NewValidationError("Источник пайплайна не связан с анализом", map[string]interface{}{
+			"pipeline_id": pipeline.ID,
+		})
+	}
+	return analysisID, nil
+}
+
+// stepMapping читает config.mapping шага: колонка таблицы → колонка файла
+func stepMapping(step models.PipelineStep) (map[string]string, error) {
+	mapping := make(map[string]string)
+	raw, ok := step.Config["mapping"]
+	if !ok {
+		return mapping, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, stepConfigError(step, "mapping", "ожидается объект колонка → колонка файла")
+	}
+	for column, value := range values {
+		source, ok := value.(string)
+		if !ok || source == "" {
+			return nil, stepConfigError(step, "mapping", fmt.Sprintf("колонка %q: ожидается имя колонки файла", column))
+		}
+		mapping[column] = source
+	}
+	return mapping, nil
+}
+
+// stepConfigInt читает неотрицательное целое из конфигурации шага; отсутствующее значение — 0
+func stepConfigInt(step models.PipelineStep, key string) (int64, error) {
+	raw, ok := step.Config[key]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	var value float64
+	switch v := raw.(type) {
+	case float64:
+		value = v
+	case int:
+		value = float64(v)
+	case int64:
+		value = float64(v)
+	default:
+		return 0, stepConfigError(step, key, "ожидается число")
+	}
+	if value < 0 || value != float64(int64(value)) {
+		return 0, stepConfigError(step, key, "ожидается неотрицательное целое")
+	}
+	return int64(value), nil
+}
+
+func stepConfigError(step models.PipelineStep, key, message string) error {
+	return models.NewValidationError("Некорректная конфигурация шага", map[string]interface{}{
+		"step_id": step.ID, "key": key, "error": message,
+	})
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/cron"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// schedulerLease имя аренды, которую удерживает экземпляр сервиса, запускающий пайплайны по расписанию
+const schedulerLease = "pipeline-scheduler"
+
+// SchedulerOptions параметры запуска пайплайнов по расписанию
+type SchedulerOptions struct {
+	// Interval период проверки расписаний
+	Interval time.Duration
+	// LeaseTTL срок аренды планировщика; должен быть больше Interval
+	LeaseTTL time.Duration
+	// CatchUp обработка пропущенных запусков для расписаний без catch_up
+	CatchUp models.CatchUpPolicy
+	// MaxCatchUp максимальное число пропущенных запусков, выполняемых при catch_up = all
+	MaxCatchUp int
+	// Holder имя экземпляра сервиса в аренде; по умолчанию имя хоста и случайный суффикс
+	Holder string
+}
+
+// PipelineScheduler ставит в очередь выполнения пайплайнов по их расписаниям.
+// Расписания и время следующего запуска хранятся в пайплайнах, поэтому переживают
+// перезапуск. Из нескольких экземпляров сервиса расписания проверяет тот, кто удерживает
+// аренду, а сдвиг времени следующего запуска выполняется сравнением с ожидаемым значением,
+// так что один запуск не ставится в очередь дважды
+type PipelineScheduler struct {
+	pipelines repository.PipelineRepository
+	leases    repository.LeaseRepository
+	executor  *PipelineExecutor
+	options   SchedulerOptions
+	logger    logger.Logger
+}
+
+// NewPipelineScheduler создает PipelineScheduler
+func NewPipelineScheduler(
+	pipelines repository.PipelineRepository,
+	leases repository.LeaseRepository,
+	executor *PipelineExecutor,
+	options SchedulerOptions,
+	logger logger.Logger,
+) *PipelineScheduler {
+	if options.Interval <= 0 {
+		options.Interval = 30 * time.Second
+	}
+	if options.LeaseTTL <= options.Interval {
+		options.LeaseTTL = 4 * options.Interval
+	}
+	switch options.CatchUp {
+	case models.CatchUpNone, models.CatchUpLatest, models.CatchUpAll:
+	default:
+		options.CatchUp = models.CatchUpLatest
+	}
+	if options.MaxCatchUp <= 0 {
+		options.MaxCatchUp = 10
+	}
+	if options.Holder == "" {
+		host, _ := os.Hostname()
+		options.Holder = host + "-" + uuid.New().String()[:8]
+	}
+	return &PipelineScheduler{
+		pipelines: pipelines,
+		leases:    leases,
+		executor:  executor,
+		options:   options,
+		logger:    logger,
+	}
+}
+
+// Start проверяет расписания каждые Interval до отмены ctx и освобождает аренду при остановке
+func (s *PipelineScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.options.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := s.leases.ReleaseLease(context.WithoutCancel(ctx), schedulerLease, s.options.Holder); err != nil {
+					s.logger.WithField("error", err.Error()).Warn("Failed to release scheduler lease")
+				}
+				return
+			case now := <-ticker.C:
+				if _, err := s.Tick(ctx, now); err != nil {
+					s.logger.WithField("error", err.Error()).Error("Failed to run pipeline schedules")
+				}
+			}
+		}
+	}()
+}
+
+// Tick ставит в очередь запуски, время которых наступило к now, и возвращает их число.
+// Без аренды планировщика ничего не делает
+func (s *PipelineScheduler) Tick(ctx context.Context, now time.Time) (int, error) {
+	acquired, err := s.leases.AcquireLease(ctx, schedulerLease, s.options.Holder, s.options.LeaseTTL)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	pipelines, err := s.pipelines.GetDuePipelines(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, pipeline := range pipelines {
+		if ctx.Err() != nil {
+			break
+		}
+		enqueued += s.fire(ctx, pipeline, now)
+	}
+	return enqueued, nil
+}
+
+// fire сдвигает расписание пайплайна за now и ставит в очередь наступившие запуски
+// согласно политике catch_up. Запуск, который не удалось поставить в очередь после сдвига,
+// сохраняется как выполнение с ошибкой, а не теряется
+func (s *PipelineScheduler) fire(ctx context.Context, pipeline *models.Pipeline, now time.Time) int {
+	log := s.logger.WithField("pipeline_id", pipeline.ID)
+	current := pipeline.Schedule
+	if current == nil || current.NextRunAt == nil {
+		return 0
+	}
+	expected := *current.NextRunAt
+	schedule := *current
+
+	spec, location, err := parseSchedule(current)
+	var slots []time.Time
+	skipped := 0
+	if err != nil {
+		// Расписание сохранено до изменения правил проверки; запуски по нему прекращаются
+		log.WithField("error", err.Error()).Error("Invalid pipeline schedule disabled")
+		schedule.NextRunAt = nil
+	} else {
+		slots, skipped = s.dueSlots(spec, location, current, now)
+		next := spec.Next(now.In(location))
+		schedule.NextRunAt = nil
+		if !next.IsZero() {
+			schedule.NextRunAt = &next
+		}
+		if len(slots) > 0 {
+			last := slots[len(slots)-1]
+			schedule.LastRunAt = &last
+		}
+	}
+
+	advanced, err := s.pipelines.AdvanceSchedule(ctx, pipeline.ID, expected, &schedule)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Failed to advance pipeline schedule")
+		return 0
+	}
+	if !advanced {
+		// Расписание изменено или запуск уже обработан другим экземпляром
+		return 0
+	}
+	if skipped > 0 {
+		log.WithField("count", skipped).Warn("Missed scheduled pipeline runs skipped")
+	}
+
+	enqueued := 0
+	for _, slot := range slots {
+		parameters := map[string]interface{}{
+			"trigger":      "schedule",
+			"scheduled_at": slot.Format(time.RFC3339),
+		}
+		if _, err := s.executor.Enqueue(ctx, pipeline.ID, "", parameters); err != nil {
+			log.WithField("error", err.Error()).WithField("scheduled_at", slot).Warn("Failed to enqueue scheduled pipeline run")
+			if _, recordErr := s.executor.RecordFailure(ctx, pipeline, "", parameters, err); recordErr != nil {
+				log.WithField("error", recordErr.Error()).WithField("scheduled_at", slot).Error("Scheduled pipeline run lost")
+			}
+			continue
+		}
+		enqueued++
+	}
+	return enqueued
+}
+
+// dueSlots возвращает запуски от NextRunAt до now, которые нужно выполнить по политике
+// catch_up, и число пропускаемых
+func (s *PipelineScheduler) dueSlots(spec *cron.Schedule, location *time.Location, schedule *models.PipelineSchedule, now time.Time) ([]time.Time, int) {
+	policy := schedule.CatchUp
+	if policy == "" {
+		policy = s.options.CatchUp
+	}
+	limit := 1
+	if policy == models.CatchUpAll {
+		limit = s.options.MaxCatchUp
+	}
+
+	// Хранятся только последние limit запусков
+	var slots []time.Time
+	total := 0
+	for slot := schedule.NextRunAt.In(location); !slot.IsZero() && !slot.After(now); slot = spec.Next(slot) {
+		total++
+		slots = append(slots, slot)
+		if len(slots) > limit {
+			slots = slots[1:]
+		}
+	}
+
+	// Без catch_up запуск выполняется, только если он не пропущен, а немного задержан
+	if policy == models.CatchUpNone && len(slots) > 0 && now.Sub(slots[0]) > 2*s.options.Interval {
+		slots = nil
+	}
+	return slots, total - len(slots)
+}
+
+// normalizeSchedule проверяет расписание из запроса и вычисляет время следующего запуска.
+// Время последнего запуска сохраняется из предыдущего расписания
+func normalizeSchedule(requested, previous *models.PipelineSchedule, now time.Time) (*models.PipelineSchedule, error) {
+	if requested == nil {
+		return nil, nil
+	}
+	schedule := &models.PipelineSchedule{
+		Cron:     requested.Cron,
+		Timezone: requested.Timezone,
+		CatchUp:  requested.CatchUp,
+	}
+	switch schedule.CatchUp {
+	case "", models.CatchUpNone, models.CatchUpLatest, models.CatchUpAll:
+	default:
+		return nil, models.NewValidationError("Некорректное расписание", map[string]interface{}{
+			"catch_up": schedule.CatchUp, "error": "ожидается none, latest или all",
+		})
+	}
+	spec, location, err := parseSchedule(schedule)
+	if err != nil {
+		return nil, models.NewValidationError("Некорректное расписание", map[string]interface{}{
+			"cron": schedule.Cron, "timezone": schedule.Timezone, "error": err.Error(),
+		})
+	}
+	next := spec.Next(now.In(location))
+	if next.IsZero() {
+		return nil, models.NewValidationError("Некорректное расписание", map[string]interface{}{
+			"cron": schedule.Cron, "error": "расписание не содержит запусков",
+		})
+	}
+	schedule.NextRunAt = &next
+	if previous != nil {
+		schedule.LastRunAt = previous.LastRunAt
+	}
+	return schedule, nil
+}
+
+// parseSchedule разбирает cron выражение и часовой пояс расписания; пустой пояс — UTC
+func parseSchedule(schedule *models.PipelineSchedule) (*cron.Schedule, *time.Location, error) {
+	spec, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+	location := time.UTC
+	if schedule.Timezone != "" {
+		if location, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("неизвестный часовой пояс %q", schedule.Timezone)
+		}
+	}
+	return spec, location, nil
+}
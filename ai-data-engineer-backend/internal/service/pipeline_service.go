@@ -1,24 +1,260 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
 	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 // PipelineService сервис для работы с пайплайнами
 type PipelineService struct {
-	logger logger.Logger
+	pipelines repository.PipelineRepository
+	analyses  repository.AnalysisRepository
+	logger    logger.Logger
 }
 
 // NewPipelineService создает новый PipelineService
-func NewPipelineService(logger logger.Logger) *PipelineService {
+func NewPipelineService(pipelines repository.PipelineRepository, analyses repository.AnalysisRepository, logger logger.Logger) *PipelineService {
 	return &PipelineService{
-		logger: logger,
+		pipelines: pipelines,
+		analyses:  analyses,
+		logger:    logger,
 	}
 }
 
-// CreatePipeline создает новый пайплайн
-func (p *PipelineService) CreatePipeline(name string) error {
-	p.logger.WithField("pipeline_name", name).Info("Creating pipeline")
-	// TODO: Implement pipeline creation
+// CreatePipeline создает пайплайн по анализу: источником становится файл анализа
+func (p *PipelineService) CreatePipeline(ctx context.Context, userID string, req *models.PipelineRequest) (*models.PipelineResponse, error) {
+	now := time.Now()
+	pipeline := &models.Pipeline{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.apply(ctx, pipeline, req); err != nil {
+		return nil, err
+	}
+	if _, err := p.pipelines.SavePipeline(ctx, pipeline); err != nil {
+		return nil, err
+	}
+	p.logger.WithField("pipeline_id", pipeline.ID).WithField("pipeline_name", pipeline.Name).Info("Pipeline created")
+	return models.NewPipelineResponse(pipeline, "Пайплайн создан"), nil
+}
+
+// GetPipeline возвращает пайплайн пользователя по ID
+func (p *PipelineService) GetPipeline(ctx context.Context, userID, id string) (*models.PipelineResponse, error) {
+	pipeline, err := p.ownedPipeline(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPipelineResponse(pipeline, ""), nil
+}
+
+// ListPipelines возвращает пайплайны пользователя; пустой status — в любом статусе
+func (p *PipelineService) ListPipelines(ctx context.Context, userID string, status models.PipelineStatus, limit, offset int) ([]*models.PipelineResponse, error) {
+	if status != "" && !validPipelineStatus(status) {
+		return nil, models.NewValidationError("Неизвестный статус пайплайна", map[string]interface{}{"status": status})
+	}
+	pipelines, err := p.pipelines.GetPipelinesByUser(ctx, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*models.PipelineResponse, len(pipelines))
+	for i, pipeline := range pipelines {
+		responses[i] = models.NewPipelineResponse(pipeline, "")
+	}
+	return responses, nil
+}
+
+// PlanPipeline возвращает порядок выполнения шагов пайплайна пользователя и уровни параллельного выполнения
+func (p *PipelineService) PlanPipeline(ctx context.Context, userID, id string) (*models.PipelinePlan, error) {
+	pipeline, err := p.ownedPipeline(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	plan, problems := planSteps(pipeline.Steps)
+	if len(problems) > 0 {
+		return nil, stepsError(problems)
+	}
+	plan.PipelineID = pipeline.ID
+	return plan, nil
+}
+
+// UpdatePipeline заменяет описание пайплайна пользователя. Выполняющийся пайплайн
+// не изменяется: статус повторно проверяется условием обновления в репозитории
+func (p *PipelineService) UpdatePipeline(ctx context.Context, userID, id string, req *models.PipelineRequest) (*models.PipelineResponse, error) {
+	pipeline, err := p.ownedPipeline(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.Status == models.PipelineStatusRunning {
+		return nil, models.NewConflictError("Пайплайн выполняется и не может быть изменен")
+	}
+	if err := p.apply(ctx, pipeline, req); err != nil {
+		return nil, err
+	}
+	pipeline.UpdatedAt = time.Now()
+	if err := p.pipelines.UpdatePipeline(ctx, pipeline); err != nil {
+		return nil, err
+	}
+	p.logger.WithField("pipeline_id", pipeline.ID).Info("Pipeline updated")
+	return models.NewPipelineResponse(pipeline, "Пайплайн изменен"), nil
+}
+
+// DeletePipeline удаляет пайплайн пользователя. Выполняющийся пайплайн не удаляется
+func (p *PipelineService) DeletePipeline(ctx context.Context, userID, id string) error {
+	pipeline, err := p.ownedPipeline(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if pipeline.Status == models.PipelineStatusRunning {
+		return models.NewConflictError("Пайплайн выполняется и не может быть удален")
+	}
+	if err := p.pipelines.DeletePipeline(ctx, id); err != nil {
+		return err
+	}
+	p.logger.WithField("pipeline_id", id).Info("Pipeline deleted")
 	return nil
 }
+
+// ownedPipeline возвращает пайплайн, если он принадлежит пользователю userID
+func (p *PipelineService) ownedPipeline(ctx context.Context, userID, id string) (*models.Pipeline, error) {
+	pipeline, err := p.pipelines.GetPipeline(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.UserID != userID {
+		return nil, errPipelineForbidden()
+	}
+	return pipeline, nil
+}
+
+func errPipelineForbidden() error {
+	return models.NewAppError(models.ErrorCodeForbidden, "Нет доступа к пайплайну", http.StatusForbidden)
+}
+
+// apply заполняет пайплайн из запроса. Пайплайн готов к выполнению (ready),
+// когда профиль анализа построен, иначе остается черновиком (draft).
+// Запрос без расписания отключает запуски по расписанию. Анализ должен принадлежать
+// владельцу пайплайна: чужой анализ не найден
+func (p *PipelineService) apply(ctx context.Context, pipeline *models.Pipeline, req *models.PipelineRequest) error {
+	analysis, err := p.analyses.GetAnalysis(ctx, req.AnalysisID)
+	if err != nil {
+		return err
+	}
+	if analysis.UserId != pipeline.UserID {
+		return models.NewAnalysisNotFoundError(req.AnalysisID)
+	}
+	steps, err := pipelineSteps(req.Steps)
+	if err != nil {
+		return err
+	}
+	schedule, err := normalizeSchedule(req.Schedule, pipeline.Schedule, time.Now())
+	if err != nil {
+		return err
+	}
+
+	pipeline.Name = req.Name
+	if pipeline.Name == "" {
+		pipeline.Name = defaultPipelineName(analysis, &req.Target)
+	}
+	pipeline.Description = req.Description
+	pipeline.Source = pipelineSource(analysis)
+	pipeline.Target = req.Target
+	pipeline.Steps = steps
+	pipeline.Schedule = schedule
+	pipeline.Config = req.Config
+	pipeline.Status = models.PipelineStatusDraft
+	if analysis.Status == models.AnalysisStatusCompleted && analysis.Profile != nil {
+		pipeline.Status = models.PipelineStatusReady
+	}
+	return nil
+}
+
+// pipelineSource описывает файл анализа как источник пайплайна
+func pipelineSource(analysis *models.AnalysisResult) models.DataSource {
+	source := models.DataSource{
+		Type:   "file",
+		Path:   analysis.FilePath,
+		Config: map[string]interface{}{"analysis_id": analysis.ID},
+	}
+	if analysis.FileID != "" {
+		source.Config["file_id"] = analysis.FileID
+	}
+	if analysis.Profile != nil {
+		source.Type = analysis.Profile.DataType
+		source.Schema = models.DataSchema{Fields: analysis.Profile.Fields}
+	}
+	return source
+}
+
+// pipelineSteps сбрасывает состояние шагов запроса и проверяет их граф и политики
+// ограничения времени и повторов. Без шагов
+// пайплайн извлекает файл и загружает его в цель
+func pipelineSteps(requested []models.PipelineStep) ([]models.PipelineStep, error) {
+	if len(requested) == 0 {
+		return []models.PipelineStep{
+			{ID: "extract", Name: "Извлечение", Type: models.StepTypeExtract, Status: models.StepStatusPending},
+			{ID: "load", Name: "Загрузка", Type: models.StepTypeLoad, DependsOn: []string{"extract"}, Status: models.StepStatusPending},
+		}, nil
+	}
+
+	steps := make([]models.PipelineStep, len(requested))
+	for i, step := range requested {
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("%s_%d", step.Type, i+1)
+		}
+		if step.Name == "" {
+			step.Name = step.ID
+		}
+		step.DependsOn = append([]string(nil), step.DependsOn...)
+		step.Status = models.StepStatusPending
+		step.StartedAt, step.CompletedAt, step.Error = nil, nil, ""
+		steps[i] = step
+	}
+	if _, problems := planSteps(steps); len(problems) > 0 {
+		return nil, stepsError(problems)
+	}
+	for _, step := range steps {
+		if _, err := newStepPolicy(step); err != nil {
+			return nil, err
+		}
+	}
+	return steps, nil
+}
+
+// stepsError ошибка валидации со списком нарушений графа шагов
+func stepsError(problems []models.StepProblem) error {
+	return models.NewValidationError("Шаги пайплайна не образуют корректный граф", map[string]interface{}{
+		"problems": problems,
+	})
+}
+
+// defaultPipelineName имя пайплайна по файлу и целевой таблице
+func defaultPipelineName(analysis *models.AnalysisResult, target *models.DataTarget) string {
+	source := path.Base(analysis.FilePath)
+	if analysis.FilePath == "" {
+		source = analysis.ID
+	}
+	if target.TableName == "" {
+		return source
+	}
+	return source + " → " + target.TableName
+}
+
+func validPipelineStatus(status models.PipelineStatus) bool {
+	switch status {
+	case models.PipelineStatusDraft, models.PipelineStatusReady, models.PipelineStatusRunning,
+		models.PipelineStatusCompleted, models.PipelineStatusFailed, models.PipelineStatusCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+)
+
+// ProgressService предоставляет потоки событий анализов и выполнений пайплайнов.
+// Если история потока уже удалена из EventBroker, события завершенной задачи
+// восстанавливаются из репозитория
+type ProgressService struct {
+	broker     *EventBroker
+	analyses   repository.AnalysisRepository
+	executions repository.ExecutionRepository
+}
+
+// NewProgressService создает новый ProgressService
+func NewProgressService(broker *EventBroker, analyses repository.AnalysisRepository, executions repository.ExecutionRepository) *ProgressService {
+	return &ProgressService{
+		broker:     broker,
+		analyses:   analyses,
+		executions: executions,
+	}
+}
+
+// AnalysisEvents подписывается на события анализа пользователя userID начиная с lastEventID.
+// Анализ другого пользователя не раскрывается: для него возвращается AnalysisNotFoundError
+func (s *ProgressService) AnalysisEvents(ctx context.Context, userID, analysisID string, lastEventID int64) (*models.EventSubscription, error) {
+	analysis, err := s.analyses.GetAnalysis(ctx, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.UserId != userID {
+		return nil, models.NewAnalysisNotFoundError(analysisID)
+	}
+
+	topic := AnalysisTopic(analysisID)
+	finished := analysis.Status == models.AnalysisStatusCompleted || analysis.Status == models.AnalysisStatusFailed
+	if !finished || s.broker.Has(topic) {
+		return s.broker.Subscribe(topic, lastEventID), nil
+	}
+
+	var events []models.ProgressEvent
+	if analysis.Profile != nil {
+		events = append(events, models.ProgressEvent{Type: models.EventTypeProfile, Data: analysis.Profile})
+	}
+	events = append(events, models.ProgressEvent{
+		Type: models.EventTypeDone,
+		Data: models.StatusEvent{Status: string(analysis.Status), Error: analysis.Error},
+	})
+	return finishedSubscription(events, analysis.UpdatedAt, lastEventID), nil
+}
+
+// ExecutionEvents подписывается на события выполнения пайплайна пользователя userID начиная
+// с lastEventID. Выполнение другого пользователя не раскрывается: для него возвращается
+// ExecutionNotFoundError
+func (s *ProgressService) ExecutionEvents(ctx context.Context, userID, executionID string, lastEventID int64) (*models.EventSubscription, error) {
+	execution, err := s.executions.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	if execution.UserID != userID {
+		return nil, models.NewExecutionNotFoundError(executionID)
+	}
+
+	topic := ExecutionTopic(executionID)
+	if !executionFinished(execution.Status) || s.broker.Has(topic) {
+		return s.broker.Subscribe(topic, lastEventID), nil
+	}
+
+	events := make([]models.ProgressEvent, 0, len(execution.Logs)+1)
+	for _, log := range execution.Logs {
+		events = append(events, models.ProgressEvent{Type: models.EventTypeLog, Data: log, Timestamp: log.Timestamp})
+	}
+	finishedAt := execution.StartedAt
+	if execution.CompletedAt != nil {
+		finishedAt = *execution.CompletedAt
+	}
+	events = append(events, models.ProgressEvent{
+		Type: models.EventTypeDone,
+		Data: models.StatusEvent{Status: string(execution.Status), Error: execution.Error},
+	})
+	return finishedSubscription(events, finishedAt, lastEventID), nil
+}
+
+// executionFinished сообщает, завершилось ли выполнение
+func executionFinished(status models.ExecutionStatus) bool {
+	switch status {
+	case models.ExecutionStatusCompleted, models.ExecutionStatusFailed, models.ExecutionStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// finishedSubscription нумерует восстановленные события и возвращает завершенную подписку.
+// Итоговое событие получает номер DoneEventID, как в живом потоке: клиент, который уже получил
+// done, не получает ничего, а клиент, пропустивший его, получает done, даже если номера живого
+// потока с промежуточными событиями превышают число восстановленных событий
+func finishedSubscription(events []models.ProgressEvent, finishedAt time.Time, lastEventID int64) *models.EventSubscription {
+	var replay []models.ProgressEvent
+	for i, event := range events {
+		event.ID = int64(i + 1)
+		if event.Type == models.EventTypeDone {
+			event.ID = models.DoneEventID
+		}
+		if event.Timestamp.IsZero() {
+			event.Timestamp = finishedAt
+		}
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+	ch := make(chan models.ProgressEvent)
+	close(ch)
+	return &models.EventSubscription{Replay: replay, Events: ch, Finished: true, Cancel: func() {}}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+)
+
+// Классы ошибок шага для config.retry.retry_on
+const (
+	StepErrorTimeout    = "timeout"    // истек config.timeout шага или таймаут внешнего сервиса
+	StepErrorConnection = "connection" // база, хранилище или сервис недоступны
+	StepErrorDatabase   = "database"   // ошибка запроса к базе
+	StepErrorStorage    = "storage"    // ошибка чтения или записи хранилища
+	StepErrorValidation = "validation" // некорректные данные или конфигурация
+	StepErrorInternal   = "internal"   // прочие ошибки
+	StepErrorAny        = "any"        // любая ошибка, кроме отмены выполнения
+)
+
+// defaultRetryOn классы ошибок, которые повторяются, если retry_on не задан
+var defaultRetryOn = []string{StepErrorTimeout, StepErrorConnection, StepErrorDatabase, StepErrorStorage}
+
+// stepPolicy ограничение времени и повторы шага из его конфигурации:
+//
+//	"timeout": "30s",
+//	"retry": {"max_attempts": 3, "backoff": "1s", "max_backoff": "1m", "multiplier": 2, "retry_on": ["timeout"]}
+//
+// Длительности задаются строкой time.ParseDuration или числом секунд
+type stepPolicy struct {
+	// timeout ограничение времени одной попытки; 0 — без ограничения
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	multiplier  float64
+	retryOn     map[string]bool
+}
+
+// newStepPolicy читает политику шага. Без config.retry шаг выполняется один раз
+func newStepPolicy(step models.PipelineStep) (*stepPolicy, error) {
+	policy := &stepPolicy{maxAttempts: 1, backoff: time.Second, maxBackoff: time.Minute, multiplier: 2}
+	var err error
+	if policy.timeout, err = configDuration(step, step.Config, "timeout", "timeout", 0); err != nil {
+		return nil, err
+	}
+
+	raw, ok := step.Config["retry"]
+	if !ok || raw == nil {
+		return policy, nil
+	}
+	retry, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, stepConfigError(step, "retry", "ожидается объект")
+	}
+	attempts, err := configNumber(step, retry, "retry.max_attempts", "max_attempts", 3)
+	if err != nil {
+		return nil, err
+	}
+	if attempts < 1 || attempts > 100 || attempts != math.Trunc(attempts) {
+		return nil, stepConfigError(step, "retry.max_attempts", "ожидается целое от 1 до 100")
+	}
+	policy.maxAttempts = int(attempts)
+	if policy.backoff, err = configDuration(step, retry, "retry.backoff", "backoff", policy.backoff); err != nil {
+		return nil, err
+	}
+	if policy.maxBackoff, err = configDuration(step, retry, "retry.max_backoff", "max_backoff", policy.maxBackoff); err != nil {
+		return nil, err
+	}
+	if policy.multiplier, err = configNumber(step, retry, "retry.multiplier", "multiplier", policy.multiplier); err != nil {
+		return nil, err
+	}
+	if policy.multiplier < 1 {
+		return nil, stepConfigError(step, "retry.multiplier", "ожидается число не меньше 1")
+	}
+
+	classes := defaultRetryOn
+	if raw, ok := retry["retry_on"]; ok {
+		values, ok := raw.([]interface{})
+		if !ok {
+			return nil, stepConfigError(step, "retry.retry_on", "ожидается список классов ошибок")
+		}
+		classes = make([]string, len(values))
+		for i, value := range values {
+			class, _ := value.(string)
+			switch class {
+			case StepErrorTimeout, StepErrorConnection, StepErrorDatabase, StepErrorStorage,
+				StepErrorValidation, StepErrorInternal, StepErrorAny:
+				classes[i] = class
+			default:
+				return nil, stepConfigError(step, "retry.retry_on", fmt.Sprintf("неизвестный класс ошибок %v", value))
+			}
+		}
+	}
+	policy.retryOn = make(map[string]bool, len(classes))
+	for _, class := range classes {
+		policy.retryOn[class] = true
+	}
+	return policy, nil
+}
+
+// retryable сообщает, повторяется ли ошибка класса class
+func (p *stepPolicy) retryable(class string) bool {
+	return p.retryOn[class] || p.retryOn[StepErrorAny]
+}
+
+// delay пауза перед попыткой attempt+1: backoff·multiplier^(attempt-1), не больше maxBackoff
+func (p *stepPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.backoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if delay > float64(p.maxBackoff) {
+		return p.maxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// stepErrorClass относит ошибку шага к классу для retry_on
+func stepErrorClass(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StepErrorTimeout
+	}
+	if appErr, ok := models.IsAppError(err); ok {
+		switch appErr.Code {
+		case models.ErrorCodeLLMTimeout:
+			return StepErrorTimeout
+		case models.ErrorCodeConnectionFailed, models.ErrorCodeServiceUnavailable,
+			models.ErrorCodeStorageUnavailable, models.ErrorCodeLLMUnavailable:
+			return StepErrorConnection
+		case models.ErrorCodeDatabaseError, models.ErrorCodeQueryFailed, models.ErrorCodeTransactionFailed:
+			return StepErrorDatabase
+		case models.ErrorCodeStorageError, models.ErrorCodeUploadFailed:
+			return StepErrorStorage
+		case models.ErrorCodeValidation, models.ErrorCodeInvalidInput, models.ErrorCodeMissingField,
+			models.ErrorCodeInvalidFormat, models.ErrorCodeUnsupportedType:
+			return StepErrorValidation
+		}
+		return StepErrorInternal
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return StepErrorConnection
+	}
+	return StepErrorInternal
+}
+
+// configDuration читает длительность из config[key]: строку time.ParseDuration или число секунд;
+// name — ключ в сообщении об ошибке
+func configDuration(step models.PipelineStep, config map[string]interface{}, name, key string, fallback time.Duration) (time.Duration, error) {
+	raw, ok := config[key]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	var duration time.Duration
+	switch v := raw.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, stepConfigError(step, name, "ожидается длительность, например 30s")
+		}
+		duration = parsed
+	case float64:
+		duration = time.Duration(v * float64(time.Second))
+	case int:
+		duration = time.Duration(v) * time.Second
+	default:
+		return 0, stepConfigError(step, name, "ожидается длительность, например 30s")
+	}
+	if duration < 0 {
+		return 0, stepConfigError(step, name, "длительность не может быть отрицательной")
+	}
+	return duration, nil
+}
+
+// configNumber читает число из config[key]; name — ключ в сообщении об ошибке
+func configNumber(step models.PipelineStep, config map[string]interface{}, name, key string, fallback float64) (float64, error) {
+	raw, ok := config[key]
+	if !ok || raw == nil {
+		return fallback, nil
+	}
+	switch v := raw.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	}
+	return 0, stepConfigError(step, name, "ожидается число")
+}
@@ -0,0 +1,616 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Ограничения multipart загрузки S3/MinIO
+const (
+	minUploadPartSize = 5 << 20
+	maxUploadPartSize = 5 << 30
+	maxUploadParts    = 10000
+)
+
+// MultipartStorage хранилище с поддержкой загрузки объекта частями
+type MultipartStorage interface {
+	StorageClient
+	NewMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error)
+	PutObjectPart(ctx context.Context, bucket, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (string, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, parts []client.CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error
+}
+
+// UploadOptions параметры возобновляемой загрузки
+type UploadOptions struct {
+	// SessionTTL время жизни сессии с момента создания
+	SessionTTL time.Duration
+	// PartSize рекомендуемый клиенту размер части
+	PartSize int64
+	// MaxUploadSize максимальный размер файла, <= 0 — без ограничения
+	MaxUploadSize int64
+	// Instance экземпляр сервиса, который завершает сессии; по умолчанию единственный экземпляр
+	Instance *Instance
+}
+
+// UploadService возобновляемая загрузка файлов частями поверх multipart загрузки хранилища
+type UploadService struct {
+	storage     MultipartStorage
+	sessionRepo repository.UploadSessionRepository
+	fileRepo    repository.FileRepository
+	blobs       *BlobStore
+	options     UploadOptions
+	logger      logger.Logger
+	// locks не дает одному экземпляру сервиса завершать сессию параллельно
+	locks keyedMutex
+}
+
+// NewUploadService создает новый UploadService
+func NewUploadService(
+	storage MultipartStorage,
+	sessionRepo repository.UploadSessionRepository,
+	fileRepo repository.FileRepository,
+	blobs *BlobStore,
+	options UploadOptions,
+	logger logger.Logger,
+) *UploadService {
+	if options.SessionTTL <= 0 {
+		options.SessionTTL = 24 * time.Hour
+	}
+	if options.PartSize < minUploadPartSize {
+		options.PartSize = minUploadPartSize
+	}
+	if options.Instance == nil {
+		options.Instance = NewInstance(nil, InstanceOptions{}, logger)
+	}
+	return &UploadService{
+		storage:     storage,
+		sessionRepo: sessionRepo,
+		fileRepo:    fileRepo,
+		blobs:       blobs,
+		options:     options,
+		logger:      logger,
+		locks:       keyedMutex{locks: make(map[string]*keyedLock)},
+	}
+}
+
+// InitUpload создает сессию загрузки и multipart загрузку в хранилище
+func (s *UploadService) InitUpload(ctx context.Context, userID, filename string, size int64) (*models.UploadSession, error) {
+	if filename == "" {
+		return nil, models.NewValidationError("Имя файла обязательно", nil)
+	}
+	if s.options.MaxUploadSize > 0 && size > s.options.MaxUploadSize {
+		return nil, s.tooLarge()
+	}
+
+	// Для больших файлов увеличиваем часть, чтобы уложиться в лимит количества частей
+	partSize := s.options.PartSize
+	if size > partSize*maxUploadParts {
+		partSize = (size + maxUploadParts - 1) / maxUploadParts
+	}
+
+	now := time.Now()
+	id := uuid.New().String()
+	session := &models.UploadSession{
+		ID:          id,
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: client.GetContentType(filename),
+		Size:        size,
+		PartSize:    partSize,
+		Bucket:      defaultBucket,
+		Path:        fmt.Sprintf("users/%s/files/%s", userID, generateFileName(id, filename)),
+		Parts:       make([]models.UploadPart, 0),
+		Status:      models.UploadSessionStatusActive,
+		ExpiresAt:   now.Add(s.options.SessionTTL),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	uploadID, err := s.storage.NewMultipartUpload(ctx, session.Bucket, session.Path, session.ContentType)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).Error("Failed to start multipart upload")
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось начать загрузку", http.StatusBadGateway, err)
+	}
+	session.UploadID = uploadID
+
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		s.abort(ctx, session)
+		return nil, err
+	}
+
+	s.logger.WithField("upload_id", session.ID).WithField("user_id", userID).WithField("size", size).Info("Upload session created")
+	return session, nil
+}
+
+// GetUpload возвращает состояние сессии, по которому клиент определяет недостающие части
+func (s *UploadService) GetUpload(ctx context.Context, userID, sessionID string) (*models.UploadSession, error) {
+	return s.ownedSession(ctx, userID, sessionID)
+}
+
+// UploadPart загружает часть файла. Повторная загрузка части с тем же номером заменяет ее
+func (s *UploadService) UploadPart(ctx context.Context, userID, sessionID string, number int, reader io.Reader, size int64) (*models.UploadPart, error) {
+	session, err := s.activeSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if number < 1 || number > maxUploadParts {
+		return nil, models.NewValidationError(fmt.Sprintf("Номер части должен быть от 1 до %d", maxUploadParts), map[string]interface{}{"part": number})
+	}
+	if size < 0 {
+		return nil, models.NewValidationError("Размер части должен быть известен заранее", nil)
+	}
+	if size > maxUploadPartSize {
+		return nil, models.NewAppError(models.ErrorCodeFileTooLarge, "Размер части превышает 5 ГБ", http.StatusRequestEntityTooLarge)
+	}
+
+	uploaded := size
+	for _, part := range session.Parts {
+		if part.Number != number {
+			uploaded += part.Size
+		}
+	}
+	if s.options.MaxUploadSize > 0 && uploaded > s.options.MaxUploadSize {
+		return nil, s.tooLarge()
+	}
+
+	etag, err := s.storage.PutObjectPart(ctx, session.Bucket, session.Path, session.UploadID, number, reader, size)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).WithField("upload_id", sessionID).WithField("part", number).Error("Failed to upload part")
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeUploadFailed, "Не удалось загрузить часть файла", http.StatusBadGateway, err)
+	}
+
+	part := models.UploadPart{
+		Number:     number,
+		Size:       size,
+		ETag:       etag,
+		UploadedAt: time.Now(),
+	}
+	if err := s.sessionRepo.SavePart(ctx, sessionID, part); err != nil {
+		return nil, err
+	}
+	return &part, nil
+}
+
+// CompleteUpload собирает файл из частей и регистрирует его метаданные. Сессия сначала
+// условно переводится в completing с этим экземпляром в качестве владельца, поэтому
+// параллельный вызов, в том числе на другом экземпляре, получает конфликт. Повтор после сбоя
+// или ошибки продолжает с уже собранного объекта (см. claim). Для завершенной сессии
+// возвращается ее файл
+func (s *UploadService) CompleteUpload(ctx context.Context, userID, sessionID string) (*models.FileMetadata, error) {
+	unlock := s.locks.lock(sessionID)
+	defer unlock()
+
+	session, err := s.ownedSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	switch session.Status {
+	case models.UploadSessionStatusCompleted:
+		return s.fileRepo.GetFile(ctx, session.FileID)
+	case models.UploadSessionStatusActive:
+		if err := s.beginCompletion(ctx, session); err != nil {
+			return nil, err
+		}
+	case models.UploadSessionStatusCompleting:
+		if err := s.claim(ctx, session); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, models.NewConflictError(fmt.Sprintf("Сессия загрузки в статусе %s", session.Status))
+	}
+
+	metadata, err := s.assemble(ctx, session)
+	if err != nil {
+		s.release(ctx, session)
+		return nil, err
+	}
+	session.Status = models.UploadSessionStatusCompleted
+	session.UpdatedAt = time.Now()
+	ok, err := s.sessionRepo.TransitionSession(ctx, session, models.UploadSessionStatusCompleting)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.NewConflictError("Сессия загрузки изменена во время завершения")
+	}
+
+	s.logger.WithField("upload_id", sessionID).WithField("file_id", metadata.ID).WithField("size", metadata.Size).Info("Upload session completed")
+	return metadata, nil
+}
+
+// AbortUpload отменяет загрузку и удаляет загруженные части. Переход условный: сессию,
+// которую параллельно начали завершать, отмена не трогает
+func (s *UploadService) AbortUpload(ctx context.Context, userID, sessionID string) error {
+	session, err := s.activeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	session.Status = models.UploadSessionStatusAborted
+	session.UpdatedAt = time.Now()
+	ok, err := s.sessionRepo.TransitionSession(ctx, session, models.UploadSessionStatusActive)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.NewConflictError("Загрузка уже завершается")
+	}
+	s.abort(ctx, session)
+	return nil
+}
+
+// ExpireSessions переводит истекшие активные сессии в expired и отменяет их multipart загрузки.
+// Переход условный: сессию, которую параллельно начали завершать, janitor не трогает.
+// Ошибка одной сессии не прерывает обработку остальных: возвращается число отмененных сессий
+// и первая ошибка
+func (s *UploadService) ExpireSessions(ctx context.Context) (int, error) {
+	sessions, err := s.sessionRepo.GetExpiredSessions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	var firstErr error
+	for _, session := range sessions {
+		session.Status = models.UploadSessionStatusExpired
+		session.UpdatedAt = time.Now()
+		ok, err := s.sessionRepo.TransitionSession(ctx, session, models.UploadSessionStatusActive)
+		if err != nil {
+			s.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Error("Failed to expire upload session")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.abort(ctx, session)
+		expired++
+	}
+	return expired, firstErr
+}
+
+// StartJanitor периодически отменяет брошенные сессии до отмены ctx
+func (s *UploadService) StartJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := s.ExpireSessions(ctx)
+				if err != nil {
+					s.logger.WithField("error", err.Error()).Error("Failed to expire upload sessions")
+				}
+				if expired > 0 {
+					s.logger.WithField("count", expired).Info("Expired upload sessions aborted")
+				}
+			}
+		}
+	}()
+}
+
+// ownedSession возвращает сессию пользователя; чужая сессия не отличается от несуществующей
+func (s *UploadService) ownedSession(ctx context.Context, userID, sessionID string) (*models.UploadSession, error) {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, models.NewUploadSessionNotFoundError(sessionID)
+	}
+	return session, nil
+}
+
+// activeSession возвращает сессию, в которую еще можно загружать части
+func (s *UploadService) activeSession(ctx context.Context, userID, sessionID string) (*models.UploadSession, error) {
+	session, err := s.ownedSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkActive(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func checkActive(session *models.UploadSession) error {
+	if session.Status != models.UploadSessionStatusActive {
+		return models.NewConflictError(fmt.Sprintf("Сессия загрузки в статусе %s", session.Status))
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return models.NewAppError(models.ErrorCodeConflict, "Срок действия сессии загрузки истек", http.StatusGone)
+	}
+	return nil
+}
+
+// beginCompletion проверяет части и переводит активную сессию в completing, назначая ID файла
+func (s *UploadService) beginCompletion(ctx context.Context, session *models.UploadSession) error {
+	if err := checkActive(session); err != nil {
+		return err
+	}
+	if _, err := completedParts(session); err != nil {
+		return err
+	}
+
+	session.Status = models.UploadSessionStatusCompleting
+	session.FileID = uuid.New().String()
+	session.Owner = s.options.Instance.ID()
+	session.UpdatedAt = time.Now()
+	ok, err := s.sessionRepo.TransitionSession(ctx, session, models.UploadSessionStatusActive)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.NewConflictError("Загрузка уже завершается")
+	}
+	return nil
+}
+
+// claim передает этому экземпляру сессию в статусе completing, завершение которой прервано:
+// владелец остановился или вернул сессию после ошибки (см. release). Сессию, которую завершает
+// другой работающий экземпляр, повтор не трогает
+func (s *UploadService) claim(ctx context.Context, session *models.UploadSession) error {
+	if owner := s.options.Instance.ID(); session.Owner != owner {
+		alive, err := s.options.Instance.Alive(ctx, session.Owner)
+		if err != nil {
+			return err
+		}
+		if alive {
+			return models.NewConflictError("Загрузка уже завершается")
+		}
+		ok, err := s.sessionRepo.ClaimSession(ctx, session.ID, owner, session.Owner)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.NewConflictError("Загрузка уже завершается")
+		}
+		session.Owner = owner
+	}
+	s.logger.WithField("upload_id", session.ID).Info("Resuming upload completion")
+	return nil
+}
+
+// release снимает владельца с сессии, завершение которой прервано ошибкой, чтобы повтор
+// продолжил его на любом экземпляре; ошибки только логируются
+func (s *UploadService) release(ctx context.Context, session *models.UploadSession) {
+	if _, err := s.sessionRepo.ClaimSession(ctx, session.ID, "", s.options.Instance.ID()); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Warn("Failed to release upload session")
+	}
+}
+
+// completedParts проверяет, что загружены все части, и возвращает их список для сборки
+func completedParts(session *models.UploadSession) ([]client.CompletedPart, error) {
+	parts := make([]client.CompletedPart, 0, len(session.Parts))
+	for i, part := range session.Parts {
+		if part.Number != i+1 {
+			return nil, models.NewValidationError("Загружены не все части файла", map[string]interface{}{"missing_part": i + 1})
+		}
+		if i < len(session.Parts)-1 && part.Size < minUploadPartSize {
+			return nil, models.NewValidationError("Все части, кроме последней, должны быть не меньше 5 МБ", map[string]interface{}{"part": part.Number})
+		}
+		parts = append(parts, client.CompletedPart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	if len(parts) == 0 {
+		return nil, models.NewValidationError("Не загружено ни одной части", nil)
+	}
+	total := session.UploadedSize()
+	if session.Size > 0 && total != session.Size {
+		return nil, models.NewValidationError("Размер загруженных частей не совпадает с заявленным", map[string]interface{}{
+			"expected": session.Size,
+			"uploaded": total,
+		})
+	}
+	return parts, nil
+}
+
+// assemble доводит сборку сессии в статусе completing до файла в статусе uploaded.
+// Каждый шаг пропускается, если его результат уже сохранен предыдущей попыткой
+func (s *UploadService) assemble(ctx context.Context, session *models.UploadSession) (*models.FileMetadata, error) {
+	metadata, err := s.fileRepo.GetFile(ctx, session.FileID)
+	if appErr, ok := models.IsAppError(err); ok && appErr.Code == models.ErrorCodeFileNotFound {
+		metadata, err = s.register(ctx, session)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Status == models.FileStatusUploaded {
+		return metadata, nil
+	}
+
+	// Собранный объект удаляется только после записи blob, поэтому без объекта ссылка уже
+	// получена и blob записан. Ссылка отмечается в сессии до записи blob: повтор после сбоя
+	// не берет ее второй раз. Сбой между получением ссылки и отметкой оставляет лишнюю ссылку,
+	// но не удаляет blob из-под файла
+	exists, err := s.storage.FileExists(ctx, session.Bucket, session.Path)
+	if err != nil {
+		return nil, models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось проверить собранный файл", http.StatusBadGateway, err)
+	}
+	var blob *models.Blob
+	created := false
+	if exists && !session.BlobAcquired {
+		if blob, created, err = s.blobs.Acquire(ctx, session.Bucket, metadata.Checksum, metadata.Size); err != nil {
+			return nil, err
+		}
+		session.BlobAcquired = true
+		session.UpdatedAt = time.Now()
+		if err := s.sessionRepo.UpdateSession(ctx, session); err != nil {
+			return nil, err
+		}
+	} else if blob, err = s.blobs.repo.GetBlob(ctx, metadata.Checksum); err != nil {
+		return nil, err
+	}
+	if exists {
+		if err := s.blobs.Store(ctx, session.Bucket, session.Path, blob, created); err != nil {
+			return nil, err
+		}
+	}
+
+	metadata.Bucket = blob.Bucket
+	metadata.Path = blob.Path
+	metadata.Status = models.FileStatusUploaded
+	metadata.UpdatedAt = time.Now()
+	if err := s.fileRepo.UpdateFile(ctx, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// register собирает объект из частей, если сессия еще не собрана, и сохраняет метаданные файла
+// в статусе uploading. ID файла назначен сессии, поэтому файл регистрируется один раз
+func (s *UploadService) register(ctx context.Context, session *models.UploadSession) (*models.FileMetadata, error) {
+	if session.Checksum == "" {
+		if err := s.assembleParts(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	metadata := &models.FileMetadata{
+		ID:          session.FileID,
+		UserID:      session.UserID,
+		Filename:    session.Filename,
+		ContentType: session.ContentType,
+		Size:        session.UploadedSize(),
+		Checksum:    session.Checksum,
+		Path:        session.Path,
+		Bucket:      session.Bucket,
+		Status:      models.FileStatusUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.fileRepo.SaveFile(ctx, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// assembleParts собирает объект из частей и сохраняет в сессии его контрольную сумму:
+// сессия с контрольной суммой считается собранной. Если сборка уже выполнена прерванной
+// попыткой, хранилище не найдет multipart загрузку; объект по пути сессии тогда собран ею,
+// так как путь содержит ID сессии
+func (s *UploadService) assembleParts(ctx context.Context, session *models.UploadSession) error {
+	parts, err := completedParts(session)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.CompleteMultipartUpload(ctx, session.Bucket, session.Path, session.UploadID, parts); err != nil {
+		exists, existsErr := s.storage.FileExists(ctx, session.Bucket, session.Path)
+		if existsErr != nil || !exists {
+			s.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Error("Failed to complete multipart upload")
+			return models.NewAppErrorWithCause(models.ErrorCodeUploadFailed, "Не удалось собрать файл из частей", http.StatusBadGateway, err)
+		}
+		s.logger.WithField("upload_id", session.ID).Info("Multipart upload already assembled")
+	}
+
+	checksum, size, err := s.checksum(ctx, session.Bucket, session.Path)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Error("Failed to compute checksum")
+		return models.NewAppErrorWithCause(models.ErrorCodeStorageError, "Не удалось вычислить контрольную сумму файла", http.StatusBadGateway, err)
+	}
+	// Параллельные UploadPart проверяют размер каждый по своему снимку частей и вместе
+	// могут превысить ограничение, поэтому размер собранного объекта проверяется повторно
+	if s.options.MaxUploadSize > 0 && size > s.options.MaxUploadSize {
+		s.reject(ctx, session)
+		return s.tooLarge()
+	}
+	session.Checksum = checksum
+	session.UpdatedAt = time.Now()
+	return s.sessionRepo.UpdateSession(ctx, session)
+}
+
+// abort отменяет multipart загрузку, ошибки только логируются
+func (s *UploadService) abort(ctx context.Context, session *models.UploadSession) {
+	if err := s.storage.AbortMultipartUpload(ctx, session.Bucket, session.Path, session.UploadID); err != nil {
+		s.logger.WithField("error", err.Error()).WithField("upload_id", session.ID).Warn("Failed to abort multipart upload")
+	}
+}
+
+// reject удаляет собранный объект, превысивший ограничение размера, и отменяет сессию
+// в статусе completing; ошибки только логируются
+func (s *UploadService) reject(ctx context.Context, session *models.UploadSession) {
+	log := s.logger.WithField("upload_id", session.ID)
+	if err := s.storage.DeleteFile(ctx, session.Bucket, session.Path); err != nil {
+		log.WithField("error", err.Error()).Warn("Failed to delete oversized upload")
+	}
+	session.Status = models.UploadSessionStatusAborted
+	session.UpdatedAt = time.Now()
+	if _, err := s.sessionRepo.TransitionSession(ctx, session, models.UploadSessionStatusCompleting); err != nil {
+		log.WithField("error", err.Error()).Error("Failed to abort oversized upload session")
+	}
+	log.Warn("Assembled upload exceeds the size limit")
+}
+
+// checksum вычисляет SHA-256 и размер собранного объекта потоковым чтением
+func (s *UploadService) checksum(ctx context.Context, bucket, objectName string) (string, int64, error) {
+	reader, err := s.storage.DownloadFile(ctx, bucket, objectName)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+func (s *UploadService) tooLarge() error {
+	return models.NewAppError(models.ErrorCodeFileTooLarge,
+		fmt.Sprintf("Размер файла превышает допустимый (%d байт)", s.options.MaxUploadSize), http.StatusRequestEntityTooLarge)
+}
+
+// keyedMutex блокировка по ключу в пределах процесса: операции с одним ключом выполняются последовательно
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock захватывает блокировку ключа и возвращает функцию освобождения
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ai-data-engineer-backend/internal/ddl"
+)
+
+// decimalPattern десятичное число без экспоненты
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+// coerceValue приводит текстовое значение CSV к типу колонки целевой таблицы.
+// Пустые значения и маркеры пропусков (null, n/a, ...) становятся NULL, как при профилировании.
+// Возвращает int64, uint64, float64, bool, time.Time, string или nil
+func coerceValue(column *ddl.ColumnType, raw string) (interface{}, error) {
+	v := strings.TrimSpace(raw)
+	if isNullToken(v) {
+		if !column.Nullable {
+			return nil, fmt.Errorf("пустое значение в колонке NOT NULL")
+		}
+		return nil, nil
+	}
+
+	switch column.Family {
+	case ddl.FamilyInteger:
+		bits := column.Bits
+		if bits == 0 {
+			bits = 64
+		}
+		if column.Unsigned {
+			n, err := strconv.ParseUint(v, 10, bits)
+			if err != nil {
+				return nil, fmt.Errorf("значение %q не является целым числом %s", v, column.Type)
+			}
+			return n, nil
+		}
+		n, err := strconv.ParseInt(v, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является целым числом %s", v, column.Type)
+		}
+		return n, nil
+	case ddl.FamilyFloat:
+		bits := 64
+		if column.Bits > 0 && column.Bits <= 24 {
+			bits = 32
+		}
+		f, err := strconv.ParseFloat(v, bits)
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является числом %s", v, column.Type)
+		}
+		return f, nil
+	case ddl.FamilyDecimal:
+		if !decimalPattern.MatchString(v) {
+			return nil, fmt.Errorf("значение %q не является десятичным числом", v)
+		}
+		if column.Precision > 0 {
+			integer, _, _ := strings.Cut(strings.TrimLeft(v, "+-"), ".")
+			if len(strings.TrimLeft(integer, "0")) > column.Precision-column.Scale {
+				return nil, fmt.Errorf("значение %q не помещается в %s", v, column.Type)
+			}
+		}
+		return v, nil
+	case ddl.FamilyBoolean:
+		switch strings.ToLower(v) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("значение %q не является логическим", v)
+	case ddl.FamilyTime:
+		t, isDate, ok := parseTemporal(v)
+		if !ok {
+			return nil, fmt.Errorf("значение %q не является датой или временем", v)
+		}
+		if column.DateOnly && !isDate {
+			return nil, fmt.Errorf("значение %q содержит время, а колонка %s — только дату", v, column.Type)
+		}
+		return t, nil
+	case ddl.FamilyString:
+		if column.Length > 0 && utf8.RuneCountInString(raw) > column.Length {
+			return nil, fmt.Errorf("значение длиннее %d символов", column.Length)
+		}
+		return raw, nil
+	default:
+		// Типы без приведения (JSONB, UUID, массивы) проверяет СУБД
+		return raw, nil
+	}
+}
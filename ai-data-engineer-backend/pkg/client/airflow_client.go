@@ -0,0 +1,351 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+const (
+	// maxAirflowErrorSize ограничение тела ответа с ошибкой Airflow
+	maxAirflowErrorSize = 64 << 10
+	// maxAirflowLogSize ограничение журнала задачи, читаемого из Airflow
+	maxAirflowLogSize = 16 << 20
+	// airflowPageSize размер страницы списков Airflow
+	airflowPageSize = 100
+	// maxAirflowPollBackoff наибольшая пауза между повторами опроса после временных ошибок
+	maxAirflowPollBackoff = time.Minute
+)
+
+// Состояния запуска DAG в Airflow
+const (
+	AirflowStateQueued  = "queued"
+	AirflowStateRunning = "running"
+	AirflowStateSuccess = "success"
+	AirflowStateFailed  = "failed"
+)
+
+// AirflowClient клиент стабильного REST API Airflow (/api/v1)
+type AirflowClient interface {
+	// SetPaused ставит DAG на паузу или снимает с нее; запуски приостановленного DAG не выполняются
+	SetPaused(ctx context.Context, dagID string, paused bool) error
+	// TriggerDAGRun создает запуск DAG с параметрами conf. Пустой runID — Airflow назначит его сам
+	TriggerDAGRun(ctx context.Context, dagID, runID string, conf map[string]interface{}) (*AirflowDAGRun, error)
+	// GetDAGRun возвращает запуск DAG
+	GetDAGRun(ctx context.Context, dagID, runID string) (*AirflowDAGRun, error)
+	// SetDAGRunState завершает запуск DAG с состоянием state (success или failed); выполняемые
+	// задачи Airflow останавливает
+	SetDAGRunState(ctx context.Context, dagID, runID, state string) error
+	// WaitDAGRun опрашивает запуск с периодом interval, пока он не завершится или не истечет ctx.
+	// После временных ошибок Airflow опрос повторяется с увеличивающейся паузой
+	WaitDAGRun(ctx context.Context, dagID, runID string, interval time.Duration) (*AirflowDAGRun, error)
+	// ListTaskInstances возвращает все задачи запуска
+	ListTaskInstances(ctx context.Context, dagID, runID string) ([]AirflowTaskInstance, error)
+	// GetTaskLog возвращает журнал попытки tryNumber задачи
+	GetTaskLog(ctx context.Context, dagID, runID, taskID string, tryNumber int) (string, error)
+}
+
+// AirflowOptions параметры подключения к Airflow
+type AirflowOptions struct {
+	// BaseURL адрес веб-сервера Airflow, например http://airflow:8080
+	BaseURL  string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// AirflowDAGRun запуск DAG
+type AirflowDAGRun struct {
+	DAGID       string                 `json:"dag_id"`
+	DAGRunID    string                 `json:"dag_run_id"`
+	State       string                 `json:"state"`
+	LogicalDate *time.Time             `json:"logical_date,omitempty"`
+	StartDate   *time.Time             `json:"start_date,omitempty"`
+	EndDate     *time.Time             `json:"end_date,omitempty"`
+	Conf        map[string]interface{} `json:"conf,omitempty"`
+}
+
+// Finished сообщает, что запуск завершен
+func (r *AirflowDAGRun) Finished() bool {
+	return r.State == AirflowStateSuccess || r.State == AirflowStateFailed
+}
+
+// ExecutionStatus статус выполнения пайплайна, соответствующий состоянию запуска
+func (r *AirflowDAGRun) ExecutionStatus() models.ExecutionStatus {
+	switch r.State {
+	case AirflowStateRunning:
+		return models.ExecutionStatusRunning
+	case AirflowStateSuccess:
+		return models.ExecutionStatusCompleted
+	case AirflowStateFailed:
+		return models.ExecutionStatusFailed
+	}
+	return models.ExecutionStatusScheduled
+}
+
+// AirflowTaskInstance задача запуска DAG
+type AirflowTaskInstance struct {
+	TaskID string `json:"task_id"`
+	// State пусто, пока задача не запланирована
+	State     string     `json:"state"`
+	TryNumber int        `json:"try_number"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+// StepStatus статус шага пайплайна, соответствующий состоянию задачи. Задачи, не выполненные
+// из-за упавшей зависимости (upstream_failed), считаются пропущенными, как в PipelineExecutor
+func (t *AirflowTaskInstance) StepStatus() models.StepStatus {
+	switch t.State {
+	case "running", "restarting", "deferred", "up_for_retry", "up_for_reschedule":
+		return models.StepStatusRunning
+	case "success":
+		return models.StepStatusCompleted
+	case "failed", "shutdown":
+		return models.StepStatusFailed
+	case "upstream_failed", "skipped", "removed":
+		return models.StepStatusSkipped
+	}
+	return models.StepStatusPending
+}
+
+// AirflowError ошибка, которую вернул API Airflow
+type AirflowError struct {
+	StatusCode int
+	Title      string
+	Detail     string
+}
+
+func (e *AirflowError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("airflow error (HTTP %d): %s", e.StatusCode, e.Title)
+	}
+	return fmt.Sprintf("airflow error (HTTP %d): %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+// airflowClient реализация AirflowClient
+type airflowClient struct {
+	opts       AirflowOptions
+	httpClient *http.Client
+	logger     logger.Logger
+}
+
+// NewAirflowClient создает клиент REST API Airflow с базовой аутентификацией
+func NewAirflowClient(opts AirflowOptions, logger logger.Logger) AirflowClient {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &airflowClient{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		logger:     logger,
+	}
+}
+
+// SetPaused изменяет is_paused DAG
+func (c *airflowClient) SetPaused(ctx context.Context, dagID string, paused bool) error {
+	query := url.Values{"update_mask": {"is_paused"}}
+	return c.do(ctx, http.MethodPatch, dagPath(dagID)+"?"+query.Encode(), map[string]interface{}{"is_paused": paused}, nil)
+}
+
+// TriggerDAGRun создает запуск DAG
+func (c *airflowClient) TriggerDAGRun(ctx context.Context, dagID, runID string, conf map[string]interface{}) (*AirflowDAGRun, error) {
+	body := map[string]interface{}{"conf": conf}
+	if conf == nil {
+		body["conf"] = map[string]interface{}{}
+	}
+	if runID != "" {
+		body["dag_run_id"] = runID
+	}
+	var run AirflowDAGRun
+	if err := c.do(ctx, http.MethodPost, dagPath(dagID)+"/dagRuns", body, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetDAGRun возвращает запуск DAG
+func (c *airflowClient) GetDAGRun(ctx context.Context, dagID, runID string) (*AirflowDAGRun, error) {
+	var run AirflowDAGRun
+	if err := c.do(ctx, http.MethodGet, dagRunPath(dagID, runID), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// SetDAGRunState изменяет состояние запуска DAG
+func (c *airflowClient) SetDAGRunState(ctx context.Context, dagID, runID, state string) error {
+	return c.do(ctx, http.MethodPatch, dagRunPath(dagID, runID), map[string]interface{}{"state": state}, nil)
+}
+
+// WaitDAGRun опрашивает запуск до завершения. Временные ошибки — сбой сети, ответы 5xx и 429 —
+// не прерывают ожидание: опрос повторяется с удваивающейся паузой, но не реже maxAirflowPollBackoff.
+// Остальные ответы 4xx завершают ожидание сразу
+func (c *airflowClient) WaitDAGRun(ctx context.Context, dagID, runID string, interval time.Duration) (*AirflowDAGRun, error) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	var last *AirflowDAGRun
+	delay := interval
+	for {
+		run, err := c.GetDAGRun(ctx, dagID, runID)
+		switch {
+		case err == nil:
+			if run.Finished() {
+				return run, nil
+			}
+			last, delay = run, interval
+		case ctx.Err() != nil:
+			return last, ctx.Err()
+		case !transientAirflowError(err):
+			return nil, err
+		default:
+			delay = min(delay*2, max(maxAirflowPollBackoff, interval))
+			c.logger.WithField("error", err.Error()).WithField("dag_id", dagID).WithField("dag_run_id", runID).
+				WithField("retry_in", delay.String()).Warn("airflowClient: failed to poll DAG run, retrying")
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// transientAirflowError сообщает, что запрос стоит повторить: ответ 5xx или 429 либо ошибка
+// без ответа Airflow (сеть, разбор ответа)
+func transientAirflowError(err error) bool {
+	var apiErr *AirflowError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.StatusCode >= http.StatusInternalServerError || apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// ListTaskInstances читает задачи запуска постранично
+func (c *airflowClient) ListTaskInstances(ctx context.Context, dagID, runID string) ([]AirflowTaskInstance, error) {
+	var tasks []AirflowTaskInstance
+	for {
+		query := url.Values{"limit": {strconv.Itoa(airflowPageSize)}, "offset": {strconv.Itoa(len(tasks))}}
+		var page struct {
+			TaskInstances []AirflowTaskInstance `json:"task_instances"`
+			TotalEntries  int                   `json:"total_entries"`
+		}
+		if err := c.do(ctx, http.MethodGet, dagRunPath(dagID, runID)+"/taskInstances?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, page.TaskInstances...)
+		if len(page.TaskInstances) == 0 || len(tasks) >= page.TotalEntries {
+			return tasks, nil
+		}
+	}
+}
+
+// GetTaskLog читает журнал попытки задачи как текст
+func (c *airflowClient) GetTaskLog(ctx context.Context, dagID, runID, taskID string, tryNumber int) (string, error) {
+	endpoint := fmt.Sprintf("%s/taskInstances/%s/logs/%d?full_content=true",
+		dagRunPath(dagID, runID), url.PathEscape(taskID), tryNumber)
+	resp, err := c.send(ctx, http.MethodGet, endpoint, nil, "text/plain")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	log, err := io.ReadAll(io.LimitReader(resp.Body, maxAirflowLogSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read airflow task log: %w", err)
+	}
+	return string(log), nil
+}
+
+// do отправляет запрос с телом JSON и декодирует ответ в result, если он задан
+func (c *airflowClient) do(ctx context.Context, method, endpoint string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode airflow request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	resp, err := c.send(ctx, method, endpoint, reader, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode airflow response: %w", err)
+	}
+	return nil
+}
+
+// send отправляет запрос к /api/v1 с учетными данными; ответ не 2xx превращается в AirflowError
+func (c *airflowClient) send(ctx context.Context, method, endpoint string, body io.Reader, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.BaseURL+"/api/v1"+endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.Username != "" {
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.WithField("error", err.Error()).WithField("endpoint", endpoint).Error("airflowClient: request failed")
+		return nil, fmt.Errorf("failed to send airflow request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, readAirflowError(resp)
+	}
+	return resp, nil
+}
+
+// readAirflowError читает ошибку в формате application/problem+json
+func readAirflowError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxAirflowErrorSize))
+	apiErr := &AirflowError{StatusCode: resp.StatusCode}
+	var problem struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(data, &problem) == nil && problem.Title != "" {
+		apiErr.Title, apiErr.Detail = problem.Title, problem.Detail
+	} else {
+		apiErr.Title = strings.TrimSpace(string(data))
+		if apiErr.Title == "" {
+			apiErr.Title = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
+
+func dagPath(dagID string) string {
+	return "/dags/" + url.PathEscape(dagID)
+}
+
+func dagRunPath(dagID, runID string) string {
+	return dagPath(dagID) + "/dagRuns/" + url.PathEscape(runID)
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-data-engineer-backend/pkg/logger"
+)
+
+// maxClickHouseErrorSize ограничение текста ошибки, читаемого из ответа ClickHouse
+const maxClickHouseErrorSize = 64 << 10
+
+// ClickHouseClient клиент HTTP интерфейса ClickHouse
+type ClickHouseClient interface {
+	// Query выполняет запрос и возвращает строки результата. Значения params
+	// подставляются в плейсхолдеры вида {name:String} на стороне сервера
+	Query(ctx context.Context, query string, params map[string]string) ([]map[string]interface{}, error)
+	// Exec выполняет команду. body передается как данные INSERT, settings — как настройки запроса
+	Exec(ctx context.Context, query string, body io.Reader, settings map[string]string) error
+	// Ping проверяет доступность сервера
+	Ping(ctx context.Context) error
+	// Database возвращает базу данных по умолчанию
+	Database() string
+}
+
+// ClickHouseOptions параметры подключения к ClickHouse
+type ClickHouseOptions struct {
+	// URL адрес HTTP интерфейса, например http://clickhouse:8123
+	URL      string
+	User     string
+	Password string
+	Database string
+	Timeout  time.Duration
+}
+
+// ClickHouseError ошибка, которую вернул сервер ClickHouse
+type ClickHouseError struct {
+	StatusCode int
+	// Code код исключения ClickHouse (X-ClickHouse-Exception-Code)
+	Code    string
+	Message string
+}
+
+func (e *ClickHouseError) Error() string {
+	return fmt.Sprintf("clickhouse error %s (HTTP %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// clickhouseClient реализация ClickHouseClient
+type clickhouseClient struct {
+	opts       ClickHouseOptions
+	httpClient *http.Client
+	logger     logger.Logger
+}
+
+// NewClickHouseClient создает клиент HTTP интерфейса ClickHouse. Соединение
+// не устанавливается до первого запроса
+func NewClickHouseClient(opts ClickHouseOptions, logger logger.Logger) ClickHouseClient {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	opts.URL = strings.TrimRight(opts.URL, "/")
+	return &clickhouseClient{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		logger:     logger,
+	}
+}
+
+// Query выполняет запрос в формате JSON
+func (c *clickhouseClient) Query(ctx context.Context, query string, params map[string]string) ([]map[string]interface{}, error) {
+	values := url.Values{"default_format": {"JSON"}}
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+
+	resp, err := c.do(ctx, values, strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		if err == io.EOF {
+			// Команды без результата возвращают пустое тело
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to decode clickhouse response: %w", err)
+	}
+	return result.Data, nil
+}
+
+// Exec выполняет команду; при наличии body текст запроса передается в параметре query
+func (c *clickhouseClient) Exec(ctx context.Context, query string, body io.Reader, settings map[string]string) error {
+	values := url.Values{}
+	for name, value := range settings {
+		values.Set(name, value)
+	}
+	if body == nil {
+		body = strings.NewReader(query)
+	} else {
+		values.Set("query", query)
+	}
+
+	resp, err := c.do(ctx, values, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// Ping проверяет доступность сервера через /ping
+func (c *clickhouseClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.URL+"/ping", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to ping clickhouse: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readClickHouseError(resp)
+	}
+	return nil
+}
+
+func (c *clickhouseClient) Database() string {
+	return c.opts.Database
+}
+
+// do отправляет POST запрос с учетными данными в заголовках
+func (c *clickhouseClient) do(ctx context.Context, values url.Values, body io.Reader) (*http.Response, error) {
+	if c.opts.Database != "" {
+		values.Set("database", c.opts.Database)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.URL+"/?"+values.Encode(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-ClickHouse-User", c.opts.User)
+	if c.opts.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.opts.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.WithField("error", err.Error()).Error("clickhouseClient: request failed")
+		return nil, fmt.Errorf("failed to send clickhouse request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readClickHouseError(resp)
+	}
+	return resp, nil
+}
+
+// readClickHouseError читает текст исключения из ответа с ошибкой
+func readClickHouseError(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxClickHouseErrorSize))
+	return &ClickHouseError{
+		StatusCode: resp.StatusCode,
+		Code:       resp.Header.Get("X-ClickHouse-Exception-Code"),
+		Message:    string(bytes.TrimSpace(message)),
+	}
+}
@@ -0,0 +1,399 @@
+package client
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ai-data-engineer-backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// tempFilePrefix префикс временных файлов незавершенной записи
+const tempFilePrefix = ".upload-"
+
+// multipartDir каталог внутри basePath для частей незавершенных multipart загрузок
+const multipartDir = ".multipart"
+
+// filesystemClient реализация StorageClient поверх локальной файловой системы.
+// Bucket соответствует каталогу внутри basePath, имя объекта — относительному пути в нем
+type filesystemClient struct {
+	basePath string
+	logger   logger.Logger
+}
+
+// NewFilesystemClient создает клиент хранилища в локальном каталоге
+func NewFilesystemClient(basePath string, logger logger.Logger) (*filesystemClient, error) {
+	if basePath == "" {
+		return nil, fmt.Errorf("storage base path is empty")
+	}
+	if err := os.MkdirAll(basePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &filesystemClient{
+		basePath: basePath,
+		logger:   logger,
+	}, nil
+}
+
+// bucketPath возвращает каталог bucket
+func (f *filesystemClient) bucketPath(bucket string) (string, error) {
+	// Имена, начинающиеся с точки, зарезервированы под служебные каталоги
+	if bucket == "" || strings.HasPrefix(bucket, ".") || strings.ContainsAny(bucket, `/\`) {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	return filepath.Join(f.basePath, bucket), nil
+}
+
+// objectPath возвращает путь к объекту, не допуская выхода за пределы bucket
+func (f *filesystemClient) objectPath(bucket, objectName string) (string, error) {
+	root, err := f.bucketPath(bucket)
+	if err != nil {
+		return "", err
+	}
+	if objectName == "" || strings.Contains(objectName, `\`) || path.Clean("/"+objectName) != "/"+objectName {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return filepath.Join(root, filepath.FromSlash(objectName)), nil
+}
+
+// UploadFile атомарно записывает объект: данные пишутся во временный файл,
+// который переименовывается только после успешной записи
+func (f *filesystemClient) UploadFile(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, contentType string) error {
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("size", size).Info("Uploading file to filesystem storage")
+
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), tempFilePrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	src := &contextReader{ctx: ctx, r: reader}
+	var written int64
+	if size >= 0 {
+		written, err = io.CopyN(tmp, src, size)
+	} else {
+		written, err = io.Copy(tmp, src)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to commit file: %w", err)
+	}
+	committed = true
+
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("size", written).Info("File uploaded to filesystem storage successfully")
+	return nil
+}
+
+// CopyFile копирует объект внутри bucket с атомарной записью копии
+func (f *filesystemClient) CopyFile(ctx context.Context, bucket, srcObject, dstObject string) error {
+	src, err := f.DownloadFile(ctx, bucket, srcObject)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return f.UploadFile(ctx, bucket, dstObject, src, -1, "")
+}
+
+// DownloadFile открывает объект на чтение
+func (f *filesystemClient) DownloadFile(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).Info("Downloading file from filesystem storage")
+
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return file, nil
+}
+
+// DownloadFileAsBytes читает объект целиком
+func (f *filesystemClient) DownloadFileAsBytes(ctx context.Context, bucket, objectName string) ([]byte, error) {
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return content, nil
+}
+
+// DeleteFile удаляет объект; как и в MinIO, удаление отсутствующего объекта не является ошибкой
+func (f *filesystemClient) DeleteFile(ctx context.Context, bucket, objectName string) error {
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).Info("Deleting file from filesystem storage")
+
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// ListFiles возвращает имена объектов bucket с указанным префиксом
+func (f *filesystemClient) ListFiles(ctx context.Context, bucket, prefix string) ([]string, error) {
+	root, err := f.bucketPath(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempFilePrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			files = append(files, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// FileExists проверяет существование объекта
+func (f *filesystemClient) FileExists(ctx context.Context, bucket, objectName string) (bool, error) {
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check file existence: %w", err)
+	}
+	return !info.IsDir(), nil
+}
+
+// GetFileInfo получает информацию об объекте
+func (f *filesystemClient) GetFileInfo(ctx context.Context, bucket, objectName string) (*FileInfo, error) {
+	target, err := f.objectPath(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	return &FileInfo{
+		Name:         objectName,
+		Size:         info.Size(),
+		ContentType:  GetContentType(objectName),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// uploadPath возвращает каталог частей multipart загрузки
+func (f *filesystemClient) uploadPath(uploadID string) (string, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", fmt.Errorf("invalid upload id %q", uploadID)
+	}
+	dir := filepath.Join(f.basePath, multipartDir, uploadID)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("multipart upload %s not found: %w", uploadID, err)
+	}
+	return dir, nil
+}
+
+// partPath возвращает путь к файлу части
+func partPath(dir string, partNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("%05d", partNumber))
+}
+
+// NewMultipartUpload начинает multipart загрузку: части хранятся в отдельном каталоге до сборки объекта
+func (f *filesystemClient) NewMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error) {
+	if _, err := f.objectPath(bucket, objectName); err != nil {
+		return "", err
+	}
+	uploadID := uuid.New().String()
+	if err := os.MkdirAll(filepath.Join(f.basePath, multipartDir, uploadID), 0o755); err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("upload_id", uploadID).Info("Multipart upload started")
+	return uploadID, nil
+}
+
+// PutObjectPart атомарно записывает часть и возвращает ее ETag (MD5 содержимого, как в S3)
+func (f *filesystemClient) PutObjectPart(ctx context.Context, bucket, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	dir, err := f.uploadPath(uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := md5.New()
+	src := &contextReader{ctx: ctx, r: reader}
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), src)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+	if size >= 0 && written != size {
+		return "", fmt.Errorf("part %d size mismatch: expected %d, got %d", partNumber, size, written)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to close part: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), partPath(dir, partNumber)); err != nil {
+		return "", fmt.Errorf("failed to commit part: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// CompleteMultipartUpload собирает объект из частей в указанном порядке и удаляет каталог загрузки
+func (f *filesystemClient) CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, parts []CompletedPart) error {
+	dir, err := f.uploadPath(uploadID)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if _, err := os.Stat(partPath(dir, part.PartNumber)); err != nil {
+			return fmt.Errorf("part %d not found: %w", part.PartNumber, err)
+		}
+	}
+
+	// Части открываются по очереди, чтобы не держать открытыми тысячи файлов
+	src := &partsReader{dir: dir, parts: parts}
+	defer src.Close()
+	if err := f.UploadFile(ctx, bucket, objectName, src, -1, ""); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		f.logger.WithField("error", err.Error()).WithField("upload_id", uploadID).Warn("Failed to remove multipart upload directory")
+	}
+	f.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("upload_id", uploadID).Info("Multipart upload completed")
+	return nil
+}
+
+// AbortMultipartUpload удаляет загруженные части
+func (f *filesystemClient) AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error {
+	dir, err := f.uploadPath(uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// partsReader последовательно читает части и сверяет MD5 каждой с ожидаемым ETag
+type partsReader struct {
+	dir     string
+	parts   []CompletedPart
+	current *os.File
+	hasher  hash.Hash
+}
+
+func (p *partsReader) Read(buf []byte) (int, error) {
+	for {
+		if p.current == nil {
+			if len(p.parts) == 0 {
+				return 0, io.EOF
+			}
+			file, err := os.Open(partPath(p.dir, p.parts[0].PartNumber))
+			if err != nil {
+				return 0, err
+			}
+			p.current, p.hasher = file, md5.New()
+		}
+
+		n, err := p.current.Read(buf)
+		p.hasher.Write(buf[:n])
+		if err == io.EOF {
+			part := p.parts[0]
+			p.current.Close()
+			p.current, p.parts = nil, p.parts[1:]
+			if etag := hex.EncodeToString(p.hasher.Sum(nil)); etag != strings.Trim(part.ETag, `"`) {
+				return n, fmt.Errorf("part %d etag mismatch", part.PartNumber)
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// Close закрывает текущую часть, если чтение было прервано
+func (p *partsReader) Close() error {
+	if p.current == nil {
+		return nil
+	}
+	return p.current.Close()
+}
+
+// contextReader прерывает чтение при отмене контекста
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -16,7 +16,7 @@ import (
 type LLMClient interface {
 	SendRequest(ctx context.Context, req *models.LLMRequest, endpoint string) (*models.LLMResponse, error)
 	GenerateDDL(ctx context.Context, req *models.GenerateDDLRequest) (*models.GenerateDDLResponse, error)
-	AnalyzeFile(ctx context.Context, userID string) (*models.LLMResponse, error)
+	AnalyzeFile(ctx context.Context, req *models.LLMRequest) (*models.LLMResponse, error)
 }
 
 // llmClient реализация LLMClient
@@ -60,7 +60,14 @@ func (c *llmClient) SendRequest(ctx context.Context, req *models.LLMRequest, end
 
 	url := c.baseURL + endpoint
 	c.logger.WithField("url", url).Info("llmClient.SendRequest: Sending request")
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Запрос прерывается при отмене ctx, например по таймауту анализа
+	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		c.logger.WithField("error", err.Error()).Error("llmClient.SendRequest: Failed to send request")
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -73,6 +80,11 @@ func (c *llmClient) SendRequest(ctx context.Context, req *models.LLMRequest, end
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		c.logger.WithField("status", resp.StatusCode).Error("llmClient.SendRequest: Unexpected response status")
+		return nil, fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, string(body))
+	}
+
 	llmResp := models.LLMResponse{
 		Content: string(body),
 	}
@@ -87,8 +99,9 @@ func (c *llmClient) GenerateDDL(ctx context.Context, req *models.GenerateDDLRequ
 	return nil, nil
 }
 
-// AnalyzeFile отправляет запрос на анализ файла в LLM
-func (c *llmClient) AnalyzeFile(ctx context.Context, userID string) (*models.LLMResponse, error) {
+// AnalyzeFile отправляет запрос на анализ файла в LLM вместе с профилем данных
+func (c *llmClient) AnalyzeFile(ctx context.Context, req *models.LLMRequest) (*models.LLMResponse, error) {
+	userID := req.UserID
 	c.logger.WithField("user_id", userID).Info("LLMClient.AnalyzeFile: Starting")
 
 	// Получаем endpoint для анализа файла
@@ -103,7 +116,7 @@ func (c *llmClient) AnalyzeFile(ctx context.Context, userID string) (*models.LLM
 	}
 
 	// Отправляем запрос через sendRequest
-	resp, err := c.SendRequest(ctx, nil, endpoint)
+	resp, err := c.SendRequest(ctx, req, endpoint)
 	if err != nil {
 		c.logger.WithField("error", err.Error()).Error("LLMClient.AnalyzeFile: Failed to send request")
 		return nil, fmt.Errorf("failed to analyze file: %w", err)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -23,16 +25,29 @@ type FileInfo struct {
 	ETag         string    `json:"etag"`
 }
 
+// CompletedPart загруженная часть multipart загрузки
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// streamPartSize размер части при потоковой загрузке объекта неизвестного размера
+const streamPartSize = 16 << 20
+
 // minioClient реализация MinIOClient
 type minioClient struct {
 	client *minio.Client
-	logger logger.Logger
+	// presigner подписывает ссылки для клиентов; по умолчанию совпадает с client
+	presigner *minio.Client
+	creds     *credentials.Credentials
+	logger    logger.Logger
 }
 
 // NewMinIOClient создает новый MinIO клиент
 func NewMinIOClient(endpoint, accessKeyID, secretAccessKey string, useSSL bool, logger logger.Logger) (*minioClient, error) {
+	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
 	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Creds:  creds,
 		Secure: useSSL,
 	})
 	if err != nil {
@@ -40,11 +55,55 @@ func NewMinIOClient(endpoint, accessKeyID, secretAccessKey string, useSSL bool,
 	}
 
 	return &minioClient{
-		client: client,
-		logger: logger,
+		client:    client,
+		presigner: client,
+		creds:     creds,
+		logger:    logger,
 	}, nil
 }
 
+// UsePublicEndpoint подписывает ссылки для адреса, доступного браузеру (например,
+// когда сервер обращается к MinIO по внутреннему имени в docker сети).
+// Регион задается явно, чтобы подпись не требовала запроса к публичному адресу
+func (m *minioClient) UsePublicEndpoint(endpoint string, useSSL bool, region string) error {
+	presigner, err := minio.New(endpoint, &minio.Options{
+		Creds:  m.creds,
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create MinIO presign client: %w", err)
+	}
+	m.presigner = presigner
+	return nil
+}
+
+// PresignedGetURL возвращает временную ссылку на скачивание объекта.
+// Если задан filename, браузер сохранит файл под этим именем
+func (m *minioClient) PresignedGetURL(ctx context.Context, bucket, objectName, filename string, expires time.Duration) (string, error) {
+	params := url.Values{}
+	if filename != "" {
+		params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	u, err := m.presigner.PresignedGetObject(ctx, bucket, objectName, expires, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download: %w", err)
+	}
+	return u.String(), nil
+}
+
+// PresignedPutURL возвращает временную ссылку на загрузку объекта PUT запросом
+func (m *minioClient) PresignedPutURL(ctx context.Context, bucket, objectName string, expires time.Duration) (string, error) {
+	if err := m.checkMinIOConnection(ctx, bucket); err != nil {
+		return "", fmt.Errorf("failed to check MinIO connection: %w", err)
+	}
+	u, err := m.presigner.PresignedPutObject(ctx, bucket, objectName, expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign upload: %w", err)
+	}
+	return u.String(), nil
+}
+
 // * checkMinIOConnection проверяет подключение к MinIO
 func (m *minioClient) checkMinIOConnection(ctx context.Context, bucket string) error {
 
@@ -83,9 +142,13 @@ func (m *minioClient) UploadFile(ctx context.Context, bucket, objectName string,
 
 	// Загружаем файл
 	m.logger.Info("Uploading file to MinIO")
-	_, err = m.client.PutObject(ctx, bucket, objectName, reader, size, minio.PutObjectOptions{
-		ContentType: contentType,
-	})
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		// Размер неизвестен: MinIO выполнит multipart загрузку частями фиксированного размера,
+		// не буферизуя весь поток в памяти
+		opts.PartSize = streamPartSize
+	}
+	_, err = m.client.PutObject(ctx, bucket, objectName, reader, size, opts)
 	if err != nil {
 		m.logger.WithField("error", err.Error()).Error("Failed to upload file to MinIO")
 		return fmt.Errorf("failed to upload file: %w", err)
@@ -95,6 +158,68 @@ func (m *minioClient) UploadFile(ctx context.Context, bucket, objectName string,
 	return nil
 }
 
+// NewMultipartUpload начинает multipart загрузку объекта и возвращает ее ID
+func (m *minioClient) NewMultipartUpload(ctx context.Context, bucket, objectName, contentType string) (string, error) {
+	if err := m.checkMinIOConnection(ctx, bucket); err != nil {
+		return "", fmt.Errorf("failed to check MinIO connection: %w", err)
+	}
+
+	core := minio.Core{Client: m.client}
+	uploadID, err := core.NewMultipartUpload(ctx, bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	m.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("upload_id", uploadID).Info("Multipart upload started")
+	return uploadID, nil
+}
+
+// PutObjectPart загружает часть multipart загрузки и возвращает ее ETag
+func (m *minioClient) PutObjectPart(ctx context.Context, bucket, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: m.client}
+	part, err := core.PutObjectPart(ctx, bucket, objectName, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteMultipartUpload собирает объект из загруженных частей
+func (m *minioClient) CompleteMultipartUpload(ctx context.Context, bucket, objectName, uploadID string, parts []CompletedPart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	core := minio.Core{Client: m.client}
+	if _, err := core.CompleteMultipartUpload(ctx, bucket, objectName, uploadID, completeParts, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	m.logger.WithField("bucket", bucket).WithField("object", objectName).WithField("upload_id", uploadID).Info("Multipart upload completed")
+	return nil
+}
+
+// AbortMultipartUpload отменяет multipart загрузку и освобождает загруженные части
+func (m *minioClient) AbortMultipartUpload(ctx context.Context, bucket, objectName, uploadID string) error {
+	core := minio.Core{Client: m.client}
+	if err := core.AbortMultipartUpload(ctx, bucket, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// CopyFile копирует объект внутри bucket на стороне MinIO.
+// ComposeObject, в отличие от CopyObject, поддерживает объекты больше 5 ГБ
+func (m *minioClient) CopyFile(ctx context.Context, bucket, srcObject, dstObject string) error {
+	_, err := m.client.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: dstObject},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcObject},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
 // DownloadFile скачивает файл из MinIO
 func (m *minioClient) DownloadFile(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
 	m.logger.WithField("bucket", bucket).WithField("object", objectName).Info("Downloading file from MinIO")
@@ -0,0 +1,213 @@
+// Package cron разбирает cron выражения из пяти полей и вычисляет время следующего запуска
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule разобранное cron выражение: минута, час, день месяца, месяц и день недели.
+// Поля хранятся битовыми масками допустимых значений
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny и dowAny — поле задано как *. Если ограничены оба поля дней,
+	// подходит день, удовлетворяющий любому из них (как в Vixie cron)
+	domAny, dowAny bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "минута", min: 0, max: 59}
+	hourField   = field{name: "час", min: 0, max: 23}
+	domField    = field{name: "день месяца", min: 1, max: 31}
+	monthField  = field{name: "месяц", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// dowField день недели: 0 и 7 — воскресенье
+	dowField = field{name: "день недели", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros сокращения для распространенных расписаний
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchYears на сколько лет вперед искать следующий запуск
+const searchYears = 5
+
+// Parse разбирает выражение "минута час день месяц день_недели". Поддерживаются *, списки (1,15),
+// диапазоны (1-5), шаги (*/15, 10-50/10), имена месяцев и дней недели (JAN, MON) и макросы @daily и др.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("ожидается 5 полей, получено %d", len(fields))
+	}
+
+	s := &Schedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	for _, target := range []struct {
+		mask  *uint64
+		value string
+		field field
+	}{
+		{&s.minute, fields[0], minuteField},
+		{&s.hour, fields[1], hourField},
+		{&s.dom, fields[2], domField},
+		{&s.month, fields[3], monthField},
+		{&s.dow, fields[4], dowField},
+	} {
+		if *target.mask, err = parseField(target.value, target.field); err != nil {
+			return nil, err
+		}
+	}
+	// Воскресенье можно задать как 0 или 7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// parseField разбирает поле выражения в битовую маску
+func parseField(value string, f field) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			parsed, err := strconv.Atoi(part[i+1:])
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("поле %q (%s): некорректный шаг", value, f.name)
+			}
+			rangePart, step = part[:i], parsed
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("поле %q (%s): начало диапазона больше конца", value, f.name)
+			}
+		default:
+			single, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			low = single
+			// Одиночное значение с шагом (5/15) означает диапазон до конца поля
+			if step == 1 {
+				high = single
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// value разбирает число или имя значения поля
+func (f field) value(token string) (int, error) {
+	if v, ok := f.names[strings.ToLower(token)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(token)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: некорректное значение %q, ожидается %d-%d", f.name, token, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next возвращает первое время запуска строго после t в часовом поясе t.
+// Нулевое время — запусков в ближайшие годы нет (например, 30 февраля)
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	// Поля проверяются от месяца к минуте, после каждого сдвига — заново с месяца.
+	// Младшие поля обнуляются при первом сдвиге, дальше сдвиги сохраняют их нулевыми
+	reset := false
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+			}
+			t = t.AddDate(0, 1, 0)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+			}
+			t = t.AddDate(0, 0, 1)
+			// Переход на летнее время мог сдвинуть полночь
+			if h := t.Hour(); h != 0 {
+				if h > 12 {
+					t = t.Add(time.Duration(24-h) * time.Hour)
+				} else {
+					t = t.Add(-time.Duration(h) * time.Hour)
+				}
+			}
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+			}
+			t = t.Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			reset = true
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches проверяет день месяца и день недели
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
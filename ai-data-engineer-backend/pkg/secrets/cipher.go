@@ -0,0 +1,98 @@
+// Package secrets шифрует секреты, которые сервис хранит в своей базе
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize размер ключа AES-256 в байтах
+const KeySize = 32
+
+// ErrDecrypt секрет поврежден, зашифрован другим ключом или для другой записи
+var ErrDecrypt = errors.New("failed to decrypt secret")
+
+// Cipher шифрует секреты AES-GCM. Результат шифрования — случайный nonce,
+// за которым следует шифртекст с тегом аутентификации
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher создает Cipher с ключом AES-256
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt шифрует plaintext. additionalData (например, ID записи) не шифруется,
+// но должен совпасть при расшифровке: шифртекст нельзя перенести в другую запись
+func (c *Cipher) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt расшифровывает результат Encrypt
+func (c *Cipher) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < c.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// LoadKey возвращает ключ из значения конфигурации или из файла keyFile.
+// Ключ задается в base64 или hex; значение имеет приоритет над файлом
+func LoadKey(value, keyFile string) ([]byte, error) {
+	if value == "" && keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		value = string(content)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, errors.New("encryption key is not configured")
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// GenerateKey создает случайный ключ AES-256
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
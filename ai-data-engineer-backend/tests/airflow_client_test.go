@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAirflowClient проверяет снятие DAG с паузы, запуск с параметрами, опрос состояния
+// запуска и задач, чтение журнала и ошибки API на заглушке REST API Airflow
+func TestAirflowClient(t *testing.T) {
+	const dagID, runID = "pipeline_orders", "manual__2024-05-01T03:00:00+00:00"
+	var mu sync.Mutex
+	paused, polls := true, 0
+	var conf map[string]interface{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/dags/"+dagID, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Query().Get("update_mask") != "is_paused" {
+			t.Errorf("Неожиданный запрос DAG: %s %s", r.Method, r.URL)
+		}
+		var body struct {
+			IsPaused bool `json:"is_paused"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		paused = body.IsPaused
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"dag_id": dagID, "is_paused": body.IsPaused})
+	})
+	mux.HandleFunc("/api/v1/dags/"+dagID+"/dagRuns", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			DAGRunID string                 `json:"dag_run_id"`
+			Conf     map[string]interface{} `json:"conf"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		conf = body.Conf
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"dag_id": dagID, "dag_run_id": body.DAGRunID, "state": "queued", "conf": body.Conf,
+			"logical_date": "2024-05-01T03:00:00+00:00", "start_date": nil,
+		})
+	})
+	mux.HandleFunc("/api/v1/dags/"+dagID+"/dagRuns/"+runID, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		attempt := polls
+		mu.Unlock()
+		// Второй и третий опросы получают временные ошибки Airflow, после которых опрос продолжается
+		switch attempt {
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		state := []string{"queued", "", "", "running", "success"}[min(attempt-1, 4)]
+		json.NewEncoder(w).Encode(map[string]interface{}{"dag_id": dagID, "dag_run_id": runID, "state": state})
+	})
+	states := []interface{}{"success", "failed", "upstream_failed", "up_for_retry", nil}
+	mux.HandleFunc("/api/v1/dags/"+dagID+"/dagRuns/"+runID+"/taskInstances", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		// Заглушка отдает по две задачи, проверяя постраничное чтение
+		var page []map[string]interface{}
+		for i := offset; i < len(states) && i < offset+2; i++ {
+			page = append(page, map[string]interface{}{"task_id": fmt.Sprintf("task_%d", i), "state": states[i], "try_number": 1})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"task_instances": page, "total_entries": len(states)})
+	})
+	mux.HandleFunc("/api/v1/dags/"+dagID+"/dagRuns/"+runID+"/taskInstances/task_1/logs/2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/plain" {
+			t.Errorf("Журнал запрошен не как текст: %s", r.Header.Get("Accept"))
+		}
+		w.Write([]byte("[2024-05-01] ERROR - таблица недоступна\n"))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"title": "Unauthorized", "detail": "No credentials", "status": 401}`))
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern == "" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title": "DAG not found", "detail": "DAG with dag_id: 'missing' not found", "status": 404}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	airflow := client.NewAirflowClient(client.AirflowOptions{BaseURL: server.URL + "/", Username: "admin", Password: "secret"}, testLogger)
+
+	if err := airflow.SetPaused(ctx, dagID, false); err != nil || paused {
+		t.Fatalf("DAG не снят с паузы: %v", err)
+	}
+	run, err := airflow.TriggerDAGRun(ctx, dagID, runID, map[string]interface{}{"trigger": "api", "limit": 10})
+	if err != nil {
+		t.Fatalf("Не удалось запустить DAG: %v", err)
+	}
+	if run.DAGRunID != runID || run.ExecutionStatus() != models.ExecutionStatusScheduled || conf["trigger"] != "api" ||
+		run.LogicalDate == nil || !run.LogicalDate.Equal(time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("Неожиданный запуск: %+v, conf %v", run, conf)
+	}
+
+	run, err = airflow.WaitDAGRun(ctx, dagID, runID, time.Millisecond)
+	if err != nil || run.State != client.AirflowStateSuccess || run.ExecutionStatus() != models.ExecutionStatusCompleted || polls != 5 {
+		t.Errorf("Ожидалось завершение запуска после 5 опросов: %+v, %v, опросов %d", run, err, polls)
+	}
+	// Ответ 4xx завершает ожидание сразу
+	if _, err := airflow.WaitDAGRun(ctx, "missing", runID, time.Millisecond); !errors.As(err, new(*client.AirflowError)) {
+		t.Errorf("Ожидалась ошибка 404 Airflow при ожидании запуска, получили %v", err)
+	}
+
+	tasks, err := airflow.ListTaskInstances(ctx, dagID, runID)
+	if err != nil || len(tasks) != len(states) {
+		t.Fatalf("Ожидалось %d задач, получили %d: %v", len(states), len(tasks), err)
+	}
+	expected := []models.StepStatus{models.StepStatusCompleted, models.StepStatusFailed, models.StepStatusSkipped,
+		models.StepStatusRunning, models.StepStatusPending}
+	for i, task := range tasks {
+		if task.StepStatus() != expected[i] {
+			t.Errorf("Задача %s (%q): ожидался статус %s, получили %s", task.TaskID, task.State, expected[i], task.StepStatus())
+		}
+	}
+
+	log, err := airflow.GetTaskLog(ctx, dagID, runID, "task_1", 2)
+	if err != nil || log != "[2024-05-01] ERROR - таблица недоступна\n" {
+		t.Errorf("Неожиданный журнал задачи: %q, %v", log, err)
+	}
+
+	_, err = airflow.GetDAGRun(ctx, "missing", runID)
+	var apiErr *client.AirflowError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Title != "DAG not found" {
+		t.Errorf("Ожидалась ошибка 404 Airflow, получили %v", err)
+	}
+	anonymous := client.NewAirflowClient(client.AirflowOptions{BaseURL: server.URL}, testLogger)
+	if err := anonymous.SetPaused(ctx, dagID, true); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Ожидалась ошибка 401 Airflow, получили %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAirflowDAG проверяет формирование DAG Airflow по шагам, зависимостям, политикам
+// и расписанию пайплайна и атомарную публикацию файла в каталог DAG
+func TestAirflowDAG(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", FilePath: "users/analyst/orders.csv", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	pipelines := service.NewPipelineService(pipelineRepo, analyses, testLogger)
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1",
+		Name:       `Ночная загрузка "orders"`,
+		Target:     models.DataTarget{Type: "postgres", ConnectionString: "postgres", TableName: "orders"},
+		Schedule:   &models.PipelineSchedule{Cron: "0 3 * * *", Timezone: "Europe/Moscow", CatchUp: models.CatchUpAll},
+		Steps: []models.PipelineStep{
+			{ID: "extract", Type: models.StepTypeExtract},
+			{ID: "clean-a", Type: models.StepTypeTransform, DependsOn: []string{"extract"},
+				Config: map[string]interface{}{"mapping": map[string]interface{}{"id": "id"}}},
+			{ID: "clean_a", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+			{ID: "load", Type: models.StepTypeLoad, DependsOn: []string{"clean-a", "clean_a"},
+				Config: map[string]interface{}{"timeout": "10m", "retry": map[string]interface{}{"max_attempts": float64(3), "backoff": "30s"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+
+	dagsPath := filepath.Join(t.TempDir(), "dags")
+	airflow := service.NewAirflowService(pipelineRepo, nil, nil, nil, service.NewEventBroker(0, 0), service.AirflowOptions{DAGsPath: dagsPath}, testLogger)
+	handler := handlers.NewAirflowHandler(airflow, testLogger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/pipelines/:id/airflow-dag", handler.GetDAG)
+	router.POST("/api/v1/pipelines/:id/airflow-dag", handler.PublishDAG)
+
+	requestAs := func(userID, method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/pipelines/"+created.PipelineID+"/airflow-dag", nil)
+		req.Header.Set("X-User-ID", userID)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if w := requestAs("intruder", method); w.Code != http.StatusForbidden {
+			t.Errorf("%s чужого DAG: ожидался статус 403, получили %d", method, w.Code)
+		}
+	}
+
+	w := requestAs("analyst", http.MethodGet)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/x-python") {
+		t.Fatalf("Ожидался DAG, получили %d %s: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	source := w.Body.String()
+	dagID := "pipeline_" + strings.ReplaceAll(created.PipelineID, "-", "_")
+	for _, expected := range []string{
+		`dag_id="` + dagID + `"`,
+		`schedule="0 3 * * *"`,
+		`tz="Europe/Moscow"`,
+		`catchup=True`,
+		`task_id="clean_a"`,
+		`task_id="clean_a_2"`,
+		`python_callable=run_transform`,
+		`"mapping": {"id": "id"}`,
+		"retries=2,\n        retry_delay=timedelta(seconds=30),\n        retry_exponential_backoff=True,\n        max_retry_delay=timedelta(seconds=60),",
+		`execution_timeout=timedelta(seconds=600)`,
+		`extract_task >> clean_a_task`,
+		`[clean_a_task, clean_a_2_task] >> load_task`,
+		`Ночная загрузка \"orders\"`,
+		`"table_name": "orders"`,
+		`"conn_id": "ai_data_engineer_postgres"`,
+		`BaseHook.get_connection(PIPELINE["target"]["conn_id"])`,
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("В DAG нет %q:\n%s", expected, source)
+		}
+	}
+
+	w = requestAs("analyst", http.MethodPost)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var published models.AirflowDAGResponse
+	json.Unmarshal(w.Body.Bytes(), &published)
+	written, err := os.ReadFile(filepath.Join(dagsPath, dagID+".py"))
+	if err != nil || string(written) != source || published.Location != filepath.Join(dagsPath, dagID+".py") {
+		t.Errorf("DAG не записан в каталог: %v, %+v", err, published)
+	}
+	if entries, _ := os.ReadDir(dagsPath); len(entries) != 1 {
+		t.Errorf("В каталоге DAG остались временные файлы: %d", len(entries))
+	}
+
+	// Без каталога и бакета публикация недоступна
+	unconfigured := service.NewAirflowService(pipelineRepo, nil, nil, nil, service.NewEventBroker(0, 0), service.AirflowOptions{}, testLogger)
+	if _, err := unconfigured.PublishDAG(ctx, "analyst", created.PipelineID); err == nil {
+		t.Error("Ожидалась ошибка публикации без настроек")
+	}
+	stored, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	taskIDs, err := service.AirflowTaskIDs(stored)
+	if err != nil || len(taskIDs) != 4 || taskIDs["extract"] != "extract" ||
+		taskIDs["clean_a"] == taskIDs["clean_a_2"] || !strings.HasPrefix(taskIDs["clean_a_2"], "clean") {
+		t.Errorf("Неожиданное сопоставление задач и шагов: %v, %v", taskIDs, err)
+	}
+	if _, err := airflow.RenderDAG(ctx, "analyst", "missing"); err == nil {
+		t.Error("Ожидалась ошибка для несуществующего пайплайна")
+	}
+
+	// Строка подключения с учетными данными в DAG не переносится
+	withDSN, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1",
+		Target:     models.DataTarget{Type: "postgres", ConnectionString: "postgres://loader:secret@db:5432/dwh", TableName: "orders"},
+		Steps:      []models.PipelineStep{{ID: "extract", Type: models.StepTypeExtract}},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	if dag, err := airflow.RenderDAG(ctx, "analyst", withDSN.PipelineID); err == nil {
+		t.Errorf("Ожидалась ошибка DAG для цели со строкой подключения:\n%s", dag.Source)
+	}
+}
@@ -0,0 +1,396 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAirflowRun проверяет запуск DAG пайплайна в Airflow с параметрами выполнения в conf,
+// перенос состояний запуска и задач на выполнение и шаги пайплайна и сохранение
+// запуска, который Airflow не принял
+func TestAirflowRun(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", FilePath: "users/analyst/orders.csv", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	pipelines := service.NewPipelineService(pipelineRepo, analyses, testLogger)
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1",
+		Name:       "orders",
+		Target:     models.DataTarget{Type: "postgres", ConnectionString: "postgres", TableName: "orders"},
+		Steps: []models.PipelineStep{
+			{ID: "extract", Type: models.StepTypeExtract},
+			{ID: "clean-a", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+			{ID: "load", Type: models.StepTypeLoad, DependsOn: []string{"clean-a"}},
+			{ID: "validate", Type: models.StepTypeValidate, DependsOn: []string{"load"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	stored, err := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if err != nil {
+		t.Fatalf("Пайплайн не сохранен: %v", err)
+	}
+	dagID := service.AirflowDAGID(stored)
+	taskIDs := make(map[string]string)
+	steps, err := service.AirflowTaskIDs(stored)
+	if err != nil {
+		t.Fatalf("Не удалось сопоставить задачи с шагами: %v", err)
+	}
+	for taskID, stepID := range steps {
+		taskIDs[stepID] = taskID
+	}
+
+	var mu sync.Mutex
+	var paused, reject bool
+	var runID string
+	var conf map[string]interface{}
+	polls := 0
+	paused = true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs := "/api/v1/dags/" + dagID + "/dagRuns"
+		switch {
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/dags/"+dagID:
+			paused = false
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_id": dagID, "is_paused": false})
+		case r.Method == http.MethodPost && r.URL.Path == runs && !reject:
+			var body struct {
+				DAGRunID string                 `json:"dag_run_id"`
+				Conf     map[string]interface{} `json:"conf"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			runID, conf = body.DAGRunID, body.Conf
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_id": dagID, "dag_run_id": runID, "state": "queued"})
+		case r.Method == http.MethodGet && runID != "" && r.URL.Path == runs+"/"+runID:
+			polls++
+			state := "running"
+			if polls > 2 {
+				state = "failed"
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_id": dagID, "dag_run_id": runID, "state": state})
+		case r.Method == http.MethodGet && runID != "" && r.URL.Path == runs+"/"+runID+"/taskInstances":
+			// Задача validate не попала в запуск
+			json.NewEncoder(w).Encode(map[string]interface{}{"task_instances": []map[string]interface{}{
+				{"task_id": taskIDs["extract"], "state": "success", "try_number": 1,
+					"start_date": "2024-05-01T03:00:00+00:00", "end_date": "2024-05-01T03:01:00+00:00"},
+				{"task_id": taskIDs["clean-a"], "state": "failed", "try_number": 2},
+				{"task_id": taskIDs["load"], "state": "upstream_failed", "try_number": 0},
+			}, "total_entries": 3})
+		default:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title": "DAG not found", "status": 404}`))
+		}
+	}))
+	defer server.Close()
+
+	airflowClient := client.NewAirflowClient(client.AirflowOptions{BaseURL: server.URL}, testLogger)
+	airflow := service.NewAirflowService(pipelineRepo, executions, nil, airflowClient, service.NewEventBroker(0, 0),
+		service.AirflowOptions{PollInterval: time.Millisecond}, testLogger)
+	handler := handlers.NewAirflowHandler(airflow, testLogger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/pipelines/:id/airflow-runs", handler.RunDAG)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pipelines/"+created.PipelineID+"/airflow-runs",
+		strings.NewReader(`{"parameters": {"date": "2024-05-01"}}`))
+	req.Header.Set("X-User-ID", "analyst")
+	router.ServeHTTP(w, req)
+	var started models.ExecutePipelineResponse
+	json.Unmarshal(w.Body.Bytes(), &started)
+	if w.Code != http.StatusAccepted || started.Status != string(models.ExecutionStatusRunning) {
+		t.Fatalf("Ожидался запуск DAG со статусом 202: %d %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var execution *models.PipelineExecution
+	for {
+		execution, err = executions.GetExecution(ctx, started.ExecutionID)
+		if err == nil && execution.Status != models.ExecutionStatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Выполнение не завершено: %+v, %v", execution, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	if paused || runID != started.ExecutionID || conf["date"] != "2024-05-01" || polls != 3 {
+		t.Errorf("Неожиданный запуск в Airflow: пауза %v, run_id %q, conf %v, опросов %d", paused, runID, conf, polls)
+	}
+	mu.Unlock()
+	if execution.Status != models.ExecutionStatusFailed || execution.UserID != "analyst" || execution.CompletedAt == nil ||
+		!strings.Contains(execution.Error, "clean-a") || len(execution.Logs) == 0 {
+		t.Errorf("Ожидалось выполнение с ошибкой в шаге clean-a: %+v", execution)
+	}
+
+	pipeline, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if pipeline.Status != models.PipelineStatusFailed || pipeline.ExecutedAt == nil {
+		t.Errorf("Ожидался пайплайн в статусе failed: %+v", pipeline)
+	}
+	expected := map[string]models.StepStatus{
+		"extract": models.StepStatusCompleted, "clean-a": models.StepStatusFailed,
+		"load": models.StepStatusSkipped, "validate": models.StepStatusSkipped,
+	}
+	for _, step := range pipeline.Steps {
+		if step.Status != expected[step.ID] {
+			t.Errorf("Шаг %s: ожидался статус %s, получили %s", step.ID, expected[step.ID], step.Status)
+		}
+	}
+	if step := pipeline.Steps[0]; step.StartedAt == nil || step.CompletedAt == nil {
+		t.Errorf("Время задачи не перенесено на шаг: %+v", step)
+	}
+	if step := pipeline.Steps[1]; step.Error == "" {
+		t.Errorf("Ожидалась ошибка шага clean-a: %+v", step)
+	}
+
+	// Airflow не принял запуск: выполнение сохраняется с ошибкой
+	mu.Lock()
+	reject = true
+	mu.Unlock()
+	runAs := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pipelines/"+created.PipelineID+"/airflow-runs", nil)
+		req.Header.Set("X-User-ID", userID)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w = runAs("intruder"); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус %d для чужого пайплайна, получили %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	w = runAs("analyst")
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Ожидался статус %d, получили %d: %s", http.StatusBadGateway, w.Code, w.Body.String())
+	}
+	history, _ := executions.GetExecutionsByPipeline(ctx, created.PipelineID, 10, 0)
+	if len(history) != 2 || history[0].Status != models.ExecutionStatusFailed || history[0].CompletedAt == nil ||
+		!strings.Contains(history[0].Error, "Airflow") {
+		t.Errorf("Ожидалось сохраненное выполнение с ошибкой запуска: %+v", history)
+	}
+	if pipeline, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID); pipeline.Status != models.PipelineStatusFailed {
+		t.Errorf("Ожидался пайплайн в статусе failed, получили %s", pipeline.Status)
+	}
+
+	// Пайплайн, запущенный другим экземпляром сервиса, повторно не запускается ни в Airflow, ни локально
+	running, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	running.Status = models.PipelineStatusRunning
+	if err := pipelineRepo.StartPipeline(ctx, running); err != nil {
+		t.Fatalf("Не удалось запустить пайплайн: %v", err)
+	}
+	if err := pipelineRepo.StartPipeline(ctx, running); err == nil {
+		t.Errorf("Ожидался конфликт повторного запуска пайплайна")
+	}
+	if w = runAs("analyst"); w.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус %d, получили %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	unconfigured := service.NewAirflowService(pipelineRepo, executions, nil, nil, service.NewEventBroker(0, 0), service.AirflowOptions{}, testLogger)
+	if _, err := unconfigured.RunDAG(ctx, created.PipelineID, "", nil); err == nil || !strings.Contains(err.Error(), "airflow.base_url") {
+		t.Errorf("Ожидалась ошибка ненастроенного Airflow, получили %v", err)
+	}
+}
+
+// TestAirflowRunCancel проверяет, что отмена выполнения завершает запуск DAG в Airflow
+// состоянием failed, а выполнение и пайплайн получают статус cancelled
+func TestAirflowRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	created, err := service.NewPipelineService(pipelineRepo, analyses, testLogger).CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1", Name: "orders",
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	stored, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	runs := "/api/v1/dags/" + service.AirflowDAGID(stored) + "/dagRuns"
+
+	var mu sync.Mutex
+	state, runID := "running", ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == runs:
+			var body struct {
+				DAGRunID string `json:"dag_run_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			runID = body.DAGRunID
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_run_id": runID, "state": "queued"})
+		case r.Method == http.MethodPatch && runID != "" && r.URL.Path == runs+"/"+runID:
+			var body struct {
+				State string `json:"state"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			state = body.State
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_run_id": runID, "state": state})
+		case r.Method == http.MethodGet && runID != "" && r.URL.Path == runs+"/"+runID:
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_run_id": runID, "state": state})
+		case r.Method == http.MethodGet && r.URL.Path == runs+"/"+runID+"/taskInstances":
+			json.NewEncoder(w).Encode(map[string]interface{}{"task_instances": []interface{}{}, "total_entries": 0})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		}
+	}))
+	defer server.Close()
+
+	airflow := service.NewAirflowService(pipelineRepo, executions, nil, client.NewAirflowClient(client.AirflowOptions{BaseURL: server.URL}, testLogger),
+		service.NewEventBroker(0, 0), service.AirflowOptions{PollInterval: 5 * time.Millisecond}, testLogger)
+	airflow.Start(ctx)
+	executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0), service.ExecutorOptions{}, testLogger)
+
+	started, err := airflow.RunDAG(ctx, created.PipelineID, "analyst", nil)
+	if err != nil {
+		t.Fatalf("Не удалось запустить DAG: %v", err)
+	}
+	if err := executor.Cancel(ctx, "analyst", started.ID); err != nil {
+		t.Fatalf("Не удалось запросить отмену: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execution, _ := executions.GetExecution(ctx, started.ID)
+		if execution.Status == models.ExecutionStatusCancelled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Выполнение не отменено: %+v", execution)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	if state != client.AirflowStateFailed {
+		t.Errorf("Запуск DAG не завершен в Airflow: %s", state)
+	}
+	mu.Unlock()
+	if pipeline, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID); pipeline.Status != models.PipelineStatusCancelled {
+		t.Errorf("Ожидался статус пайплайна cancelled, получен %s", pipeline.Status)
+	}
+}
+
+// TestAirflowRunResume проверяет, что после остановки экземпляра сервиса отслеживание запусков DAG
+// возобновляется, а выполнение завершается ошибкой, только если Airflow не знает запуска
+func TestAirflowRunResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	leases := memory.NewLeaseRepository()
+
+	alive := service.NewInstance(leases, service.InstanceOptions{ID: "alive", LeaseTTL: time.Minute}, testLogger)
+	if err := alive.Start(ctx); err != nil {
+		t.Fatalf("Не удалось зарегистрировать экземпляр: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	for _, id := range []string{"dead", "missing", "alive"} {
+		pipelineRepo.SavePipeline(ctx, &models.Pipeline{
+			ID: id, UserID: "analyst", Status: models.PipelineStatusRunning, CreatedAt: old, UpdatedAt: old,
+			Steps: []models.PipelineStep{{ID: "extract", Type: models.StepTypeExtract, Status: models.StepStatusPending}},
+		})
+		owner := "dead"
+		if id == "alive" {
+			owner = "alive"
+		}
+		executions.SaveExecution(ctx, &models.PipelineExecution{
+			ID: "execution-" + id, PipelineID: id, UserID: "analyst", Status: models.ExecutionStatusRunning,
+			StartedAt: old, Owner: owner, DAGRunID: "run-" + id,
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dagRuns/run-dead"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_run_id": "run-dead", "state": "success"})
+		case strings.HasSuffix(r.URL.Path, "/taskInstances"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"task_instances": []map[string]interface{}{
+				{"task_id": "extract", "state": "success", "try_number": 1},
+			}, "total_entries": 1})
+		case strings.HasSuffix(r.URL.Path, "/dagRuns/run-alive"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"dag_run_id": "run-alive", "state": "running"})
+		default:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title": "DAGRun not found", "status": 404}`))
+		}
+	}))
+	defer server.Close()
+
+	instance := service.NewInstance(leases, service.InstanceOptions{ID: "local", LeaseTTL: time.Minute}, testLogger)
+	// Выполнения с запуском DAG не завершаются PipelineExecutor
+	executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0),
+		service.ExecutorOptions{Instance: instance}, testLogger)
+	if err := executor.Start(ctx); err != nil {
+		t.Fatalf("Не удалось запустить выполнение пайплайнов: %v", err)
+	}
+	airflow := service.NewAirflowService(pipelineRepo, executions, nil, client.NewAirflowClient(client.AirflowOptions{BaseURL: server.URL}, testLogger),
+		service.NewEventBroker(0, 0), service.AirflowOptions{PollInterval: 5 * time.Millisecond, Instance: instance}, testLogger)
+	if err := airflow.Start(ctx); err != nil {
+		t.Fatalf("Не удалось возобновить отслеживание запусков: %v", err)
+	}
+
+	expected := map[string]models.ExecutionStatus{
+		"dead": models.ExecutionStatusCompleted, "missing": models.ExecutionStatusFailed, "alive": models.ExecutionStatusRunning,
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for id, status := range expected {
+		for {
+			execution, _ := executions.GetExecution(ctx, "execution-"+id)
+			if execution.Status == status && (status == models.ExecutionStatusRunning || execution.CompletedAt != nil) {
+				if id == "missing" && !strings.Contains(execution.Error, "не найден") {
+					t.Errorf("Ожидалась ошибка отсутствующего запуска: %+v", execution)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Выполнение %s: статус %s, ожидался %s", id, execution.Status, status)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	pipelineStatuses := map[string]models.PipelineStatus{
+		"dead": models.PipelineStatusCompleted, "missing": models.PipelineStatusFailed, "alive": models.PipelineStatusRunning,
+	}
+	for id, status := range pipelineStatuses {
+		if pipeline, _ := pipelineRepo.GetPipeline(ctx, id); pipeline.Status != status {
+			t.Errorf("Пайплайн %s: статус %s, ожидался %s", id, pipeline.Status, status)
+		}
+	}
+	if pipeline, _ := pipelineRepo.GetPipeline(ctx, "dead"); pipeline.Steps[0].Status != models.StepStatusCompleted {
+		t.Errorf("Состояние задачи не перенесено на шаг: %+v", pipeline.Steps[0])
+	}
+	if execution, _ := executions.GetExecution(ctx, "execution-alive"); execution.Owner != "alive" {
+		t.Errorf("Выполнение работающего экземпляра передано: %+v", execution)
+	}
+}
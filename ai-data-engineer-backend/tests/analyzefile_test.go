@@ -1,13 +1,18 @@
 package tests
 
 import (
+	"ai-data-engineer-backend/domain/models"
 	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
 	"ai-data-engineer-backend/internal/service"
 	"ai-data-engineer-backend/pkg/client"
 	"ai-data-engineer-backend/pkg/logger"
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,15 +26,160 @@ func executeAnalyzeFileRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 	llmClient := client.NewLLMClient("http://localhost:8124", "", logger.NewLogger("info", "json", "stdout"), map[string]string{"analyze_file": "/api/v1/analyze-file"})
-	analyzeService := service.NewDataAnalyzer(logger.NewLogger("info", "json", "stdout"), llmClient)
+	minioClient, err := client.NewMinIOClient("localhost:9000", "minioadmin", "minioadmin", false, logger.NewLogger("info", "json", "stdout"))
+	if err != nil {
+		t.Fatalf("Не удалось создать MinIO клиент: %v", err)
+	}
+	fileRepo := memory.NewFileRepository()
+	analyzer := service.NewDataAnalyzer(logger.NewLogger("info", "json", "stdout"), llmClient, minioClient, fileRepo)
+	analyzeService := service.NewAnalysisService(analyzer, memory.NewAnalysisRepository(), fileRepo, service.NewEventBroker(0, 0), service.AnalysisOptions{}, logger.NewLogger("info", "json", "stdout"))
 	analyzeHandler := handlers.NewAnalyzeHandler(analyzeService, logger.NewLogger("info", "json", "stdout"))
 	router.POST("/api/v1/analyze-file", analyzeHandler.AnalyzeFile)
 
 	req := httptest.NewRequest("POST", "/api/v1/analyze-file?user_id=default_user", nil)
 	response := httptest.NewRecorder()
 	router.ServeHTTP(response, req)
-	if status := response.Code; status != http.StatusOK {
-		t.Errorf("Ожидался статус %d, получили %d", http.StatusOK, status)
+	if status := response.Code; status != http.StatusAccepted {
+		t.Errorf("Ожидался статус %d, получили %d", http.StatusAccepted, status)
 		return
 	}
 }
+
+// TestAnalysisReusedByChecksum проверяет, что файл с уже проанализированным содержимым
+// получает готовый анализ без постановки в очередь
+func TestAnalysisReusedByChecksum(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	fileRepo := memory.NewFileRepository()
+	analyses := memory.NewAnalysisRepository()
+	llmClient := client.NewLLMClient("http://127.0.0.1:1", "", testLogger, map[string]string{"analyze_file": "/api/v1/analyze-file"})
+	fileService := service.NewFileService(storage, fileRepo, service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger), service.FileServiceOptions{}, testLogger)
+	analysisService := service.NewAnalysisService(service.NewDataAnalyzer(testLogger, llmClient, storage, fileRepo), analyses, fileRepo,
+		service.NewEventBroker(0, 0), service.AnalysisOptions{Workers: 1}, testLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := analysisService.Start(ctx); err != nil {
+		t.Fatalf("Не удалось запустить анализ: %v", err)
+	}
+
+	content := "id,amount\n1,10.5\n2,11\n"
+	first, err := fileService.UploadFile(ctx, "analyst", "export.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	if _, err := analysisService.SubmitAnalysis(ctx, &models.AnalysisRequest{UserID: "intruder", FileID: first.ID}); !isForbidden(err) {
+		t.Fatalf("Ожидался отказ в анализе чужого файла, получили %v", err)
+	}
+	submitted, err := analysisService.SubmitAnalysis(ctx, &models.AnalysisRequest{UserID: "analyst", FileID: first.ID})
+	if err != nil || submitted.Status != models.AnalysisStatusPending {
+		t.Fatalf("Ожидался анализ в очереди: %+v, %v", submitted, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		analysis, _ := analysisService.GetAnalysis(ctx, "analyst", submitted.ID)
+		if analysis.Status == models.AnalysisStatusCompleted {
+			break
+		}
+		if analysis.Status == models.AnalysisStatusFailed || time.Now().After(deadline) {
+			t.Fatalf("Анализ не завершен: %+v", analysis)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Та же выгрузка под другим именем
+	second, err := fileService.UploadFile(ctx, "analyst", "export_again.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	if _, err := analysisService.GetAnalysis(ctx, "intruder", submitted.ID); !isForbidden(err) {
+		t.Errorf("Ожидался отказ в чтении чужого анализа, получили %v", err)
+	}
+	reused, err := analysisService.SubmitAnalysis(ctx, &models.AnalysisRequest{UserID: "analyst", FileID: second.ID})
+	if err != nil {
+		t.Fatalf("Не удалось запросить анализ: %v", err)
+	}
+	if reused.ID == submitted.ID || reused.Status != models.AnalysisStatusCompleted || reused.FileID != second.ID ||
+		reused.Profile == nil || reused.Profile.TotalRows != 2 {
+		t.Errorf("Ожидалась копия завершенного анализа: %+v", reused)
+	}
+	if stored, err := analyses.GetAnalysis(ctx, reused.ID); err != nil || stored.Status != models.AnalysisStatusCompleted {
+		t.Errorf("Копия анализа не сохранена: %+v, %v", stored, err)
+	}
+
+	// Анализ другого пользователя не копируется, даже если содержимое совпадает
+	foreign, err := fileService.UploadFile(ctx, "intruder", "export.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	queued, err := analysisService.SubmitAnalysis(ctx, &models.AnalysisRequest{UserID: "intruder", FileID: foreign.ID})
+	if err != nil || queued.Status != models.AnalysisStatusPending {
+		t.Errorf("Ожидался новый анализ в очереди: %+v, %v", queued, err)
+	}
+}
+
+// isForbidden проверяет, что операция отклонена из-за доступа к чужому ресурсу
+func isForbidden(err error) bool {
+	appErr, ok := models.IsAppError(err)
+	return ok && appErr.Code == models.ErrorCodeForbidden
+}
+
+// TestAnalysisFilePathAndRecovery проверяет отказ в анализе пути вне каталога пользователя
+// и возврат в очередь только анализов остановленных экземпляров сервиса
+func TestAnalysisFilePathAndRecovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	fileRepo := memory.NewFileRepository()
+	analyses := memory.NewAnalysisRepository()
+	leases := memory.NewLeaseRepository()
+	if err := service.NewInstance(leases, service.InstanceOptions{ID: "alive"}, testLogger).Start(ctx); err != nil {
+		t.Fatalf("Не удалось зарегистрировать экземпляр: %v", err)
+	}
+	for _, owner := range []string{"dead", "alive", "local"} {
+		analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+			ID: "analysis-" + owner, UserId: "analyst", FilePath: "users/analyst/missing.csv",
+			Status: models.AnalysisStatusRunning, CreatedAt: time.Now(), UpdatedAt: time.Now(), Owner: owner,
+		})
+	}
+
+	llmClient := client.NewLLMClient("http://127.0.0.1:1", "", testLogger, map[string]string{"analyze_file": "/api/v1/analyze-file"})
+	analysisService := service.NewAnalysisService(service.NewDataAnalyzer(testLogger, llmClient, storage, fileRepo), analyses, fileRepo,
+		service.NewEventBroker(0, 0), service.AnalysisOptions{
+			Workers:  1,
+			Instance: service.NewInstance(leases, service.InstanceOptions{ID: "local"}, testLogger),
+		}, testLogger)
+	if err := analysisService.Start(ctx); err != nil {
+		t.Fatalf("Не удалось запустить анализ: %v", err)
+	}
+	for owner, expected := range map[string]string{"dead": "local", "alive": "alive", "local": "local"} {
+		analysis, _ := analyses.GetAnalysis(ctx, "analysis-"+owner)
+		if analysis.Owner != expected {
+			t.Errorf("Анализ экземпляра %q: владелец %q, ожидался %q", owner, analysis.Owner, expected)
+		}
+	}
+	if analysis, _ := analyses.GetAnalysis(ctx, "analysis-alive"); analysis.Status != models.AnalysisStatusRunning {
+		t.Errorf("Анализ работающего экземпляра изменен: %+v", analysis)
+	}
+
+	for _, request := range []models.AnalysisRequest{
+		{UserID: "analyst", FilePath: "users/analyst/../intruder/orders.csv"},
+		{UserID: "analyst", FilePath: "users/analyst/reports/../../intruder/orders.csv"},
+		{UserID: "analyst", FilePath: "users/intruder/orders.csv"},
+		{UserID: "analyst/reports", FilePath: "users/analyst/reports/orders.csv"},
+	} {
+		if _, err := analysisService.SubmitAnalysis(ctx, &request); !isForbidden(err) {
+			t.Errorf("Ожидался отказ в анализе %q от имени %q, получили %v", request.FilePath, request.UserID, err)
+		}
+	}
+	submitted, err := analysisService.SubmitAnalysis(ctx, &models.AnalysisRequest{UserID: "analyst", FilePath: "users/analyst//reports/./orders.csv"})
+	if err != nil || submitted.FilePath != "users/analyst/reports/orders.csv" {
+		t.Errorf("Ожидался анализ нормализованного пути: %+v, %v", submitted, err)
+	}
+}
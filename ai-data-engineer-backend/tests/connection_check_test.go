@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestClickHouseConnectionCheck проверяет подключение к ClickHouse: версию, задержку и права
+func TestClickHouseConnectionCheck(t *testing.T) {
+	var mu sync.Mutex
+	var statements []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			io.WriteString(w, "Ok.\n")
+			return
+		}
+		if r.Header.Get("X-ClickHouse-User") != "reader" || r.URL.Query().Get("database") != "analytics" {
+			t.Errorf("Неожиданные учетные данные: %s %s", r.Header.Get("X-ClickHouse-User"), r.URL.RawQuery)
+		}
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		mu.Lock()
+		statements = append(statements, query)
+		mu.Unlock()
+		switch {
+		case strings.Contains(query, "version()"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]interface{}{{"version": "24.3.1.1"}}})
+		case strings.HasPrefix(query, "INSERT"):
+			w.Header().Set("X-ClickHouse-Exception-Code", "497")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "Code: 497. DB::Exception: reader: Not enough privileges")
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(serverURL.Host)
+	healthService := service.NewHealthService(logger.NewLogger("error", "json", "stdout"))
+
+	response, err := healthService.TestDatabaseConnection(context.Background(), &models.DatabaseTestRequest{
+		Type: "clickhouse", Host: host, Port: port, User: "reader", DBName: "analytics",
+	})
+	if err != nil {
+		t.Fatalf("Подключение не удалось: %v", err)
+	}
+	if !response.Connected || response.Status != "limited" {
+		t.Errorf("Ожидалось подключение с ограниченными правами, получено %s", response.Status)
+	}
+	if response.Details["server_version"] != "24.3.1.1" || response.Details["ssl"] != false {
+		t.Errorf("Неожиданные детали: %+v", response.Details)
+	}
+	permissions, _ := response.Details["permissions"].(map[string]bool)
+	if !permissions["create_table"] || permissions["insert"] {
+		t.Errorf("Неожиданные права: %+v", permissions)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if last := statements[len(statements)-1]; !strings.HasPrefix(last, "DROP TABLE IF EXISTS `aien_probe_") {
+		t.Errorf("Проверочная таблица не удалена: %s", last)
+	}
+}
+
+// TestPostgresConnectionCheckUnreachable проверяет ошибку подключения к недоступному PostgreSQL
+func TestPostgresConnectionCheckUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Не удалось занять порт: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	healthService := service.NewHealthService(logger.NewLogger("error", "json", "stdout"))
+	_, err = healthService.TestDatabaseConnection(context.Background(), &models.DatabaseTestRequest{
+		Type: "postgres", Host: "127.0.0.1", Port: port, User: "postgres", Password: "secret", DBName: "app",
+		TimeoutSeconds: 2,
+	})
+	appErr, ok := models.IsAppError(err)
+	if !ok || appErr.Code != models.ErrorCodeConnectionFailed {
+		t.Fatalf("Ожидалась ошибка connection_failed, получено %v", err)
+	}
+	if appErr.Details["reason"] != "unreachable" || appErr.Details["password"] != nil {
+		t.Errorf("Неожиданные детали ошибки: %+v", appErr.Details)
+	}
+	if strings.Contains(appErr.Details["error"].(string), "secret") {
+		t.Errorf("Пароль попал в ошибку: %v", appErr.Details["error"])
+	}
+}
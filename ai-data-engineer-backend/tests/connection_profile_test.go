@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/logger"
+	"ai-data-engineer-backend/pkg/secrets"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestConnectionProfiles проверяет CRUD сохраненных подключений: пароль хранится
+// зашифрованным, не возвращается в ответах и сохраняется при изменении без пароля
+func TestConnectionProfiles(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	key, _ := secrets.GenerateKey()
+	cipher, err := secrets.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Не удалось создать шифр: %v", err)
+	}
+	repo := memory.NewConnectionRepository()
+	connectionService := service.NewConnectionService(repo, cipher, service.NewHealthService(testLogger), testLogger)
+	handler := handlers.NewConnectionHandler(connectionService, testLogger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/connections", handler.CreateConnection)
+	router.GET("/api/v1/connections", handler.ListConnections)
+	router.GET("/api/v1/connections/:id", handler.GetConnection)
+	router.PUT("/api/v1/connections/:id", handler.UpdateConnection)
+	router.DELETE("/api/v1/connections/:id", handler.DeleteConnection)
+
+	sendAs := func(userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if strings.Contains(w.Body.String(), "s3cr3t") {
+			t.Errorf("%s %s: пароль попал в ответ: %s", method, path, w.Body.String())
+		}
+		return w
+	}
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return sendAs("analyst", method, path, body)
+	}
+	request := map[string]interface{}{
+		"name": "warehouse", "type": "postgres", "host": "db.internal", "port": "5432",
+		"user": "loader", "password": "s3cr3t", "dbname": "dwh",
+	}
+
+	w := send(http.MethodPost, "/api/v1/connections", request)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получили %d: %s", w.Code, w.Body.String())
+	}
+	var created models.ConnectionProfile
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !created.HasPassword || created.UserID != "analyst" {
+		t.Errorf("Неожиданный профиль: %+v", created)
+	}
+	if w := send(http.MethodPost, "/api/v1/connections", request); w.Code != http.StatusConflict {
+		t.Errorf("Повторное имя: ожидался статус 409, получили %d", w.Code)
+	}
+
+	stored, _ := repo.GetConnection(context.Background(), created.ID)
+	if len(stored.Secret) == 0 || bytes.Contains(stored.Secret, []byte("s3cr3t")) {
+		t.Errorf("Пароль хранится в открытом виде: %q", stored.Secret)
+	}
+
+	delete(request, "password")
+	request["host"] = "db2.internal"
+	if w := send(http.MethodPut, "/api/v1/connections/"+created.ID, request); w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	_, params, err := connectionService.ResolveConnection(context.Background(), "analyst", created.ID)
+	if err != nil || params.Password != "s3cr3t" || params.Host != "db2.internal" {
+		t.Errorf("Неожиданные параметры подключения: %+v, %v", params, err)
+	}
+
+	// Чужое подключение не найдено ни для чтения, ни для изменения, ни для удаления
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if w := sendAs("intruder", method, "/api/v1/connections/"+created.ID, request); w.Code != http.StatusNotFound {
+			t.Errorf("%s чужого подключения: ожидался статус 404, получили %d", method, w.Code)
+		}
+	}
+	if _, _, err := connectionService.ResolveConnection(context.Background(), "intruder", created.ID); err == nil {
+		t.Error("Чужое подключение расшифровано")
+	}
+	if stored, _ := repo.GetConnection(context.Background(), created.ID); stored.Host != "db2.internal" {
+		t.Errorf("Чужой запрос изменил подключение: %+v", stored)
+	}
+
+	if w := send(http.MethodGet, "/api/v1/connections", nil); !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Неожиданный список: %s", w.Body.String())
+	}
+	if w := send(http.MethodDelete, "/api/v1/connections/"+created.ID, nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получили %d", w.Code)
+	}
+	if w := send(http.MethodGet, "/api/v1/connections/"+created.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404, получили %d", w.Code)
+	}
+}
+
+// TestDatabaseServiceResolvesProfile проверяет обращение к базе по ID сохраненного подключения
+func TestDatabaseServiceResolvesProfile(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	key, _ := secrets.GenerateKey()
+	cipher, _ := secrets.NewCipher(key)
+	connectionService := service.NewConnectionService(memory.NewConnectionRepository(), cipher, service.NewHealthService(testLogger), testLogger)
+
+	profile, err := connectionService.CreateConnection(context.Background(), "analyst", &models.ConnectionRequest{
+		Name: "target",
+		DatabaseTestRequest: models.DatabaseTestRequest{
+			Type: "postgres", Host: "db.internal", Port: "5432", User: "loader", Password: "s3cr3t", DBName: "dwh",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось сохранить подключение: %v", err)
+	}
+
+	target := memory.NewDatabaseRepository()
+	opened := 0
+	open := func(params *models.DatabaseTestRequest) (repository.DatabaseRepository, func() error, error) {
+		opened++
+		if params.Password != "s3cr3t" {
+			t.Errorf("Пароль не расшифрован: %q", params.Password)
+		}
+		return target, nil, nil
+	}
+	databaseService := service.NewDatabaseService(map[string]repository.DatabaseRepository{}, connectionService, open, testLogger)
+	defer databaseService.Close()
+
+	for i := 0; i < 2; i++ {
+		database, err := databaseService.TargetDatabase(context.Background(), "analyst", &models.DataTarget{Type: "postgres", ConnectionString: profile.ID})
+		if err != nil || database != target {
+			t.Fatalf("Не удалось получить базу подключения: %v", err)
+		}
+	}
+	if opened != 1 {
+		t.Errorf("Ожидалось одно открытие базы, получено %d", opened)
+	}
+
+	for _, req := range []struct{ userID, conn string }{{"analyst", "missing"}, {"intruder", profile.ID}} {
+		_, err = databaseService.TargetDatabase(context.Background(), req.userID, &models.DataTarget{Type: "postgres", ConnectionString: req.conn})
+		if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeNotFound {
+			t.Errorf("%s/%s: ожидалась ошибка not_found, получено %v", req.userID, req.conn, err)
+		}
+	}
+	// Запрос чужого пользователя не закрывает открытую базу владельца
+	if _, err := databaseService.TargetDatabase(context.Background(), "analyst", &models.DataTarget{ConnectionString: profile.ID}); err != nil || opened != 1 {
+		t.Errorf("База владельца переоткрыта: открытий %d, %v", opened, err)
+	}
+}
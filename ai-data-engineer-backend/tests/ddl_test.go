@@ -0,0 +1,394 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/ddl"
+	"strings"
+	"testing"
+)
+
+func TestPostgresDDLFromProfile(t *testing.T) {
+	profile := &models.DataProfile{
+		TotalRows:   3,
+		SampledRows: 3,
+		Fields: []models.DataField{
+			{Name: "ID", Type: models.FieldTypeInteger, DistinctCount: 3, MinValue: 1, MaxValue: 3},
+			{Name: "Customer Name", Type: models.FieldTypeString, DistinctCount: 2, Description: "Имя клиента's"},
+			{Name: "amount", Type: models.FieldTypeInteger, NullCount: 1, Nullable: true, MinValue: 0, MaxValue: 5e9},
+			{Name: "createdAt", Type: models.FieldTypeDate, DistinctCount: 3},
+		},
+	}
+	schema := ddl.SchemaFromProfile("Orders 2024", profile)
+
+	generator, err := ddl.NewGenerator("postgresql")
+	if err != nil {
+		t.Fatalf("Не удалось получить генератор: %v", err)
+	}
+	response, err := generator.Generate(schema, profile, ddl.Options{Schema: "staging"})
+	if err != nil {
+		t.Fatalf("Не удалось сгенерировать DDL: %v", err)
+	}
+
+	expected := `CREATE TABLE IF NOT EXISTS "staging"."orders_2024" (
+    "id" BIGINT NOT NULL,
+    "customer_name" TEXT NOT NULL,
+    "amount" BIGINT,
+    "created_at" DATE NOT NULL,
+    CONSTRAINT "orders_2024_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON COLUMN "staging"."orders_2024"."customer_name" IS 'Имя клиента''s';
+`
+	if response.DDL != expected {
+		t.Errorf("Неожиданный DDL:\n%s\nожидался:\n%s", response.DDL, expected)
+	}
+	if response.Database != ddl.DialectPostgres || len(response.Tables) != 1 || response.Tables[0] != "orders_2024" {
+		t.Errorf("Неожиданные метаданные ответа: %+v", response)
+	}
+}
+
+func TestPostgresDDLFromTableSchema(t *testing.T) {
+	generator, _ := ddl.NewGenerator("postgres")
+	schema := &models.TableSchema{
+		TableName: `weird"table`,
+		Fields: []models.TableField{
+			{Name: "id", Type: "uuid"},
+			{Name: "email", Type: "VARCHAR(320)", Indexed: true},
+			{Name: "tags", Type: "json", Nullable: true},
+		},
+		PrimaryKey:  []string{"id"},
+		Indexes:     []models.TableIndex{{Name: "idx_tags", Fields: []string{"tags"}, Type: "gin"}},
+		Constraints: []models.TableConstraint{{Name: "email_unique", Type: "unique", Expression: "email"}},
+	}
+	response, err := generator.Generate(schema, nil, ddl.Options{})
+	if err != nil {
+		t.Fatalf("Не удалось сгенерировать DDL: %v", err)
+	}
+
+	expected := `CREATE TABLE IF NOT EXISTS "weird""table" (
+    "id" UUID NOT NULL,
+    "email" VARCHAR(320) NOT NULL,
+    "tags" JSONB,
+    CONSTRAINT "weird""table_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "email_unique" UNIQUE ("email")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_tags" ON "weird""table" USING gin ("tags");
+CREATE INDEX IF NOT EXISTS "idx_weird""table_email" ON "weird""table" ("email");
+`
+	if response.DDL != expected {
+		t.Errorf("Неожиданный DDL:\n%s\nожидался:\n%s", response.DDL, expected)
+	}
+
+	// Недопустимый тип и ссылка на несуществующую колонку отклоняются
+	schema.Fields[1].Type = "TEXT); DROP TABLE users; --"
+	schema.PrimaryKey = []string{"missing"}
+	_, err = generator.Generate(schema, nil, ddl.Options{})
+	appErr, ok := models.IsAppError(err)
+	if !ok || appErr.Code != models.ErrorCodeValidation {
+		t.Fatalf("Ожидалась ошибка валидации, получили %v", err)
+	}
+	if problems, _ := appErr.Details["problems"].([]string); len(problems) != 2 {
+		t.Errorf("Ожидалось 2 проблемы в схеме, получили %v", appErr.Details["problems"])
+	}
+}
+
+// TestPostgresDDLConstraints проверяет построение CHECK и внешнего ключа из полей ограничения
+// и отказ от выражений, которые пришлось бы вставить в DDL как есть
+func TestPostgresDDLConstraints(t *testing.T) {
+	generator, _ := ddl.NewGenerator("postgres")
+	schema := &models.TableSchema{
+		TableName: "orders",
+		Fields: []models.TableField{
+			{Name: "id", Type: "bigint"},
+			{Name: "customerId", Type: "bigint"},
+			{Name: "amount", Type: "numeric"},
+			{Name: "status", Type: "text"},
+		},
+		PrimaryKey: []string{"id"},
+		Constraints: []models.TableConstraint{
+			{Name: "orders_customer_fkey", Type: "foreign_key",
+				Column: "customerId", RefSchema: "sales", RefTable: "Customers", RefColumn: "id", OnDelete: "set_null"},
+			{Name: "amount_positive", Type: "check", Column: "amount", Operator: ">", Value: float64(0)},
+			{Name: "status_known", Type: "check", Column: "status", Operator: "in", Value: []interface{}{"new", "it's; done"}},
+		},
+	}
+	response, err := generator.Generate(schema, nil, ddl.Options{})
+	if err != nil {
+		t.Fatalf("Не удалось сгенерировать DDL: %v", err)
+	}
+	for _, expected := range []string{
+		`CONSTRAINT "orders_customer_fkey" FOREIGN KEY ("customerId") REFERENCES "sales"."Customers" ("id") ON DELETE SET NULL`,
+		`CONSTRAINT "amount_positive" CHECK ("amount" > 0)`,
+		`CONSTRAINT "status_known" CHECK ("status" IN ('new', 'it''s; done'))`,
+	} {
+		if !strings.Contains(response.DDL, expected) {
+			t.Errorf("В DDL нет %q:\n%s", expected, response.DDL)
+		}
+	}
+
+	for _, constraint := range []models.TableConstraint{
+		{Type: "foreign_key", Expression: "(customerId) REFERENCES customers(id); DROP TABLE users"},
+		{Type: "foreign_key", Column: "customerId", RefTable: "customers"},
+		{Type: "foreign_key", Column: "customerId", RefTable: "customers", RefColumn: "id", OnDelete: "cascade; DROP TABLE users"},
+		{Type: "check", Expression: "amount > 0"},
+		{Type: "check", Column: "amount", Operator: "> 0 OR", Value: float64(1)},
+		{Type: "check", Column: "amount", Operator: "in", Value: "1, 2"},
+		{Type: "check", Column: "amount", Operator: ">", Value: map[string]interface{}{"sql": "0"}},
+		{Type: "check", Column: "missing", Operator: ">", Value: float64(0)},
+	} {
+		schema.Constraints = []models.TableConstraint{constraint}
+		_, err := generator.Generate(schema, nil, ddl.Options{})
+		if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeValidation {
+			t.Errorf("Ожидалась ошибка валидации для %+v, получили %v", constraint, err)
+		}
+	}
+}
+
+// TestSchemaDiffLiveConstraints проверяет, что ограничение живой таблицы, которое нельзя
+// построить из полей, сохраняется без изменений, но не может быть добавлено или изменено
+func TestSchemaDiffLiveConstraints(t *testing.T) {
+	live := models.TableConstraint{Name: "amount_range", Type: "check", Expression: "(amount > (0)::numeric) AND (amount < (100)::numeric)"}
+	from := &models.TableSchema{
+		TableName:   "orders",
+		Fields:      []models.TableField{{Name: "id", Type: "bigint"}, {Name: "amount", Type: "numeric"}},
+		Constraints: []models.TableConstraint{live},
+	}
+	to := *from
+	to.Fields = append(to.Fields[:2:2], models.TableField{Name: "note", Type: "text", Nullable: true})
+	response, err := ddl.Diff("postgres", from, &to, nil, nil, ddl.Options{})
+	if err != nil {
+		t.Fatalf("Не удалось сравнить схемы: %v", err)
+	}
+	for _, change := range response.Changes {
+		if change.Object == "amount_range" {
+			t.Errorf("Неизменное ограничение попало в изменения: %+v", change)
+		}
+	}
+
+	to.Constraints = []models.TableConstraint{{Name: "amount_range", Type: "check", Expression: "amount > 0 OR true"}}
+	_, err = ddl.Diff("postgres", from, &to, nil, nil, ddl.Options{})
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeValidation {
+		t.Errorf("Ожидалась ошибка валидации измененного выражения, получили %v", err)
+	}
+
+	to.Constraints = nil
+	response, err = ddl.Diff("postgres", from, &to, nil, nil, ddl.Options{ApproveDestructive: true})
+	if err != nil {
+		t.Fatalf("Не удалось сравнить схемы: %v", err)
+	}
+	dropped := false
+	for _, change := range response.Changes {
+		dropped = dropped || (change.Type == models.SchemaChangeDropConstraint && change.Object == "amount_range")
+	}
+	if !dropped {
+		t.Errorf("Удаленное ограничение не удаляется: %+v", response.Changes)
+	}
+}
+
+func TestClickHouseDDLFromProfile(t *testing.T) {
+	profile := &models.DataProfile{
+		TotalRows:   100,
+		SampledRows: 100,
+		Fields: []models.DataField{
+			{Name: "event_date", Type: models.FieldTypeDate, DistinctCount: 30, MinValue: 1704067200, MaxValue: 1706659200},
+			{Name: "country", Type: models.FieldTypeString, DistinctCount: 3},
+			{Name: "user_id", Type: models.FieldTypeInteger, DistinctCount: 50, MinValue: 1, MaxValue: 5000},
+			{Name: "comment", Type: models.FieldTypeString, DistinctCount: 95, NullCount: 5, Nullable: true},
+			{Name: "amount", Type: models.FieldTypeFloat, DistinctCount: 90, Description: "Сумма"},
+		},
+	}
+	generator, err := ddl.NewGenerator("clickhouse")
+	if err != nil {
+		t.Fatalf("Не удалось получить генератор: %v", err)
+	}
+	response, err := generator.Generate(ddl.SchemaFromProfile("events", profile), profile, ddl.Options{Schema: "analytics"})
+	if err != nil {
+		t.Fatalf("Не удалось сгенерировать DDL: %v", err)
+	}
+
+	expected := "CREATE TABLE IF NOT EXISTS `analytics`.`events` (\n" +
+		"    `event_date` Date,\n" +
+		"    `country` LowCardinality(String),\n" +
+		"    `user_id` UInt32,\n" +
+		"    `comment` Nullable(String),\n" +
+		"    `amount` Float64 COMMENT 'Сумма'\n" +
+		")\n" +
+		"ENGINE = MergeTree()\n" +
+		"PARTITION BY toYYYYMM(`event_date`)\n" +
+		"ORDER BY (`country`, `event_date`, `user_id`)\n" +
+		"SETTINGS non_replicated_deduplication_window = 1000;\n"
+	if response.DDL != expected {
+		t.Errorf("Неожиданный DDL:\n%s\nожидался:\n%s", response.DDL, expected)
+	}
+	for _, key := range []string{"columns", "engine", "order_by", "partition_by", "settings"} {
+		if _, ok := response.Metadata[key]; !ok {
+			t.Errorf("Metadata не содержит объяснения %q", key)
+		}
+	}
+
+	// Уникальный id становится ключом: ReplacingMergeTree, без партиций по дате вне ключа
+	profile.Fields = append(profile.Fields, models.DataField{Name: "id", Type: models.FieldTypeInteger, DistinctCount: 100, MinValue: 1, MaxValue: 100})
+	response, err = generator.Generate(ddl.SchemaFromProfile("events", profile), profile, ddl.Options{})
+	if err != nil {
+		t.Fatalf("Не удалось сгенерировать DDL: %v", err)
+	}
+	if !strings.Contains(response.DDL, "ENGINE = ReplacingMergeTree()\nORDER BY (`id`)\nSETTINGS") || strings.Contains(response.DDL, "PARTITION BY") {
+		t.Errorf("Неожиданный DDL для таблицы с ключом:\n%s", response.DDL)
+	}
+}
+
+func TestPostgresSchemaDiff(t *testing.T) {
+	from := &models.TableSchema{
+		TableName: "orders",
+		Fields: []models.TableField{
+			{Name: "id", Type: "BIGINT"},
+			{Name: "email", Type: "character varying(100)", Indexed: true},
+			{Name: "amount", Type: "NUMERIC(10,2)"},
+			{Name: "note", Type: "TEXT", Nullable: true},
+			{Name: "legacy", Type: "TEXT", Nullable: true},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	to := &models.TableSchema{
+		TableName: "orders",
+		Fields: []models.TableField{
+			{Name: "id", Type: "int8"},
+			{Name: "email", Type: "VARCHAR(320)", Indexed: true},
+			{Name: "amount", Type: "INTEGER"},
+			{Name: "note", Type: models.FieldTypeString},
+			{Name: "status", Type: models.FieldTypeString, Description: "Статус"},
+			{Name: "created_at", Type: models.FieldTypeDate, Nullable: true},
+		},
+		PrimaryKey: []string{"id"},
+	}
+
+	response, err := ddl.Diff("postgres", from, to, nil, nil, ddl.Options{})
+	if err != nil {
+		t.Fatalf("Не удалось сравнить схемы: %v", err)
+	}
+	expected := []string{
+		`ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "status" TEXT;`,
+		`ALTER TABLE "orders" ALTER COLUMN "status" SET NOT NULL;`,
+		`ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "created_at" DATE;`,
+		`ALTER TABLE "orders" ALTER COLUMN "email" TYPE VARCHAR(320) USING "email"::VARCHAR(320);`,
+		`ALTER TABLE "orders" ALTER COLUMN "note" SET NOT NULL;`,
+		`COMMENT ON COLUMN "orders"."status" IS 'Статус';`,
+	}
+	if strings.Join(response.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Неожиданные команды:\n%s\nожидались:\n%s", strings.Join(response.Statements, "\n"), strings.Join(expected, "\n"))
+	}
+	if !response.Destructive || !response.RequiresApproval {
+		t.Errorf("Сужение NUMERIC до INTEGER и удаление колонки должны требовать подтверждения: %+v", response)
+	}
+	destructive := map[string]bool{}
+	for _, change := range response.Changes {
+		if change.Destructive {
+			destructive[change.Type+":"+change.Object] = true
+		}
+	}
+	if len(destructive) != 2 || !destructive["alter_type:amount"] || !destructive["drop_column:legacy"] {
+		t.Errorf("Неожиданные разрушающие изменения: %v", destructive)
+	}
+
+	// После подтверждения разрушающие изменения попадают в команды в своем порядке
+	response, err = ddl.Diff("postgres", from, to, nil, nil, ddl.Options{ApproveDestructive: true})
+	if err != nil {
+		t.Fatalf("Не удалось сравнить схемы: %v", err)
+	}
+	statements := strings.Join(response.Statements, "\n")
+	if response.RequiresApproval || !strings.Contains(statements, `ALTER COLUMN "amount" TYPE INTEGER USING "amount"::INTEGER;`+"\n"+`ALTER TABLE "orders" ALTER COLUMN "note" SET NOT NULL;`+"\n"+`ALTER TABLE "orders" DROP COLUMN IF EXISTS "legacy";`) {
+		t.Errorf("Неожиданные команды после подтверждения:\n%s", statements)
+	}
+}
+
+func TestClickHouseSchemaDiff(t *testing.T) {
+	from := &models.TableSchema{
+		TableName: "events",
+		Fields: []models.TableField{
+			{Name: "id", Type: "UInt64"},
+			{Name: "user_id", Type: "UInt32"},
+			{Name: "country", Type: "LowCardinality(String)"},
+			{Name: "comment", Type: "Nullable(String)", Nullable: true},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	to := &models.TableSchema{
+		TableName: "events",
+		Fields: []models.TableField{
+			{Name: "id", Type: "UInt64"},
+			{Name: "user_id", Type: "UInt64"},
+			{Name: "country", Type: "LowCardinality(String)"},
+			{Name: "comment", Type: "String"},
+			{Name: "amount", Type: models.FieldTypeFloat, Nullable: true, Description: "Сумма"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes:    []models.TableIndex{{Name: "idx_country", Fields: []string{"country"}}},
+	}
+
+	response, err := ddl.Diff("clickhouse", from, to, nil, nil, ddl.Options{Schema: "analytics"})
+	if err != nil {
+		t.Fatalf("Не удалось сравнить схемы: %v", err)
+	}
+	expected := []string{
+		"ALTER TABLE `analytics`.`events` ADD COLUMN IF NOT EXISTS `amount` Nullable(Float64);",
+		"ALTER TABLE `analytics`.`events` MODIFY COLUMN `user_id` UInt64;",
+		"ALTER TABLE `analytics`.`events` ADD INDEX `idx_country` `country` TYPE bloom_filter GRANULARITY 4;",
+		"ALTER TABLE `analytics`.`events` MATERIALIZE INDEX `idx_country`;",
+		"ALTER TABLE `analytics`.`events` COMMENT COLUMN `amount` 'Сумма';",
+	}
+	if strings.Join(response.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Неожиданные команды:\n%s\nожидались:\n%s", strings.Join(response.Statements, "\n"), strings.Join(expected, "\n"))
+	}
+	if !response.RequiresApproval {
+		t.Errorf("Снятие Nullable должно требовать подтверждения: %+v", response.Changes)
+	}
+
+	// Колонки ORDER BY за пределами первичного ключа и выбранные генератором без ключа
+	// не изменяются и не удаляются командами ALTER
+	sorted := *from
+	sorted.SortingKey = []string{"id", "user_id"}
+	changed := *to
+	changed.Indexes = nil
+	response, _ = ddl.Diff("clickhouse", &sorted, &changed, nil, nil, ddl.Options{ApproveDestructive: true})
+	for _, change := range response.Changes {
+		if change.Object == "user_id" && (!change.Destructive || len(change.Statements) != 0) {
+			t.Errorf("Колонка ключа сортировки изменена командой ALTER: %+v", change)
+		}
+	}
+	derived := &models.TableSchema{TableName: "visits", Fields: []models.TableField{
+		{Name: "visit_id", Type: models.FieldTypeInteger},
+		{Name: "page", Type: models.FieldTypeString, Nullable: true},
+	}}
+	generated, _ := ddl.NewGenerator("clickhouse")
+	created, err := generated.Generate(derived, nil, ddl.Options{})
+	if err != nil || !strings.Contains(created.DDL, "ORDER BY (`visit_id`)") {
+		t.Fatalf("Ожидался ключ сортировки visit_id: %v", err)
+	}
+	response, _ = ddl.Diff("clickhouse", derived, &models.TableSchema{TableName: "visits", Fields: []models.TableField{
+		{Name: "visit_id", Type: models.FieldTypeString},
+	}}, nil, nil, ddl.Options{ApproveDestructive: true})
+	for _, change := range response.Changes {
+		if change.Object == "visit_id" && len(change.Statements) != 0 {
+			t.Errorf("Колонка ключа сортировки изменена командой ALTER: %+v", change)
+		}
+	}
+	if len(response.Statements) != 1 || !strings.Contains(response.Statements[0], "DROP COLUMN IF EXISTS `page`") {
+		t.Errorf("Ожидалось только удаление page: %v", response.Statements)
+	}
+
+	// PRIMARY KEY короче ORDER BY указывается отдельно
+	created, err = generated.Generate(&sorted, nil, ddl.Options{})
+	if err != nil || !strings.Contains(created.DDL, "ORDER BY (`id`, `user_id`)\nPRIMARY KEY (`id`)") {
+		t.Errorf("Ожидались ORDER BY и PRIMARY KEY: %v, %+v", err, created)
+	}
+
+	// Смена ключа сортировки требует пересоздания таблицы и не содержит команд
+	to.PrimaryKey = []string{"id", "user_id"}
+	response, _ = ddl.Diff("clickhouse", from, to, nil, nil, ddl.Options{ApproveDestructive: true})
+	for _, change := range response.Changes {
+		if change.Type == models.SchemaChangePrimaryKey && (!change.Destructive || len(change.Statements) != 0) {
+			t.Errorf("Смена ключа должна быть разрушающей и без команд: %+v", change)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestContentDeduplication проверяет хранение одинакового содержимого в одном объекте
+// и удаление объекта вместе с последней ссылкой
+func TestContentDeduplication(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	blobs := memory.NewBlobRepository()
+	fileService := service.NewFileService(storage, memory.NewFileRepository(), service.NewBlobStore(storage, blobs, testLogger), service.FileServiceOptions{}, testLogger)
+
+	content := "id,name\n1,John\n"
+	first, err := fileService.UploadFile(ctx, "alice", "export.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	second, err := fileService.UploadFile(ctx, "alice", "export_copy.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	if first.ID == second.ID || first.Path != second.Path {
+		t.Errorf("Ожидались разные файлы с общим объектом: %s, %s", first.Path, second.Path)
+	}
+	if objects, _ := storage.ListFiles(ctx, first.Bucket, ""); len(objects) != 1 {
+		t.Errorf("Ожидался 1 объект в хранилище, получили %v", objects)
+	}
+
+	// Повторная загрузка по контрольной сумме не требует передачи содержимого
+	third, presigned, err := fileService.PresignUpload(ctx, "alice", "again.csv", first.Checksum)
+	if err != nil || presigned != nil || third.Path != first.Path {
+		t.Fatalf("Ожидалась мгновенная регистрация файла, получили %+v, %v", third, err)
+	}
+	// Другой пользователь не может получить файл, зная только хеш
+	_, _, err = fileService.PresignUpload(ctx, "bob", "stolen.csv", first.Checksum)
+	if appErr, ok := models.IsAppError(err); !ok || appErr.HTTPCode != http.StatusNotImplemented {
+		t.Errorf("Ожидалась обычная загрузка для другого пользователя, получили %v", err)
+	}
+
+	// Чужой файл нельзя прочитать или удалить, освободив общую ссылку
+	if _, err := fileService.GetFileInfo(ctx, first.ID, "bob"); err == nil {
+		t.Error("Ожидалась ошибка чтения чужого файла")
+	}
+	if err := fileService.DeleteFile(ctx, first.ID, "bob"); err == nil {
+		t.Error("Ожидалась ошибка удаления чужого файла")
+	}
+
+	for _, file := range []*models.FileMetadata{first, second} {
+		if err := fileService.DeleteFile(ctx, file.ID, "alice"); err != nil {
+			t.Fatalf("Не удалось удалить файл: %v", err)
+		}
+		if exists, _ := storage.FileExists(ctx, first.Bucket, first.Path); !exists {
+			t.Fatalf("Объект удален, пока на него есть ссылки")
+		}
+	}
+	if err := fileService.DeleteFile(ctx, third.ID, "alice"); err != nil {
+		t.Fatalf("Не удалось удалить файл: %v", err)
+	}
+	if exists, _ := storage.FileExists(ctx, first.Bucket, first.Path); exists {
+		t.Errorf("Объект не удален после удаления последней ссылки")
+	}
+	if _, err := blobs.GetBlob(ctx, first.Checksum); err == nil {
+		t.Errorf("Запись blob не удалена")
+	}
+
+	// Удаленный blob нельзя переиспользовать по старой контрольной сумме
+	if _, err := blobs.ReferenceBlob(ctx, first.Checksum); err == nil {
+		t.Error("Ожидалась ошибка ссылки на удаленный blob")
+	}
+	// Запись последней ссылки остается, если объект не удалось удалить
+	blob := &models.Blob{Checksum: first.Checksum, Bucket: first.Bucket, Path: first.Path, Size: first.Size}
+	if _, _, err := blobs.AcquireBlob(ctx, blob); err != nil {
+		t.Fatalf("Не удалось создать blob: %v", err)
+	}
+	failure := errors.New("хранилище недоступно")
+	if _, err := blobs.ReleaseBlob(ctx, first.Checksum, func(*models.Blob) error { return failure }); !errors.Is(err, failure) {
+		t.Errorf("Ожидалась ошибка удаления объекта, получили %v", err)
+	}
+	if stored, err := blobs.GetBlob(ctx, first.Checksum); err != nil || stored.RefCount != 1 {
+		t.Errorf("Ожидалась сохраненная ссылка после ошибки удаления: %+v, %v", stored, err)
+	}
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/logger"
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readSSE читает события потока до его закрытия и возвращает пары "id:тип"
+func readSSE(resp *http.Response, received chan<- string) {
+	defer close(received)
+	scanner := bufio.NewScanner(resp.Body)
+	var id string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "event:"):
+			received <- id + ":" + strings.TrimPrefix(line, "event:")
+		}
+	}
+}
+
+// TestAnalysisEventsStream проверяет доставку событий и продолжение потока с Last-Event-ID
+func TestAnalysisEventsStream(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	broker := service.NewEventBroker(0, 0)
+	progress := service.NewProgressService(broker, analyses, memory.NewExecutionRepository())
+
+	analysis := &models.AnalysisResult{ID: "analysis-1", UserId: "user", Status: models.AnalysisStatusRunning, CreatedAt: time.Now()}
+	if err := analyses.SaveAnalysis(ctx, analysis); err != nil {
+		t.Fatalf("Не удалось сохранить анализ: %v", err)
+	}
+	topic := service.AnalysisTopic(analysis.ID)
+	broker.Publish(topic, models.EventTypeStage, models.StageEvent{Stage: "profile"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	eventsHandler := handlers.NewEventsHandler(progress, testLogger)
+	router.GET("/api/v1/analyses/:id/events", eventsHandler.AnalysisEvents)
+	router.GET("/api/v1/executions/:id/events", eventsHandler.ExecutionEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/analyses/analysis-1/events?user_id=user")
+	if err != nil {
+		t.Fatalf("Не удалось подключиться к потоку: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("Ожидался поток text/event-stream, получили %d %s", resp.StatusCode, ct)
+	}
+
+	done := strconv.FormatInt(models.DoneEventID, 10) + ":done"
+	received := make(chan string, 10)
+	go readSSE(resp, received)
+	if event := <-received; event != "1:stage" {
+		t.Fatalf("Ожидалось событие из истории 1:stage, получили %s", event)
+	}
+
+	// События после подключения доставляются сразу, завершающее событие закрывает поток
+	broker.Publish(topic, models.EventTypeProfile, &models.DataProfile{TotalRows: 2})
+	broker.Finish(topic, models.StatusEvent{Status: string(models.AnalysisStatusCompleted)})
+	var events []string
+	for event := range received {
+		events = append(events, event)
+	}
+	if strings.Join(events, ",") != "2:profile,"+done {
+		t.Errorf("Неожиданные события: %v", events)
+	}
+
+	// Переподключение получает только пропущенные события
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/analyses/analysis-1/events", nil)
+	req.Header.Set("X-User-ID", "user")
+	req.Header.Set("Last-Event-ID", "2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Не удалось переподключиться к потоку: %v", err)
+	}
+	defer resp.Body.Close()
+	received = make(chan string, 10)
+	go readSSE(resp, received)
+	events = nil
+	for event := range received {
+		events = append(events, event)
+	}
+	if strings.Join(events, ",") != done {
+		t.Errorf("После переподключения ожидалось только %s, получили %v", done, events)
+	}
+
+	// Все события получены: поток больше не открывается
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(models.DoneEventID, 10))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Не удалось переподключиться к потоку: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Ожидался статус %d, получили %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	// История потока удалена из брокера: номера живого потока выше восстановленных,
+	// но итоговый статус все равно доставляется, а после него поток не открывается
+	restored := service.NewProgressService(service.NewEventBroker(0, 0), analyses, memory.NewExecutionRepository())
+	analysis.Status = models.AnalysisStatusCompleted
+	analyses.UpdateAnalysis(ctx, analysis)
+	subscription, err := restored.AnalysisEvents(ctx, "user", analysis.ID, 7)
+	if err != nil || !subscription.Finished || len(subscription.Replay) != 1 ||
+		subscription.Replay[0].Type != models.EventTypeDone || subscription.Replay[0].ID != models.DoneEventID {
+		t.Errorf("Ожидалось только событие done, получили %+v, %v", subscription, err)
+	}
+	subscription, err = restored.AnalysisEvents(ctx, "user", analysis.ID, models.DoneEventID)
+	if err != nil || !subscription.Finished || len(subscription.Replay) != 0 {
+		t.Errorf("Полученное событие done отправлено повторно: %+v, %v", subscription, err)
+	}
+
+	// Поток чужого анализа не раскрывается
+	resp, err = http.Get(server.URL + "/api/v1/analyses/analysis-1/events?user_id=intruder")
+	if err != nil {
+		t.Fatalf("Не удалось выполнить запрос: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Ожидался статус %d для чужого анализа, получили %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/api/v1/executions/unknown/events")
+	if err != nil {
+		t.Fatalf("Не удалось выполнить запрос: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Ожидался статус %d для неизвестного выполнения, получили %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFilesystemStorageClient(t *testing.T) {
+	ctx := context.Background()
+	storage, err := client.NewFilesystemClient(t.TempDir(), logger.NewLogger("error", "json", "stdout"))
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+
+	if err := storage.UploadFile(ctx, "bucket", "users/u1/files/a.csv", strings.NewReader("a,b\n1,2\n"), -1, "text/csv"); err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	content, err := storage.DownloadFileAsBytes(ctx, "bucket", "users/u1/files/a.csv")
+	if err != nil || string(content) != "a,b\n1,2\n" {
+		t.Fatalf("Неверное содержимое файла: %q, %v", content, err)
+	}
+
+	files, err := storage.ListFiles(ctx, "bucket", "users/u1/")
+	if err != nil || len(files) != 1 || files[0] != "users/u1/files/a.csv" {
+		t.Errorf("Неверный список файлов: %v, %v", files, err)
+	}
+
+	// Выход за пределы bucket запрещен
+	if err := storage.UploadFile(ctx, "bucket", "../escape.csv", strings.NewReader("x"), 1, "text/csv"); err == nil {
+		t.Errorf("Ожидалась ошибка для пути вне bucket")
+	}
+
+	if err := storage.DeleteFile(ctx, "bucket", "users/u1/files/a.csv"); err != nil {
+		t.Fatalf("Не удалось удалить файл: %v", err)
+	}
+	if exists, _ := storage.FileExists(ctx, "bucket", "users/u1/files/a.csv"); exists {
+		t.Errorf("Файл не был удален")
+	}
+}
+
+// TestUploadAndAnalyzeOffline проверяет загрузку и анализ файла без MinIO, PostgreSQL и LLM
+func TestUploadAndAnalyzeOffline(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	fileRepo := memory.NewFileRepository()
+
+	// LLM сервис недоступен: анализ должен вернуть нативный профиль
+	llmClient := client.NewLLMClient("http://127.0.0.1:1", "", testLogger, map[string]string{"analyze_file": "/api/v1/analyze-file"})
+	fileService := service.NewFileService(storage, fileRepo, service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger), service.FileServiceOptions{}, testLogger)
+	analyzer := service.NewDataAnalyzer(testLogger, llmClient, storage, fileRepo)
+	analysisService := service.NewAnalysisService(analyzer, memory.NewAnalysisRepository(), fileRepo, service.NewEventBroker(0, 0), service.AnalysisOptions{Workers: 1}, testLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := analysisService.Start(ctx); err != nil {
+		t.Fatalf("Не удалось запустить анализ: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	analyzeHandler := handlers.NewAnalyzeHandler(analysisService, testLogger)
+	router.POST("/api/v1/files/upload", handlers.NewFileHandler(fileService, testLogger).UploadFile)
+	router.POST("/api/v1/analyze-file", analyzeHandler.AnalyzeFile)
+	router.GET("/api/v1/analyses/:id", analyzeHandler.GetAnalysis)
+
+	buf, writer := prepareFile(t)
+	req := httptest.NewRequest("POST", "/api/v1/files/upload", bytes.NewReader(buf.Bytes()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получили %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var uploaded models.FileUploadResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &uploaded); err != nil || uploaded.FileID == "" {
+		t.Fatalf("Ответ не содержит file_id: %s", rr.Body.String())
+	}
+
+	body, _ := json.Marshal(models.AnalysisRequest{FileID: uploaded.FileID})
+	req = httptest.NewRequest("POST", "/api/v1/analyze-file", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Ожидался статус %d, получили %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+
+	var analysis models.AnalysisResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &analysis); err != nil || analysis.AnalysisID == "" {
+		t.Fatalf("Ответ не содержит analysis_id: %s", rr.Body.String())
+	}
+
+	// Ожидаем завершения фонового анализа
+	deadline := time.Now().Add(5 * time.Second)
+	for analysis.Status != string(models.AnalysisStatusCompleted) {
+		if analysis.Status == string(models.AnalysisStatusFailed) || time.Now().After(deadline) {
+			t.Fatalf("Анализ не завершился: %s", rr.Body.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/analyses/"+analysis.AnalysisID, nil))
+		if err := json.Unmarshal(rr.Body.Bytes(), &analysis); err != nil {
+			t.Fatalf("Ответ не является JSON: %s", rr.Body.String())
+		}
+	}
+	profile, ok := analysis.Result["profile"].(map[string]interface{})
+	if !ok || profile["total_rows"] != float64(2) {
+		t.Errorf("Ответ не содержит профиль данных: %s", rr.Body.String())
+	}
+
+	metadata, err := fileRepo.GetFile(context.Background(), uploaded.FileID)
+	if err != nil || metadata.Status != models.FileStatusProcessed {
+		t.Errorf("Ожидался статус файла processed, получили %+v, %v", metadata, err)
+	}
+}
+
+// TestUploadFileStreaming проверяет подсчет размера, контрольной суммы и ограничение размера загрузки
+func TestUploadFileStreaming(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	blobs := service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger)
+	fileService := service.NewFileService(storage, memory.NewFileRepository(), blobs, service.FileServiceOptions{MaxUploadSize: 20}, testLogger)
+
+	content := "name,age\nJohn,30\n"
+	metadata, err := fileService.UploadFile(ctx, "user", "data.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if metadata.Size != int64(len(content)) || metadata.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Неверные размер или контрольная сумма: %d, %s", metadata.Size, metadata.Checksum)
+	}
+
+	_, err = fileService.UploadFile(ctx, "user", "big.csv", strings.NewReader(content+"Jane,25\n"))
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeFileTooLarge {
+		t.Fatalf("Ожидалась ошибка file_too_large, получили %v", err)
+	}
+	if files, _ := storage.ListFiles(ctx, "ai-data-engineer", ""); len(files) != 1 {
+		t.Errorf("Слишком большой файл не должен сохраняться, получили %v", files)
+	}
+}
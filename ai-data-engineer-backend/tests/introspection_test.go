@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/clickhouse"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestClickHouseTableSchema проверяет чтение схемы таблицы из системных таблиц ClickHouse
+func TestClickHouseTableSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		if r.URL.Query().Get("param_database") != "analytics" || r.URL.Query().Get("param_table") != "events" {
+			t.Errorf("Неожиданные параметры запроса: %s", r.URL.RawQuery)
+		}
+		var data []map[string]interface{}
+		switch {
+		case strings.Contains(query, "system.tables"):
+			data = []map[string]interface{}{{
+				"primary_key": "event_date, user_id",
+				"sorting_key": "event_date, user_id",
+				"create_table_query": "CREATE TABLE analytics.events (`event_date` Date, `user_id` UInt64, " +
+					"`country` LowCardinality(String), `amount` Nullable(Float64) COMMENT 'Сумма', " +
+					"INDEX idx_country country TYPE bloom_filter GRANULARITY 4, " +
+					"CONSTRAINT positive CHECK amount > 0, CONSTRAINT `known` CHECK country IN ('RU', 'KZ')) " +
+					"ENGINE = MergeTree ORDER BY (event_date, user_id)",
+			}}
+		case strings.Contains(query, "system.columns"):
+			data = []map[string]interface{}{
+				{"name": "event_date", "type": "Date", "comment": ""},
+				{"name": "user_id", "type": "UInt64", "comment": ""},
+				{"name": "country", "type": "LowCardinality(String)", "comment": ""},
+				{"name": "amount", "type": "Nullable(Float64)", "comment": "Сумма"},
+			}
+		case strings.Contains(query, "system.data_skipping_indices"):
+			data = []map[string]interface{}{
+				{"name": "idx_country", "type": "bloom_filter", "expr": "country"},
+				{"name": "idx_lower", "type": "bloom_filter", "expr": "lower(country)"},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	database := clickhouse.NewDatabaseRepository(client.NewClickHouseClient(client.ClickHouseOptions{
+		URL: server.URL, User: "default", Database: "analytics",
+	}, testLogger), testLogger)
+
+	schema, err := database.GetTableSchema(context.Background(), "events")
+	if err != nil {
+		t.Fatalf("Не удалось получить схему: %v", err)
+	}
+	expected := &models.TableSchema{
+		TableName: "events",
+		Fields: []models.TableField{
+			{Name: "event_date", Type: "Date"},
+			{Name: "user_id", Type: "UInt64"},
+			{Name: "country", Type: "LowCardinality(String)"},
+			{Name: "amount", Type: "Nullable(Float64)", Nullable: true, Description: "Сумма"},
+		},
+		PrimaryKey: []string{"event_date", "user_id"},
+		Indexes:    []models.TableIndex{{Name: "idx_country", Fields: []string{"country"}, Type: "bloom_filter"}},
+		Constraints: []models.TableConstraint{
+			{Name: "positive", Type: "check", Expression: "amount > 0"},
+			{Name: "known", Type: "check", Expression: "country IN ('RU', 'KZ')"},
+		},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Неожиданная схема:\n%+v\nожидалась:\n%+v", schema, expected)
+	}
+}
+
+// TestTableSchemaEndpoint проверяет GET /databases/:conn/tables/:name/schema
+func TestTableSchemaEndpoint(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	database := memory.NewDatabaseRepository()
+	table := &models.TableSchema{TableName: "orders", Fields: []models.TableField{{Name: "id", Type: "BIGINT"}}, PrimaryKey: []string{"id"}}
+	if err := database.CreateTable(context.Background(), table); err != nil {
+		t.Fatalf("Не удалось создать таблицу: %v", err)
+	}
+	databaseService := service.NewDatabaseService(map[string]repository.DatabaseRepository{"memory": database}, nil, nil, testLogger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/databases/:conn/tables/:name/schema", handlers.NewDatabaseHandler(databaseService, testLogger).GetTableSchema)
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/api/v1/databases/memory/tables/orders/schema", http.StatusOK},
+		{"/api/v1/databases/memory/tables/missing/schema", http.StatusNotFound},
+		{"/api/v1/databases/unknown/tables/orders/schema", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.code {
+			t.Errorf("%s: ожидался статус %d, получили %d: %s", tc.path, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
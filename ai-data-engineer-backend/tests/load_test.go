@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/repository/clickhouse"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// capturingDatabase целевая база, запоминающая загруженные строки
+type capturingDatabase struct {
+	repository.DatabaseRepository
+	columns []string
+	rows    [][]interface{}
+}
+
+func (d *capturingDatabase) Dialect() string { return "postgres" }
+
+func (d *capturingDatabase) BulkInsert(ctx context.Context, tableName string, columns []string, next func() ([]interface{}, error), opts models.BulkInsertOptions) (int64, error) {
+	d.columns = columns
+	for {
+		row, err := next()
+		if err == io.EOF {
+			return int64(len(d.rows)), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		d.rows = append(d.rows, row)
+	}
+}
+
+// TestLoadFile проверяет загрузку CSV с приведением типов и выносом неподходящих строк в файл отклоненных
+func TestLoadFile(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+
+	sample := "Order ID,amount,created\n1,10.50,2024-01-01\n2,7.25,2024-01-02\n"
+	profile, err := service.ProfileCSV(ctx, strings.NewReader(sample), service.ProfileOptions{})
+	if err != nil {
+		t.Fatalf("Не удалось построить профиль: %v", err)
+	}
+	content := sample + "x,1.00,2024-01-03\n4,2.00,not a date\n5,3.00,2024-01-05\n"
+	if err := storage.UploadFile(ctx, "bucket", "users/u1/orders.csv", strings.NewReader(content), -1, "text/csv"); err != nil {
+		t.Fatalf("Не удалось загрузить файл: %v", err)
+	}
+
+	files := memory.NewFileRepository()
+	files.SaveFile(ctx, &models.FileMetadata{ID: "file-1", UserID: "u1", Bucket: "bucket", Path: "users/u1/orders.csv", Status: models.FileStatusUploaded})
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{ID: "analysis-1", UserId: "u1", FileID: "file-1", Profile: profile, Status: models.AnalysisStatusCompleted, CreatedAt: time.Now()})
+
+	target := &capturingDatabase{DatabaseRepository: memory.NewDatabaseRepository()}
+	databases := service.NewDatabaseService(map[string]repository.DatabaseRepository{"warehouse": target}, nil, nil, testLogger)
+	loads := service.NewLoadService(storage, analyses, files, databases, testLogger)
+
+	if _, err := loads.Load(ctx, "intruder", &models.LoadRequest{AnalysisID: "analysis-1", Connection: "warehouse", TableName: "orders"}); !isForbidden(err) {
+		t.Fatalf("Ожидался отказ в загрузке чужого анализа, получили %v", err)
+	}
+	result, err := loads.Load(ctx, "u1", &models.LoadRequest{AnalysisID: "analysis-1", Connection: "warehouse", TableName: "orders"})
+	if err != nil {
+		t.Fatalf("Загрузка завершилась ошибкой: %v", err)
+	}
+	if result.RowsRead != 5 || result.RowsLoaded != 3 || result.RowsRejected != 2 {
+		t.Errorf("Неожиданная статистика: прочитано %d, загружено %d, отклонено %d", result.RowsRead, result.RowsLoaded, result.RowsRejected)
+	}
+	if strings.Join(target.columns, ",") != "order_id,amount,created" {
+		t.Errorf("Неожиданные колонки: %v", target.columns)
+	}
+	if len(target.rows) > 0 {
+		if id, ok := target.rows[0][0].(int64); !ok || id != 1 {
+			t.Errorf("ID не приведен к целому: %#v", target.rows[0][0])
+		}
+		if _, ok := target.rows[0][2].(time.Time); !ok {
+			t.Errorf("Дата не приведена к времени: %#v", target.rows[0][2])
+		}
+	}
+
+	rejects, err := storage.DownloadFileAsBytes(ctx, result.RejectsBucket, result.RejectsPath)
+	if err != nil {
+		t.Fatalf("Файл отклоненных строк не сохранен: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(rejects)), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "4,") {
+		t.Errorf("Неожиданный файл отклоненных строк:\n%s", rejects)
+	}
+
+	_, err = loads.Load(ctx, "u1", &models.LoadRequest{AnalysisID: "analysis-1", Connection: "warehouse", TableName: "orders", MaxRejects: 1})
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeValidation {
+		t.Errorf("Ожидалась ошибка превышения max_rejects, получено %v", err)
+	}
+}
+
+// TestClickHouseBulkInsert проверяет вставку батчами с токенами дедупликации и повтор после сбоя
+func TestClickHouseBulkInsert(t *testing.T) {
+	var mu sync.Mutex
+	var tokens []string
+	var bodies []string
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.URL.Query().Get("query"), "INSERT INTO `analytics`.`events` (`id`, `created`)") {
+			t.Errorf("Неожиданный запрос: %s", r.URL.Query().Get("query"))
+		}
+		tokens = append(tokens, r.URL.Query().Get("insert_deduplication_token"))
+		if len(tokens) == 2 && !failed {
+			// Второй батч падает один раз и повторяется с тем же токеном
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	database := clickhouse.NewDatabaseRepository(client.NewClickHouseClient(client.ClickHouseOptions{URL: server.URL}, testLogger), testLogger)
+	inserter, ok := database.(repository.BulkInserter)
+	if !ok {
+		t.Fatalf("Репозиторий ClickHouse не поддерживает массовую загрузку")
+	}
+
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	rows := 0
+	loaded, err := inserter.BulkInsert(context.Background(), "analytics.events", []string{"id", "created"}, func() ([]interface{}, error) {
+		if rows == 3 {
+			return nil, io.EOF
+		}
+		rows++
+		return []interface{}{int64(rows), created}, nil
+	}, models.BulkInsertOptions{BatchSize: 2, DedupKey: "load"})
+	if err != nil {
+		t.Fatalf("Загрузка завершилась ошибкой: %v", err)
+	}
+	if loaded != 3 {
+		t.Errorf("Ожидалось 3 загруженные строки, получено %d", loaded)
+	}
+	if strings.Join(tokens, ",") != "load-0,load-1,load-1" {
+		t.Errorf("Неожиданные токены дедупликации: %v", tokens)
+	}
+	if len(bodies) != 2 || bodies[0] != "[1,\"2024-01-02\"]\n[2,\"2024-01-02\"]\n" {
+		t.Errorf("Неожиданные батчи: %q", bodies)
+	}
+}
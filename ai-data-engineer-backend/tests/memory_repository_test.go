@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryFileRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewFileRepository()
+
+	// Сохраняем файлы параллельно, чтобы проверить потокобезопасность
+	base := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.SaveFile(ctx, &models.FileMetadata{
+				ID:        fmt.Sprintf("file-%02d", i),
+				UserID:    "user",
+				Status:    models.FileStatusUploaded,
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			})
+			if err != nil {
+				t.Errorf("Не удалось сохранить файл: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	page, err := repo.GetFilesByUser(ctx, "user", 5, 5)
+	if err != nil {
+		t.Fatalf("Не удалось получить список файлов: %v", err)
+	}
+	if len(page) != 5 || page[0].ID != "file-14" {
+		t.Errorf("Неверная страница: получили %d файлов, первый %s", len(page), page[0].ID)
+	}
+
+	// Изменение возвращенной копии не должно менять сохраненные данные
+	file, _ := repo.GetFile(ctx, "file-01")
+	file.Status = models.FileStatusProcessing
+	stored, _ := repo.GetFile(ctx, "file-01")
+	if stored.Status != models.FileStatusUploaded {
+		t.Errorf("Сохраненный файл изменился без UpdateFile")
+	}
+
+	if err := repo.UpdateFile(ctx, file); err != nil {
+		t.Fatalf("Не удалось обновить файл: %v", err)
+	}
+	processing, _ := repo.GetFilesByStatus(ctx, models.FileStatusProcessing)
+	if len(processing) != 1 {
+		t.Errorf("Ожидался 1 файл в статусе processing, получили %d", len(processing))
+	}
+
+	if err := repo.DeleteFile(ctx, "file-01"); err != nil {
+		t.Fatalf("Не удалось удалить файл: %v", err)
+	}
+	_, err = repo.GetFile(ctx, "file-01")
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeFileNotFound {
+		t.Errorf("Ожидалась ошибка file_not_found, получили %v", err)
+	}
+}
+
+func TestMemoryPipelineRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewPipelineRepository()
+
+	pipeline := &models.Pipeline{
+		UserID: "user",
+		Name:   "nightly",
+		Status: models.PipelineStatusDraft,
+		Steps:  []models.PipelineStep{{ID: "extract", Type: models.StepTypeExtract}},
+	}
+	id, err := repo.SavePipeline(ctx, pipeline)
+	if err != nil || id == "" {
+		t.Fatalf("Не удалось сохранить пайплайн: %v", err)
+	}
+
+	pipeline.Steps[0].Status = models.StepStatusRunning
+	stored, err := repo.GetPipeline(ctx, id)
+	if err != nil {
+		t.Fatalf("Не удалось получить пайплайн: %v", err)
+	}
+	if stored.Steps[0].Status != "" {
+		t.Errorf("Сохраненные шаги изменились без UpdatePipeline")
+	}
+
+	_, err = repo.GetPipeline(ctx, "missing")
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodePipelineNotFound {
+		t.Errorf("Ожидалась ошибка pipeline_not_found, получили %v", err)
+	}
+}
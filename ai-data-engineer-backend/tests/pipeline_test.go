@@ -0,0 +1,631 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPipelineCRUD проверяет создание пайплайна по анализу, список с фильтром статуса,
+// изменение и удаление
+func TestPipelineCRUD(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", FilePath: "users/analyst/orders.csv", Status: models.AnalysisStatusCompleted,
+		Profile:   &models.DataProfile{DataType: "csv", Fields: []models.DataField{{Name: "id", Type: models.FieldTypeInteger}}},
+		CreatedAt: time.Now(),
+	})
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{ID: "analysis-2", UserId: "analyst", Status: models.AnalysisStatusRunning, CreatedAt: time.Now()})
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{ID: "analysis-3", UserId: "intruder", Status: models.AnalysisStatusCompleted, CreatedAt: time.Now()})
+	repo := memory.NewPipelineRepository()
+	handler := handlers.NewPipelineHandler(service.NewPipelineService(repo, analyses, testLogger), nil, testLogger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/pipelines", handler.CreatePipeline)
+	router.GET("/api/v1/pipelines", handler.ListPipelines)
+	router.GET("/api/v1/pipelines/:id", handler.GetPipeline)
+	router.PUT("/api/v1/pipelines/:id", handler.UpdatePipeline)
+	router.DELETE("/api/v1/pipelines/:id", handler.DeletePipeline)
+
+	sendAs := func(userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return sendAs("analyst", method, path, body)
+	}
+
+	target := map[string]interface{}{"type": "postgres", "connection_string": "postgres", "table_name": "orders"}
+	w := send(http.MethodPost, "/api/v1/pipelines", map[string]interface{}{"analysis_id": "analysis-1", "target": target})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получили %d: %s", w.Code, w.Body.String())
+	}
+	var created models.PipelineResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Status != string(models.PipelineStatusReady) || created.UserID != "analyst" || created.Name != "orders.csv → orders" {
+		t.Errorf("Неожиданный пайплайн: %+v", created)
+	}
+	if created.Source.Config["analysis_id"] != "analysis-1" || len(created.Source.Schema.Fields) != 1 || len(created.Steps) != 2 {
+		t.Errorf("Источник или шаги не построены по анализу: %+v, %+v", created.Source, created.Steps)
+	}
+
+	// Анализ еще выполняется: пайплайн остается черновиком
+	send(http.MethodPost, "/api/v1/pipelines", map[string]interface{}{"analysis_id": "analysis-2", "name": "draft", "target": target})
+	if w := send(http.MethodGet, "/api/v1/pipelines?status=draft", nil); !strings.Contains(w.Body.String(), `"count":1`) || !strings.Contains(w.Body.String(), `"name":"draft"`) {
+		t.Errorf("Неожиданный список черновиков: %s", w.Body.String())
+	}
+	if w := send(http.MethodGet, "/api/v1/pipelines?limit=1&offset=1", nil); !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Неожиданная страница списка: %s", w.Body.String())
+	}
+	if w := send(http.MethodGet, "/api/v1/pipelines?status=unknown", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестный статус: ожидался статус 400, получили %d", w.Code)
+	}
+
+	steps := []map[string]interface{}{{"id": "check", "type": "validate"}, {"id": "check", "type": "load"}}
+	if w := send(http.MethodPut, "/api/v1/pipelines/"+created.PipelineID, map[string]interface{}{"analysis_id": "analysis-1", "target": target, "steps": steps}); w.Code != http.StatusBadRequest {
+		t.Errorf("Повторяющийся ID шага: ожидался статус 400, получили %d", w.Code)
+	}
+	w = send(http.MethodPut, "/api/v1/pipelines/"+created.PipelineID, map[string]interface{}{"analysis_id": "analysis-1", "name": "nightly", "target": target})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"nightly"`) {
+		t.Errorf("Не удалось изменить пайплайн: %d %s", w.Code, w.Body.String())
+	}
+
+	// Чужой анализ не найден, чужой пайплайн недоступен
+	if w := send(http.MethodPost, "/api/v1/pipelines", map[string]interface{}{"analysis_id": "analysis-3", "target": target}); w.Code != http.StatusNotFound {
+		t.Errorf("Пайплайн по чужому анализу: ожидался статус 404, получили %d", w.Code)
+	}
+	if w := send(http.MethodPut, "/api/v1/pipelines/"+created.PipelineID, map[string]interface{}{"analysis_id": "analysis-3", "target": target}); w.Code != http.StatusNotFound {
+		t.Errorf("Замена анализа на чужой: ожидался статус 404, получили %d", w.Code)
+	}
+	if w := sendAs("intruder", http.MethodPut, "/api/v1/pipelines/"+created.PipelineID, map[string]interface{}{"analysis_id": "analysis-3", "target": target}); w.Code != http.StatusForbidden {
+		t.Errorf("Изменение чужого пайплайна: ожидался статус 403, получили %d", w.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := sendAs("intruder", method, "/api/v1/pipelines/"+created.PipelineID, nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s чужого пайплайна: ожидался статус 403, получили %d", method, w.Code)
+		}
+	}
+
+	// Пайплайн, запущенный после чтения, не перезаписывается: статус проверяется в репозитории
+	stale, _ := repo.GetPipeline(ctx, created.PipelineID)
+	running := *stale
+	running.Status = models.PipelineStatusRunning
+	repo.UpdatePipelineState(ctx, &running)
+	stale.Name = "stale"
+	if appErr, ok := models.IsAppError(repo.UpdatePipeline(ctx, stale)); !ok || appErr.Code != models.ErrorCodeConflict {
+		t.Errorf("Ожидался конфликт при изменении выполняющегося пайплайна, получили %v", appErr)
+	}
+	if appErr, ok := models.IsAppError(repo.DeletePipeline(ctx, created.PipelineID)); !ok || appErr.Code != models.ErrorCodeConflict {
+		t.Errorf("Ожидался конфликт при удалении выполняющегося пайплайна, получили %v", appErr)
+	}
+	running.Status = models.PipelineStatusReady
+	repo.UpdatePipelineState(ctx, &running)
+
+	if w := send(http.MethodDelete, "/api/v1/pipelines/"+created.PipelineID, nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200, получили %d", w.Code)
+	}
+	w = send(http.MethodGet, "/api/v1/pipelines/"+created.PipelineID, nil)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), string(models.ErrorCodePipelineNotFound)) {
+		t.Errorf("Ожидалась ошибка pipeline_not_found, получили %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestPipelineStepsDAG проверяет обнаружение нарушений графа шагов и порядок выполнения по уровням
+func TestPipelineStepsDAG(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted, CreatedAt: time.Now()})
+	pipelines := service.NewPipelineService(memory.NewPipelineRepository(), analyses, testLogger)
+
+	invalid := []models.PipelineStep{
+		{ID: "extract", Type: models.StepTypeExtract, DependsOn: []string{"load"}},
+		{ID: "load", Type: models.StepTypeLoad, DependsOn: []string{"extract"}},
+		{ID: "clean", Type: models.StepTypeTransform, DependsOn: []string{"missing"}},
+		{ID: "clean", Type: models.StepTypeValidate},
+		{ID: "early", Type: models.StepTypeLoad},
+		{ID: "late", Type: models.StepTypeExtract, DependsOn: []string{"early"}},
+	}
+	_, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: invalid})
+	appErr, ok := models.IsAppError(err)
+	if !ok || appErr.Code != models.ErrorCodeValidation {
+		t.Fatalf("Ожидалась ошибка валидации, получено %v", err)
+	}
+	problems, _ := appErr.Details["problems"].([]models.StepProblem)
+	codes := map[models.StepProblemCode]int{}
+	for _, problem := range problems {
+		codes[problem.Code]++
+	}
+	if codes[models.StepProblemCycle] != 1 || codes[models.StepProblemMissingDependency] != 1 || codes[models.StepProblemDuplicateID] != 1 {
+		t.Errorf("Неожиданные нарушения: %+v", problems)
+	}
+
+	// Без циклов проверяется порядок стадий: extract после load и load без extract
+	_, err = pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: invalid[4:]})
+	appErr, _ = models.IsAppError(err)
+	if problems, _ := appErr.Details["problems"].([]models.StepProblem); len(problems) != 2 || problems[0].Code != models.StepProblemOrder {
+		t.Errorf("Ожидались нарушения порядка, получено %+v", problems)
+	}
+
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: []models.PipelineStep{
+		{ID: "extract", Type: models.StepTypeExtract},
+		{ID: "clean", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+		{ID: "check", Type: models.StepTypeValidate, DependsOn: []string{"extract"}},
+		{ID: "load", Type: models.StepTypeLoad, DependsOn: []string{"clean", "check"}},
+	}})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	if _, err := pipelines.PlanPipeline(ctx, "intruder", created.PipelineID); !isForbidden(err) {
+		t.Errorf("Ожидался отказ в плане чужого пайплайна, получили %v", err)
+	}
+	plan, err := pipelines.PlanPipeline(ctx, "analyst", created.PipelineID)
+	if err != nil {
+		t.Fatalf("Не удалось построить порядок выполнения: %v", err)
+	}
+	if strings.Join(plan.Order, ",") != "extract,clean,check,load" || len(plan.Levels) != 3 || len(plan.Levels[1]) != 2 {
+		t.Errorf("Неожиданный план: %+v", plan)
+	}
+}
+
+// TestPipelineExecution проверяет выполнение шагов по уровням с ограниченным параллелизмом,
+// журнал выполнения и пропуск шагов после ошибки
+func TestPipelineExecution(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", FilePath: "users/analyst/orders.csv", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	pipelines := service.NewPipelineService(pipelineRepo, analyses, testLogger)
+	executor := service.NewPipelineExecutor(pipelineRepo, memory.NewExecutionRepository(), service.NewEventBroker(0, 0),
+		service.ExecutorOptions{Parallelism: 2}, testLogger)
+
+	var running, peak int32
+	step := func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			observed := atomic.LoadInt32(&peak)
+			if current <= observed || atomic.CompareAndSwapInt32(&peak, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		run.Log(models.LogLevelInfo, "обработано "+run.Step.ID)
+		return run.Step.ID, nil
+	}
+	executor.Register(models.StepTypeExtract, service.StepRunnerFunc(step))
+	executor.Register(models.StepTypeTransform, service.StepRunnerFunc(step))
+	executor.Register(models.StepTypeValidate, service.StepRunnerFunc(step))
+	executor.Register(models.StepTypeLoad, service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		if len(run.Inputs) != 3 || run.Inputs["clean_a"] != "clean_a" {
+			return nil, errors.New("неожиданные результаты предыдущих шагов")
+		}
+		if run.Parameters["fail"] == true {
+			return nil, models.NewValidationError("таблица недоступна", nil)
+		}
+		return nil, nil
+	}))
+
+	handler := handlers.NewPipelineHandler(pipelines, executor, testLogger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/pipelines/:id/execute", handler.ExecutePipeline)
+	router.GET("/api/v1/pipelines/:id/executions", handler.ListExecutions)
+	router.GET("/api/v1/executions/:id", handler.GetExecution)
+
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: []models.PipelineStep{
+		{ID: "extract", Type: models.StepTypeExtract},
+		{ID: "clean_a", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+		{ID: "clean_b", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+		{ID: "clean_c", Type: models.StepTypeTransform, DependsOn: []string{"extract"}},
+		{ID: "load", Type: models.StepTypeLoad, DependsOn: []string{"clean_a", "clean_b", "clean_c"}},
+		{ID: "check", Type: models.StepTypeValidate, DependsOn: []string{"load"}},
+	}})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+
+	requestAs := func(userID, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	execute := func(body string) models.PipelineExecution {
+		w := requestAs("analyst", http.MethodPost, "/api/v1/pipelines/"+created.PipelineID+"/execute", body)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Ожидался статус 202, получили %d: %s", w.Code, w.Body.String())
+		}
+		var started models.ExecutePipelineResponse
+		json.Unmarshal(w.Body.Bytes(), &started)
+
+		// Повторный запуск до завершения отклоняется
+		if w = requestAs("analyst", http.MethodPost, "/api/v1/pipelines/"+created.PipelineID+"/execute", ""); w.Code != http.StatusConflict {
+			t.Errorf("Повторный запуск: ожидался статус 409, получили %d", w.Code)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			w = requestAs("analyst", http.MethodGet, "/api/v1/executions/"+started.ExecutionID, "")
+			var execution models.PipelineExecution
+			json.Unmarshal(w.Body.Bytes(), &execution)
+			if execution.Status != models.ExecutionStatusRunning {
+				return execution
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Выполнение не завершилось: %s", w.Body.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	stepStatuses := func() map[string]models.StepStatus {
+		pipeline, _ := pipelines.GetPipeline(ctx, "analyst", created.PipelineID)
+		statuses := map[string]models.StepStatus{}
+		for _, step := range pipeline.Steps {
+			statuses[step.ID] = step.Status
+		}
+		return statuses
+	}
+
+	if w := requestAs("intruder", http.MethodPost, "/api/v1/pipelines/"+created.PipelineID+"/execute", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Запуск чужого пайплайна: ожидался статус 403, получили %d", w.Code)
+	}
+	execution := execute("")
+	if execution.Status != models.ExecutionStatusCompleted || execution.CompletedAt == nil {
+		t.Fatalf("Ожидалось успешное выполнение, получено %+v", execution)
+	}
+	if execution.UserID != "analyst" {
+		t.Errorf("Выполнение записано на пользователя %q", execution.UserID)
+	}
+	if w := requestAs("intruder", http.MethodGet, "/api/v1/executions/"+execution.ID, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Чужое выполнение: ожидался статус 403, получили %d", w.Code)
+	}
+	if peak != 2 {
+		t.Errorf("Ожидалось не больше двух шагов одновременно и параллельное выполнение уровня, получено %d", peak)
+	}
+	logged := map[string]bool{}
+	for _, entry := range execution.Logs {
+		logged[entry.StepID+": "+entry.Message] = true
+	}
+	if !logged["clean_b: обработано clean_b"] || !logged["check: обработано check"] {
+		t.Errorf("Журнал не содержит записей шагов: %+v", execution.Logs)
+	}
+	for id, status := range stepStatuses() {
+		if status != models.StepStatusCompleted {
+			t.Errorf("Шаг %s: ожидался статус completed, получен %s", id, status)
+		}
+	}
+
+	execution = execute(`{"parameters": {"fail": true}}`)
+	if execution.Status != models.ExecutionStatusFailed || !strings.Contains(execution.Error, "таблица недоступна") {
+		t.Errorf("Ожидалось выполнение с ошибкой шага load, получено %+v", execution)
+	}
+	statuses := stepStatuses()
+	if statuses["load"] != models.StepStatusFailed || statuses["check"] != models.StepStatusSkipped || statuses["clean_c"] != models.StepStatusCompleted {
+		t.Errorf("Неожиданные статусы шагов: %+v", statuses)
+	}
+	pipeline, _ := pipelines.GetPipeline(ctx, "analyst", created.PipelineID)
+	if pipeline.Status != string(models.PipelineStatusFailed) || pipeline.ExecutedAt == nil {
+		t.Errorf("Неожиданный статус пайплайна: %s", pipeline.Status)
+	}
+
+	if w := requestAs("analyst", http.MethodGet, "/api/v1/pipelines/"+created.PipelineID+"/executions", ""); !strings.Contains(w.Body.String(), `"count":2`) {
+		t.Errorf("Ожидалось два выполнения: %s", w.Body.String())
+	}
+	if w := requestAs("intruder", http.MethodGet, "/api/v1/pipelines/"+created.PipelineID+"/executions", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Выполнения чужого пайплайна: ожидался статус 403, получили %d", w.Code)
+	}
+}
+
+// TestPipelineExecutionPolicies проверяет повторы и ограничение времени шага, пропуск
+// зависящих от упавшего шага и отмену выполнения
+func TestPipelineExecutionPolicies(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	pipelines := service.NewPipelineService(pipelineRepo, analyses, testLogger)
+	executor := service.NewPipelineExecutor(pipelineRepo, memory.NewExecutionRepository(), service.NewEventBroker(0, 0),
+		service.ExecutorOptions{}, testLogger)
+
+	var flakyAttempts int32
+	blocking := make(chan struct{})
+	done := func(ctx context.Context, run *service.StepRun) (interface{}, error) { return nil, nil }
+	wait := func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		close(blocking)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	executor.Register(models.StepTypeLoad, service.StepRunnerFunc(done))
+	executor.Register(models.StepTypeTransform, service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		if run.Step.ID == "flaky" && atomic.AddInt32(&flakyAttempts, 1) < 3 {
+			return nil, models.NewDatabaseError("база недоступна", nil)
+		}
+		if run.Step.ID == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}))
+	executor.Register(models.StepTypeExtract, service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		if run.Parameters["block"] == true {
+			return wait(ctx, run)
+		}
+		return nil, nil
+	}))
+
+	finished := func(executionID string) *models.PipelineExecution {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			execution, err := executor.GetExecution(ctx, "analyst", executionID)
+			if err != nil {
+				t.Fatalf("Не удалось получить выполнение: %v", err)
+			}
+			if execution.Status != models.ExecutionStatusRunning && execution.Status != models.ExecutionStatusCancelling {
+				return execution
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Выполнение не завершилось: %+v", execution)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	steps := func(pipelineID string) map[string]models.PipelineStep {
+		pipeline, _ := pipelines.GetPipeline(ctx, "analyst", pipelineID)
+		byID := map[string]models.PipelineStep{}
+		for _, step := range pipeline.Steps {
+			byID[step.ID] = step
+		}
+		return byID
+	}
+
+	_, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: []models.PipelineStep{
+		{ID: "extract", Type: models.StepTypeExtract, Config: map[string]interface{}{"retry": map[string]interface{}{"max_attempts": 0.0}}},
+	}})
+	if appErr, ok := models.IsAppError(err); !ok || appErr.Code != models.ErrorCodeValidation {
+		t.Errorf("Некорректная политика повторов: ожидалась ошибка валидации, получено %v", err)
+	}
+
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1", Steps: []models.PipelineStep{
+		{ID: "extract", Type: models.StepTypeExtract},
+		{ID: "flaky", Type: models.StepTypeTransform, DependsOn: []string{"extract"}, Config: map[string]interface{}{
+			"retry": map[string]interface{}{"max_attempts": 3.0, "backoff": "1ms", "retry_on": []interface{}{"database"}},
+		}},
+		{ID: "slow", Type: models.StepTypeTransform, DependsOn: []string{"extract"}, Config: map[string]interface{}{"timeout": "20ms"}},
+		{ID: "load_flaky", Type: models.StepTypeLoad, DependsOn: []string{"flaky"}},
+		{ID: "load_slow", Type: models.StepTypeLoad, DependsOn: []string{"slow"}},
+	}})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	started, err := executor.Execute(ctx, created.PipelineID, "", nil)
+	if err != nil {
+		t.Fatalf("Не удалось запустить пайплайн: %v", err)
+	}
+	execution := finished(started.ID)
+	byID := steps(created.PipelineID)
+	if execution.Status != models.ExecutionStatusFailed || !strings.Contains(byID["slow"].Error, "20ms") {
+		t.Errorf("Ожидалась ошибка по таймауту шага slow, получено %q / %q", execution.Error, byID["slow"].Error)
+	}
+	if flakyAttempts != 3 || byID["flaky"].Status != models.StepStatusCompleted || byID["load_flaky"].Status != models.StepStatusCompleted {
+		t.Errorf("Шаг flaky должен выполниться с третьей попытки: попыток %d, шаги %+v", flakyAttempts, byID)
+	}
+	if byID["load_slow"].Status != models.StepStatusSkipped {
+		t.Errorf("Шаг после упавшего должен быть пропущен, получен статус %s", byID["load_slow"].Status)
+	}
+
+	// Отмена прерывает выполняемый шаг через контекст и пропускает оставшиеся
+	handler := handlers.NewPipelineHandler(pipelines, executor, testLogger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/executions/:id/cancel", handler.CancelExecution)
+	cancelAs := func(userID, id string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/executions/"+id+"/cancel", nil)
+		req.Header.Set("X-User-ID", userID)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	cancel := func(id string) int { return cancelAs("analyst", id) }
+
+	started, err = executor.Execute(ctx, created.PipelineID, "", map[string]interface{}{"block": true})
+	if err != nil {
+		t.Fatalf("Не удалось запустить пайплайн: %v", err)
+	}
+	<-blocking
+	if code := cancelAs("intruder", started.ID); code != http.StatusForbidden {
+		t.Fatalf("Ожидался статус 403 для чужого выполнения, получили %d", code)
+	}
+	if code := cancel(started.ID); code != http.StatusAccepted {
+		t.Fatalf("Ожидался статус 202, получили %d", code)
+	}
+	execution = finished(started.ID)
+	byID = steps(created.PipelineID)
+	if execution.Status != models.ExecutionStatusCancelled || byID["extract"].Status != models.StepStatusFailed || byID["flaky"].Status != models.StepStatusSkipped {
+		t.Errorf("Неожиданное состояние после отмены: %s, %+v", execution.Status, byID)
+	}
+	if pipeline, _ := pipelines.GetPipeline(ctx, "analyst", created.PipelineID); pipeline.Status != string(models.PipelineStatusCancelled) {
+		t.Errorf("Ожидался статус пайплайна cancelled, получен %s", pipeline.Status)
+	}
+	if code := cancel(started.ID); code != http.StatusConflict {
+		t.Errorf("Отмена завершенного выполнения: ожидался статус 409, получили %d", code)
+	}
+}
+
+// TestPipelineExecutorRecovery проверяет, что при запуске завершаются только выполнения
+// экземпляров с истекшей арендой и прежнего процесса с тем же ID, а выполнения работающих
+// экземпляров не затрагиваются
+func TestPipelineExecutorRecovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	leases := memory.NewLeaseRepository()
+
+	alive := service.NewInstance(leases, service.InstanceOptions{ID: "alive", LeaseTTL: time.Minute}, testLogger)
+	if err := alive.Start(ctx); err != nil {
+		t.Fatalf("Не удалось зарегистрировать экземпляр: %v", err)
+	}
+	if err := service.NewInstance(leases, service.InstanceOptions{ID: "alive"}, testLogger).Start(ctx); err == nil {
+		t.Error("Ожидалась ошибка для экземпляра с именем работающего экземпляра")
+	}
+
+	old := time.Now().Add(-time.Hour)
+	for _, id := range []string{"dead", "alive", "local", "orphan", "fresh"} {
+		updated := old
+		if id == "fresh" {
+			updated = time.Now()
+		}
+		pipelineRepo.SavePipeline(ctx, &models.Pipeline{
+			ID: id, UserID: "analyst", Status: models.PipelineStatusRunning, CreatedAt: old, UpdatedAt: updated,
+			Steps: []models.PipelineStep{{ID: "extract", Type: models.StepTypeExtract, Status: models.StepStatusRunning}},
+		})
+	}
+	for _, owner := range []string{"dead", "alive", "local"} {
+		executions.SaveExecution(ctx, &models.PipelineExecution{
+			ID: "execution-" + owner, PipelineID: owner, UserID: "analyst", Status: models.ExecutionStatusRunning,
+			StartedAt: old, Owner: owner,
+		})
+	}
+	// Отмена запрошена, но экземпляр остановился до ее выполнения
+	pipelineRepo.SavePipeline(ctx, &models.Pipeline{ID: "cancelling", UserID: "analyst", Status: models.PipelineStatusRunning, CreatedAt: old, UpdatedAt: old})
+	executions.SaveExecution(ctx, &models.PipelineExecution{
+		ID: "execution-cancelling", PipelineID: "cancelling", UserID: "analyst", Status: models.ExecutionStatusCancelling,
+		StartedAt: old, Owner: "dead",
+	})
+
+	instance := service.NewInstance(leases, service.InstanceOptions{ID: "local", LeaseTTL: time.Minute}, testLogger)
+	executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0),
+		service.ExecutorOptions{Instance: instance}, testLogger)
+	if err := executor.Start(ctx); err != nil {
+		t.Fatalf("Не удалось запустить выполнение пайплайнов: %v", err)
+	}
+
+	expected := map[string]models.ExecutionStatus{
+		"dead": models.ExecutionStatusFailed, "alive": models.ExecutionStatusRunning, "local": models.ExecutionStatusFailed,
+		"cancelling": models.ExecutionStatusCancelled,
+	}
+	for owner, status := range expected {
+		execution, _ := executions.GetExecution(ctx, "execution-"+owner)
+		if execution.Status != status {
+			t.Errorf("Выполнение экземпляра %q: статус %s, ожидался %s", owner, execution.Status, status)
+		}
+	}
+	pipelineStatuses := map[string]models.PipelineStatus{
+		"dead": models.PipelineStatusFailed, "alive": models.PipelineStatusRunning, "local": models.PipelineStatusFailed,
+		"orphan": models.PipelineStatusFailed, "fresh": models.PipelineStatusRunning, "cancelling": models.PipelineStatusCancelled,
+	}
+	for id, status := range pipelineStatuses {
+		pipeline, _ := pipelineRepo.GetPipeline(ctx, id)
+		if pipeline.Status != status {
+			t.Errorf("Пайплайн %q: статус %s, ожидался %s", id, pipeline.Status, status)
+		}
+		if status == models.PipelineStatusFailed && pipeline.Steps[0].Status != models.StepStatusFailed {
+			t.Errorf("Пайплайн %q: прерванный шаг не завершен: %+v", id, pipeline.Steps[0])
+		}
+	}
+}
+
+// TestPipelineCancelAcrossInstances проверяет отмену выполнения, запрошенную через экземпляр
+// сервиса, который его не ведет: экземпляр-владелец замечает статус cancelling и прерывает шаги
+func TestPipelineCancelAcrossInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	leases := memory.NewLeaseRepository()
+	newExecutor := func(id string) *service.PipelineExecutor {
+		executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0), service.ExecutorOptions{
+			Instance:           service.NewInstance(leases, service.InstanceOptions{ID: id}, testLogger),
+			CancelPollInterval: 5 * time.Millisecond,
+		}, testLogger)
+		executor.Register(models.StepTypeExtract, service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}))
+		executor.Register(models.StepTypeLoad, service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+			return nil, nil
+		}))
+		if err := executor.Start(ctx); err != nil {
+			t.Fatalf("Не удалось запустить выполнение пайплайнов: %v", err)
+		}
+		return executor
+	}
+	owner, other := newExecutor("owner"), newExecutor("other")
+
+	created, err := service.NewPipelineService(pipelineRepo, analyses, testLogger).CreatePipeline(ctx, "analyst", &models.PipelineRequest{AnalysisID: "analysis-1"})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	started, err := owner.Execute(ctx, created.PipelineID, "analyst", nil)
+	if err != nil {
+		t.Fatalf("Не удалось запустить пайплайн: %v", err)
+	}
+	if err := other.Cancel(ctx, "analyst", started.ID); err != nil {
+		t.Fatalf("Не удалось запросить отмену: %v", err)
+	}
+	if execution, _ := executions.GetExecution(ctx, started.ID); execution.Status != models.ExecutionStatusCancelling &&
+		execution.Status != models.ExecutionStatusCancelled {
+		t.Errorf("Ожидался статус cancelling после запроса отмены, получен %s", execution.Status)
+	}
+	if err := other.Cancel(ctx, "analyst", started.ID); err != nil {
+		t.Errorf("Повторный запрос отмены: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execution, _ := executions.GetExecution(ctx, started.ID)
+		if execution.Status == models.ExecutionStatusCancelled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Выполнение не отменено: %+v", execution)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pipeline, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID); pipeline.Status != models.PipelineStatusCancelled {
+		t.Errorf("Ожидался статус пайплайна cancelled, получен %s", pipeline.Status)
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPresignedDownloadURL проверяет выдачу ссылки на скачивание только владельцу файла.
+// Подпись ссылки вычисляется локально, запущенный MinIO не нужен
+func TestPresignedDownloadURL(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	minioClient, err := client.NewMinIOClient("localhost:9000", "minioadmin", "minioadmin", false, testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать MinIO клиент: %v", err)
+	}
+	if err := minioClient.UsePublicEndpoint("files.example.com", true, "us-east-1"); err != nil {
+		t.Fatalf("Не удалось задать публичный адрес: %v", err)
+	}
+
+	fileRepo := memory.NewFileRepository()
+	fileRepo.SaveFile(context.Background(), &models.FileMetadata{
+		ID:        "file-1",
+		UserID:    "alice",
+		Filename:  "report.csv",
+		Path:      "users/alice/files/report.csv",
+		Bucket:    "ai-data-engineer",
+		Status:    models.FileStatusUploaded,
+		CreatedAt: time.Now(),
+	})
+	fileService := service.NewFileService(minioClient, fileRepo, service.NewBlobStore(minioClient, memory.NewBlobRepository(), testLogger), service.FileServiceOptions{PresignExpiry: time.Minute}, testLogger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/files/:id/download-url", handlers.NewFileHandler(fileService, testLogger).GetDownloadURL)
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/files/file-1/download-url", nil)
+		req.Header.Set("X-User-ID", userID)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := get("bob"); rr.Code != http.StatusForbidden {
+		t.Errorf("Ожидался статус %d для чужого файла, получили %d", http.StatusForbidden, rr.Code)
+	}
+
+	rr := get("alice")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получили %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var presigned models.PresignedURL
+	json.Unmarshal(rr.Body.Bytes(), &presigned)
+	u, err := url.Parse(presigned.URL)
+	if err != nil || u.Host != "files.example.com" || u.Query().Get("X-Amz-Signature") == "" {
+		t.Errorf("Неверная ссылка: %s", presigned.URL)
+	}
+	if u.Query().Get("X-Amz-Expires") != "60" {
+		t.Errorf("Ожидался срок действия 60 секунд, получили %s", u.Query().Get("X-Amz-Expires"))
+	}
+
+	// Файловое хранилище временные ссылки не поддерживает
+	storage, _ := client.NewFilesystemClient(t.TempDir(), testLogger)
+	_, err = service.NewFileService(storage, fileRepo, service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger), service.FileServiceOptions{}, testLogger).PresignDownload(context.Background(), "file-1", "alice")
+	if appErr, ok := models.IsAppError(err); !ok || appErr.HTTPCode != http.StatusNotImplemented {
+		t.Errorf("Ожидалась ошибка 501, получили %v", err)
+	}
+}
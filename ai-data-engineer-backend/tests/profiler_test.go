@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	"ai-data-engineer-backend/internal/service"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProfileCSV(t *testing.T) {
+	content := "\xEF\xBB\xBFid;name;price;active;created_at;zip\n" +
+		"1;John;10.5;true;2024-01-02;0123\n" +
+		"2;Jane;;false;2024-02-03;0456\n" +
+		"3;;7;yes;2024-03-04;0789\n"
+
+	profile, err := service.ProfileCSV(context.Background(), strings.NewReader(content), service.ProfileOptions{SampleRows: 2})
+	if err != nil {
+		t.Fatalf("Не удалось построить профиль: %v", err)
+	}
+
+	if profile.Delimiter != ";" {
+		t.Errorf("Ожидался разделитель ';', получили %q", profile.Delimiter)
+	}
+	if !profile.HasHeaders {
+		t.Errorf("Ожидалось наличие заголовка")
+	}
+	if profile.Encoding != "UTF-8-BOM" {
+		t.Errorf("Ожидалась кодировка UTF-8-BOM, получили %s", profile.Encoding)
+	}
+	if profile.TotalRows != 3 {
+		t.Errorf("Ожидалось 3 строки, получили %d", profile.TotalRows)
+	}
+	if profile.FileSize != int64(len(content)) {
+		t.Errorf("Ожидался размер %d, получили %d", len(content), profile.FileSize)
+	}
+
+	expected := map[string]string{
+		"id":         models.FieldTypeInteger,
+		"name":       models.FieldTypeString,
+		"price":      models.FieldTypeFloat,
+		"active":     models.FieldTypeBoolean,
+		"created_at": models.FieldTypeDate,
+		"zip":        models.FieldTypeString,
+	}
+	if len(profile.Fields) != len(expected) {
+		t.Fatalf("Ожидалось %d полей, получили %d", len(expected), len(profile.Fields))
+	}
+	for _, field := range profile.Fields {
+		if field.Type != expected[field.Name] {
+			t.Errorf("Поле %s: ожидался тип %s, получили %s", field.Name, expected[field.Name], field.Type)
+		}
+	}
+
+	price := profile.Fields[2]
+	if price.NullCount != 1 || !price.Nullable {
+		t.Errorf("Поле price: ожидался 1 null, получили %d", price.NullCount)
+	}
+	if price.MinValue != 7 || price.MaxValue != 10.5 {
+		t.Errorf("Поле price: ожидались min=7 max=10.5, получили min=%v max=%v", price.MinValue, price.MaxValue)
+	}
+	if profile.Fields[0].DistinctCount != 3 {
+		t.Errorf("Поле id: ожидалось 3 уникальных значения, получили %d", profile.Fields[0].DistinctCount)
+	}
+	if lines := strings.Count(profile.SampleData, "\n"); lines != 3 {
+		t.Errorf("Ожидалось 3 строки в образце (заголовок + 2), получили %d", lines)
+	}
+}
+
+func TestProfileCSVWithoutHeader(t *testing.T) {
+	content := "1,10.5\n2,11\n3,12.25\n"
+
+	profile, err := service.ProfileCSV(context.Background(), strings.NewReader(content), service.ProfileOptions{})
+	if err != nil {
+		t.Fatalf("Не удалось построить профиль: %v", err)
+	}
+
+	if profile.HasHeaders {
+		t.Errorf("Заголовок не ожидался")
+	}
+	if profile.TotalRows != 3 {
+		t.Errorf("Ожидалось 3 строки, получили %d", profile.TotalRows)
+	}
+	if profile.Fields[0].Name != "column_1" || profile.Fields[1].Type != models.FieldTypeFloat {
+		t.Errorf("Неожиданные поля: %+v", profile.Fields)
+	}
+}
+
+func TestProfileCSVSingleRow(t *testing.T) {
+	profile, err := service.ProfileCSV(context.Background(), strings.NewReader("1,10.5,2024-05-01\n"), service.ProfileOptions{})
+	if err != nil {
+		t.Fatalf("Не удалось построить профиль: %v", err)
+	}
+	if profile.HasHeaders || profile.TotalRows != 1 || profile.Fields[0].Name != "column_1" {
+		t.Errorf("Единственная строка с числами должна быть данными: %+v", profile)
+	}
+
+	profile, err = service.ProfileCSV(context.Background(), strings.NewReader("id,price,created_at\n"), service.ProfileOptions{})
+	if err != nil {
+		t.Fatalf("Не удалось построить профиль: %v", err)
+	}
+	if !profile.HasHeaders || profile.TotalRows != 0 || profile.Fields[0].Name != "id" {
+		t.Errorf("Единственная строка из имен должна быть заголовком: %+v", profile)
+	}
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/cron"
+	"ai-data-engineer-backend/pkg/logger"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestCronNext проверяет вычисление следующего запуска по cron выражению
+func TestCronNext(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("Нет данных часового пояса: %v", err)
+	}
+	cases := []struct {
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 3, 8, 10, 7, 30, 0, time.UTC), time.Date(2024, 3, 8, 10, 15, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 8, 10, 15, 0, 0, time.UTC), time.Date(2024, 3, 8, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2024, 3, 8, 10, 0, 0, 0, time.UTC), time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// День месяца и день недели ограничены: подходит любой из них
+		{"0 0 20 * 5", time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 9, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 9, 1, 13, 0, 0, 0, time.UTC), time.Date(2024, 9, 8, 12, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 3, 8, 1, 0, 0, 0, moscow), time.Date(2024, 3, 8, 3, 0, 0, 0, moscow)},
+	}
+	for _, tc := range cases {
+		schedule, err := cron.Parse(tc.spec)
+		if err != nil {
+			t.Errorf("%q: не удалось разобрать: %v", tc.spec, err)
+			continue
+		}
+		if next := schedule.Next(tc.from); !next.Equal(tc.expected) {
+			t.Errorf("%q от %s: ожидался %s, получили %s", tc.spec, tc.from, tc.expected, next)
+		}
+	}
+
+	for _, spec := range []string{"60 * * * *", "* * *", "*/0 * * * *", "5-1 * * * *", "* * * 13 *", "@often"} {
+		if _, err := cron.Parse(spec); err == nil {
+			t.Errorf("%q: ожидалась ошибка разбора", spec)
+		}
+	}
+}
+
+// TestPipelineScheduler проверяет постановку запусков в очередь по расписанию,
+// защиту от повторного запуска несколькими экземплярами и обработку пропущенных запусков
+func TestPipelineScheduler(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	pipelines := service.NewPipelineService(pipelineRepo, analyses, testLogger)
+	executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0), service.ExecutorOptions{}, testLogger)
+	noop := service.StepRunnerFunc(func(ctx context.Context, run *service.StepRun) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	})
+	executor.Register(models.StepTypeExtract, noop)
+	executor.Register(models.StepTypeLoad, noop)
+
+	if _, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "0 25 * * *"},
+	}); err == nil {
+		t.Error("Ожидалась ошибка для некорректного cron выражения")
+	}
+	if _, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "@hourly", Timezone: "Mars/Olympus"},
+	}); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного часового пояса")
+	}
+
+	created, err := pipelines.CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "*/5 * * * *", Timezone: "Europe/Moscow"},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	if created.Schedule == nil || created.Schedule.NextRunAt == nil || created.Schedule.NextRunAt.Minute()%5 != 0 {
+		t.Fatalf("Не вычислен следующий запуск: %+v", created.Schedule)
+	}
+	due := *created.Schedule.NextRunAt
+
+	leases := memory.NewLeaseRepository()
+	options := service.SchedulerOptions{Interval: time.Minute, MaxCatchUp: 3}
+	newScheduler := func(holder string, leases repository.LeaseRepository) *service.PipelineScheduler {
+		options.Holder = holder
+		return service.NewPipelineScheduler(pipelineRepo, leases, executor, options, testLogger)
+	}
+	first, second := newScheduler("first", leases), newScheduler("second", leases)
+
+	if count, err := first.Tick(ctx, due.Add(-time.Second)); err != nil || count != 0 {
+		t.Errorf("Запуск до наступления времени: %d, %v", count, err)
+	}
+	if count, err := first.Tick(ctx, due.Add(time.Second)); err != nil || count != 1 {
+		t.Fatalf("Ожидался один запуск, получили %d, %v", count, err)
+	}
+	// Аренду удерживает первый экземпляр
+	if count, _ := second.Tick(ctx, due.Add(time.Second)); count != 0 {
+		t.Errorf("Второй экземпляр поставил в очередь %d запусков", count)
+	}
+	// Даже без общей аренды запуск уже сдвинут и не повторяется
+	if count, _ := newScheduler("third", memory.NewLeaseRepository()).Tick(ctx, due.Add(time.Second)); count != 0 {
+		t.Errorf("Запуск повторен другим экземпляром: %d", count)
+	}
+
+	waitExecutions := func(expected int) []*models.PipelineExecution {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			list, _ := executions.GetExecutionsByStatus(ctx, models.ExecutionStatusCompleted)
+			if len(list) >= expected || time.Now().After(deadline) {
+				return list
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	completed := waitExecutions(1)
+	if len(completed) != 1 || completed[0].Parameters["trigger"] != "schedule" ||
+		completed[0].Parameters["scheduled_at"] != due.Format(time.RFC3339) {
+		t.Fatalf("Неожиданные выполнения по расписанию: %+v", completed)
+	}
+
+	stored, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if !stored.Schedule.NextRunAt.Equal(due.Add(5*time.Minute)) || !stored.Schedule.LastRunAt.Equal(due) {
+		t.Errorf("Расписание не сдвинуто: %+v", stored.Schedule)
+	}
+
+	// Сервер не работал 22 минуты: catch_up = all выполняет не больше MaxCatchUp запусков по очереди
+	_, err = pipelines.UpdatePipeline(ctx, "analyst", created.PipelineID, &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "*/5 * * * *", Timezone: "Europe/Moscow", CatchUp: models.CatchUpAll},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось изменить пайплайн: %v", err)
+	}
+	stored, _ = pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if !stored.Schedule.LastRunAt.Equal(due) {
+		t.Errorf("Время последнего запуска потеряно при изменении: %+v", stored.Schedule)
+	}
+	next := *stored.Schedule.NextRunAt
+	if count, _ := first.Tick(ctx, next.Add(22*time.Minute)); count != 3 {
+		t.Errorf("Ожидалось 3 пропущенных запуска, получили %d", count)
+	}
+	if completed := waitExecutions(4); len(completed) != 4 {
+		t.Errorf("Ожидалось 4 завершенных выполнения, получили %d", len(completed))
+	}
+
+	// catch_up = none пропускает давно пропущенные запуски, но выполняет недавний
+	pipelines.UpdatePipeline(ctx, "analyst", created.PipelineID, &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "*/5 * * * *", CatchUp: models.CatchUpNone},
+	})
+	stored, _ = pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	next = *stored.Schedule.NextRunAt
+	if count, _ := first.Tick(ctx, next.Add(time.Hour+3*time.Minute)); count != 0 {
+		t.Errorf("Пропущенный запуск выполнен при catch_up = none: %d", count)
+	}
+	stored, _ = pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if !stored.Schedule.NextRunAt.After(next.Add(time.Hour + 3*time.Minute)) {
+		t.Errorf("Расписание не сдвинуто за пропущенные запуски: %+v", stored.Schedule)
+	}
+	if count, _ := first.Tick(ctx, stored.Schedule.NextRunAt.Add(30*time.Second)); count != 1 {
+		t.Errorf("Ожидался запуск с небольшой задержкой, получили %d", count)
+	}
+}
+
+// TestSchedulerRecordsFailedEnqueue проверяет, что запуск, который не удалось поставить
+// в очередь после сдвига расписания, сохраняется как выполнение с ошибкой
+func TestSchedulerRecordsFailedEnqueue(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	analyses := memory.NewAnalysisRepository()
+	analyses.SaveAnalysis(ctx, &models.AnalysisResult{
+		ID: "analysis-1", UserId: "analyst", Status: models.AnalysisStatusCompleted,
+		Profile: &models.DataProfile{Fields: []models.DataField{{Name: "id"}}}, CreatedAt: time.Now(),
+	})
+	pipelineRepo := memory.NewPipelineRepository()
+	executions := memory.NewExecutionRepository()
+	created, err := service.NewPipelineService(pipelineRepo, analyses, testLogger).CreatePipeline(ctx, "analyst", &models.PipelineRequest{
+		AnalysisID: "analysis-1", Schedule: &models.PipelineSchedule{Cron: "*/5 * * * *"},
+	})
+	if err != nil {
+		t.Fatalf("Не удалось создать пайплайн: %v", err)
+	}
+	due := *created.Schedule.NextRunAt
+
+	// Исполнители шагов не зарегистрированы: Enqueue завершается ошибкой
+	executor := service.NewPipelineExecutor(pipelineRepo, executions, service.NewEventBroker(0, 0), service.ExecutorOptions{}, testLogger)
+	scheduler := service.NewPipelineScheduler(pipelineRepo, memory.NewLeaseRepository(), executor,
+		service.SchedulerOptions{Interval: time.Minute, Holder: "first"}, testLogger)
+	if count, err := scheduler.Tick(ctx, due.Add(time.Second)); err != nil || count != 0 {
+		t.Errorf("Ожидалось 0 запусков в очереди, получили %d, %v", count, err)
+	}
+
+	failed, _ := executions.GetExecutionsByStatus(ctx, models.ExecutionStatusFailed)
+	if len(failed) != 1 || failed[0].PipelineID != created.PipelineID || failed[0].UserID != "analyst" ||
+		failed[0].Parameters["scheduled_at"] != due.Format(time.RFC3339) || failed[0].Error == "" || failed[0].CompletedAt == nil {
+		t.Errorf("Неудавшийся запуск не сохранен: %+v", failed)
+	}
+	stored, _ := pipelineRepo.GetPipeline(ctx, created.PipelineID)
+	if !stored.Schedule.NextRunAt.After(due) {
+		t.Errorf("Расписание не сдвинуто: %+v", stored.Schedule)
+	}
+}
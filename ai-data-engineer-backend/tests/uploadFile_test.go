@@ -2,6 +2,7 @@ package tests
 
 import (
 	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
 	"ai-data-engineer-backend/internal/service"
 	"ai-data-engineer-backend/pkg/client"
 	"ai-data-engineer-backend/pkg/logger"
@@ -68,8 +69,8 @@ func createTestServices(t *testing.T) (*service.FileService, logger.Logger) {
 		t.Fatalf("Не удалось создать MinIO клиент: %v", err)
 	}
 
-	// Создаем реальный FileService с реальным MinIO клиентом
-	fileService := service.NewFileService(minioClient, testLogger)
+	// Создаем реальный FileService с реальным MinIO клиентом и метаданными в памяти
+	fileService := service.NewFileService(minioClient, memory.NewFileRepository(), service.NewBlobStore(minioClient, memory.NewBlobRepository(), testLogger), service.FileServiceOptions{}, testLogger)
 
 	return fileService, testLogger
 }
@@ -0,0 +1,404 @@
+package tests
+
+import (
+	"ai-data-engineer-backend/domain/models"
+	repository "ai-data-engineer-backend/domain/repo"
+	"ai-data-engineer-backend/internal/api/handlers"
+	"ai-data-engineer-backend/internal/repository/memory"
+	"ai-data-engineer-backend/internal/service"
+	"ai-data-engineer-backend/pkg/client"
+	"ai-data-engineer-backend/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestResumableUpload проверяет загрузку частями в произвольном порядке с повтором части
+func TestResumableUpload(t *testing.T) {
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	fileRepo := memory.NewFileRepository()
+	blobs := service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger)
+	uploadService := service.NewUploadService(storage, memory.NewUploadSessionRepository(), fileRepo, blobs, service.UploadOptions{}, testLogger)
+	fileHandler := handlers.NewFileHandler(service.NewFileService(storage, fileRepo, blobs, service.FileServiceOptions{}, testLogger), testLogger)
+	uploadHandler := handlers.NewUploadHandler(uploadService, testLogger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	files := router.Group("/api/v1/files")
+	files.GET("/:id", fileHandler.GetFileInfo)
+	files.POST("/uploads", uploadHandler.InitUpload)
+	files.GET("/uploads/:id", uploadHandler.GetUpload)
+	files.PUT("/uploads/:id/parts/:number", uploadHandler.UploadPart)
+	files.POST("/uploads/:id/complete", uploadHandler.CompleteUpload)
+
+	first := bytes.Repeat([]byte("id,value\n"), (5<<20)/9+1)
+	second := []byte("1,last\n")
+	content := append(append([]byte{}, first...), second...)
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("X-User-ID", "analyst")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	initBody, _ := json.Marshal(models.UploadInitRequest{UserID: "analyst", Filename: "extract.csv", Size: int64(len(content))})
+	rr := do("POST", "/api/v1/files/uploads", initBody)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получили %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var session models.UploadSession
+	json.Unmarshal(rr.Body.Bytes(), &session)
+	base := "/api/v1/files/uploads/" + session.ID
+
+	// Последняя часть загружается первой, затем повторно — после "обрыва"
+	for i := 0; i < 2; i++ {
+		if rr := do("PUT", base+"/parts/2", second); rr.Code != http.StatusOK {
+			t.Fatalf("Не удалось загрузить часть 2: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	// Без первой части загрузку завершить нельзя
+	if rr := do("POST", base+"/complete", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус %d при неполной загрузке, получили %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = do("GET", base, nil)
+	json.Unmarshal(rr.Body.Bytes(), &session)
+	if len(session.Parts) != 1 || session.Parts[0].Number != 2 {
+		t.Fatalf("Ожидалась одна загруженная часть 2, получили %+v", session.Parts)
+	}
+
+	if rr := do("PUT", base+"/parts/1", first); rr.Code != http.StatusOK {
+		t.Fatalf("Не удалось загрузить часть 1: %d %s", rr.Code, rr.Body.String())
+	}
+	rr = do("POST", base+"/complete", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получили %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var uploaded models.FileUploadResponse
+	json.Unmarshal(rr.Body.Bytes(), &uploaded)
+
+	metadata, err := fileRepo.GetFile(context.Background(), uploaded.FileID)
+	if err != nil {
+		t.Fatalf("Метаданные файла не сохранены: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if metadata.Size != int64(len(content)) || metadata.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Неверные размер или контрольная сумма: %d, %s", metadata.Size, metadata.Checksum)
+	}
+	stored, _ := storage.DownloadFileAsBytes(context.Background(), metadata.Bucket, metadata.Path)
+	if !bytes.Equal(stored, content) {
+		t.Errorf("Собранный файл не совпадает с исходным")
+	}
+
+	// Повторное завершение возвращает тот же файл
+	rr = do("POST", base+"/complete", nil)
+	var repeated models.FileUploadResponse
+	json.Unmarshal(rr.Body.Bytes(), &repeated)
+	if rr.Code != http.StatusOK || repeated.FileID != uploaded.FileID {
+		t.Errorf("Ожидался тот же файл %s, получили %d %s", uploaded.FileID, rr.Code, rr.Body.String())
+	}
+
+	// Чужая сессия не видна
+	req := httptest.NewRequest("GET", base, nil)
+	req.Header.Set("X-User-ID", "intruder")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус %d для чужой сессии, получили %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// flakyStorage хранилище, которое не отдает объекты первые failDownloads раз
+// и не копирует их первые failCopies раз
+type flakyStorage struct {
+	service.MultipartStorage
+	failDownloads int
+	failCopies    int
+}
+
+func (s *flakyStorage) CopyFile(ctx context.Context, bucket, srcObject, dstObject string) error {
+	if s.failCopies > 0 {
+		s.failCopies--
+		return errors.New("connection reset")
+	}
+	return s.MultipartStorage.CopyFile(ctx, bucket, srcObject, dstObject)
+}
+
+func (s *flakyStorage) DownloadFile(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	if s.failDownloads > 0 {
+		s.failDownloads--
+		return nil, errors.New("connection reset")
+	}
+	return s.MultipartStorage.DownloadFile(ctx, bucket, objectName)
+}
+
+// flakyFileRepository репозиторий, который не обновляет файлы первые failUpdates раз
+type flakyFileRepository struct {
+	repository.FileRepository
+	failUpdates int
+}
+
+func (r *flakyFileRepository) UpdateFile(ctx context.Context, file *models.FileMetadata) error {
+	if r.failUpdates > 0 {
+		r.failUpdates--
+		return models.NewDatabaseError("Не удалось обновить метаданные файла", errors.New("connection reset"))
+	}
+	return r.FileRepository.UpdateFile(ctx, file)
+}
+
+// TestCompleteUploadResume проверяет, что повтор завершения после сбоя продолжает сборку
+// с собранного объекта и регистрирует один файл с одной ссылкой на содержимое
+func TestCompleteUploadResume(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	filesystem, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	storage := &flakyStorage{MultipartStorage: filesystem, failDownloads: 1}
+	fileRepo := &flakyFileRepository{FileRepository: memory.NewFileRepository(), failUpdates: 1}
+	blobRepo := memory.NewBlobRepository()
+	uploadService := service.NewUploadService(storage, memory.NewUploadSessionRepository(), fileRepo,
+		service.NewBlobStore(storage, blobRepo, testLogger), service.UploadOptions{}, testLogger)
+
+	content := []byte("id,value\n1,a\n")
+	session, err := uploadService.InitUpload(ctx, "analyst", "data.csv", int64(len(content)))
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	if _, err := uploadService.UploadPart(ctx, "intruder", session.ID, 1, bytes.NewReader(content), int64(len(content))); err == nil {
+		t.Error("Ожидалась ошибка загрузки части в чужую сессию")
+	}
+	if _, err := uploadService.UploadPart(ctx, "analyst", session.ID, 1, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Не удалось загрузить часть: %v", err)
+	}
+
+	// Первая попытка падает на контрольной сумме, вторая — на обновлении метаданных
+	for attempt := 1; attempt <= 2; attempt++ {
+		if _, err := uploadService.CompleteUpload(ctx, "analyst", session.ID); err == nil {
+			t.Fatalf("Попытка %d: ожидалась ошибка", attempt)
+		}
+		stored, _ := uploadService.GetUpload(ctx, "analyst", session.ID)
+		if stored.Status != models.UploadSessionStatusCompleting {
+			t.Errorf("Попытка %d: ожидался статус completing, получили %s", attempt, stored.Status)
+		}
+	}
+	metadata, err := uploadService.CompleteUpload(ctx, "analyst", session.ID)
+	if err != nil {
+		t.Fatalf("Повтор завершения не удался: %v", err)
+	}
+
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	if metadata.Status != models.FileStatusUploaded || metadata.Checksum != checksum {
+		t.Errorf("Неожиданные метаданные файла: %+v", metadata)
+	}
+	if files, _ := fileRepo.GetFilesByUser(ctx, "analyst", 10, 0); len(files) != 1 {
+		t.Errorf("Ожидался 1 файл, получили %d", len(files))
+	}
+	if blob, err := blobRepo.GetBlob(ctx, checksum); err != nil || blob.RefCount != 1 {
+		t.Errorf("Ожидалась одна ссылка на содержимое: %+v, %v", blob, err)
+	}
+	if stored, _ := filesystem.DownloadFileAsBytes(ctx, metadata.Bucket, metadata.Path); !bytes.Equal(stored, content) {
+		t.Errorf("Собранный файл не совпадает с исходным")
+	}
+}
+
+// TestCompleteUploadAcrossInstances проверяет, что сессию, которую завершает работающий экземпляр,
+// другой экземпляр не трогает, а после остановки владельца продолжает завершение без второй
+// ссылки на содержимое
+func TestCompleteUploadAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	filesystem, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	leases := memory.NewLeaseRepository()
+	busyCtx, stopBusy := context.WithCancel(ctx)
+	defer stopBusy()
+	busy := service.NewInstance(leases, service.InstanceOptions{ID: "busy", LeaseTTL: time.Minute}, testLogger)
+	if err := busy.Start(busyCtx); err != nil {
+		t.Fatalf("Не удалось зарегистрировать экземпляр: %v", err)
+	}
+	local := service.NewInstance(leases, service.InstanceOptions{ID: "local", LeaseTTL: time.Minute}, testLogger)
+
+	sessions := memory.NewUploadSessionRepository()
+	fileRepo := memory.NewFileRepository()
+	blobRepo := memory.NewBlobRepository()
+	storage := &flakyStorage{MultipartStorage: filesystem, failCopies: 1}
+	blobs := service.NewBlobStore(storage, blobRepo, testLogger)
+	first := service.NewUploadService(storage, sessions, fileRepo, blobs, service.UploadOptions{Instance: busy}, testLogger)
+	second := service.NewUploadService(storage, sessions, fileRepo, blobs, service.UploadOptions{Instance: local}, testLogger)
+
+	content := []byte("id,value\n1,a\n")
+	session, err := first.InitUpload(ctx, "analyst", "data.csv", int64(len(content)))
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	if _, err := first.UploadPart(ctx, "analyst", session.ID, 1, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Не удалось загрузить часть: %v", err)
+	}
+	// Ссылка на содержимое получена, но объект blob не записан
+	if _, err := first.CompleteUpload(ctx, "analyst", session.ID); err == nil {
+		t.Fatal("Ожидалась ошибка записи blob")
+	}
+	stored, _ := sessions.GetSession(ctx, session.ID)
+	if stored.Status != models.UploadSessionStatusCompleting || !stored.BlobAcquired || stored.Owner != "" {
+		t.Fatalf("Ожидалась сессия completing без владельца с полученной ссылкой: %+v", stored)
+	}
+
+	// Экземпляр busy снова завершает сессию
+	if ok, err := sessions.ClaimSession(ctx, session.ID, "busy", ""); !ok || err != nil {
+		t.Fatalf("Не удалось передать сессию: %v, %v", ok, err)
+	}
+	_, err = second.CompleteUpload(ctx, "analyst", session.ID)
+	if appErr, ok := models.IsAppError(err); !ok || appErr.HTTPCode != http.StatusConflict {
+		t.Fatalf("Ожидался конфликт с работающим владельцем, получили %v", err)
+	}
+	if err := second.AbortUpload(ctx, "analyst", session.ID); err == nil {
+		t.Error("Ожидался отказ в отмене завершаемой сессии")
+	}
+
+	stopBusy()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if holder, _ := leases.LeaseHolder(ctx, "instance:busy"); holder == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Аренда остановленного экземпляра не освобождена")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	metadata, err := second.CompleteUpload(ctx, "analyst", session.ID)
+	if err != nil {
+		t.Fatalf("Завершение после остановки владельца не удалось: %v", err)
+	}
+	if blob, err := blobRepo.GetBlob(ctx, metadata.Checksum); err != nil || blob.RefCount != 1 {
+		t.Errorf("Ожидалась одна ссылка на содержимое: %+v, %v", blob, err)
+	}
+	if data, _ := filesystem.DownloadFileAsBytes(ctx, metadata.Bucket, metadata.Path); !bytes.Equal(data, content) {
+		t.Errorf("Собранный файл не совпадает с исходным")
+	}
+	if stored, _ := sessions.GetSession(ctx, session.ID); stored.Status != models.UploadSessionStatusCompleted || stored.Owner != "local" {
+		t.Errorf("Ожидалась сессия completed экземпляра local: %+v", stored)
+	}
+}
+
+func TestExpireUploadSessions(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	sessions := memory.NewUploadSessionRepository()
+	uploadService := service.NewUploadService(storage, sessions, memory.NewFileRepository(), service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger), service.UploadOptions{SessionTTL: time.Millisecond}, testLogger)
+
+	session, err := uploadService.InitUpload(ctx, "user", "data.csv", 0)
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	// Сессию начали завершать после того, как janitor получил список истекших
+	completing, err := uploadService.InitUpload(ctx, "user", "other.csv", 0)
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	sessions = &completingSessions{UploadSessionRepository: sessions, sessionID: completing.ID}
+	uploadService = service.NewUploadService(storage, sessions, memory.NewFileRepository(), service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger), service.UploadOptions{SessionTTL: time.Millisecond}, testLogger)
+
+	expired, err := uploadService.ExpireSessions(ctx)
+	if err != nil || expired != 1 {
+		t.Fatalf("Ожидалась 1 истекшая сессия, получили %d, %v", expired, err)
+	}
+	stored, _ := sessions.GetSession(ctx, session.ID)
+	if stored.Status != models.UploadSessionStatusExpired {
+		t.Errorf("Ожидался статус expired, получили %s", stored.Status)
+	}
+	if stored, _ := sessions.GetSession(ctx, completing.ID); stored.Status != models.UploadSessionStatusCompleting {
+		t.Errorf("Завершаемая сессия не должна истекать, получили статус %s", stored.Status)
+	}
+}
+
+// completingSessions переводит сессию sessionID в completing сразу после выборки истекших сессий,
+// как параллельный CompleteUpload
+type completingSessions struct {
+	repository.UploadSessionRepository
+	sessionID string
+}
+
+func (r *completingSessions) GetExpiredSessions(ctx context.Context, before time.Time) ([]*models.UploadSession, error) {
+	sessions, err := r.UploadSessionRepository.GetExpiredSessions(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	session, err := r.GetSession(ctx, r.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	session.Status = models.UploadSessionStatusCompleting
+	if _, err := r.TransitionSession(ctx, session, models.UploadSessionStatusActive); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TestCompleteUploadSizeLimit проверяет, что размер собранного файла проверяется повторно:
+// части, каждая из которых прошла проверку, вместе могут превысить ограничение
+func TestCompleteUploadSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	testLogger := logger.NewLogger("error", "json", "stdout")
+	storage, err := client.NewFilesystemClient(t.TempDir(), testLogger)
+	if err != nil {
+		t.Fatalf("Не удалось создать файловое хранилище: %v", err)
+	}
+	sessions := memory.NewUploadSessionRepository()
+	fileRepo := memory.NewFileRepository()
+	blobs := service.NewBlobStore(storage, memory.NewBlobRepository(), testLogger)
+	// Части загружены по снимку сессии, в котором ограничение еще не превышено
+	unlimited := service.NewUploadService(storage, sessions, fileRepo, blobs, service.UploadOptions{}, testLogger)
+	limited := service.NewUploadService(storage, sessions, fileRepo, blobs, service.UploadOptions{MaxUploadSize: 10}, testLogger)
+
+	content := []byte("id,value\n1,a\n2,b\n")
+	session, err := unlimited.InitUpload(ctx, "analyst", "data.csv", 0)
+	if err != nil {
+		t.Fatalf("Не удалось создать сессию: %v", err)
+	}
+	if _, err := unlimited.UploadPart(ctx, "analyst", session.ID, 1, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Не удалось загрузить часть: %v", err)
+	}
+
+	_, err = limited.CompleteUpload(ctx, "analyst", session.ID)
+	if appErr, ok := models.IsAppError(err); !ok || appErr.HTTPCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Ожидалась ошибка 413, получили %v", err)
+	}
+	stored, _ := sessions.GetSession(ctx, session.ID)
+	if stored.Status != models.UploadSessionStatusAborted {
+		t.Errorf("Ожидался статус aborted, получили %s", stored.Status)
+	}
+	if exists, _ := storage.FileExists(ctx, stored.Bucket, stored.Path); exists {
+		t.Error("Собранный объект сверх ограничения не удален")
+	}
+	if files, _ := fileRepo.GetFilesByUser(ctx, "analyst", 10, 0); len(files) != 0 {
+		t.Errorf("Файл сверх ограничения зарегистрирован: %d", len(files))
+	}
+}
@@ -4,10 +4,11 @@ export const apiService = {
   // Загрузка файла
   async uploadFile(file, fileType, userId = "default_user", targetDb = "postgres") {
     const formData = new FormData();
-    formData.append("file", file);
+    // Поля формы должны идти до файла: сервер читает тело потоково
     formData.append("file_type", fileType);
     formData.append("user_id", userId);
     formData.append("target_db", targetDb);
+    formData.append("file", file);
 
     const response = await fetch(`${API_BASE_URL}/files/upload`, {
       method: "POST",